@@ -69,50 +69,92 @@ func main() {
 	contentRepo := repositories.NewContentRepository(db)
 	supportRepo := repositories.NewSupportRepository(db)
 	notificationRepo := repositories.NewNotificationRepository(db)
+	templateRepo := repositories.NewNotificationTemplateRepository(db)
 	deviceTokenRepo := repositories.NewDeviceTokenRepository(db)
 	preferenceRepo := repositories.NewPreferenceRepository(db)
+	healthRepo := repositories.NewHealthRepository(db)
+	adminRepo := repositories.NewAdminRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	syncRepo := repositories.NewSyncRepository(db)
+	escalationRepo := repositories.NewEscalationRepository(db)
+	refillRepo := repositories.NewRefillRepository(db)
 
 	smsSender, err := newSmsSender(cfg, logger)
 	if err != nil {
 		logger.Fatal("sms sender init failed", zap.Error(err))
 	}
-	notificationSender := services.ConsoleNotificationSender{Logger: logger}
-	notificationService := services.NewNotificationService(cfg.Notifications, db, notificationRepo, preferenceRepo, notificationSender, logger)
+	notificationSender, err := services.NewConfiguredNotificationSender(cfg, deviceTokenRepo, userRepo, logger)
+	if err != nil {
+		logger.Fatal("notification sender init failed", zap.Error(err))
+	}
+	notificationService := services.NewNotificationService(cfg.Notifications, notificationRepo, preferenceRepo, medicineRepo, appointmentRepo, notificationSender, logger)
 
-	authService := services.NewAuthService(cfg, authRepo, userRepo, redisClient, smsSender)
-	userService := services.NewUserService(userRepo, profileRepo, deviceTokenRepo, preferenceRepo, notificationService)
+	auditService := services.NewAuditService(auditRepo, cfg.Notifications.Timezone, logger)
+	authService := services.NewAuthService(cfg, authRepo, userRepo, redisClient, smsSender, auditService)
 	caregiverService := services.NewCaregiverService(caregiverRepo)
-	medicineService := services.NewMedicineService(medicineRepo, notificationService)
-	intakeService := services.NewIntakeService(intakeRepo, notificationService)
-	appointmentService := services.NewAppointmentService(appointmentRepo, notificationService)
+	medicineService := services.NewMedicineService(medicineRepo, preferenceRepo, notificationService, cfg.Notifications.Timezone)
+	appointmentService := services.NewAppointmentService(appointmentRepo, notificationService, syncRepo)
+	userService := services.NewUserService(userRepo, profileRepo, deviceTokenRepo, preferenceRepo, notificationService, medicineService, appointmentService, auditService)
+	refillService := services.NewRefillService(refillRepo, medicineRepo, preferenceRepo, cfg.Refill, cfg.Notifications.Timezone)
+	intakeService := services.NewIntakeService(intakeRepo, medicineRepo, preferenceRepo, notificationService, refillService, cfg.Intake, cfg.Notifications.Timezone)
+	adherenceService := services.NewAdherenceService(medicineRepo, intakeRepo, preferenceRepo, cfg.Notifications.Timezone)
+	healthService := services.NewHealthService(healthRepo)
 	contentService := services.NewContentService(contentRepo)
 	supportService := services.NewSupportService(supportRepo)
+	escalationService := services.NewEscalationService(escalationRepo, userRepo, cfg.Escalation)
 
 	router := httptransport.NewRouter(httptransport.Dependencies{
-		Config:              cfg,
-		Logger:              logger,
-		DB:                  db,
-		Redis:               redisClient,
-		AuthService:         authService,
-		UserService:         userService,
-		CaregiverService:    caregiverService,
-		MedicineService:     medicineService,
-		IntakeService:       intakeService,
-		HealthService:       services.NewHealthService(),
-		AppointmentService:  appointmentService,
-		ContentService:      contentService,
-		NotificationService: notificationService,
-		SupportService:      supportService,
-		AdminService:        services.NewAdminService(),
-		AuditService:        services.NewAuditService(),
+		Config:                      cfg,
+		Logger:                      logger,
+		DB:                          db,
+		Redis:                       redisClient,
+		AuthService:                 authService,
+		UserService:                 userService,
+		CaregiverService:            caregiverService,
+		MedicineService:             medicineService,
+		IntakeService:               intakeService,
+		AdherenceService:            adherenceService,
+		HealthService:               healthService,
+		AppointmentService:          appointmentService,
+		ContentService:              contentService,
+		NotificationService:         notificationService,
+		NotificationTemplateService: services.NewNotificationTemplateService(templateRepo, preferenceRepo, medicineRepo, appointmentRepo, deviceTokenRepo, notificationSender, cfg.Notifications.Timezone),
+		SupportService:              supportService,
+		AdminService:                services.NewAdminService(authService, adminRepo, adherenceService, auditService),
+		AuditService:                auditService,
+		LineService:                 services.NewLineService(cfg.Line, userRepo, preferenceRepo, redisClient, intakeService, cfg.Notifications.DefaultLocale, logger),
+		SyncService:                 services.NewSyncService(syncRepo, intakeService, healthService),
+		EscalationService:           escalationService,
+		RefillService:               refillService,
 	})
 
 	addr := server.Address(cfg.HTTP.Host, cfg.HTTP.Port)
 	srv := server.New(addr, router, cfg.HTTP.ReadTimeout, cfg.HTTP.WriteTimeout, cfg.HTTP.IdleTimeout)
 
-	workerCtx, workerCancel := context.WithCancel(context.Background())
-	worker := jobs.NewNotificationWorker(notificationService, cfg.Notifications.JobInterval, logger)
-	go worker.Start(workerCtx)
+	// Jobs can also run in cmd/worker; leader election keeps a single active
+	// scheduler either way. JOBS_RUN_IN_API=false leaves them to the worker.
+	jobsCtx, jobsCancel := context.WithCancel(context.Background())
+	jobsDone := make(chan struct{})
+	if cfg.Jobs.RunInAPI {
+		scheduler, err := jobs.NewDefaultScheduler(jobs.Dependencies{
+			Config:        cfg,
+			DB:            db,
+			Logger:        logger,
+			Notifications: notificationService,
+			Medicines:     medicineService,
+			Intake:        intakeService,
+			Escalation:    escalationService,
+		})
+		if err != nil {
+			logger.Fatal("job scheduler init failed", zap.Error(err))
+		}
+		go func() {
+			defer close(jobsDone)
+			scheduler.Start(jobsCtx)
+		}()
+	} else {
+		close(jobsDone)
+	}
 
 	go func() {
 		logger.Info("server started", zap.String("addr", addr))
@@ -130,7 +172,7 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
-	workerCancel()
+	jobsCancel()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -138,6 +180,10 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("server shutdown failed", zap.Error(err))
 	}
+	select {
+	case <-jobsDone:
+	case <-ctx.Done():
+	}
 	logger.Info("server stopped")
 }
 
@@ -129,18 +129,27 @@ func seedMedicineCategories(ctx context.Context, dbConn *gorm.DB) error {
 }
 
 func seedNotificationTemplates(ctx context.Context, dbConn *gorm.DB) error {
+	en, th := constants.LocaleEnglish, constants.LocaleThai
 	templates := []db.NotificationTemplate{
-		{Code: constants.TemplateMedBeforeMeal5Min, Title: "Medicine reminder", Body: "Reminder: take your medicine before meal in 5 minutes.", IsActive: true, CreatedAt: time.Now().UTC()},
-		{Code: constants.TemplateMedBeforeMeal20Min, Title: "Medicine reminder", Body: "Reminder: please take your medicine if you have not yet.", IsActive: true, CreatedAt: time.Now().UTC()},
-		{Code: constants.TemplateMedAfterMealNow, Title: "Medicine reminder", Body: "หลังทานอาหารอย่าลืมทานยา", IsActive: true, CreatedAt: time.Now().UTC()},
-		{Code: constants.TemplateAppt5Days, Title: "Appointment reminder", Body: "Upcoming appointment in 5 days.", IsActive: true, CreatedAt: time.Now().UTC()},
-		{Code: constants.TemplateAppt1Day, Title: "Appointment reminder", Body: "Upcoming appointment tomorrow.", IsActive: true, CreatedAt: time.Now().UTC()},
-		{Code: constants.TemplateWeeklyHealthLog, Title: "Weekly health log", Body: "Please complete your weekly health behavior log.", IsActive: true, CreatedAt: time.Now().UTC()},
+		{Code: constants.TemplateMedBeforeMeal5Min, Locale: en, Title: "Medicine reminder", Body: "Take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} at {{time .dose_at}}."},
+		{Code: constants.TemplateMedBeforeMeal20Min, Locale: en, Title: "Medicine reminder", Body: "Have you taken {{.medicine_name}} {{.dosage}}? It was due at {{time .dose_at}}."},
+		{Code: constants.TemplateMedAfterMealNow, Locale: en, Title: "Medicine reminder", Body: "Time to take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}}."},
+		{Code: constants.TemplateAppt5Days, Locale: en, Title: "Appointment reminder", Body: "{{.appointment_title}} on {{date .appointment_at}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}."},
+		{Code: constants.TemplateAppt1Day, Locale: en, Title: "Appointment reminder", Body: "Tomorrow: {{.appointment_title}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}."},
+		{Code: constants.TemplateWeeklyHealthLog, Locale: en, Title: "Weekly health log", Body: "Please complete your weekly health behavior log."},
+		{Code: constants.TemplateMedBeforeMeal5Min, Locale: th, Title: "เตือนทานยา", Body: "เตรียมทานยา {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} เวลา {{time .dose_at}}"},
+		{Code: constants.TemplateMedBeforeMeal20Min, Locale: th, Title: "เตือนทานยา", Body: "ทานยา {{.medicine_name}} {{.dosage}} ของเวลา {{time .dose_at}} แล้วหรือยัง"},
+		{Code: constants.TemplateMedAfterMealNow, Locale: th, Title: "เตือนทานยา", Body: "ถึงเวลาทานยา {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}}"},
+		{Code: constants.TemplateAppt5Days, Locale: th, Title: "เตือนนัดหมาย", Body: "อีก 5 วันมีนัด {{.appointment_title}} วันที่ {{date .appointment_at}} เวลา {{time .appointment_at}}{{with .appointment_location}} ที่ {{.}}{{end}}"},
+		{Code: constants.TemplateAppt1Day, Locale: th, Title: "เตือนนัดหมาย", Body: "พรุ่งนี้มีนัด {{.appointment_title}} เวลา {{time .appointment_at}}{{with .appointment_location}} ที่ {{.}}{{end}}"},
+		{Code: constants.TemplateWeeklyHealthLog, Locale: th, Title: "บันทึกสุขภาพประจำสัปดาห์", Body: "กรุณาบันทึกพฤติกรรมสุขภาพประจำสัปดาห์ของคุณ"},
 	}
 
 	for _, tpl := range templates {
+		tpl.IsActive = true
+		tpl.CreatedAt = time.Now().UTC()
 		var count int64
-		if err := dbConn.WithContext(ctx).Model(&db.NotificationTemplate{}).Where("code = ?", tpl.Code).Count(&count).Error; err != nil {
+		if err := dbConn.WithContext(ctx).Model(&db.NotificationTemplate{}).Where("code = ? AND locale = ?", tpl.Code, tpl.Locale).Count(&count).Error; err != nil {
 			return err
 		}
 		if count == 0 {
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/database/postgres"
+	"github.com/ParkPawapon/mhp-be/internal/jobs"
+	"github.com/ParkPawapon/mhp-be/internal/logging"
+	"github.com/ParkPawapon/mhp-be/internal/observability"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config load failed: %v", err)
+	}
+
+	logger, err := logging.New(cfg.App.LogLevel)
+	if err != nil {
+		log.Fatalf("logger init failed: %v", err)
+	}
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	shutdownTracer, err := observability.InitTracerProvider(cfg.Observability)
+	if err != nil {
+		logger.Fatal("otel init failed", zap.Error(err))
+	}
+	defer func() {
+		_ = shutdownTracer(context.Background())
+	}()
+
+	db, err := postgres.New(cfg.DB)
+	if err != nil {
+		logger.Fatal("database connection failed", zap.Error(err))
+	}
+
+	userRepo := repositories.NewUserRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
+	deviceTokenRepo := repositories.NewDeviceTokenRepository(db)
+	preferenceRepo := repositories.NewPreferenceRepository(db)
+	medicineRepo := repositories.NewMedicineRepository(db)
+	intakeRepo := repositories.NewIntakeRepository(db)
+	appointmentRepo := repositories.NewAppointmentRepository(db)
+	escalationRepo := repositories.NewEscalationRepository(db)
+	refillRepo := repositories.NewRefillRepository(db)
+
+	notificationSender, err := services.NewConfiguredNotificationSender(cfg, deviceTokenRepo, userRepo, logger)
+	if err != nil {
+		logger.Fatal("notification sender init failed", zap.Error(err))
+	}
+	notificationService := services.NewNotificationService(cfg.Notifications, notificationRepo, preferenceRepo, medicineRepo, appointmentRepo, notificationSender, logger)
+	medicineService := services.NewMedicineService(medicineRepo, preferenceRepo, notificationService, cfg.Notifications.Timezone)
+	refillService := services.NewRefillService(refillRepo, medicineRepo, preferenceRepo, cfg.Refill, cfg.Notifications.Timezone)
+	intakeService := services.NewIntakeService(intakeRepo, medicineRepo, preferenceRepo, notificationService, refillService, cfg.Intake, cfg.Notifications.Timezone)
+	escalationService := services.NewEscalationService(escalationRepo, userRepo, cfg.Escalation)
+
+	scheduler, err := jobs.NewDefaultScheduler(jobs.Dependencies{
+		Config:        cfg,
+		DB:            db,
+		Logger:        logger,
+		Notifications: notificationService,
+		Medicines:     medicineService,
+		Intake:        intakeService,
+		Escalation:    escalationService,
+	})
+	if err != nil {
+		logger.Fatal("job scheduler init failed", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("worker started")
+		scheduler.Start(ctx)
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		logger.Warn("worker shutdown timed out")
+	}
+	logger.Info("worker stopped")
+}
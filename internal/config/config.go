@@ -18,10 +18,16 @@ type Config struct {
 	JWT           JWTConfig
 	OTP           OTPConfig
 	SMS           SMSConfig
+	Push          PushConfig
+	Line          LineConfig
 	RateLimit     RateLimitConfig
 	CORS          CORSConfig
 	Observability ObservabilityConfig
 	Notifications NotificationConfig
+	Intake        IntakeConfig
+	Escalation    EscalationConfig
+	Refill        RefillConfig
+	Jobs          JobsConfig
 }
 
 type AppConfig struct {
@@ -95,10 +101,72 @@ type ThaiBulkSMSConfig struct {
 	Timeout     time.Duration `env:"THAIBULKSMS_TIMEOUT" envDefault:"10s"`
 }
 
+type PushConfig struct {
+	Provider string        `env:"PUSH_PROVIDER" envDefault:"console"`
+	Timeout  time.Duration `env:"PUSH_TIMEOUT" envDefault:"10s"`
+	FCM      FCMConfig
+	APNs     APNsConfig
+}
+
+// FCMConfig configures FCM HTTP v1. TokenURL overrides the token_uri of the
+// service account file, for running against a stub server.
+type FCMConfig struct {
+	BaseURL         string `env:"FCM_BASE_URL" envDefault:"https://fcm.googleapis.com"`
+	ProjectID       string `env:"FCM_PROJECT_ID"`
+	CredentialsFile string `env:"FCM_CREDENTIALS_FILE"`
+	TokenURL        string `env:"FCM_TOKEN_URL"`
+}
+
+type APNsConfig struct {
+	BaseURL string `env:"APNS_BASE_URL" envDefault:"https://api.push.apple.com"`
+	KeyID   string `env:"APNS_KEY_ID"`
+	TeamID  string `env:"APNS_TEAM_ID"`
+	Topic   string `env:"APNS_TOPIC"`
+	KeyFile string `env:"APNS_KEY_FILE"`
+}
+
+func (c FCMConfig) Enabled() bool {
+	return strings.TrimSpace(c.ProjectID) != "" && strings.TrimSpace(c.CredentialsFile) != ""
+}
+
+func (c APNsConfig) Enabled() bool {
+	return strings.TrimSpace(c.KeyID) != "" && strings.TrimSpace(c.TeamID) != "" &&
+		strings.TrimSpace(c.Topic) != "" && strings.TrimSpace(c.KeyFile) != ""
+}
+
+// LineConfig holds the Messaging API channel (push, webhook) and the LINE
+// Login channel used for account linking.
+type LineConfig struct {
+	MessagingBaseURL   string        `env:"LINE_MESSAGING_BASE_URL" envDefault:"https://api.line.me"`
+	ChannelAccessToken string        `env:"LINE_CHANNEL_ACCESS_TOKEN"`
+	ChannelSecret      string        `env:"LINE_CHANNEL_SECRET"`
+	LoginBaseURL       string        `env:"LINE_LOGIN_BASE_URL" envDefault:"https://api.line.me"`
+	LoginAuthorizeURL  string        `env:"LINE_LOGIN_AUTHORIZE_URL" envDefault:"https://access.line.me/oauth2/v2.1/authorize"`
+	LoginChannelID     string        `env:"LINE_LOGIN_CHANNEL_ID"`
+	LoginChannelSecret string        `env:"LINE_LOGIN_CHANNEL_SECRET"`
+	LoginRedirectURI   string        `env:"LINE_LOGIN_REDIRECT_URI"`
+	LinkStateTTL       time.Duration `env:"LINE_LINK_STATE_TTL" envDefault:"10m"`
+	Timeout            time.Duration `env:"LINE_TIMEOUT" envDefault:"10s"`
+}
+
+func (c LineConfig) MessagingEnabled() bool {
+	return strings.TrimSpace(c.ChannelAccessToken) != ""
+}
+
+func (c LineConfig) LoginEnabled() bool {
+	return strings.TrimSpace(c.LoginChannelID) != "" && strings.TrimSpace(c.LoginChannelSecret) != "" &&
+		strings.TrimSpace(c.LoginRedirectURI) != ""
+}
+
 type RateLimitConfig struct {
 	OTPPerPhone int           `env:"OTP_RATE_LIMIT_PER_PHONE" envDefault:"5"`
 	OTPPerIP    int           `env:"OTP_RATE_LIMIT_PER_IP" envDefault:"5"`
 	Window      time.Duration `env:"OTP_RATE_LIMIT_WINDOW" envDefault:"1m"`
+
+	StaffLoginMaxAttempts int           `env:"STAFF_LOGIN_MAX_ATTEMPTS" envDefault:"5"`
+	StaffLoginPerIP       int           `env:"STAFF_LOGIN_RATE_LIMIT_PER_IP" envDefault:"20"`
+	StaffLoginWindow      time.Duration `env:"STAFF_LOGIN_WINDOW" envDefault:"15m"`
+	StaffLockoutDuration  time.Duration `env:"STAFF_LOGIN_LOCKOUT" envDefault:"15m"`
 }
 
 type CORSConfig struct {
@@ -120,6 +188,58 @@ type NotificationConfig struct {
 	WeeklyReminderHour   int           `env:"NOTIFICATION_WEEKLY_HOUR" envDefault:"19"`
 	WeeklyReminderMinute int           `env:"NOTIFICATION_WEEKLY_MINUTE" envDefault:"0"`
 	Timezone             string        `env:"NOTIFICATION_TIMEZONE" envDefault:"Asia/Bangkok"`
+	DefaultLocale        string        `env:"NOTIFICATION_DEFAULT_LOCALE" envDefault:"th"`
+	MaxAttempts          int           `env:"NOTIFICATION_MAX_ATTEMPTS" envDefault:"5"`
+	RetryBaseDelay       time.Duration `env:"NOTIFICATION_RETRY_BASE_DELAY" envDefault:"1m"`
+	RetryMaxDelay        time.Duration `env:"NOTIFICATION_RETRY_MAX_DELAY" envDefault:"1h"`
+	LeaseDuration        time.Duration `env:"NOTIFICATION_LEASE_DURATION" envDefault:"5m"`
+	WorkerConcurrency    int           `env:"NOTIFICATION_WORKER_CONCURRENCY" envDefault:"8"`
+}
+
+// IntakeConfig controls automatic MISSED marking: a dose with no intake record
+// MissedGrace after its time slot is marked MISSED, looking back
+// MissedLookbackDays local days. Patients may correct or delete a record for
+// CorrectionWindow after it was created; zero disables the limit. A TAKEN dose
+// within OnTimeWindow of its time slot is ON_TIME, otherwise EARLY or LATE;
+// client-reported taken_at may be at most MaxTakenOffset from the time slot.
+type IntakeConfig struct {
+	MissedGrace        time.Duration `env:"INTAKE_MISSED_GRACE" envDefault:"2h"`
+	MissedLookbackDays int           `env:"INTAKE_MISSED_LOOKBACK_DAYS" envDefault:"2"`
+	CorrectionWindow   time.Duration `env:"INTAKE_CORRECTION_WINDOW" envDefault:"24h"`
+	OnTimeWindow       time.Duration `env:"INTAKE_ON_TIME_WINDOW" envDefault:"1h"`
+	MaxTakenOffset     time.Duration `env:"INTAKE_MAX_TAKEN_OFFSET" envDefault:"24h"`
+}
+
+// EscalationConfig sets the default missed-dose escalation thresholds, used
+// for patients without their own policy. A streak of CaregiverThreshold
+// consecutive MISSED doses notifies assigned caregivers and NurseThreshold the
+// patient's nurse. Only doses due in the last LookbackDays days are counted.
+type EscalationConfig struct {
+	Enabled            bool `env:"ESCALATION_ENABLED" envDefault:"true"`
+	CaregiverThreshold int  `env:"ESCALATION_CAREGIVER_THRESHOLD" envDefault:"1"`
+	NurseThreshold     int  `env:"ESCALATION_NURSE_THRESHOLD" envDefault:"3"`
+	LookbackDays       int  `env:"ESCALATION_LOOKBACK_DAYS" envDefault:"7"`
+}
+
+// RefillConfig sets when a patient is reminded to refill: once the stock on
+// hand covers fewer than LowSupplyDays days of scheduled doses.
+type RefillConfig struct {
+	LowSupplyDays int `env:"REFILL_LOW_SUPPLY_DAYS" envDefault:"7"`
+}
+
+// JobsConfig controls the background job scheduler. Schedules are either
+// "@every <duration>" or a five-field cron expression in NOTIFICATION_TIMEZONE.
+type JobsConfig struct {
+	RunInAPI                 bool          `env:"JOBS_RUN_IN_API" envDefault:"true"`
+	Disabled                 string        `env:"JOBS_DISABLED"`
+	LeaderLockKey            int64         `env:"JOBS_LEADER_LOCK_KEY" envDefault:"7240513"`
+	LeaderRetryInterval      time.Duration `env:"JOBS_LEADER_RETRY_INTERVAL" envDefault:"15s"`
+	WeeklyReminderSchedule   string        `env:"JOBS_WEEKLY_REMINDER_SCHEDULE" envDefault:"0 * * * *"`
+	MedicineReminderSchedule string        `env:"JOBS_MEDICINE_REMINDER_SCHEDULE" envDefault:"30 * * * *"`
+	MissedDoseSchedule       string        `env:"JOBS_MISSED_DOSE_SCHEDULE" envDefault:"@every 15m"`
+	EscalationSchedule       string        `env:"JOBS_ESCALATION_SCHEDULE" envDefault:"@every 15m"`
+	RunPruneSchedule         string        `env:"JOBS_RUN_PRUNE_SCHEDULE" envDefault:"15 3 * * *"`
+	RunRetention             time.Duration `env:"JOBS_RUN_RETENTION" envDefault:"720h"`
 }
 
 func Load() (Config, error) {
@@ -167,6 +287,29 @@ func (c Config) Validate() error {
 		}
 	}
 
+	if c.Line.MessagingEnabled() && strings.TrimSpace(c.Line.ChannelSecret) == "" {
+		return fmt.Errorf("LINE_CHANNEL_SECRET is required when LINE_CHANNEL_ACCESS_TOKEN is set")
+	}
+
+	if strings.EqualFold(strings.TrimSpace(c.Push.Provider), "push") {
+		if !c.Push.FCM.Enabled() && !c.Push.APNs.Enabled() {
+			return fmt.Errorf("FCM_PROJECT_ID/FCM_CREDENTIALS_FILE or APNS_KEY_ID/APNS_TEAM_ID/APNS_TOPIC/APNS_KEY_FILE are required")
+		}
+	}
+
+	if c.Escalation.CaregiverThreshold < 1 || c.Escalation.NurseThreshold < c.Escalation.CaregiverThreshold {
+		return fmt.Errorf("ESCALATION_CAREGIVER_THRESHOLD must be at least 1 and ESCALATION_NURSE_THRESHOLD not below it")
+	}
+	if locale := c.Notifications.DefaultLocale; locale != "th" && locale != "en" {
+		return fmt.Errorf("NOTIFICATION_DEFAULT_LOCALE must be th or en")
+	}
+	if c.Refill.LowSupplyDays < 1 {
+		return fmt.Errorf("REFILL_LOW_SUPPLY_DAYS must be at least 1")
+	}
+	if c.Jobs.RunRetention < 24*time.Hour {
+		return fmt.Errorf("JOBS_RUN_RETENTION must be at least 24h")
+	}
+
 	return nil
 }
 
@@ -227,3 +370,14 @@ func (c CORSConfig) HeadersList() []string {
 	}
 	return parts
 }
+
+func (c JobsConfig) DisabledList() []string {
+	if strings.TrimSpace(c.Disabled) == "" {
+		return nil
+	}
+	parts := strings.Split(c.Disabled, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
@@ -4,4 +4,7 @@ const (
 	RequestIDKey = "request_id"
 	ActorIDKey   = "actor_id"
 	RoleKey      = "role"
+
+	AuditActionKey = "audit_action"
+	AuditTargetKey = "audit_target_user_id"
 )
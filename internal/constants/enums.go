@@ -8,6 +8,8 @@ type AppointmentCategory string
 
 type AppointmentStatus string
 
+type AuditAction string
+
 const (
 	GenderMale   GenderType = "MALE"
 	GenderFemale GenderType = "FEMALE"
@@ -18,6 +20,8 @@ const (
 	MedTaken   MedIntakeStatus = "TAKEN"
 	MedMissed  MedIntakeStatus = "MISSED"
 	MedSkipped MedIntakeStatus = "SKIPPED"
+	// MedPending marks a dose with no intake record yet; it is never stored.
+	MedPending MedIntakeStatus = "PENDING"
 )
 
 const (
@@ -31,3 +35,21 @@ const (
 	ApptCompleted AppointmentStatus = "COMPLETED"
 	ApptCancelled AppointmentStatus = "CANCELLED"
 )
+
+const (
+	AuditLogin         AuditAction = "LOGIN"
+	AuditStaffLogin    AuditAction = "STAFF_LOGIN"
+	AuditPatientRead   AuditAction = "PATIENT_READ"
+	AuditPatientList   AuditAction = "PATIENT_LIST"
+	AuditProfileUpdate AuditAction = "PROFILE_UPDATE"
+	AuditCitizenIDView AuditAction = "CITIZEN_ID_VIEW"
+)
+
+var AuditActions = []string{
+	string(AuditLogin),
+	string(AuditStaffLogin),
+	string(AuditPatientRead),
+	string(AuditPatientList),
+	string(AuditProfileUpdate),
+	string(AuditCitizenIDView),
+}
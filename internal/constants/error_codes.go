@@ -9,6 +9,7 @@ const (
 	AuthOTPUsed            = "AUTH_OTP_USED"
 	AuthTokenInvalid       = "AUTH_TOKEN_INVALID"
 	AuthTokenExpired       = "AUTH_TOKEN_EXPIRED"
+	AuthAccountLocked      = "AUTH_ACCOUNT_LOCKED"
 
 	UserNotFound   = "USER_NOT_FOUND"
 	UserConflict   = "USER_CONFLICT"
@@ -18,17 +19,26 @@ const (
 	MedInvalid  = "MED_INVALID"
 	MedNotFound = "MED_NOT_FOUND"
 
+	IntakeNotFound         = "INTAKE_NOT_FOUND"
+	IntakeConflict         = "INTAKE_CONFLICT"
+	IntakeCorrectionClosed = "INTAKE_CORRECTION_CLOSED"
+
 	ApptInvalid  = "APPT_INVALID"
 	ApptNotFound = "APPT_NOT_FOUND"
 
 	HealthInvalid  = "HEALTH_INVALID"
 	HealthNotFound = "HEALTH_NOT_FOUND"
+	HealthConflict = "HEALTH_CONFLICT"
 
 	ContentInvalid  = "CONTENT_INVALID"
 	ContentNotFound = "CONTENT_NOT_FOUND"
 
 	AuditInvalid = "AUDIT_INVALID"
 
+	NotificationInvalid  = "NOTIFICATION_INVALID"
+	NotificationNotFound = "NOTIFICATION_NOT_FOUND"
+	NotificationConflict = "NOTIFICATION_CONFLICT"
+
 	RateLimited = "RATE_LIMITED"
 
 	ValidationFailed = "VALIDATION_FAILED"
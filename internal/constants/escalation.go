@@ -0,0 +1,9 @@
+package constants
+
+// Escalation levels, in the order they fire as a missed-dose streak grows.
+const (
+	EscalationLevelCaregiver = "CAREGIVER"
+	EscalationLevelNurse     = "NURSE"
+)
+
+const MaxEscalationThreshold = 50
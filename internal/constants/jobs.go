@@ -0,0 +1,9 @@
+package constants
+
+type JobRunStatus string
+
+const (
+	JobRunRunning   JobRunStatus = "RUNNING"
+	JobRunSucceeded JobRunStatus = "SUCCEEDED"
+	JobRunFailed    JobRunStatus = "FAILED"
+)
@@ -3,10 +3,12 @@ package constants
 type NotificationStatus string
 
 const (
-	NotificationPending   NotificationStatus = "PENDING"
-	NotificationSent      NotificationStatus = "SENT"
-	NotificationCancelled NotificationStatus = "CANCELLED"
-	NotificationFailed    NotificationStatus = "FAILED"
+	NotificationPending    NotificationStatus = "PENDING"
+	NotificationProcessing NotificationStatus = "PROCESSING"
+	NotificationSent       NotificationStatus = "SENT"
+	NotificationCancelled  NotificationStatus = "CANCELLED"
+	NotificationFailed     NotificationStatus = "FAILED"
+	NotificationDead       NotificationStatus = "DEAD"
 )
 
 const (
@@ -15,5 +17,39 @@ const (
 	TemplateMedAfterMealNow    = "MED_AFTER_MEAL_NOW"
 	TemplateAppt5Days          = "APPT_5D"
 	TemplateAppt1Day           = "APPT_1D"
+	TemplateApptReminder       = "APPT_REMINDER"
 	TemplateWeeklyHealthLog    = "WEEKLY_HEALTH_LOG"
+	TemplateMedLowSupply       = "MED_LOW_SUPPLY"
+
+	TemplateEscalationMissedCaregiver = "ESCALATION_MISSED_CAREGIVER"
+	TemplateEscalationMissedNurse     = "ESCALATION_MISSED_NURSE"
+)
+
+// Channels a user can receive notifications through.
+const (
+	NotificationChannelPush = "push"
+	NotificationChannelLine = "line"
+	NotificationChannelSMS  = "sms"
+)
+
+// Reminder lead-time defaults and bounds for user preferences.
+const (
+	DefaultBeforeMealLeadMinutes     = 5
+	DefaultBeforeMealFollowUpMinutes = 20
+	MaxBeforeMealLeadMinutes         = 60
+	MaxBeforeMealFollowUpMinutes     = 120
+	MaxAppointmentReminderDays       = 30
+	MaxAppointmentReminders          = 5
+)
+
+// Page size bounds of the notification inbox.
+const (
+	DefaultInboxLimit = 20
+	MaxInboxLimit     = 100
+)
+
+// Locales notification templates are written in.
+const (
+	LocaleThai    = "th"
+	LocaleEnglish = "en"
 )
@@ -22,6 +22,46 @@ var MealTimingOptions = []string{
 
 var DosageOptions = []string{"1/4", "1/2", "1", "2"}
 
+// Weekdays is indexed by time.Weekday; bit i of a schedule's weekday mask
+// enables Weekdays[i].
+var Weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+const (
+	WeekdayMaskAll  = 127
+	MaxIntervalDays = 365
+)
+
+const (
+	IntakeChangeUpdate = "UPDATE"
+	IntakeChangeDelete = "DELETE"
+)
+
+// IntakeStatuses are the statuses a client may record.
+var IntakeStatuses = []string{"TAKEN", "SKIPPED", "MISSED"}
+
+// Intake timing classifies a TAKEN dose against its scheduled time slot.
+const (
+	IntakeTimingOnTime = "ON_TIME"
+	IntakeTimingLate   = "LATE"
+	IntakeTimingEarly  = "EARLY"
+)
+
+var IntakeTimings = []string{IntakeTimingOnTime, IntakeTimingLate, IntakeTimingEarly}
+
+const (
+	HealthTimePeriodMorning   = "MORNING"
+	HealthTimePeriodAfternoon = "AFTERNOON"
+	HealthTimePeriodEvening   = "EVENING"
+	HealthTimePeriodBeforeBed = "BEFORE_BED"
+)
+
+var HealthTimePeriods = []string{
+	HealthTimePeriodMorning,
+	HealthTimePeriodAfternoon,
+	HealthTimePeriodEvening,
+	HealthTimePeriodBeforeBed,
+}
+
 const (
 	SupportCategoryGeneral     = "GENERAL"
 	SupportCategoryMedicine    = "MEDICINE"
@@ -0,0 +1,34 @@
+package constants
+
+// Entity types used both for offline mutations and the change feed.
+const (
+	SyncEntityIntake       = "INTAKE"
+	SyncEntityHealthRecord = "HEALTH_RECORD"
+	SyncEntityAssessment   = "ASSESSMENT"
+	SyncEntityAppointment  = "APPOINTMENT"
+	SyncEntityVisitNote    = "VISIT_NOTE"
+)
+
+// SyncMutationTypes are the entity types a device may push in a batch.
+var SyncMutationTypes = []string{
+	SyncEntityIntake,
+	SyncEntityHealthRecord,
+	SyncEntityAssessment,
+}
+
+const (
+	SyncApplied  = "APPLIED"
+	SyncRejected = "REJECTED"
+	SyncFailed   = "FAILED"
+)
+
+const (
+	SyncOpUpsert = "UPSERT"
+	SyncOpDelete = "DELETE"
+)
+
+const (
+	MaxSyncBatchItems      = 100
+	DefaultSyncChangeLimit = 100
+	MaxSyncChangeLimit     = 500
+)
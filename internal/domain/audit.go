@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+)
+
+// AuditEntry describes one access to or change of a user's personal data.
+// IP address and user agent are taken from the request metadata on ctx.
+type AuditEntry struct {
+	ActorID      *uuid.UUID
+	TargetUserID *uuid.UUID
+	Action       constants.AuditAction
+}
+
+type RequestMeta struct {
+	IPAddress string
+	UserAgent string
+}
+
+type requestMetaKey struct{}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
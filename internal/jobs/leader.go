@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LeaderElector decides whether this process may run scheduled jobs.
+type LeaderElector interface {
+	IsLeader(ctx context.Context) bool
+	Release(ctx context.Context)
+}
+
+// PostgresLeader holds a session-level advisory lock on a dedicated
+// connection. Leadership is lost when that connection dies, at which point
+// Postgres releases the lock and another process can take it.
+type PostgresLeader struct {
+	db       *sql.DB
+	key      int64
+	interval time.Duration
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu          sync.Mutex
+	conn        *sql.Conn
+	lastAttempt time.Time
+}
+
+// NewPostgresLeader checks (and, when not leader, tries to take) the lock at
+// most once per interval.
+func NewPostgresLeader(db *sql.DB, key int64, interval time.Duration, logger *zap.Logger) *PostgresLeader {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	return &PostgresLeader{db: db, key: key, interval: interval, logger: logger, now: time.Now}
+}
+
+func (l *PostgresLeader) IsLeader(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastAttempt) < l.interval {
+		return l.conn != nil
+	}
+	l.lastAttempt = now
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true
+		}
+		l.warn("leader connection lost", nil)
+		_ = l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		l.warn("leader election failed", err)
+		return false
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil || !acquired {
+		if err != nil {
+			l.warn("leader election failed", err)
+		}
+		_ = conn.Close()
+		return false
+	}
+
+	l.conn = conn
+	if l.logger != nil {
+		l.logger.Info("acquired job leadership", zap.String("request_id", "job"), zap.Int64("lock_key", l.key))
+	}
+	return true
+}
+
+func (l *PostgresLeader) Release(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return
+	}
+	_, _ = l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	_ = l.conn.Close()
+	l.conn = nil
+}
+
+func (l *PostgresLeader) warn(msg string, err error) {
+	if l.logger == nil {
+		return
+	}
+	l.logger.Warn(msg, zap.String("request_id", "job"), zap.Int64("lock_key", l.key), zap.Error(err))
+}
@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+)
+
+const (
+	JobNotificationDispatch = "notifications.dispatch"
+	JobWeeklyReminders      = "notifications.weekly_reminders"
+	JobMedicineReminders    = "medicines.reminder_horizon"
+	JobMarkMissedDoses      = "intake.mark_missed"
+	JobEscalateMissedDoses  = "intake.escalate_missed"
+	JobPruneJobRuns         = "jobs.prune_runs"
+)
+
+type Dependencies struct {
+	Config        config.Config
+	DB            *gorm.DB
+	Logger        *zap.Logger
+	Notifications services.NotificationService
+	Medicines     services.MedicineService
+	Intake        services.IntakeService
+	Escalation    services.EscalationService
+}
+
+// DefaultJobs is the job set shared by cmd/api and cmd/worker. Cron schedules
+// are evaluated in NOTIFICATION_TIMEZONE.
+func DefaultJobs(deps Dependencies) ([]Job, error) {
+	location, err := time.LoadLocation(deps.Config.Notifications.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	weekly, err := ParseSchedule(deps.Config.Jobs.WeeklyReminderSchedule, location)
+	if err != nil {
+		return nil, fmt.Errorf("JOBS_WEEKLY_REMINDER_SCHEDULE: %w", err)
+	}
+	medicine, err := ParseSchedule(deps.Config.Jobs.MedicineReminderSchedule, location)
+	if err != nil {
+		return nil, fmt.Errorf("JOBS_MEDICINE_REMINDER_SCHEDULE: %w", err)
+	}
+	missed, err := ParseSchedule(deps.Config.Jobs.MissedDoseSchedule, location)
+	if err != nil {
+		return nil, fmt.Errorf("JOBS_MISSED_DOSE_SCHEDULE: %w", err)
+	}
+	escalation, err := ParseSchedule(deps.Config.Jobs.EscalationSchedule, location)
+	if err != nil {
+		return nil, fmt.Errorf("JOBS_ESCALATION_SCHEDULE: %w", err)
+	}
+	prune, err := ParseSchedule(deps.Config.Jobs.RunPruneSchedule, location)
+	if err != nil {
+		return nil, fmt.Errorf("JOBS_RUN_PRUNE_SCHEDULE: %w", err)
+	}
+	runs := repositories.NewJobRunRepository(deps.DB)
+
+	return []Job{
+		{Name: JobNotificationDispatch, Schedule: Every(deps.Config.Notifications.JobInterval), Run: deps.Notifications.ProcessDue},
+		{Name: JobWeeklyReminders, Schedule: weekly, Run: deps.Notifications.EnsureWeeklyReminders},
+		{Name: JobMedicineReminders, Schedule: medicine, Run: deps.Medicines.EnsureReminderHorizon},
+		{Name: JobMarkMissedDoses, Schedule: missed, Run: func(ctx context.Context) error {
+			marked, err := deps.Intake.MarkMissedDoses(ctx)
+			if marked > 0 && deps.Logger != nil {
+				deps.Logger.Info("marked missed doses", zap.String("request_id", "job"), zap.Int64("count", marked))
+			}
+			return err
+		}},
+		{Name: JobEscalateMissedDoses, Schedule: escalation, Run: func(ctx context.Context) error {
+			escalated, err := deps.Escalation.EscalateMissedDoses(ctx)
+			if escalated > 0 && deps.Logger != nil {
+				deps.Logger.Info("escalated missed doses", zap.String("request_id", "job"), zap.Int("count", escalated))
+			}
+			return err
+		}},
+		{Name: JobPruneJobRuns, Schedule: prune, Run: pruneJobRuns(runs, deps.Config.Jobs.RunRetention, time.Now, deps.Logger)},
+	}, nil
+}
+
+// pruneJobRuns deletes job_runs rows older than retention; without it the
+// table grows by a row per job tick.
+func pruneJobRuns(runs repositories.JobRunRepository, retention time.Duration, now func() time.Time, logger *zap.Logger) func(context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := runs.DeleteStartedBefore(ctx, now().UTC().Add(-retention))
+		if deleted > 0 && logger != nil {
+			logger.Info("pruned job runs", zap.String("request_id", "job"), zap.Int64("count", deleted))
+		}
+		return err
+	}
+}
+
+// NewDefaultScheduler runs DefaultJobs under Postgres advisory-lock leader
+// election and records every run in job_runs.
+func NewDefaultScheduler(deps Dependencies) (*Scheduler, error) {
+	jobs, err := DefaultJobs(deps)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := deps.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := deps.Config.Jobs
+	leader := NewPostgresLeader(sqlDB, cfg.LeaderLockKey, cfg.LeaderRetryInterval, deps.Logger)
+	return NewScheduler(jobs, cfg.DisabledList(), leader, repositories.NewJobRunRepository(deps.DB), deps.Logger), nil
+}
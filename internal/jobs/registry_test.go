@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPruneJobRunsDeletesRunsPastRetention(t *testing.T) {
+	repo := newFakeJobRunRepo()
+	now := time.Date(2026, 1, 31, 3, 15, 0, 0, time.UTC)
+
+	run := pruneJobRuns(repo, 720*time.Hour, func() time.Time { return now }, nil)
+	if err := run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2026, 1, 1, 3, 15, 0, 0, time.UTC); !repo.pruneBefore.Equal(want) {
+		t.Fatalf("expected runs before %s pruned, got %s", want, repo.pruneBefore)
+	}
+}
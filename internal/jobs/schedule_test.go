@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleCron(t *testing.T) {
+	bangkok, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "hourly alias",
+			spec:  "@hourly",
+			after: time.Date(2026, 3, 2, 10, 15, 30, 0, time.UTC),
+			want:  time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "exact minute is exclusive",
+			spec:  "30 8 * * *",
+			after: time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 3, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "step",
+			spec:  "*/15 * * * *",
+			after: time.Date(2026, 3, 2, 10, 16, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "range and list",
+			spec:  "0 9-17/4 * * 1,3",
+			after: time.Date(2026, 3, 2, 17, 1, 0, 0, time.UTC), // Monday
+			want:  time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "sunday as seven",
+			spec:  "0 0 * * 7",
+			after: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "day of month or day of week",
+			spec:  "0 0 15 * 5",
+			after: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "leap day",
+			spec:  "0 0 29 2 *",
+			after: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tc.spec, time.UTC)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tc.spec, err)
+			}
+			if got := schedule.Next(tc.after); !got.Equal(tc.want) {
+				t.Fatalf("next: got %s want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("location", func(t *testing.T) {
+		schedule, err := ParseSchedule("@daily", bangkok)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		got := schedule.Next(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+		want := time.Date(2026, 3, 2, 17, 0, 0, 0, time.UTC)
+		if !got.Equal(want) {
+			t.Fatalf("next: got %s want %s", got, want)
+		}
+	})
+}
+
+func TestParseScheduleEvery(t *testing.T) {
+	schedule, err := ParseSchedule("@every 90s", nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	after := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	if got := schedule.Next(after); !got.Equal(after.Add(90 * time.Second)) {
+		t.Fatalf("unexpected next: %s", got)
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every",
+		"@every -1m",
+		"@weekly",
+	} {
+		if _, err := ParseSchedule(spec, time.UTC); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+const maxJobErrorLength = 2000
+
+// Job is a named unit of background work. Run must be idempotent: a run can
+// repeat after a leadership change or a crash.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs on their schedules while this process holds
+// leadership. A job never overlaps with itself; each run is recorded in
+// job_runs when a repository is provided.
+type Scheduler struct {
+	jobs   []Job
+	leader LeaderElector
+	runs   repositories.JobRunRepository
+	logger *zap.Logger
+	tick   time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+// NewScheduler skips jobs named in disabled. A nil leader runs jobs
+// unconditionally.
+func NewScheduler(jobs []Job, disabled []string, leader LeaderElector, runs repositories.JobRunRepository, logger *zap.Logger) *Scheduler {
+	skip := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		skip[name] = true
+	}
+	enabled := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		if skip[job.Name] {
+			if logger != nil {
+				logger.Info("job disabled", zap.String("request_id", "job"), zap.String("job", job.Name))
+			}
+			continue
+		}
+		enabled = append(enabled, job)
+	}
+	return &Scheduler{
+		jobs:    enabled,
+		leader:  leader,
+		runs:    runs,
+		logger:  logger,
+		tick:    time.Second,
+		now:     time.Now,
+		running: make(map[string]bool, len(enabled)),
+	}
+}
+
+// Start blocks until ctx is cancelled, then waits for in-flight runs and
+// releases leadership.
+func (s *Scheduler) Start(ctx context.Context) {
+	if len(s.jobs) == 0 {
+		return
+	}
+
+	// Every job runs once as soon as this process is leader, then on schedule.
+	next := make(map[string]time.Time, len(s.jobs))
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx, next)
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			if s.leader != nil {
+				releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				s.leader.Release(releaseCtx)
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context, next map[string]time.Time) {
+	if ctx.Err() != nil {
+		return
+	}
+	if s.leader != nil && !s.leader.IsLeader(ctx) {
+		return
+	}
+
+	now := s.now()
+	for _, job := range s.jobs {
+		if due, ok := next[job.Name]; ok && now.Before(due) {
+			continue
+		}
+		if !s.markRunning(job.Name) {
+			continue
+		}
+		next[job.Name] = job.Schedule.Next(now)
+
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			defer s.markDone(job.Name)
+			s.runJob(ctx, job)
+		}(job)
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job Job) {
+	started := s.now()
+	run := &db.JobRun{JobName: job.Name, Status: constants.JobRunRunning, StartedAt: started.UTC()}
+	recorded := false
+	if s.runs != nil {
+		if err := s.runs.Start(ctx, run); err != nil {
+			s.logError("record job start failed", job.Name, err)
+		} else {
+			recorded = true
+		}
+	}
+
+	err := runSafely(ctx, job)
+
+	finished := s.now()
+	duration := finished.Sub(started)
+	status := constants.JobRunSucceeded
+	var errMsg *string
+	if err != nil {
+		status = constants.JobRunFailed
+		msg := err.Error()
+		if runes := []rune(msg); len(runes) > maxJobErrorLength {
+			msg = string(runes[:maxJobErrorLength])
+		}
+		errMsg = &msg
+		s.logError("job failed", job.Name, err)
+	} else if s.logger != nil {
+		s.logger.Debug("job finished", zap.String("request_id", "job"), zap.String("job", job.Name), zap.Duration("duration", duration))
+	}
+
+	if recorded {
+		// Record the outcome even when shutdown cancelled the run.
+		finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := s.runs.Finish(finishCtx, run.ID, status, finished.UTC(), duration, errMsg); err != nil {
+			s.logError("record job finish failed", job.Name, err)
+		}
+	}
+}
+
+func runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) markRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *Scheduler) markDone(name string) {
+	s.mu.Lock()
+	delete(s.running, name)
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) logError(msg, name string, err error) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.Warn(msg, zap.String("request_id", "job"), zap.String("job", name), zap.Error(err))
+}
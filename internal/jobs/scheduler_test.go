@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+type fakeLeader struct {
+	leader   atomic.Bool
+	released atomic.Bool
+}
+
+func (l *fakeLeader) IsLeader(ctx context.Context) bool {
+	return l.leader.Load()
+}
+
+func (l *fakeLeader) Release(ctx context.Context) {
+	l.released.Store(true)
+}
+
+type finishedRun struct {
+	name   string
+	status constants.JobRunStatus
+	errMsg *string
+}
+
+type fakeJobRunRepo struct {
+	mu          sync.Mutex
+	names       map[uuid.UUID]string
+	finished    []finishedRun
+	pruneBefore time.Time
+}
+
+func newFakeJobRunRepo() *fakeJobRunRepo {
+	return &fakeJobRunRepo{names: make(map[uuid.UUID]string)}
+}
+
+func (r *fakeJobRunRepo) Start(ctx context.Context, run *db.JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.ID = uuid.New()
+	r.names[run.ID] = run.JobName
+	return nil
+}
+
+func (r *fakeJobRunRepo) Finish(ctx context.Context, id uuid.UUID, status constants.JobRunStatus, finishedAt time.Time, duration time.Duration, errMsg *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, finishedRun{name: r.names[id], status: status, errMsg: errMsg})
+	return nil
+}
+
+func (r *fakeJobRunRepo) DeleteStartedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pruneBefore = before
+	return 3, nil
+}
+
+func (r *fakeJobRunRepo) runs() []finishedRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]finishedRun(nil), r.finished...)
+}
+
+func startScheduler(t *testing.T, s *Scheduler) func() {
+	t.Helper()
+	s.tick = 5 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Start(ctx)
+	}()
+	return func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("scheduler did not stop")
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSchedulerRecordsRunsAndSkipsDisabled(t *testing.T) {
+	leader := &fakeLeader{}
+	leader.leader.Store(true)
+	repo := newFakeJobRunRepo()
+
+	var okRuns, disabledRuns atomic.Int32
+	scheduler := NewScheduler([]Job{
+		{Name: "ok", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			okRuns.Add(1)
+			return nil
+		}},
+		{Name: "failing", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			return errors.New("boom")
+		}},
+		{Name: "panicking", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			panic("bad job")
+		}},
+		{Name: "disabled", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			disabledRuns.Add(1)
+			return nil
+		}},
+	}, []string{"disabled"}, leader, repo, nil)
+
+	stop := startScheduler(t, scheduler)
+	waitFor(t, func() bool { return len(repo.runs()) == 3 })
+	stop()
+
+	if okRuns.Load() != 1 {
+		t.Fatalf("expected one run within the interval, got %d", okRuns.Load())
+	}
+	if disabledRuns.Load() != 0 {
+		t.Fatalf("disabled job ran")
+	}
+	if !leader.released.Load() {
+		t.Fatalf("expected leadership released on stop")
+	}
+
+	byName := map[string]finishedRun{}
+	for _, run := range repo.runs() {
+		byName[run.name] = run
+	}
+	if byName["ok"].status != constants.JobRunSucceeded || byName["ok"].errMsg != nil {
+		t.Fatalf("unexpected ok run: %+v", byName["ok"])
+	}
+	if byName["failing"].status != constants.JobRunFailed || byName["failing"].errMsg == nil || *byName["failing"].errMsg != "boom" {
+		t.Fatalf("unexpected failing run: %+v", byName["failing"])
+	}
+	if byName["panicking"].status != constants.JobRunFailed || byName["panicking"].errMsg == nil {
+		t.Fatalf("unexpected panicking run: %+v", byName["panicking"])
+	}
+}
+
+func TestSchedulerWaitsForLeadership(t *testing.T) {
+	leader := &fakeLeader{}
+	var runs atomic.Int32
+	scheduler := NewScheduler([]Job{
+		{Name: "job", Schedule: Every(time.Hour), Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}},
+	}, nil, leader, nil, nil)
+
+	stop := startScheduler(t, scheduler)
+	defer stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != 0 {
+		t.Fatalf("job ran without leadership")
+	}
+
+	leader.leader.Store(true)
+	waitFor(t, func() bool { return runs.Load() == 1 })
+}
+
+func TestSchedulerDoesNotOverlapRuns(t *testing.T) {
+	var active, maxActive, runs atomic.Int32
+	release := make(chan struct{})
+	scheduler := NewScheduler([]Job{
+		{Name: "slow", Schedule: Every(time.Millisecond), Run: func(ctx context.Context) error {
+			n := active.Add(1)
+			defer active.Add(-1)
+			if n > maxActive.Load() {
+				maxActive.Store(n)
+			}
+			if runs.Add(1) == 1 {
+				<-release
+			}
+			return nil
+		}},
+	}, nil, nil, nil, nil)
+
+	stop := startScheduler(t, scheduler)
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != 1 {
+		t.Fatalf("expected a single in-flight run, got %d", runs.Load())
+	}
+	close(release)
+	waitFor(t, func() bool { return runs.Load() >= 2 })
+	stop()
+
+	if maxActive.Load() != 1 {
+		t.Fatalf("runs overlapped: %d", maxActive.Load())
+	}
+}
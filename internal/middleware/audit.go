@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+)
+
+type AuditRecorder interface {
+	Record(ctx context.Context, entry domain.AuditEntry)
+}
+
+// Audit attaches the client IP and user agent to the request context for
+// service-level audit hooks. After the handler runs it records the access a
+// handler declared with MarkAudit, unless the request failed.
+func Audit(recorder AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := domain.WithRequestMeta(c.Request.Context(), domain.RequestMeta{
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if recorder == nil || c.Writer.Status() >= 400 {
+			return
+		}
+		v, ok := c.Get(constants.AuditActionKey)
+		if !ok {
+			return
+		}
+		action, ok := v.(constants.AuditAction)
+		if !ok {
+			return
+		}
+		entry := domain.AuditEntry{Action: action}
+		if v, ok := c.Get(constants.AuditTargetKey); ok {
+			if target, ok := v.(uuid.UUID); ok {
+				entry.TargetUserID = &target
+			}
+		}
+		if actorID, ok := GetActorID(c); ok {
+			entry.ActorID = &actorID
+		}
+		recorder.Record(c.Request.Context(), entry)
+	}
+}
+
+func MarkAudit(c *gin.Context, action constants.AuditAction, targetUserID uuid.UUID) {
+	c.Set(constants.AuditActionKey, action)
+	c.Set(constants.AuditTargetKey, targetUserID)
+}
+
+// MarkAuditAction declares an access that has no single target user, such as
+// a search across the patient directory.
+func MarkAuditAction(c *gin.Context, action constants.AuditAction) {
+	c.Set(constants.AuditActionKey, action)
+}
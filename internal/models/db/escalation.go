@@ -0,0 +1,41 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EscalationPolicy overrides the default missed-dose escalation settings for
+// one patient; nil thresholds fall back to the configured defaults.
+type EscalationPolicy struct {
+	UserID             uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	Enabled            bool       `gorm:"not null"`
+	CaregiverThreshold *int       `gorm:"type:int"`
+	NurseThreshold     *int       `gorm:"type:int"`
+	NurseID            *uuid.UUID `gorm:"type:uuid"`
+	UpdatedBy          *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (EscalationPolicy) TableName() string {
+	return "escalation_policies"
+}
+
+// MissedDoseEscalation records that a level was notified about a streak of
+// missed doses ending at LastMissedAt.
+type MissedDoseEscalation struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	Level          string    `gorm:"size:20;not null"`
+	MissedCount    int       `gorm:"not null"`
+	FirstMissedAt  time.Time `gorm:"type:timestamptz;not null"`
+	LastMissedAt   time.Time `gorm:"type:timestamptz;not null"`
+	RecipientCount int       `gorm:"not null"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (MissedDoseEscalation) TableName() string {
+	return "missed_dose_escalations"
+}
@@ -14,11 +14,35 @@ type IntakeHistory struct {
 	ScheduleID *uuid.UUID                `gorm:"type:uuid;index"`
 	TargetDate time.Time                 `gorm:"type:date;not null"`
 	TakenAt    *time.Time                `gorm:"type:timestamptz"`
+	DueAt      *time.Time                `gorm:"type:timestamptz"`
+	Timing     *string                   `gorm:"size:10"`
 	Status     constants.MedIntakeStatus `gorm:"type:med_intake_status;not null"`
 	SkipReason *string                   `gorm:"type:text"`
 	CreatedAt  time.Time                 `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time                 `gorm:"autoUpdateTime"`
 }
 
 func (IntakeHistory) TableName() string {
 	return "intake_history"
 }
+
+// IntakeChange is an append-only audit of a correction or removal of an
+// intake record.
+type IntakeChange struct {
+	ID                 uuid.UUID                  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	IntakeID           uuid.UUID                  `gorm:"type:uuid;not null;index"`
+	UserID             uuid.UUID                  `gorm:"type:uuid;not null"`
+	ActorID            *uuid.UUID                 `gorm:"type:uuid"`
+	Action             string                     `gorm:"size:20;not null"`
+	PreviousStatus     *constants.MedIntakeStatus `gorm:"type:med_intake_status"`
+	NewStatus          *constants.MedIntakeStatus `gorm:"type:med_intake_status"`
+	PreviousTakenAt    *time.Time                 `gorm:"type:timestamptz"`
+	NewTakenAt         *time.Time                 `gorm:"type:timestamptz"`
+	PreviousSkipReason *string                    `gorm:"type:text"`
+	NewSkipReason      *string                    `gorm:"type:text"`
+	CreatedAt          time.Time                  `gorm:"autoCreateTime"`
+}
+
+func (IntakeChange) TableName() string {
+	return "intake_history_changes"
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+)
+
+type JobRun struct {
+	ID         uuid.UUID              `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	JobName    string                 `gorm:"size:100;not null"`
+	Status     constants.JobRunStatus `gorm:"type:job_run_status;not null;default:RUNNING"`
+	StartedAt  time.Time              `gorm:"type:timestamptz;not null"`
+	FinishedAt *time.Time             `gorm:"type:timestamptz"`
+	DurationMs *int64
+	Error      *string `gorm:"type:text"`
+}
+
+func (JobRun) TableName() string {
+	return "job_runs"
+}
@@ -21,25 +21,34 @@ func (MedicineMaster) TableName() string {
 }
 
 type PatientMedicine struct {
-	ID               uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	UserID           uuid.UUID      `gorm:"type:uuid;not null;index"`
-	MedicineMasterID *uuid.UUID     `gorm:"type:uuid"`
-	CategoryItemID   *uuid.UUID     `gorm:"type:uuid"`
-	CustomName       *string        `gorm:"size:255"`
-	DosageAmount     string         `gorm:"size:100;not null"`
-	Instruction      *string        `gorm:"type:text"`
-	Indication       *string        `gorm:"type:text"`
-	MyDrugImageURL   *string        `gorm:"type:text"`
-	IsActive         bool           `gorm:"default:true"`
-	CreatedAt        time.Time      `gorm:"autoCreateTime"`
-	UpdatedAt        time.Time      `gorm:"autoUpdateTime"`
-	DeletedAt        gorm.DeletedAt `gorm:"index"`
+	ID                  uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID              uuid.UUID      `gorm:"type:uuid;not null;index"`
+	MedicineMasterID    *uuid.UUID     `gorm:"type:uuid"`
+	CategoryItemID      *uuid.UUID     `gorm:"type:uuid"`
+	CustomName          *string        `gorm:"size:255"`
+	DosageAmount        string         `gorm:"size:100;not null"`
+	Instruction         *string        `gorm:"type:text"`
+	Indication          *string        `gorm:"type:text"`
+	MyDrugImageURL      *string        `gorm:"type:text"`
+	QuantityOnHand      *float64       `gorm:"type:numeric(10,2)"`
+	PackSize            *float64       `gorm:"type:numeric(10,2)"`
+	RefillDate          *time.Time     `gorm:"type:date"`
+	LowSupplyNotifiedAt *time.Time     `gorm:"type:timestamptz"`
+	IsActive            bool           `gorm:"default:true"`
+	CreatedAt           time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt           time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
 }
 
 type MedicineSchedule struct {
-	ID                uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	PatientMedicineID uuid.UUID `gorm:"type:uuid;not null;index"`
-	TimeSlot          time.Time `gorm:"type:time;not null"`
-	MealTiming        *string   `gorm:"size:50"`
-	CreatedAt         time.Time `gorm:"autoCreateTime"`
+	ID                uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	PatientMedicineID uuid.UUID      `gorm:"type:uuid;not null;index"`
+	TimeSlot          time.Time      `gorm:"type:time;not null"`
+	MealTiming        *string        `gorm:"size:50"`
+	StartDate         time.Time      `gorm:"type:date;not null"`
+	EndDate           *time.Time     `gorm:"type:date"`
+	IntervalDays      int            `gorm:"not null;default:1"`
+	WeekdayMask       int            `gorm:"type:smallint;not null;default:127"`
+	CreatedAt         time.Time      `gorm:"autoCreateTime"`
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
 }
@@ -11,7 +11,8 @@ import (
 
 type NotificationTemplate struct {
 	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	Code      string         `gorm:"size:50;uniqueIndex;not null"`
+	Code      string         `gorm:"size:50;uniqueIndex:uq_notification_templates_code_locale;not null"`
+	Locale    string         `gorm:"size:10;uniqueIndex:uq_notification_templates_code_locale;not null;default:en"`
 	Title     string         `gorm:"size:255;not null"`
 	Body      string         `gorm:"type:text;not null"`
 	Data      datatypes.JSON `gorm:"type:jsonb"`
@@ -24,14 +25,22 @@ func (NotificationTemplate) TableName() string {
 }
 
 type NotificationEvent struct {
-	ID           uuid.UUID                    `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	UserID       uuid.UUID                    `gorm:"type:uuid;not null;index"`
-	TemplateCode string                       `gorm:"size:50;not null"`
-	ScheduledAt  time.Time                    `gorm:"type:timestamptz;not null;index"`
-	SentAt       *time.Time                   `gorm:"type:timestamptz"`
-	Status       constants.NotificationStatus `gorm:"type:notification_status;not null;default:PENDING"`
-	Payload      datatypes.JSON               `gorm:"type:jsonb"`
-	CreatedAt    time.Time                    `gorm:"autoCreateTime"`
+	ID            uuid.UUID                    `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID        uuid.UUID                    `gorm:"type:uuid;not null;index"`
+	TemplateCode  string                       `gorm:"size:50;not null"`
+	ScheduledAt   time.Time                    `gorm:"type:timestamptz;not null;index"`
+	SentAt        *time.Time                   `gorm:"type:timestamptz"`
+	Status        constants.NotificationStatus `gorm:"type:notification_status;not null;default:PENDING"`
+	Payload       datatypes.JSON               `gorm:"type:jsonb"`
+	Attempts      int                          `gorm:"not null;default:0"`
+	NextAttemptAt *time.Time                   `gorm:"type:timestamptz"`
+	LastError     *string                      `gorm:"type:text"`
+	LockedUntil   *time.Time                   `gorm:"type:timestamptz"`
+	Title         *string                      `gorm:"type:text"`
+	Body          *string                      `gorm:"type:text"`
+	ReadAt        *time.Time                   `gorm:"type:timestamptz"`
+	ArchivedAt    *time.Time                   `gorm:"type:timestamptz"`
+	CreatedAt     time.Time                    `gorm:"autoCreateTime"`
 }
 
 func (NotificationEvent) TableName() string {
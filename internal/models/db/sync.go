@@ -0,0 +1,37 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// SyncMutation is the stored outcome of one offline mutation, keyed by the
+// client-generated id so replays return the original result.
+type SyncMutation struct {
+	UserID          uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	ClientID        uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	MutationType    string     `gorm:"size:30;not null"`
+	ClientTimestamp time.Time  `gorm:"type:timestamptz;not null"`
+	Status          string     `gorm:"size:20;not null"`
+	EntityID        *uuid.UUID `gorm:"type:uuid"`
+	ErrorCode       *string    `gorm:"size:50"`
+	ErrorMessage    *string    `gorm:"type:text"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
+}
+
+// SyncChange is one entry of a patient's change feed. TxID, the id of the
+// recording transaction, and ID together form the pull cursor; TxID is set by
+// the database.
+type SyncChange struct {
+	ID         int64          `gorm:"primaryKey;autoIncrement"`
+	TxID       uint64         `gorm:"column:txid;->"`
+	UserID     uuid.UUID      `gorm:"type:uuid;not null;index"`
+	EntityType string         `gorm:"size:30;not null"`
+	EntityID   uuid.UUID      `gorm:"type:uuid;not null"`
+	Operation  string         `gorm:"size:10;not null"`
+	Payload    datatypes.JSON `gorm:"type:jsonb"`
+	ActorID    *uuid.UUID     `gorm:"type:uuid"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime"`
+}
@@ -4,13 +4,24 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 )
 
+// UserPreference holds a user's notification settings. Nil fields keep the
+// defaults.
 type UserPreference struct {
-	UserID                uuid.UUID `gorm:"type:uuid;primaryKey"`
-	WeeklyReminderEnabled bool      `gorm:"default:true"`
-	CreatedAt             time.Time `gorm:"autoCreateTime"`
-	UpdatedAt             time.Time `gorm:"autoUpdateTime"`
+	UserID                    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	WeeklyReminderEnabled     bool
+	Timezone                  *string
+	NotificationChannels      datatypes.JSON `gorm:"type:jsonb"`
+	QuietHoursStart           *time.Time     `gorm:"type:time"`
+	QuietHoursEnd             *time.Time     `gorm:"type:time"`
+	AppointmentReminderDays   datatypes.JSON `gorm:"type:jsonb"`
+	BeforeMealLeadMinutes     *int
+	BeforeMealFollowUpMinutes *int
+	Language                  *string
+	CreatedAt                 time.Time `gorm:"autoCreateTime"`
+	UpdatedAt                 time.Time `gorm:"autoUpdateTime"`
 }
 
 func (UserPreference) TableName() string {
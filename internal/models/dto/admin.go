@@ -7,18 +7,72 @@ type StaffLoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type PatientListQuery struct {
+	Query       string
+	IsActive    *bool
+	CaregiverID string
+}
+
 type PatientSummaryResponse struct {
 	ID        string    `json:"id"`
+	Username  string    `json:"username"`
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
 	HN        *string   `json:"hn,omitempty"`
+	IsActive  bool      `json:"is_active"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 type PatientDetailResponse struct {
-	ID        string  `json:"id"`
-	FirstName string  `json:"first_name"`
-	LastName  string  `json:"last_name"`
-	HN        *string `json:"hn,omitempty"`
-	CitizenID *string `json:"citizen_id,omitempty"`
+	ID              string                         `json:"id"`
+	Username        string                         `json:"username"`
+	IsActive        bool                           `json:"is_active"`
+	Profile         ProfileResponse                `json:"profile"`
+	Medicines       []PatientMedicineWithSchedules `json:"medicines"`
+	NextAppointment *AppointmentResponse           `json:"next_appointment,omitempty"`
+	LatestBP        *HealthRecordResponse          `json:"latest_bp,omitempty"`
+	Caregivers      []PatientCaregiverResponse     `json:"caregivers"`
+	Adherence30d    AdherenceReportResponse        `json:"adherence_30d"`
+}
+
+type PatientMedicineWithSchedules struct {
+	PatientMedicineResponse
+	Schedules []MedicineScheduleResponse `json:"schedules"`
+}
+
+type PatientCaregiverResponse struct {
+	AssignmentID string  `json:"assignment_id"`
+	CaregiverID  string  `json:"caregiver_id"`
+	Relationship string  `json:"relationship"`
+	Username     string  `json:"username"`
+	FirstName    *string `json:"first_name,omitempty"`
+	LastName     *string `json:"last_name,omitempty"`
+}
+
+type DoseTimingQuery struct {
+	PatientID       string
+	Timing          string
+	From            string
+	To              string
+	SlotFrom        string
+	SlotTo          string
+	MinDelayMinutes int
+}
+
+// DoseTimingResponse is one TAKEN dose in the timing report; DelayMinutes is
+// negative for doses taken early.
+type DoseTimingResponse struct {
+	IntakeID     string    `json:"intake_id"`
+	UserID       string    `json:"user_id"`
+	Username     string    `json:"username"`
+	HN           *string   `json:"hn,omitempty"`
+	FirstName    *string   `json:"first_name,omitempty"`
+	LastName     *string   `json:"last_name,omitempty"`
+	MedicineName string    `json:"medicine_name"`
+	TimeSlot     string    `json:"time_slot"`
+	TargetDate   string    `json:"target_date"`
+	DueAt        time.Time `json:"due_at"`
+	TakenAt      time.Time `json:"taken_at"`
+	DelayMinutes int       `json:"delay_minutes"`
+	Timing       string    `json:"timing"`
 }
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+type UpdateEscalationPolicyRequest struct {
+	Enabled            *bool   `json:"enabled"`
+	CaregiverThreshold *int    `json:"caregiver_threshold" validate:"omitempty,min=1,max=50"`
+	NurseThreshold     *int    `json:"nurse_threshold" validate:"omitempty,min=1,max=50"`
+	NurseID            *string `json:"nurse_id"`
+}
+
+// EscalationPolicyResponse shows the effective settings; IsDefault is true
+// when the patient has no policy of their own.
+type EscalationPolicyResponse struct {
+	UserID             string     `json:"user_id"`
+	Enabled            bool       `json:"enabled"`
+	CaregiverThreshold int        `json:"caregiver_threshold"`
+	NurseThreshold     int        `json:"nurse_threshold"`
+	NurseID            *string    `json:"nurse_id,omitempty"`
+	IsDefault          bool       `json:"is_default"`
+	UpdatedBy          *string    `json:"updated_by,omitempty"`
+	UpdatedAt          *time.Time `json:"updated_at,omitempty"`
+}
@@ -11,6 +11,13 @@ type CreateIntakeRequest struct {
 	TargetDate string                    `json:"target_date" validate:"required"`
 	Status     constants.MedIntakeStatus `json:"status" validate:"required"`
 	SkipReason *string                   `json:"skip_reason"`
+	TakenAt    *string                   `json:"taken_at"`
+}
+
+type UpdateIntakeRequest struct {
+	Status     constants.MedIntakeStatus `json:"status" validate:"required"`
+	SkipReason *string                   `json:"skip_reason"`
+	TakenAt    *string                   `json:"taken_at"`
 }
 
 type IntakeHistoryResponse struct {
@@ -19,7 +26,77 @@ type IntakeHistoryResponse struct {
 	ScheduleID *string                   `json:"schedule_id,omitempty"`
 	TargetDate string                    `json:"target_date"`
 	TakenAt    *time.Time                `json:"taken_at,omitempty"`
+	DueAt      *time.Time                `json:"due_at,omitempty"`
+	Timing     *string                   `json:"timing,omitempty"`
 	Status     constants.MedIntakeStatus `json:"status"`
 	SkipReason *string                   `json:"skip_reason,omitempty"`
 	CreatedAt  time.Time                 `json:"created_at"`
 }
+
+type IntakeChangeResponse struct {
+	ID                 string                     `json:"id"`
+	IntakeID           string                     `json:"intake_id"`
+	ActorID            *string                    `json:"actor_id,omitempty"`
+	Action             string                     `json:"action"`
+	PreviousStatus     *constants.MedIntakeStatus `json:"previous_status,omitempty"`
+	NewStatus          *constants.MedIntakeStatus `json:"new_status,omitempty"`
+	PreviousTakenAt    *time.Time                 `json:"previous_taken_at,omitempty"`
+	NewTakenAt         *time.Time                 `json:"new_taken_at,omitempty"`
+	PreviousSkipReason *string                    `json:"previous_skip_reason,omitempty"`
+	NewSkipReason      *string                    `json:"new_skip_reason,omitempty"`
+	CreatedAt          time.Time                  `json:"created_at"`
+}
+
+// TodayDoseItem is one expected dose on the checklist; IntakeID is set once
+// the dose has been recorded.
+type TodayDoseItem struct {
+	ScheduleID        string                    `json:"schedule_id"`
+	PatientMedicineID string                    `json:"patient_medicine_id"`
+	MedicineName      string                    `json:"medicine_name"`
+	DosageAmount      string                    `json:"dosage_amount"`
+	Instruction       *string                   `json:"instruction,omitempty"`
+	TimeSlot          string                    `json:"time_slot"`
+	MealTiming        *string                   `json:"meal_timing,omitempty"`
+	Status            constants.MedIntakeStatus `json:"status"`
+	IntakeID          *string                   `json:"intake_id,omitempty"`
+	TakenAt           *time.Time                `json:"taken_at,omitempty"`
+	Timing            *string                   `json:"timing,omitempty"`
+}
+
+type TodayChecklistResponse struct {
+	UserID string          `json:"user_id"`
+	Date   string          `json:"date"`
+	Items  []TodayDoseItem `json:"items"`
+}
+
+// AdherenceStats counts expected doses by outcome. OnTime, Late and Early
+// break down the Taken doses that have a timing classification.
+type AdherenceStats struct {
+	Expected int     `json:"expected"`
+	Taken    int     `json:"taken"`
+	Skipped  int     `json:"skipped"`
+	Missed   int     `json:"missed"`
+	OnTime   int     `json:"on_time"`
+	Late     int     `json:"late"`
+	Early    int     `json:"early"`
+	Percent  float64 `json:"percent"`
+}
+
+type MedicineAdherenceResponse struct {
+	PatientMedicineID string `json:"patient_medicine_id"`
+	Name              string `json:"name"`
+	AdherenceStats
+}
+
+type AdherenceReportResponse struct {
+	UserID        string                      `json:"user_id"`
+	From          string                      `json:"from"`
+	To            string                      `json:"to"`
+	Overall       AdherenceStats              `json:"overall"`
+	DaysExpected  int                         `json:"days_expected"`
+	DaysCovered   int                         `json:"days_covered"`
+	PDC           float64                     `json:"pdc"`
+	CurrentStreak int                         `json:"current_streak"`
+	LongestStreak int                         `json:"longest_streak"`
+	Medicines     []MedicineAdherenceResponse `json:"medicines"`
+}
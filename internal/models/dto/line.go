@@ -0,0 +1,15 @@
+package dto
+
+type LineLinkURLResponse struct {
+	AuthorizeURL string `json:"authorize_url"`
+	State        string `json:"state"`
+}
+
+type LineLinkRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+type LineLinkResponse struct {
+	Linked bool `json:"linked"`
+}
@@ -11,13 +11,16 @@ type MedicineMasterResponse struct {
 }
 
 type CreatePatientMedicineRequest struct {
-	MedicineMasterID *string `json:"medicine_master_id"`
-	CategoryItemID   *string `json:"category_item_id"`
-	CustomName       *string `json:"custom_name"`
-	DosageAmount     string  `json:"dosage_amount"`
-	Instruction      *string `json:"instruction"`
-	Indication       *string `json:"indication"`
-	MyDrugImageURL   *string `json:"my_drug_image_url"`
+	MedicineMasterID *string  `json:"medicine_master_id"`
+	CategoryItemID   *string  `json:"category_item_id"`
+	CustomName       *string  `json:"custom_name"`
+	DosageAmount     string   `json:"dosage_amount"`
+	Instruction      *string  `json:"instruction"`
+	Indication       *string  `json:"indication"`
+	MyDrugImageURL   *string  `json:"my_drug_image_url"`
+	QuantityOnHand   *float64 `json:"quantity_on_hand" validate:"omitempty,min=0"`
+	PackSize         *float64 `json:"pack_size" validate:"omitempty,gt=0"`
+	RefillDate       *string  `json:"refill_date"`
 }
 
 type PatientMedicineResponse struct {
@@ -30,22 +33,52 @@ type PatientMedicineResponse struct {
 	Instruction      *string   `json:"instruction,omitempty"`
 	Indication       *string   `json:"indication,omitempty"`
 	MyDrugImageURL   *string   `json:"my_drug_image_url,omitempty"`
+	QuantityOnHand   *float64  `json:"quantity_on_hand,omitempty"`
+	PackSize         *float64  `json:"pack_size,omitempty"`
+	RefillDate       *string   `json:"refill_date,omitempty"`
 	IsActive         bool      `json:"is_active"`
 	CreatedAt        time.Time `json:"created_at"`
 }
 
 type UpdatePatientMedicineRequest struct {
-	CustomName     *string `json:"custom_name"`
-	DosageAmount   *string `json:"dosage_amount"`
-	Instruction    *string `json:"instruction"`
-	Indication     *string `json:"indication"`
-	MyDrugImageURL *string `json:"my_drug_image_url"`
-	IsActive       *bool   `json:"is_active"`
+	CustomName     *string  `json:"custom_name"`
+	DosageAmount   *string  `json:"dosage_amount"`
+	Instruction    *string  `json:"instruction"`
+	Indication     *string  `json:"indication"`
+	MyDrugImageURL *string  `json:"my_drug_image_url"`
+	QuantityOnHand *float64 `json:"quantity_on_hand" validate:"omitempty,min=0"`
+	PackSize       *float64 `json:"pack_size" validate:"omitempty,gt=0"`
+	RefillDate     *string  `json:"refill_date"`
+	IsActive       *bool    `json:"is_active"`
+}
+
+// RefillPatientMedicineRequest adds Packs packs of the medicine's pack size,
+// or Quantity units, to the stock on hand. Neither means one pack.
+type RefillPatientMedicineRequest struct {
+	Packs      *float64 `json:"packs" validate:"omitempty,gt=0"`
+	Quantity   *float64 `json:"quantity" validate:"omitempty,gt=0"`
+	RefillDate *string  `json:"refill_date"`
+}
+
+// ScheduleRecurrenceRequest is shared by schedule create and update. Dates are
+// YYYY-MM-DD; weekdays are SUN..SAT.
+type ScheduleRecurrenceRequest struct {
+	StartDate    *string  `json:"start_date"`
+	EndDate      *string  `json:"end_date"`
+	IntervalDays *int     `json:"interval_days"`
+	Weekdays     []string `json:"weekdays"`
 }
 
 type CreateMedicineScheduleRequest struct {
 	TimeSlot   string  `json:"time_slot" validate:"required"`
 	MealTiming *string `json:"meal_timing"`
+	ScheduleRecurrenceRequest
+}
+
+type UpdateMedicineScheduleRequest struct {
+	TimeSlot   *string `json:"time_slot"`
+	MealTiming *string `json:"meal_timing"`
+	ScheduleRecurrenceRequest
 }
 
 type MedicineScheduleResponse struct {
@@ -53,5 +86,9 @@ type MedicineScheduleResponse struct {
 	PatientMedicineID string    `json:"patient_medicine_id"`
 	TimeSlot          string    `json:"time_slot"`
 	MealTiming        *string   `json:"meal_timing,omitempty"`
+	StartDate         string    `json:"start_date"`
+	EndDate           *string   `json:"end_date,omitempty"`
+	IntervalDays      int       `json:"interval_days"`
+	Weekdays          []string  `json:"weekdays"`
 	CreatedAt         time.Time `json:"created_at"`
 }
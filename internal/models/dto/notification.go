@@ -1,6 +1,9 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type NotificationUpcomingItem struct {
 	ID           string    `json:"id"`
@@ -9,10 +12,118 @@ type NotificationUpcomingItem struct {
 	Status       string    `json:"status"`
 }
 
+// NotificationInboxItem is a sent notification as the user received it.
+// payload carries the ids the app opens, such as schedule_id.
+type NotificationInboxItem struct {
+	ID           string          `json:"id"`
+	TemplateCode string          `json:"template_code"`
+	Title        string          `json:"title"`
+	Body         string          `json:"body"`
+	Payload      json.RawMessage `json:"payload,omitempty"`
+	SentAt       time.Time       `json:"sent_at"`
+	ReadAt       *time.Time      `json:"read_at"`
+}
+
+type NotificationInboxResponse struct {
+	Items      []NotificationInboxItem `json:"items"`
+	NextCursor *string                 `json:"next_cursor"`
+	HasMore    bool                    `json:"has_more"`
+}
+
+type NotificationUnreadCountResponse struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type NotificationEventResponse struct {
+	ID            string     `json:"id"`
+	UserID        string     `json:"user_id"`
+	TemplateCode  string     `json:"template_code"`
+	ScheduledAt   time.Time  `json:"scheduled_at"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// UpdatePreferencesRequest changes only the fields that are present. An empty
+// timezone or quiet hours clears them; an empty appointment_reminder_days
+// turns appointment reminders off.
 type UpdatePreferencesRequest struct {
-	WeeklyReminderEnabled *bool `json:"weekly_reminder_enabled" validate:"required"`
+	WeeklyReminderEnabled     *bool    `json:"weekly_reminder_enabled"`
+	Timezone                  *string  `json:"timezone" validate:"omitempty,max=64"`
+	Language                  *string  `json:"language"`
+	Channels                  []string `json:"channels"`
+	QuietHoursStart           *string  `json:"quiet_hours_start"`
+	QuietHoursEnd             *string  `json:"quiet_hours_end"`
+	AppointmentReminderDays   []int    `json:"appointment_reminder_days"`
+	BeforeMealLeadMinutes     *int     `json:"before_meal_lead_minutes"`
+	BeforeMealFollowUpMinutes *int     `json:"before_meal_follow_up_minutes"`
 }
 
 type PreferencesResponse struct {
-	WeeklyReminderEnabled bool `json:"weekly_reminder_enabled"`
+	WeeklyReminderEnabled     bool     `json:"weekly_reminder_enabled"`
+	Timezone                  *string  `json:"timezone"`
+	Language                  *string  `json:"language"`
+	Channels                  []string `json:"channels"`
+	QuietHoursStart           *string  `json:"quiet_hours_start"`
+	QuietHoursEnd             *string  `json:"quiet_hours_end"`
+	AppointmentReminderDays   []int    `json:"appointment_reminder_days"`
+	BeforeMealLeadMinutes     int      `json:"before_meal_lead_minutes"`
+	BeforeMealFollowUpMinutes int      `json:"before_meal_follow_up_minutes"`
+}
+
+type NotificationTemplateResponse struct {
+	ID        string         `json:"id"`
+	Code      string         `json:"code"`
+	Locale    string         `json:"locale"`
+	Title     string         `json:"title"`
+	Body      string         `json:"body"`
+	Data      map[string]any `json:"data"`
+	IsActive  bool           `json:"is_active"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// CreateNotificationTemplateRequest adds a template for a built-in code in a
+// locale. New templates are inactive until activated.
+type CreateNotificationTemplateRequest struct {
+	Code   string         `json:"code" validate:"required,max=50"`
+	Locale string         `json:"locale" validate:"required"`
+	Title  string         `json:"title" validate:"required,max=255"`
+	Body   string         `json:"body" validate:"required"`
+	Data   map[string]any `json:"data"`
+}
+
+// UpdateNotificationTemplateRequest changes only the fields that are present.
+// An empty data object clears it.
+type UpdateNotificationTemplateRequest struct {
+	Title *string        `json:"title" validate:"omitempty,max=255"`
+	Body  *string        `json:"body"`
+	Data  map[string]any `json:"data"`
+}
+
+// PreviewNotificationTemplateRequest renders a template as if an event with
+// payload were delivered to user_id, the caller when omitted.
+type PreviewNotificationTemplateRequest struct {
+	UserID  *string        `json:"user_id"`
+	Payload map[string]any `json:"payload"`
+}
+
+type TestSendNotificationTemplateRequest struct {
+	Payload map[string]any `json:"payload"`
+}
+
+type NotificationPreviewResponse struct {
+	TemplateID string            `json:"template_id"`
+	Code       string            `json:"code"`
+	Locale     string            `json:"locale"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Data       map[string]string `json:"data"`
+}
+
+type NotificationTestSendResponse struct {
+	NotificationPreviewResponse
+	Devices int `json:"devices"`
 }
@@ -0,0 +1,30 @@
+package dto
+
+type RefillListQuery struct {
+	PatientID  string
+	WithinDays *int
+}
+
+// PatientRefillResponse lists one patient's medicines that need a refill,
+// soonest run-out first.
+type PatientRefillResponse struct {
+	UserID    string                   `json:"user_id"`
+	Username  string                   `json:"username"`
+	FirstName *string                  `json:"first_name,omitempty"`
+	LastName  *string                  `json:"last_name,omitempty"`
+	HN        *string                  `json:"hn,omitempty"`
+	Medicines []RefillMedicineResponse `json:"medicines"`
+}
+
+// RefillMedicineResponse projects when a medicine runs out from its stock on
+// hand and schedules, counting from today.
+type RefillMedicineResponse struct {
+	PatientMedicineID string   `json:"patient_medicine_id"`
+	MedicineName      string   `json:"medicine_name"`
+	DosageAmount      string   `json:"dosage_amount"`
+	QuantityOnHand    float64  `json:"quantity_on_hand"`
+	PackSize          *float64 `json:"pack_size,omitempty"`
+	RefillDate        *string  `json:"refill_date,omitempty"`
+	DaysRemaining     int      `json:"days_remaining"`
+	RunOutDate        string   `json:"run_out_date"`
+}
@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type SyncMutationRequest struct {
+	ClientID        string          `json:"client_id" validate:"required"`
+	Type            string          `json:"type" validate:"required"`
+	ClientTimestamp string          `json:"client_timestamp" validate:"required"`
+	Data            json.RawMessage `json:"data" validate:"required"`
+}
+
+type SyncBatchRequest struct {
+	Items []SyncMutationRequest `json:"items" validate:"required,min=1,max=100,dive"`
+}
+
+type SyncItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type SyncMutationResult struct {
+	ClientID  string         `json:"client_id"`
+	Status    string         `json:"status"`
+	Duplicate bool           `json:"duplicate"`
+	EntityID  *string        `json:"entity_id,omitempty"`
+	Error     *SyncItemError `json:"error,omitempty"`
+}
+
+type SyncBatchResponse struct {
+	Results []SyncMutationResult `json:"results"`
+}
+
+type SyncChangeItem struct {
+	Cursor     string          `json:"cursor"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Operation  string          `json:"operation"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	ActorID    *string         `json:"actor_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type SyncChangesResponse struct {
+	Changes    []SyncChangeItem `json:"changes"`
+	NextCursor string           `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+}
@@ -7,9 +7,10 @@ import (
 )
 
 type MeResponse struct {
-	ID      string          `json:"id"`
-	Role    constants.Role  `json:"role"`
-	Profile ProfileResponse `json:"profile"`
+	ID         string          `json:"id"`
+	Role       constants.Role  `json:"role"`
+	LineLinked bool            `json:"line_linked"`
+	Profile    ProfileResponse `json:"profile"`
 }
 
 type ProfileResponse struct {
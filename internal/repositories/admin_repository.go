@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+// minCitizenIDSuffix is the shortest all-digit query treated as a citizen ID
+// suffix; shorter digit runs would match most of the directory.
+const minCitizenIDSuffix = 4
+
+type PatientListFilter struct {
+	Query       string
+	IsActive    *bool
+	CaregiverID *uuid.UUID
+}
+
+type PatientRow struct {
+	ID        uuid.UUID
+	Username  string
+	IsActive  bool
+	HN        *string
+	FirstName *string
+	LastName  *string
+	CreatedAt time.Time
+}
+
+// DoseTimingFilter narrows the dose timing report. From/To bound the target
+// date and SlotFrom/SlotTo ("HH:MM") the scheduled time slot; zero values are
+// unbounded.
+type DoseTimingFilter struct {
+	PatientID *uuid.UUID
+	Timing    string
+	From      time.Time
+	To        time.Time
+	SlotFrom  string
+	SlotTo    string
+	MinDelay  time.Duration
+}
+
+type DoseTimingRow struct {
+	IntakeID     uuid.UUID
+	UserID       uuid.UUID
+	Username     string
+	HN           *string
+	FirstName    *string
+	LastName     *string
+	MedicineName string
+	TimeSlot     time.Time
+	TargetDate   time.Time
+	DueAt        time.Time
+	TakenAt      time.Time
+	Timing       string
+}
+
+type PatientCaregiverRow struct {
+	AssignmentID uuid.UUID
+	CaregiverID  uuid.UUID
+	Relationship string
+	Username     string
+	FirstName    *string
+	LastName     *string
+}
+
+type AdminRepository interface {
+	ListPatients(ctx context.Context, filter PatientListFilter, page, pageSize int) ([]PatientRow, int64, error)
+	FindPatient(ctx context.Context, id uuid.UUID) (*db.User, error)
+	ListActiveMedicines(ctx context.Context, userID uuid.UUID) ([]db.PatientMedicine, error)
+	ListSchedulesByMedicineIDs(ctx context.Context, medicineIDs []uuid.UUID) ([]db.MedicineSchedule, error)
+	FindNextAppointment(ctx context.Context, userID uuid.UUID, after time.Time) (*db.Appointment, error)
+	FindLatestBloodPressure(ctx context.Context, userID uuid.UUID) (*db.HealthRecord, error)
+	ListCaregivers(ctx context.Context, patientID uuid.UUID) ([]PatientCaregiverRow, error)
+	ListDoseTimings(ctx context.Context, filter DoseTimingFilter, page, pageSize int) ([]DoseTimingRow, int64, error)
+}
+
+type adminRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminRepository(dbConn *gorm.DB) AdminRepository {
+	return &adminRepository{db: dbConn}
+}
+
+func (r *adminRepository) ListPatients(ctx context.Context, filter PatientListFilter, page, pageSize int) ([]PatientRow, int64, error) {
+	query := conn(ctx, r.db).
+		Table("users AS u").
+		Joins("LEFT JOIN user_profiles AS p ON p.user_id = u.id").
+		Where("u.role = ? AND u.deleted_at IS NULL", constants.RolePatient)
+
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		like := "%" + escapeLike(q) + "%"
+		conds := []string{
+			"p.hn ILIKE ?",
+			"p.first_name ILIKE ?",
+			"p.last_name ILIKE ?",
+			"(p.first_name || ' ' || p.last_name) ILIKE ?",
+			"u.username ILIKE ?",
+		}
+		args := []any{like, like, like, like, like}
+		if len(q) >= minCitizenIDSuffix && isDigits(q) {
+			conds = append(conds, "p.citizen_id LIKE ?")
+			args = append(args, "%"+q)
+		}
+		query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("u.is_active = ?", *filter.IsActive)
+	}
+	if filter.CaregiverID != nil {
+		query = query.Where("EXISTS (SELECT 1 FROM caregiver_assignments ca WHERE ca.patient_id = u.id AND ca.caregiver_id = ?)", *filter.CaregiverID)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "count patients failed", err)
+	}
+
+	var items []PatientRow
+	if err := query.
+		Select("u.id, u.username, u.is_active, p.hn, p.first_name, p.last_name, u.created_at").
+		Order("u.created_at desc").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Scan(&items).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "list patients failed", err)
+	}
+	return items, total, nil
+}
+
+func (r *adminRepository) FindPatient(ctx context.Context, id uuid.UUID) (*db.User, error) {
+	var user db.User
+	if err := conn(ctx, r.db).
+		Preload("Profile").
+		Where("id = ? AND role = ?", id, constants.RolePatient).
+		First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.NewError(constants.UserNotFound, "patient not found")
+		}
+		return nil, domain.WrapError(constants.InternalError, "find patient failed", err)
+	}
+	return &user, nil
+}
+
+func (r *adminRepository) ListActiveMedicines(ctx context.Context, userID uuid.UUID) ([]db.PatientMedicine, error) {
+	var items []db.PatientMedicine
+	if err := conn(ctx, r.db).
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Order("created_at desc").
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list active medicines failed", err)
+	}
+	return items, nil
+}
+
+func (r *adminRepository) ListSchedulesByMedicineIDs(ctx context.Context, medicineIDs []uuid.UUID) ([]db.MedicineSchedule, error) {
+	if len(medicineIDs) == 0 {
+		return nil, nil
+	}
+	var items []db.MedicineSchedule
+	if err := conn(ctx, r.db).
+		Where("patient_medicine_id IN ?", medicineIDs).
+		Order("time_slot asc").
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list medicine schedules failed", err)
+	}
+	return items, nil
+}
+
+func (r *adminRepository) FindNextAppointment(ctx context.Context, userID uuid.UUID, after time.Time) (*db.Appointment, error) {
+	var items []db.Appointment
+	if err := conn(ctx, r.db).
+		Where("user_id = ? AND appt_datetime >= ?", userID, after).
+		Where("status IN ?", []constants.AppointmentStatus{constants.ApptPending, constants.ApptConfirmed}).
+		Order("appt_datetime asc").
+		Limit(1).
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "find next appointment failed", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+func (r *adminRepository) FindLatestBloodPressure(ctx context.Context, userID uuid.UUID) (*db.HealthRecord, error) {
+	var items []db.HealthRecord
+	if err := conn(ctx, r.db).
+		Where("user_id = ? AND systolic_bp IS NOT NULL AND diastolic_bp IS NOT NULL", userID).
+		Order("record_date desc, created_at desc").
+		Limit(1).
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "find latest blood pressure failed", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+func (r *adminRepository) ListCaregivers(ctx context.Context, patientID uuid.UUID) ([]PatientCaregiverRow, error) {
+	var items []PatientCaregiverRow
+	if err := conn(ctx, r.db).
+		Table("caregiver_assignments AS ca").
+		Select("ca.id AS assignment_id, ca.caregiver_id, ca.relationship, u.username, p.first_name, p.last_name").
+		Joins("JOIN users AS u ON u.id = ca.caregiver_id AND u.deleted_at IS NULL").
+		Joins("LEFT JOIN user_profiles AS p ON p.user_id = ca.caregiver_id").
+		Where("ca.patient_id = ?", patientID).
+		Order("ca.created_at asc").
+		Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list patient caregivers failed", err)
+	}
+	return items, nil
+}
+
+// ListDoseTimings lists classified TAKEN doses across patients, latest first.
+func (r *adminRepository) ListDoseTimings(ctx context.Context, filter DoseTimingFilter, page, pageSize int) ([]DoseTimingRow, int64, error) {
+	query := conn(ctx, r.db).
+		Table("intake_history AS ih").
+		Joins("JOIN medicine_schedules AS ms ON ms.id = ih.schedule_id").
+		Joins("JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id").
+		Joins("LEFT JOIN medicines_master AS mm ON mm.id = pm.medicine_master_id").
+		Joins("LEFT JOIN medicine_category_items AS mci ON mci.id = pm.category_item_id").
+		Joins("JOIN users AS u ON u.id = ih.user_id AND u.deleted_at IS NULL").
+		Joins("LEFT JOIN user_profiles AS p ON p.user_id = ih.user_id").
+		Where("ih.status = ? AND ih.timing IS NOT NULL", constants.MedTaken)
+
+	if filter.PatientID != nil {
+		query = query.Where("ih.user_id = ?", *filter.PatientID)
+	}
+	if filter.Timing != "" {
+		query = query.Where("ih.timing = ?", filter.Timing)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("ih.target_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("ih.target_date <= ?", filter.To)
+	}
+	if filter.SlotFrom != "" {
+		query = query.Where("ms.time_slot >= ?::time", filter.SlotFrom)
+	}
+	if filter.SlotTo != "" {
+		query = query.Where("ms.time_slot <= ?::time", filter.SlotTo)
+	}
+	if filter.MinDelay > 0 {
+		query = query.Where("ih.taken_at - ih.due_at >= ? * INTERVAL '1 second'", int64(filter.MinDelay.Seconds()))
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "count dose timings failed", err)
+	}
+
+	var items []DoseTimingRow
+	if err := query.
+		Select(`ih.id AS intake_id, ih.user_id, u.username, p.hn, p.first_name, p.last_name,
+			COALESCE(pm.custom_name, mm.trade_name, mci.display_name, '') AS medicine_name,
+			ms.time_slot, ih.target_date, ih.due_at, ih.taken_at, ih.timing`).
+		Order("ih.target_date desc, ih.due_at desc").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Scan(&items).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "list dose timings failed", err)
+	}
+	return items, total, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
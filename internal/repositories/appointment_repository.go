@@ -45,7 +45,7 @@ func NewAppointmentRepository(dbConn *gorm.DB) AppointmentRepository {
 
 func (r *appointmentRepository) ListAppointments(ctx context.Context, userID uuid.UUID) ([]db.Appointment, error) {
 	var items []db.Appointment
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Where("user_id = ?", userID).
 		Order("appt_datetime desc").
 		Find(&items).Error; err != nil {
@@ -55,7 +55,7 @@ func (r *appointmentRepository) ListAppointments(ctx context.Context, userID uui
 }
 
 func (r *appointmentRepository) CreateAppointment(ctx context.Context, appt *db.Appointment) error {
-	if err := r.db.WithContext(ctx).Create(appt).Error; err != nil {
+	if err := conn(ctx, r.db).Create(appt).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create appointment failed", err)
 	}
 	return nil
@@ -63,7 +63,7 @@ func (r *appointmentRepository) CreateAppointment(ctx context.Context, appt *db.
 
 func (r *appointmentRepository) FindByID(ctx context.Context, id uuid.UUID) (*db.Appointment, error) {
 	var appt db.Appointment
-	if err := r.db.WithContext(ctx).First(&appt, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&appt, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.ApptNotFound, "appointment not found")
 		}
@@ -73,7 +73,7 @@ func (r *appointmentRepository) FindByID(ctx context.Context, id uuid.UUID) (*db
 }
 
 func (r *appointmentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status constants.AppointmentStatus) error {
-	result := r.db.WithContext(ctx).Model(&db.Appointment{}).Where("id = ?", id).Update("status", status)
+	result := conn(ctx, r.db).Model(&db.Appointment{}).Where("id = ?", id).Update("status", status)
 	if result.Error != nil {
 		return domain.WrapError(constants.InternalError, "update appointment status failed", result.Error)
 	}
@@ -84,7 +84,7 @@ func (r *appointmentRepository) UpdateStatus(ctx context.Context, id uuid.UUID,
 }
 
 func (r *appointmentRepository) DeleteAppointment(ctx context.Context, id uuid.UUID) error {
-	result := r.db.WithContext(ctx).Delete(&db.Appointment{}, "id = ?", id)
+	result := conn(ctx, r.db).Delete(&db.Appointment{}, "id = ?", id)
 	if result.Error != nil {
 		return domain.WrapError(constants.InternalError, "delete appointment failed", result.Error)
 	}
@@ -95,7 +95,7 @@ func (r *appointmentRepository) DeleteAppointment(ctx context.Context, id uuid.U
 }
 
 func (r *appointmentRepository) CreateNurseVisitNote(ctx context.Context, note *db.NurseVisitNote) error {
-	if err := r.db.WithContext(ctx).Create(note).Error; err != nil {
+	if err := conn(ctx, r.db).Create(note).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create visit note failed", err)
 	}
 	return nil
@@ -103,7 +103,7 @@ func (r *appointmentRepository) CreateNurseVisitNote(ctx context.Context, note *
 
 func (r *appointmentRepository) ListVisitHistory(ctx context.Context, userID uuid.UUID) ([]VisitHistoryRow, error) {
 	var rows []VisitHistoryRow
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Table("nurse_visit_notes").
 		Select("nurse_visit_notes.id as visit_note_id, nurse_visit_notes.nurse_id, nurse_visit_notes.visit_details, nurse_visit_notes.vital_signs_summary, nurse_visit_notes.next_action_plan, nurse_visit_notes.created_at, appointments.id as appointment_id, appointments.appt_datetime, appointments.title, appointments.location_name").
 		Joins("join appointments on appointments.id = nurse_visit_notes.appointment_id").
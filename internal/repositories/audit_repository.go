@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+// AuditLogFilter bounds are half-open: From inclusive, To exclusive.
+type AuditLogFilter struct {
+	From       time.Time
+	To         time.Time
+	ActorID    *uuid.UUID
+	ActionType string
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *db.AuditLog) error
+	List(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]db.AuditLog, int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(dbConn *gorm.DB) AuditRepository {
+	return &auditRepository{db: dbConn}
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *db.AuditLog) error {
+	if err := conn(ctx, r.db).Create(entry).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "create audit log failed", err)
+	}
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, filter AuditLogFilter, page, pageSize int) ([]db.AuditLog, int64, error) {
+	query := conn(ctx, r.db).Model(&db.AuditLog{})
+	if !filter.From.IsZero() {
+		query = query.Where("timestamp >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("timestamp < ?", filter.To)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.ActionType != "" {
+		query = query.Where("action_type = ?", filter.ActionType)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "count audit logs failed", err)
+	}
+
+	var items []db.AuditLog
+	if err := query.
+		Order("timestamp desc").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&items).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "list audit logs failed", err)
+	}
+	return items, total, nil
+}
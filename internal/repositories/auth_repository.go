@@ -27,7 +27,7 @@ func NewAuthRepository(dbConn *gorm.DB) AuthRepository {
 }
 
 func (r *authRepository) CreateOTP(ctx context.Context, otp *db.AuthOtpCode) error {
-	if err := r.db.WithContext(ctx).Create(otp).Error; err != nil {
+	if err := conn(ctx, r.db).Create(otp).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create otp failed", err)
 	}
 	return nil
@@ -35,7 +35,7 @@ func (r *authRepository) CreateOTP(ctx context.Context, otp *db.AuthOtpCode) err
 
 func (r *authRepository) FindOTP(ctx context.Context, phone, refCode string) (*db.AuthOtpCode, error) {
 	var otp db.AuthOtpCode
-	err := r.db.WithContext(ctx).
+	err := conn(ctx, r.db).
 		Where("phone_number = ? AND ref_code = ?", phone, refCode).
 		Order("created_at desc").
 		First(&otp).Error
@@ -55,7 +55,7 @@ func (r *authRepository) FindOTP(ctx context.Context, phone, refCode string) (*d
 }
 
 func (r *authRepository) MarkOTPUsed(ctx context.Context, id uuid.UUID) error {
-	if err := r.db.WithContext(ctx).Model(&db.AuthOtpCode{}).Where("id = ?", id).Update("is_used", true).Error; err != nil {
+	if err := conn(ctx, r.db).Model(&db.AuthOtpCode{}).Where("id = ?", id).Update("is_used", true).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "mark otp used failed", err)
 	}
 	return nil
@@ -26,7 +26,7 @@ func NewCaregiverRepository(dbConn *gorm.DB) CaregiverRepository {
 }
 
 func (r *caregiverRepository) CreateAssignment(ctx context.Context, assignment *db.CaregiverAssignment) error {
-	if err := r.db.WithContext(ctx).Create(assignment).Error; err != nil {
+	if err := conn(ctx, r.db).Create(assignment).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create caregiver assignment failed", err)
 	}
 	return nil
@@ -34,7 +34,7 @@ func (r *caregiverRepository) CreateAssignment(ctx context.Context, assignment *
 
 func (r *caregiverRepository) ListAssignmentsByPatient(ctx context.Context, patientID uuid.UUID) ([]db.CaregiverAssignment, error) {
 	var items []db.CaregiverAssignment
-	if err := r.db.WithContext(ctx).Where("patient_id = ?", patientID).Find(&items).Error; err != nil {
+	if err := conn(ctx, r.db).Where("patient_id = ?", patientID).Find(&items).Error; err != nil {
 		return nil, domain.WrapError(constants.InternalError, "list caregiver assignments failed", err)
 	}
 	return items, nil
@@ -42,7 +42,7 @@ func (r *caregiverRepository) ListAssignmentsByPatient(ctx context.Context, pati
 
 func (r *caregiverRepository) IsAssigned(ctx context.Context, caregiverID, patientID uuid.UUID) (bool, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Model(&db.CaregiverAssignment{}).
 		Where("caregiver_id = ? AND patient_id = ?", caregiverID, patientID).
 		Count(&count).Error; err != nil {
@@ -29,7 +29,7 @@ func NewContentRepository(dbConn *gorm.DB) ContentRepository {
 
 func (r *contentRepository) ListHealthContent(ctx context.Context, publishedOnly bool) ([]db.HealthContent, error) {
 	var items []db.HealthContent
-	query := r.db.WithContext(ctx)
+	query := conn(ctx, r.db)
 	if publishedOnly {
 		query = query.Where("is_published = ?", true)
 	}
@@ -40,14 +40,14 @@ func (r *contentRepository) ListHealthContent(ctx context.Context, publishedOnly
 }
 
 func (r *contentRepository) CreateHealthContent(ctx context.Context, content *db.HealthContent) error {
-	if err := r.db.WithContext(ctx).Create(content).Error; err != nil {
+	if err := conn(ctx, r.db).Create(content).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create health content failed", err)
 	}
 	return nil
 }
 
 func (r *contentRepository) UpdateHealthContent(ctx context.Context, id uuid.UUID, updates map[string]any) error {
-	result := r.db.WithContext(ctx).Model(&db.HealthContent{}).Where("id = ?", id).Updates(updates)
+	result := conn(ctx, r.db).Model(&db.HealthContent{}).Where("id = ?", id).Updates(updates)
 	if result.Error != nil {
 		return domain.WrapError(constants.InternalError, "update health content failed", result.Error)
 	}
@@ -58,7 +58,7 @@ func (r *contentRepository) UpdateHealthContent(ctx context.Context, id uuid.UUI
 }
 
 func (r *contentRepository) SetPublished(ctx context.Context, id uuid.UUID, published bool) error {
-	result := r.db.WithContext(ctx).Model(&db.HealthContent{}).Where("id = ?", id).Update("is_published", published)
+	result := conn(ctx, r.db).Model(&db.HealthContent{}).Where("id = ?", id).Update("is_published", published)
 	if result.Error != nil {
 		return domain.WrapError(constants.InternalError, "update publish status failed", result.Error)
 	}
@@ -70,7 +70,7 @@ func (r *contentRepository) SetPublished(ctx context.Context, id uuid.UUID, publ
 
 func (r *contentRepository) FindByID(ctx context.Context, id uuid.UUID) (*db.HealthContent, error) {
 	var item db.HealthContent
-	if err := r.db.WithContext(ctx).First(&item, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&item, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.ContentNotFound, "health content not found")
 		}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
@@ -12,6 +13,8 @@ import (
 
 type DeviceTokenRepository interface {
 	Save(ctx context.Context, token *db.DeviceToken) error
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]db.DeviceToken, error)
+	Deactivate(ctx context.Context, id uuid.UUID) error
 }
 
 type deviceTokenRepository struct {
@@ -24,12 +27,12 @@ func NewDeviceTokenRepository(dbConn *gorm.DB) DeviceTokenRepository {
 
 func (r *deviceTokenRepository) Save(ctx context.Context, token *db.DeviceToken) error {
 	var existing db.DeviceToken
-	err := r.db.WithContext(ctx).
+	err := conn(ctx, r.db).
 		Where("user_id = ? AND platform = ? AND token = ?", token.UserID, token.Platform, token.Token).
 		First(&existing).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+			if err := conn(ctx, r.db).Create(token).Error; err != nil {
 				return domain.WrapError(constants.InternalError, "create device token failed", err)
 			}
 			return nil
@@ -37,8 +40,26 @@ func (r *deviceTokenRepository) Save(ctx context.Context, token *db.DeviceToken)
 		return domain.WrapError(constants.InternalError, "find device token failed", err)
 	}
 
-	if err := r.db.WithContext(ctx).Model(&db.DeviceToken{}).Where("id = ?", existing.ID).Update("is_active", true).Error; err != nil {
+	if err := conn(ctx, r.db).Model(&db.DeviceToken{}).Where("id = ?", existing.ID).Update("is_active", true).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "update device token failed", err)
 	}
 	return nil
 }
+
+func (r *deviceTokenRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]db.DeviceToken, error) {
+	var items []db.DeviceToken
+	if err := conn(ctx, r.db).
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Order("created_at desc").
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list device tokens failed", err)
+	}
+	return items, nil
+}
+
+func (r *deviceTokenRepository) Deactivate(ctx context.Context, id uuid.UUID) error {
+	if err := conn(ctx, r.db).Model(&db.DeviceToken{}).Where("id = ?", id).Update("is_active", false).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "deactivate device token failed", err)
+	}
+	return nil
+}
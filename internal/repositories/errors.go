@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"errors"
+
+	pgconnv1 "github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const pgUniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation.
+// The GORM postgres driver returns pgx v5 errors; the v1 type is kept for older callers.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return true
+	}
+	var pgErrV1 *pgconnv1.PgError
+	return errors.As(err, &pgErrV1) && pgErrV1.Code == pgUniqueViolation
+}
@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+// EscalationCandidate is an active patient with a recent MISSED dose and
+// escalation enabled, together with their policy overrides, if any.
+type EscalationCandidate struct {
+	UserID             uuid.UUID
+	CaregiverThreshold *int
+	NurseThreshold     *int
+	NurseID            *uuid.UUID
+}
+
+// DoseOutcomeRow is a recorded scheduled dose; DueAt falls back to the start
+// of the target date for records created before due times were stored.
+type DoseOutcomeRow struct {
+	Status constants.MedIntakeStatus
+	DueAt  time.Time
+}
+
+type EscalationRepository interface {
+	GetPolicy(ctx context.Context, userID uuid.UUID) (*db.EscalationPolicy, error)
+	SavePolicy(ctx context.Context, policy *db.EscalationPolicy) error
+	ListMissedPatients(ctx context.Context, since time.Time) ([]EscalationCandidate, error)
+	ListRecentDoses(ctx context.Context, userID uuid.UUID, since time.Time) ([]DoseOutcomeRow, error)
+	FindLatestEscalation(ctx context.Context, userID uuid.UUID, level string) (*db.MissedDoseEscalation, error)
+	HasDoseOutcomeSince(ctx context.Context, userID uuid.UUID, after time.Time) (bool, error)
+	ListCaregiverIDs(ctx context.Context, patientID uuid.UUID) ([]uuid.UUID, error)
+	FindLatestVisitNurse(ctx context.Context, patientID uuid.UUID) (*uuid.UUID, error)
+	CreateEscalations(ctx context.Context, escalations []db.MissedDoseEscalation, events []db.NotificationEvent) error
+}
+
+type escalationRepository struct {
+	db *gorm.DB
+}
+
+func NewEscalationRepository(dbConn *gorm.DB) EscalationRepository {
+	return &escalationRepository{db: dbConn}
+}
+
+const doseDueAtExpr = "COALESCE(ih.due_at, ih.target_date::timestamp AT TIME ZONE 'UTC')"
+
+// GetPolicy returns the patient's policy, or nil when the defaults apply.
+func (r *escalationRepository) GetPolicy(ctx context.Context, userID uuid.UUID) (*db.EscalationPolicy, error) {
+	var items []db.EscalationPolicy
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Limit(1).Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "find escalation policy failed", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+func (r *escalationRepository) SavePolicy(ctx context.Context, policy *db.EscalationPolicy) error {
+	if err := conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "caregiver_threshold", "nurse_threshold", "nurse_id", "updated_by", "updated_at"}),
+		}).
+		Create(policy).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "save escalation policy failed", err)
+	}
+	return nil
+}
+
+func (r *escalationRepository) ListMissedPatients(ctx context.Context, since time.Time) ([]EscalationCandidate, error) {
+	var items []EscalationCandidate
+	if err := conn(ctx, r.db).
+		Table("users AS u").
+		Select("u.id AS user_id, ep.caregiver_threshold, ep.nurse_threshold, ep.nurse_id").
+		Joins("LEFT JOIN escalation_policies AS ep ON ep.user_id = u.id").
+		Where("u.role = ? AND u.is_active = ? AND u.deleted_at IS NULL", constants.RolePatient, true).
+		Where("ep.enabled IS NULL OR ep.enabled").
+		Where("EXISTS (SELECT 1 FROM intake_history AS ih WHERE ih.user_id = u.id AND ih.status = ? AND ih.schedule_id IS NOT NULL AND "+doseDueAtExpr+" >= ?)", constants.MedMissed, since).
+		Order("u.id asc").
+		Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list patients with missed doses failed", err)
+	}
+	return items, nil
+}
+
+// ListRecentDoses returns the patient's recorded scheduled doses due since
+// since, latest first.
+func (r *escalationRepository) ListRecentDoses(ctx context.Context, userID uuid.UUID, since time.Time) ([]DoseOutcomeRow, error) {
+	var items []DoseOutcomeRow
+	if err := conn(ctx, r.db).
+		Table("intake_history AS ih").
+		Select("ih.status, "+doseDueAtExpr+" AS due_at").
+		Where("ih.user_id = ? AND ih.schedule_id IS NOT NULL AND "+doseDueAtExpr+" >= ?", userID, since).
+		Order("due_at desc, ih.created_at desc").
+		Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list recent doses failed", err)
+	}
+	return items, nil
+}
+
+func (r *escalationRepository) FindLatestEscalation(ctx context.Context, userID uuid.UUID, level string) (*db.MissedDoseEscalation, error) {
+	var items []db.MissedDoseEscalation
+	if err := conn(ctx, r.db).
+		Where("user_id = ? AND level = ?", userID, level).
+		Order("created_at desc").
+		Limit(1).
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "find latest escalation failed", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+// HasDoseOutcomeSince reports whether a non-MISSED scheduled dose due after
+// after has been recorded, i.e. whether a missed-dose streak was broken.
+func (r *escalationRepository) HasDoseOutcomeSince(ctx context.Context, userID uuid.UUID, after time.Time) (bool, error) {
+	var count int64
+	if err := conn(ctx, r.db).
+		Table("intake_history AS ih").
+		Where("ih.user_id = ? AND ih.schedule_id IS NOT NULL AND ih.status <> ? AND "+doseDueAtExpr+" > ?", userID, constants.MedMissed, after).
+		Limit(1).
+		Count(&count).Error; err != nil {
+		return false, domain.WrapError(constants.InternalError, "find dose outcome failed", err)
+	}
+	return count > 0, nil
+}
+
+func (r *escalationRepository) ListCaregiverIDs(ctx context.Context, patientID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := conn(ctx, r.db).
+		Table("caregiver_assignments AS ca").
+		Joins("JOIN users AS u ON u.id = ca.caregiver_id AND u.is_active = ? AND u.deleted_at IS NULL", true).
+		Where("ca.patient_id = ?", patientID).
+		Distinct().
+		Pluck("ca.caregiver_id", &ids).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list caregivers failed", err)
+	}
+	return ids, nil
+}
+
+// FindLatestVisitNurse returns the active nurse who wrote the patient's most
+// recent visit note, or nil.
+func (r *escalationRepository) FindLatestVisitNurse(ctx context.Context, patientID uuid.UUID) (*uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := conn(ctx, r.db).
+		Table("nurse_visit_notes AS n").
+		Joins("JOIN appointments AS a ON a.id = n.appointment_id").
+		Joins("JOIN users AS u ON u.id = n.nurse_id AND u.is_active = ? AND u.deleted_at IS NULL", true).
+		Where("a.user_id = ?", patientID).
+		Order("n.created_at desc").
+		Limit(1).
+		Pluck("n.nurse_id", &ids).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "find visit nurse failed", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return &ids[0], nil
+}
+
+// CreateEscalations records escalations and queues their notification events
+// in one transaction.
+func (r *escalationRepository) CreateEscalations(ctx context.Context, escalations []db.MissedDoseEscalation, events []db.NotificationEvent) error {
+	if len(escalations) == 0 {
+		return nil
+	}
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&escalations).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Clauses(notificationEventConflict).CreateInBatches(events, 100).Error
+	})
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "create escalations failed", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+type HealthRepository interface {
+	CreateHealthRecord(ctx context.Context, record *db.HealthRecord) error
+	ListHealthRecords(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.HealthRecord, error)
+	CreateDailyAssessment(ctx context.Context, assessment *db.DailyAssessment) error
+	ListDailyAssessments(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.DailyAssessment, error)
+}
+
+type healthRepository struct {
+	db *gorm.DB
+}
+
+func NewHealthRepository(dbConn *gorm.DB) HealthRepository {
+	return &healthRepository{db: dbConn}
+}
+
+func (r *healthRepository) CreateHealthRecord(ctx context.Context, record *db.HealthRecord) error {
+	if err := conn(ctx, r.db).Create(record).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "create health record failed", err)
+	}
+	return nil
+}
+
+func (r *healthRepository) ListHealthRecords(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.HealthRecord, error) {
+	var items []db.HealthRecord
+	query := conn(ctx, r.db).Where("user_id = ?", userID)
+	if !from.IsZero() {
+		query = query.Where("record_date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("record_date <= ?", to)
+	}
+	if err := query.Order("record_date desc, created_at desc").Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list health records failed", err)
+	}
+	return items, nil
+}
+
+func (r *healthRepository) CreateDailyAssessment(ctx context.Context, assessment *db.DailyAssessment) error {
+	if err := conn(ctx, r.db).Create(assessment).Error; err != nil {
+		if isUniqueViolation(err) {
+			return domain.NewError(constants.HealthConflict, "daily assessment already exists for log_date")
+		}
+		return domain.WrapError(constants.InternalError, "create daily assessment failed", err)
+	}
+	return nil
+}
+
+func (r *healthRepository) ListDailyAssessments(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.DailyAssessment, error) {
+	var items []db.DailyAssessment
+	query := conn(ctx, r.db).Where("user_id = ?", userID)
+	if !from.IsZero() {
+		query = query.Where("log_date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("log_date <= ?", to)
+	}
+	if err := query.Order("log_date desc").Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list daily assessments failed", err)
+	}
+	return items, nil
+}
@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
@@ -12,9 +14,28 @@ import (
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 )
 
+// MissedDose is an expected dose (schedule plus local calendar date) that
+// should be recorded as MISSED.
+type MissedDose struct {
+	UserID     uuid.UUID
+	ScheduleID uuid.UUID
+	TargetDate time.Time
+	DueAt      time.Time
+}
+
+// errIntakeChanged means another request changed an intake's status after
+// the caller read it.
+var errIntakeChanged = errors.New("intake changed concurrently")
+
 type IntakeRepository interface {
 	Create(ctx context.Context, intake *db.IntakeHistory) error
+	GetByID(ctx context.Context, id uuid.UUID) (*db.IntakeHistory, error)
+	FindByDose(ctx context.Context, userID, scheduleID uuid.UUID, targetDate time.Time) (*db.IntakeHistory, error)
+	Correct(ctx context.Context, intake *db.IntakeHistory, change *db.IntakeChange) error
+	Delete(ctx context.Context, id uuid.UUID, change *db.IntakeChange) error
+	ListChanges(ctx context.Context, intakeID uuid.UUID) ([]db.IntakeChange, error)
 	ListHistory(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.IntakeHistory, error)
+	CreateMissed(ctx context.Context, doses []MissedDose) (int64, error)
 }
 
 type intakeRepository struct {
@@ -25,16 +46,126 @@ func NewIntakeRepository(dbConn *gorm.DB) IntakeRepository {
 	return &intakeRepository{db: dbConn}
 }
 
+// Create inserts intake in its own (sub)transaction, so inside a caller's
+// transaction a duplicate dose only rolls back the insert and the caller can
+// still read the record that won.
 func (r *intakeRepository) Create(ctx context.Context, intake *db.IntakeHistory) error {
-	if err := r.db.WithContext(ctx).Create(intake).Error; err != nil {
+	if err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(intake).Error
+	}); err != nil {
+		if isUniqueViolation(err) {
+			return domain.NewError(constants.IntakeConflict, "intake already recorded for this dose")
+		}
 		return domain.WrapError(constants.InternalError, "create intake failed", err)
 	}
 	return nil
 }
 
+func (r *intakeRepository) GetByID(ctx context.Context, id uuid.UUID) (*db.IntakeHistory, error) {
+	var item db.IntakeHistory
+	if err := conn(ctx, r.db).First(&item, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.NewError(constants.IntakeNotFound, "intake not found")
+		}
+		return nil, domain.WrapError(constants.InternalError, "find intake failed", err)
+	}
+	return &item, nil
+}
+
+// FindByDose returns the record of one scheduled dose, or nil when none exists.
+func (r *intakeRepository) FindByDose(ctx context.Context, userID, scheduleID uuid.UUID, targetDate time.Time) (*db.IntakeHistory, error) {
+	var items []db.IntakeHistory
+	if err := conn(ctx, r.db).
+		Where("user_id = ? AND schedule_id = ? AND target_date = ?", userID, scheduleID, targetDate).
+		Limit(1).
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "find intake failed", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+// Correct overwrites the status fields of intake and appends change in one
+// transaction, provided the record still has change.PreviousStatus. It returns
+// IntakeConflict when another request changed the status first, so the
+// effects of one transition are never applied twice.
+func (r *intakeRepository) Correct(ctx context.Context, intake *db.IntakeHistory, change *db.IntakeChange) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&db.IntakeHistory{}).Where("id = ? AND status = ?", intake.ID, change.PreviousStatus).Updates(map[string]any{
+			"status":      intake.Status,
+			"taken_at":    intake.TakenAt,
+			"skip_reason": intake.SkipReason,
+			"updated_at":  intake.UpdatedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleIntake(tx, intake.ID)
+		}
+		return tx.Create(change).Error
+	})
+	return intakeWriteError(err, "correct intake failed")
+}
+
+// Delete removes an intake record and appends change in one transaction, with
+// the same status guard as Correct.
+func (r *intakeRepository) Delete(ctx context.Context, id uuid.UUID, change *db.IntakeChange) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&db.IntakeHistory{}, "id = ? AND status = ?", id, change.PreviousStatus)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return staleIntake(tx, id)
+		}
+		return tx.Create(change).Error
+	})
+	return intakeWriteError(err, "delete intake failed")
+}
+
+// staleIntake explains why a guarded write matched no row: the record is gone
+// or its status changed.
+func staleIntake(tx *gorm.DB, id uuid.UUID) error {
+	var count int64
+	if err := tx.Model(&db.IntakeHistory{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return errIntakeChanged
+}
+
+func intakeWriteError(err error, message string) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == gorm.ErrRecordNotFound:
+		return domain.NewError(constants.IntakeNotFound, "intake not found")
+	case errors.Is(err, errIntakeChanged):
+		return domain.NewError(constants.IntakeConflict, "intake was changed by another request")
+	default:
+		return domain.WrapError(constants.InternalError, message, err)
+	}
+}
+
+func (r *intakeRepository) ListChanges(ctx context.Context, intakeID uuid.UUID) ([]db.IntakeChange, error) {
+	var items []db.IntakeChange
+	if err := conn(ctx, r.db).
+		Where("intake_id = ?", intakeID).
+		Order("created_at asc").
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list intake changes failed", err)
+	}
+	return items, nil
+}
+
 func (r *intakeRepository) ListHistory(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.IntakeHistory, error) {
 	var items []db.IntakeHistory
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	query := conn(ctx, r.db).Where("user_id = ?", userID)
 	if !from.IsZero() {
 		query = query.Where("target_date >= ?", from)
 	}
@@ -46,3 +177,39 @@ func (r *intakeRepository) ListHistory(ctx context.Context, userID uuid.UUID, fr
 	}
 	return items, nil
 }
+
+// CreateMissed inserts a MISSED row for every dose that has no intake record
+// yet, publishes each to the owner's sync change feed, and returns how many
+// were inserted. Doses already recorded, including ones recorded concurrently,
+// are skipped by the unique dose index.
+func (r *intakeRepository) CreateMissed(ctx context.Context, doses []MissedDose) (int64, error) {
+	if len(doses) == 0 {
+		return 0, nil
+	}
+	userIDs := make([]string, 0, len(doses))
+	scheduleIDs := make([]string, 0, len(doses))
+	dates := make([]string, 0, len(doses))
+	dueAts := make([]string, 0, len(doses))
+	for _, dose := range doses {
+		userIDs = append(userIDs, dose.UserID.String())
+		scheduleIDs = append(scheduleIDs, dose.ScheduleID.String())
+		dates = append(dates, dose.TargetDate.Format("2006-01-02"))
+		dueAts = append(dueAts, dose.DueAt.UTC().Format(time.RFC3339))
+	}
+
+	result := conn(ctx, r.db).Exec(`WITH inserted AS (
+			INSERT INTO intake_history (user_id, schedule_id, target_date, due_at, status)
+			SELECT d.user_id, d.schedule_id, d.target_date, d.due_at, ?::med_intake_status
+			FROM unnest(?::uuid[], ?::uuid[], ?::date[], ?::timestamptz[]) AS d(user_id, schedule_id, target_date, due_at)
+			ON CONFLICT (user_id, schedule_id, target_date) WHERE schedule_id IS NOT NULL DO NOTHING
+			RETURNING id, user_id, schedule_id, target_date, due_at, status, created_at
+		)
+		INSERT INTO sync_changes (user_id, entity_type, entity_id, operation, payload)
+		SELECT user_id, ?, id, ?, jsonb_build_object('id', id, 'user_id', user_id, 'schedule_id', schedule_id, 'target_date', target_date, 'due_at', due_at, 'status', status, 'created_at', created_at)
+		FROM inserted`,
+		constants.MedMissed, pq.Array(userIDs), pq.Array(scheduleIDs), pq.Array(dates), pq.Array(dueAts), constants.SyncEntityIntake, constants.SyncOpUpsert)
+	if result.Error != nil {
+		return 0, domain.WrapError(constants.InternalError, "mark missed intake failed", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+type JobRunRepository interface {
+	Start(ctx context.Context, run *db.JobRun) error
+	Finish(ctx context.Context, id uuid.UUID, status constants.JobRunStatus, finishedAt time.Time, duration time.Duration, errMsg *string) error
+	DeleteStartedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type jobRunRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRunRepository(dbConn *gorm.DB) JobRunRepository {
+	return &jobRunRepository{db: dbConn}
+}
+
+func (r *jobRunRepository) Start(ctx context.Context, run *db.JobRun) error {
+	if err := conn(ctx, r.db).Create(run).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "create job run failed", err)
+	}
+	return nil
+}
+
+func (r *jobRunRepository) Finish(ctx context.Context, id uuid.UUID, status constants.JobRunStatus, finishedAt time.Time, duration time.Duration, errMsg *string) error {
+	if err := conn(ctx, r.db).
+		Model(&db.JobRun{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":      status,
+			"finished_at": finishedAt,
+			"duration_ms": duration.Milliseconds(),
+			"error":       errMsg,
+		}).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "finish job run failed", err)
+	}
+	return nil
+}
+
+// DeleteStartedBefore removes runs started before the cutoff and returns how
+// many were deleted.
+func (r *jobRunRepository) DeleteStartedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("started_at < ?", before).Delete(&db.JobRun{})
+	if result.Error != nil {
+		return 0, domain.WrapError(constants.InternalError, "delete job runs failed", result.Error)
+	}
+	return result.RowsAffected, nil
+}
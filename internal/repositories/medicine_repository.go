@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,6 +12,37 @@ import (
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 )
 
+// ScheduleWithMedicineRow is a schedule with its medicine. A row from
+// ListScheduleHistory may be an earlier version of the schedule, in effect
+// from ValidFrom through ValidUntil (unbounded when nil); DeletedAt is when
+// the schedule or its medicine was deleted.
+type ScheduleWithMedicineRow struct {
+	ScheduleID        uuid.UUID
+	PatientMedicineID uuid.UUID
+	MedicineName      string
+	DosageAmount      string
+	Instruction       *string
+	MedicineActive    bool
+	TimeSlot          time.Time
+	MealTiming        *string
+	StartDate         time.Time
+	EndDate           *time.Time
+	IntervalDays      int
+	WeekdayMask       int
+	ScheduleCreatedAt time.Time
+	ValidFrom         *time.Time
+	ValidUntil        *time.Time
+	DeletedAt         *time.Time
+}
+
+// ActiveScheduleRow is a schedule whose medicine and owner are both active,
+// with the owner's preferred timezone, if any.
+type ActiveScheduleRow struct {
+	db.MedicineSchedule
+	UserID   uuid.UUID
+	Timezone *string
+}
+
 type MedicineRepository interface {
 	ListMaster(ctx context.Context, page, pageSize int) ([]db.MedicineMaster, int64, error)
 	GetMasterByID(ctx context.Context, id uuid.UUID) (*db.MedicineMaster, error)
@@ -18,10 +50,17 @@ type MedicineRepository interface {
 	ListPatientMedicines(ctx context.Context, userID uuid.UUID) ([]db.PatientMedicine, error)
 	GetPatientMedicineByID(ctx context.Context, id uuid.UUID) (*db.PatientMedicine, error)
 	UpdatePatientMedicine(ctx context.Context, id uuid.UUID, updates map[string]any) error
+	UpdatePatientMedicineStatus(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time) error
 	DeletePatientMedicine(ctx context.Context, id uuid.UUID) error
 	CreateSchedule(ctx context.Context, schedule *db.MedicineSchedule) error
 	GetScheduleByID(ctx context.Context, id uuid.UUID) (*db.MedicineSchedule, error)
+	ListSchedulesByMedicine(ctx context.Context, patientMedicineID uuid.UUID) ([]db.MedicineSchedule, error)
+	UpdateSchedule(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time) error
+	UpdateScheduleWithReminders(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time, userID uuid.UUID, reminders []db.NotificationEvent) error
 	DeleteSchedule(ctx context.Context, id uuid.UUID) error
+	ListSchedulesWithMedicine(ctx context.Context, userID uuid.UUID) ([]ScheduleWithMedicineRow, error)
+	ListScheduleHistory(ctx context.Context, userID uuid.UUID) ([]ScheduleWithMedicineRow, error)
+	ListActiveSchedules(ctx context.Context, afterID uuid.UUID, limit int) ([]ActiveScheduleRow, error)
 	ListCategories(ctx context.Context) ([]db.MedicineCategory, error)
 	ListCategoryItems(ctx context.Context, categoryID uuid.UUID) ([]db.MedicineCategoryItem, error)
 	GetCategoryItemByID(ctx context.Context, id uuid.UUID) (*db.MedicineCategoryItem, error)
@@ -37,11 +76,11 @@ func NewMedicineRepository(dbConn *gorm.DB) MedicineRepository {
 
 func (r *medicineRepository) ListMaster(ctx context.Context, page, pageSize int) ([]db.MedicineMaster, int64, error) {
 	var total int64
-	if err := r.db.WithContext(ctx).Model(&db.MedicineMaster{}).Count(&total).Error; err != nil {
+	if err := conn(ctx, r.db).Model(&db.MedicineMaster{}).Count(&total).Error; err != nil {
 		return nil, 0, domain.WrapError(constants.InternalError, "count medicine master failed", err)
 	}
 	var items []db.MedicineMaster
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Order("created_at desc").
 		Limit(pageSize).
 		Offset((page - 1) * pageSize).
@@ -53,7 +92,7 @@ func (r *medicineRepository) ListMaster(ctx context.Context, page, pageSize int)
 
 func (r *medicineRepository) GetMasterByID(ctx context.Context, id uuid.UUID) (*db.MedicineMaster, error) {
 	var item db.MedicineMaster
-	if err := r.db.WithContext(ctx).First(&item, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&item, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.MedNotFound, "medicine master not found")
 		}
@@ -63,7 +102,7 @@ func (r *medicineRepository) GetMasterByID(ctx context.Context, id uuid.UUID) (*
 }
 
 func (r *medicineRepository) CreatePatientMedicine(ctx context.Context, med *db.PatientMedicine) error {
-	if err := r.db.WithContext(ctx).Create(med).Error; err != nil {
+	if err := conn(ctx, r.db).Create(med).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create patient medicine failed", err)
 	}
 	return nil
@@ -71,7 +110,7 @@ func (r *medicineRepository) CreatePatientMedicine(ctx context.Context, med *db.
 
 func (r *medicineRepository) ListPatientMedicines(ctx context.Context, userID uuid.UUID) ([]db.PatientMedicine, error) {
 	var items []db.PatientMedicine
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Where("user_id = ?", userID).
 		Order("created_at desc").
 		Find(&items).Error; err != nil {
@@ -82,7 +121,7 @@ func (r *medicineRepository) ListPatientMedicines(ctx context.Context, userID uu
 
 func (r *medicineRepository) GetPatientMedicineByID(ctx context.Context, id uuid.UUID) (*db.PatientMedicine, error) {
 	var item db.PatientMedicine
-	if err := r.db.WithContext(ctx).First(&item, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&item, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.MedNotFound, "patient medicine not found")
 		}
@@ -92,7 +131,7 @@ func (r *medicineRepository) GetPatientMedicineByID(ctx context.Context, id uuid
 }
 
 func (r *medicineRepository) UpdatePatientMedicine(ctx context.Context, id uuid.UUID, updates map[string]any) error {
-	result := r.db.WithContext(ctx).Model(&db.PatientMedicine{}).Where("id = ?", id).Updates(updates)
+	result := conn(ctx, r.db).Model(&db.PatientMedicine{}).Where("id = ?", id).Updates(updates)
 	if result.Error != nil {
 		return domain.WrapError(constants.InternalError, "update patient medicine failed", result.Error)
 	}
@@ -102,8 +141,34 @@ func (r *medicineRepository) UpdatePatientMedicine(ctx context.Context, id uuid.
 	return nil
 }
 
+// UpdatePatientMedicineStatus updates a medicine whose is_active changes on
+// the calendar date effectiveFrom. Its schedules are versioned as they stood
+// before, in the same transaction, so earlier days keep their old status.
+func (r *medicineRepository) UpdatePatientMedicineStatus(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := versionSchedules(tx, "ms.patient_medicine_id = ?", id, effectiveFrom); err != nil {
+			return err
+		}
+		result := tx.Model(&db.PatientMedicine{}).Where("id = ?", id).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err == gorm.ErrRecordNotFound {
+		return domain.NewError(constants.MedNotFound, "patient medicine not found")
+	}
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "update patient medicine failed", err)
+	}
+	return nil
+}
+
 func (r *medicineRepository) DeletePatientMedicine(ctx context.Context, id uuid.UUID) error {
-	result := r.db.WithContext(ctx).Delete(&db.PatientMedicine{}, "id = ?", id)
+	result := conn(ctx, r.db).Delete(&db.PatientMedicine{}, "id = ?", id)
 	if result.Error != nil {
 		return domain.WrapError(constants.InternalError, "delete patient medicine failed", result.Error)
 	}
@@ -114,7 +179,7 @@ func (r *medicineRepository) DeletePatientMedicine(ctx context.Context, id uuid.
 }
 
 func (r *medicineRepository) CreateSchedule(ctx context.Context, schedule *db.MedicineSchedule) error {
-	if err := r.db.WithContext(ctx).Create(schedule).Error; err != nil {
+	if err := conn(ctx, r.db).Create(schedule).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create medicine schedule failed", err)
 	}
 	return nil
@@ -122,7 +187,7 @@ func (r *medicineRepository) CreateSchedule(ctx context.Context, schedule *db.Me
 
 func (r *medicineRepository) GetScheduleByID(ctx context.Context, id uuid.UUID) (*db.MedicineSchedule, error) {
 	var item db.MedicineSchedule
-	if err := r.db.WithContext(ctx).First(&item, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&item, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.MedNotFound, "medicine schedule not found")
 		}
@@ -131,8 +196,86 @@ func (r *medicineRepository) GetScheduleByID(ctx context.Context, id uuid.UUID)
 	return &item, nil
 }
 
+func (r *medicineRepository) ListSchedulesByMedicine(ctx context.Context, patientMedicineID uuid.UUID) ([]db.MedicineSchedule, error) {
+	var items []db.MedicineSchedule
+	if err := conn(ctx, r.db).
+		Where("patient_medicine_id = ?", patientMedicineID).
+		Order("time_slot asc").
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list medicine schedules failed", err)
+	}
+	return items, nil
+}
+
+// UpdateSchedule applies updates from the calendar date effectiveFrom,
+// versioning the schedule as it stood before in the same transaction.
+func (r *medicineRepository) UpdateSchedule(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return updateScheduleVersioned(tx, id, updates, effectiveFrom)
+	})
+	if err == gorm.ErrRecordNotFound {
+		return domain.NewError(constants.MedNotFound, "medicine schedule not found")
+	}
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "update medicine schedule failed", err)
+	}
+	return nil
+}
+
+// UpdateScheduleWithReminders updates a schedule like UpdateSchedule and
+// replaces its unsent reminders with reminders in one transaction, so a
+// failure leaves neither the new time without reminders nor reminders at the
+// old time.
+func (r *medicineRepository) UpdateScheduleWithReminders(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time, userID uuid.UUID, reminders []db.NotificationEvent) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := updateScheduleVersioned(tx, id, updates, effectiveFrom); err != nil {
+			return err
+		}
+		return replaceScheduleEvents(tx, userID, id, reminders)
+	})
+	if err == gorm.ErrRecordNotFound {
+		return domain.NewError(constants.MedNotFound, "medicine schedule not found")
+	}
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "update medicine schedule failed", err)
+	}
+	return nil
+}
+
+func updateScheduleVersioned(tx *gorm.DB, id uuid.UUID, updates map[string]any, effectiveFrom time.Time) error {
+	if err := versionSchedules(tx, "ms.id = ?", id, effectiveFrom); err != nil {
+		return err
+	}
+	result := tx.Model(&db.MedicineSchedule{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// versionSchedules keeps the schedules matching where, and their medicine's
+// status, as versions valid through the day before effectiveFrom. A schedule
+// that already has a version reaching that day keeps it: what is replaced now
+// was only in effect today.
+func versionSchedules(tx *gorm.DB, where string, id uuid.UUID, effectiveFrom time.Time) error {
+	validUntil := effectiveFrom.AddDate(0, 0, -1).Format("2006-01-02")
+	return tx.Exec(`INSERT INTO medicine_schedule_versions
+			(schedule_id, time_slot, meal_timing, start_date, end_date, interval_days, weekday_mask, medicine_active, valid_until)
+		SELECT ms.id, ms.time_slot, ms.meal_timing, ms.start_date, ms.end_date, ms.interval_days, ms.weekday_mask, pm.is_active, ?::date
+		FROM medicine_schedules AS ms
+		JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id
+		WHERE ms.deleted_at IS NULL AND `+where+`
+			AND NOT EXISTS (SELECT 1 FROM medicine_schedule_versions AS v WHERE v.schedule_id = ms.id AND v.valid_until >= ?::date)`,
+		validUntil, id, validUntil).Error
+}
+
+// DeleteSchedule soft-deletes a schedule; ListScheduleHistory keeps it for the
+// days before.
 func (r *medicineRepository) DeleteSchedule(ctx context.Context, id uuid.UUID) error {
-	result := r.db.WithContext(ctx).Delete(&db.MedicineSchedule{}, "id = ?", id)
+	result := conn(ctx, r.db).Delete(&db.MedicineSchedule{}, "id = ?", id)
 	if result.Error != nil {
 		return domain.WrapError(constants.InternalError, "delete medicine schedule failed", result.Error)
 	}
@@ -142,9 +285,94 @@ func (r *medicineRepository) DeleteSchedule(ctx context.Context, id uuid.UUID) e
 	return nil
 }
 
+func (r *medicineRepository) ListSchedulesWithMedicine(ctx context.Context, userID uuid.UUID) ([]ScheduleWithMedicineRow, error) {
+	var items []ScheduleWithMedicineRow
+	if err := conn(ctx, r.db).
+		Table("medicine_schedules AS ms").
+		Select(`ms.id AS schedule_id, pm.id AS patient_medicine_id,
+			COALESCE(pm.custom_name, mm.trade_name, mci.display_name, '') AS medicine_name,
+			pm.dosage_amount, pm.instruction,
+			pm.is_active AS medicine_active, pm.updated_at AS medicine_updated_at,
+			ms.time_slot, ms.meal_timing, ms.start_date, ms.end_date, ms.interval_days, ms.weekday_mask,
+			ms.created_at AS schedule_created_at`).
+		Joins("JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id AND pm.deleted_at IS NULL").
+		Joins("LEFT JOIN medicines_master AS mm ON mm.id = pm.medicine_master_id").
+		Joins("LEFT JOIN medicine_category_items AS mci ON mci.id = pm.category_item_id").
+		Where("pm.user_id = ? AND ms.deleted_at IS NULL", userID).
+		Order("pm.created_at asc, ms.time_slot asc").
+		Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list medicine schedules failed", err)
+	}
+	return items, nil
+}
+
+// ListScheduleHistory lists every schedule the patient has had, deleted ones
+// included, with one row per version so each day can be read against the
+// schedule as it stood then.
+func (r *medicineRepository) ListScheduleHistory(ctx context.Context, userID uuid.UUID) ([]ScheduleWithMedicineRow, error) {
+	var items []ScheduleWithMedicineRow
+	if err := conn(ctx, r.db).Raw(`
+		WITH owned AS (
+			SELECT ms.id FROM medicine_schedules AS ms
+			JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id
+			WHERE pm.user_id = ?
+		), spans AS (
+			SELECT v.schedule_id, v.time_slot, v.meal_timing, v.start_date, v.end_date, v.interval_days, v.weekday_mask,
+				v.medicine_active,
+				LAG(v.valid_until) OVER (PARTITION BY v.schedule_id ORDER BY v.valid_until) + 1 AS valid_from,
+				v.valid_until
+			FROM medicine_schedule_versions AS v
+			WHERE v.schedule_id IN (SELECT id FROM owned)
+			UNION ALL
+			SELECT ms.id, ms.time_slot, ms.meal_timing, ms.start_date, ms.end_date, ms.interval_days, ms.weekday_mask,
+				pm.is_active,
+				(SELECT MAX(v.valid_until) + 1 FROM medicine_schedule_versions AS v WHERE v.schedule_id = ms.id),
+				NULL
+			FROM medicine_schedules AS ms
+			JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id
+			WHERE ms.id IN (SELECT id FROM owned)
+		)
+		SELECT s.schedule_id, pm.id AS patient_medicine_id,
+			COALESCE(pm.custom_name, mm.trade_name, mci.display_name, '') AS medicine_name,
+			pm.dosage_amount, pm.instruction, s.medicine_active,
+			s.time_slot, s.meal_timing, s.start_date, s.end_date, s.interval_days, s.weekday_mask,
+			ms.created_at AS schedule_created_at, s.valid_from, s.valid_until,
+			LEAST(ms.deleted_at, pm.deleted_at) AS deleted_at
+		FROM spans AS s
+		JOIN medicine_schedules AS ms ON ms.id = s.schedule_id
+		JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id
+		LEFT JOIN medicines_master AS mm ON mm.id = pm.medicine_master_id
+		LEFT JOIN medicine_category_items AS mci ON mci.id = pm.category_item_id
+		ORDER BY pm.created_at ASC, ms.time_slot ASC, s.valid_from ASC NULLS FIRST`,
+		userID,
+	).Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list medicine schedule history failed", err)
+	}
+	return items, nil
+}
+
+// ListActiveSchedules pages through schedules by id; pass the last id of the
+// previous page (uuid.Nil for the first).
+func (r *medicineRepository) ListActiveSchedules(ctx context.Context, afterID uuid.UUID, limit int) ([]ActiveScheduleRow, error) {
+	var items []ActiveScheduleRow
+	if err := conn(ctx, r.db).
+		Table("medicine_schedules AS ms").
+		Select("ms.*, pm.user_id, up.timezone").
+		Joins("JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id AND pm.deleted_at IS NULL AND pm.is_active = ?", true).
+		Joins("JOIN users AS u ON u.id = pm.user_id AND u.deleted_at IS NULL AND u.is_active = ?", true).
+		Joins("LEFT JOIN user_preferences AS up ON up.user_id = u.id").
+		Where("ms.id > ? AND ms.deleted_at IS NULL", afterID).
+		Order("ms.id asc").
+		Limit(limit).
+		Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list active medicine schedules failed", err)
+	}
+	return items, nil
+}
+
 func (r *medicineRepository) ListCategories(ctx context.Context) ([]db.MedicineCategory, error) {
 	var items []db.MedicineCategory
-	if err := r.db.WithContext(ctx).Where("is_active = ?", true).Order("created_at asc").Find(&items).Error; err != nil {
+	if err := conn(ctx, r.db).Where("is_active = ?", true).Order("created_at asc").Find(&items).Error; err != nil {
 		return nil, domain.WrapError(constants.InternalError, "list medicine categories failed", err)
 	}
 	return items, nil
@@ -152,7 +380,7 @@ func (r *medicineRepository) ListCategories(ctx context.Context) ([]db.MedicineC
 
 func (r *medicineRepository) ListCategoryItems(ctx context.Context, categoryID uuid.UUID) ([]db.MedicineCategoryItem, error) {
 	var items []db.MedicineCategoryItem
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Where("category_id = ? AND is_active = ?", categoryID, true).
 		Order("created_at asc").
 		Find(&items).Error; err != nil {
@@ -163,7 +391,7 @@ func (r *medicineRepository) ListCategoryItems(ctx context.Context, categoryID u
 
 func (r *medicineRepository) GetCategoryItemByID(ctx context.Context, id uuid.UUID) (*db.MedicineCategoryItem, error) {
 	var item db.MedicineCategoryItem
-	if err := r.db.WithContext(ctx).First(&item, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&item, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.MedNotFound, "medicine category item not found")
 		}
@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,16 +15,56 @@ import (
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 )
 
+// NotificationDeliveryUpdate records the outcome of one delivery attempt.
+// LockedUntil is the lease returned by ClaimDue; the update is only applied
+// while the event still holds that lease.
+type NotificationDeliveryUpdate struct {
+	LockedUntil   time.Time
+	Status        constants.NotificationStatus
+	Attempts      int
+	SentAt        *time.Time
+	NextAttemptAt *time.Time
+	LastError     *string
+	// Title and Body are the rendered message, stored with a sent event for
+	// the inbox.
+	Title *string
+	Body  *string
+}
+
+type NotificationEventFilter struct {
+	Statuses     []constants.NotificationStatus
+	UserID       *uuid.UUID
+	TemplateCode string
+}
+
+// NotificationInboxCursor is the position of the last inbox item of a page;
+// the next page starts below it.
+type NotificationInboxCursor struct {
+	SentAt time.Time
+	ID     uuid.UUID
+}
+
 type NotificationRepository interface {
-	WithTx(tx *gorm.DB) NotificationRepository
 	CreateEvents(ctx context.Context, events []db.NotificationEvent) error
 	ListUpcoming(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.NotificationEvent, error)
-	ListDueForUpdate(ctx context.Context, now time.Time, limit int) ([]db.NotificationEvent, error)
-	UpdateEventStatus(ctx context.Context, id uuid.UUID, status constants.NotificationStatus, sentAt *time.Time) error
-	FindTemplateByCode(ctx context.Context, code string) (*db.NotificationTemplate, error)
+	ClaimDue(ctx context.Context, now, lockedUntil time.Time, limit int) ([]db.NotificationEvent, error)
+	UpdateEventDelivery(ctx context.Context, id uuid.UUID, update NotificationDeliveryUpdate) error
+	ListEvents(ctx context.Context, filter NotificationEventFilter, page, pageSize int) ([]db.NotificationEvent, int64, error)
+	Requeue(ctx context.Context, id uuid.UUID) (*db.NotificationEvent, error)
+	FindTemplate(ctx context.Context, code string, locales ...string) (*db.NotificationTemplate, error)
 	CancelPendingBySchedule(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate string) error
+	RestoreCancelledBySchedule(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate string, after time.Time) error
+	DeletePendingBySchedules(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error
+	ReplaceScheduleEvents(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, events []db.NotificationEvent) error
 	CancelPendingByAppointment(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error
+	ReplaceAppointmentEvents(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID, events []db.NotificationEvent) error
 	CancelPendingByTemplate(ctx context.Context, userID uuid.UUID, templateCode string) error
+	ReplacePendingByTemplate(ctx context.Context, userID uuid.UUID, templateCode string, events []db.NotificationEvent) error
+	ListInbox(ctx context.Context, userID uuid.UUID, before *NotificationInboxCursor, unreadOnly bool, limit int) ([]db.NotificationEvent, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkRead(ctx context.Context, userID, id uuid.UUID, at time.Time) error
+	MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error)
+	Archive(ctx context.Context, userID, id uuid.UUID, at time.Time) error
 }
 
 type notificationRepository struct {
@@ -33,19 +75,20 @@ func NewNotificationRepository(dbConn *gorm.DB) NotificationRepository {
 	return &notificationRepository{db: dbConn}
 }
 
-func (r *notificationRepository) WithTx(tx *gorm.DB) NotificationRepository {
-	return &notificationRepository{db: tx}
+// notificationEventConflict skips an event already queued for the same user,
+// template, time and medicine schedule (uq_notification_events_dedupe), so
+// reminders of two medicines due together stay separate events.
+var notificationEventConflict = clause.OnConflict{
+	Columns:   []clause.Column{{Name: "user_id"}, {Name: "template_code"}, {Name: "scheduled_at"}, {Name: "payload_schedule_id"}},
+	DoNothing: true,
 }
 
 func (r *notificationRepository) CreateEvents(ctx context.Context, events []db.NotificationEvent) error {
 	if len(events) == 0 {
 		return nil
 	}
-	if err := r.db.WithContext(ctx).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "user_id"}, {Name: "template_code"}, {Name: "scheduled_at"}},
-			DoNothing: true,
-		}).
+	if err := conn(ctx, r.db).
+		Clauses(notificationEventConflict).
 		CreateInBatches(events, 100).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create notification events failed", err)
 	}
@@ -54,7 +97,7 @@ func (r *notificationRepository) CreateEvents(ctx context.Context, events []db.N
 
 func (r *notificationRepository) ListUpcoming(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.NotificationEvent, error) {
 	var items []db.NotificationEvent
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	query := conn(ctx, r.db).Where("user_id = ?", userID)
 	if !from.IsZero() {
 		query = query.Where("scheduled_at >= ?", from)
 	}
@@ -67,35 +110,147 @@ func (r *notificationRepository) ListUpcoming(ctx context.Context, userID uuid.U
 	return items, nil
 }
 
-func (r *notificationRepository) ListDueForUpdate(ctx context.Context, now time.Time, limit int) ([]db.NotificationEvent, error) {
+// ClaimDue leases up to limit due events by moving them to PROCESSING until
+// lockedUntil. Due means pending and scheduled (and past next_attempt_at when
+// deferred for quiet hours), failed with its backoff elapsed, or processing
+// with an expired lease (a worker that died mid-send). The claim counts as an
+// attempt, so an event whose worker keeps dying still runs out of attempts.
+// The claim is a single statement, so concurrent workers never share an event
+// and no lock is held while sending.
+func (r *notificationRepository) ClaimDue(ctx context.Context, now, lockedUntil time.Time, limit int) ([]db.NotificationEvent, error) {
+	// Postgres keeps microseconds; UpdateEventDelivery matches the lease exactly.
+	lockedUntil = lockedUntil.UTC().Truncate(time.Microsecond)
+
 	var items []db.NotificationEvent
-	if err := r.db.WithContext(ctx).
-		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
-		Where("status = ? AND scheduled_at <= ?", constants.NotificationPending, now).
-		Order("scheduled_at asc").
-		Limit(limit).
-		Find(&items).Error; err != nil {
-		return nil, domain.WrapError(constants.InternalError, "list due notifications failed", err)
+	if err := conn(ctx, r.db).Raw(`
+		UPDATE notification_events
+		SET status = ?, locked_until = ?, attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM notification_events
+			WHERE (status = ? AND scheduled_at <= ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?))
+				OR (status = ? AND next_attempt_at <= ?)
+				OR (status = ? AND locked_until <= ?)
+			ORDER BY scheduled_at ASC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		constants.NotificationProcessing, lockedUntil,
+		constants.NotificationPending, now, now,
+		constants.NotificationFailed, now,
+		constants.NotificationProcessing, now,
+		limit,
+	).Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "claim due notifications failed", err)
 	}
 	return items, nil
 }
 
-func (r *notificationRepository) UpdateEventStatus(ctx context.Context, id uuid.UUID, status constants.NotificationStatus, sentAt *time.Time) error {
-	updates := map[string]any{"status": status}
-	if sentAt != nil {
-		updates["sent_at"] = *sentAt
+// UpdateEventDelivery releases the lease and records the attempt. It returns
+// NotificationNotFound when the lease was lost, e.g. it expired and another
+// worker reclaimed the event.
+func (r *notificationRepository) UpdateEventDelivery(ctx context.Context, id uuid.UUID, update NotificationDeliveryUpdate) error {
+	updates := map[string]any{
+		"status":          update.Status,
+		"attempts":        update.Attempts,
+		"next_attempt_at": update.NextAttemptAt,
+		"last_error":      update.LastError,
+		"locked_until":    nil,
+	}
+	if update.SentAt != nil {
+		updates["sent_at"] = *update.SentAt
+	}
+	if update.Title != nil {
+		updates["title"] = *update.Title
+	}
+	if update.Body != nil {
+		updates["body"] = *update.Body
+	}
+	result := conn(ctx, r.db).
+		Model(&db.NotificationEvent{}).
+		Where("id = ? AND status = ? AND locked_until = ?", id, constants.NotificationProcessing, update.LockedUntil).
+		Updates(updates)
+	if result.Error != nil {
+		return domain.WrapError(constants.InternalError, "update notification status failed", result.Error)
 	}
-	if err := r.db.WithContext(ctx).Model(&db.NotificationEvent{}).Where("id = ?", id).Updates(updates).Error; err != nil {
-		return domain.WrapError(constants.InternalError, "update notification status failed", err)
+	if result.RowsAffected == 0 {
+		return domain.NewError(constants.NotificationNotFound, "notification lease lost")
 	}
 	return nil
 }
 
-func (r *notificationRepository) FindTemplateByCode(ctx context.Context, code string) (*db.NotificationTemplate, error) {
+func (r *notificationRepository) ListEvents(ctx context.Context, filter NotificationEventFilter, page, pageSize int) ([]db.NotificationEvent, int64, error) {
+	query := conn(ctx, r.db).Model(&db.NotificationEvent{})
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.TemplateCode != "" {
+		query = query.Where("template_code = ?", filter.TemplateCode)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "count notification events failed", err)
+	}
+
+	var items []db.NotificationEvent
+	if err := query.
+		Order("scheduled_at desc").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&items).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "list notification events failed", err)
+	}
+	return items, total, nil
+}
+
+// Requeue resets a FAILED or DEAD event to PENDING with a fresh attempt
+// budget. scheduled_at is left alone, so the event is due on the next worker
+// run.
+func (r *notificationRepository) Requeue(ctx context.Context, id uuid.UUID) (*db.NotificationEvent, error) {
+	var event db.NotificationEvent
+	result := conn(ctx, r.db).
+		Model(&event).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status IN ?", id, []constants.NotificationStatus{constants.NotificationFailed, constants.NotificationDead}).
+		Updates(map[string]any{
+			"status":          constants.NotificationPending,
+			"attempts":        0,
+			"next_attempt_at": nil,
+		})
+	if result.Error != nil {
+		return nil, domain.WrapError(constants.InternalError, "requeue notification failed", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.NewError(constants.NotificationNotFound, "failed notification not found")
+	}
+	return &event, nil
+}
+
+// FindTemplate returns the active template for code in the first of locales
+// it exists in, else in any locale.
+func (r *notificationRepository) FindTemplate(ctx context.Context, code string, locales ...string) (*db.NotificationTemplate, error) {
+	rank := make([]string, 0, len(locales))
+	vars := make([]any, 0, len(locales))
+	for i, locale := range locales {
+		rank = append(rank, fmt.Sprintf("WHEN ? THEN %d", i))
+		vars = append(vars, locale)
+	}
+	order := "locale"
+	if len(rank) > 0 {
+		order = fmt.Sprintf("CASE locale %s ELSE %d END, locale", strings.Join(rank, " "), len(rank))
+	}
+
 	var tpl db.NotificationTemplate
-	if err := r.db.WithContext(ctx).Where("code = ? AND is_active = ?", code, true).First(&tpl).Error; err != nil {
+	if err := conn(ctx, r.db).
+		Where("code = ? AND is_active = ?", code, true).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: order, Vars: vars, WithoutParentheses: true}}).
+		Take(&tpl).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, domain.NewError(constants.InternalError, "notification template not found")
+			return nil, domain.NewError(constants.NotificationNotFound, "notification template not found")
 		}
 		return nil, domain.WrapError(constants.InternalError, "find notification template failed", err)
 	}
@@ -103,7 +258,7 @@ func (r *notificationRepository) FindTemplateByCode(ctx context.Context, code st
 }
 
 func (r *notificationRepository) CancelPendingBySchedule(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate string) error {
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Model(&db.NotificationEvent{}).
 		Where("user_id = ? AND status = ? AND template_code = ? AND payload->>'schedule_id' = ? AND payload->>'target_date' = ?", userID, constants.NotificationPending, constants.TemplateMedBeforeMeal20Min, scheduleID.String(), targetDate).
 		Update("status", constants.NotificationCancelled).Error; err != nil {
@@ -112,18 +267,100 @@ func (r *notificationRepository) CancelPendingBySchedule(ctx context.Context, us
 	return nil
 }
 
+// RestoreCancelledBySchedule undoes CancelPendingBySchedule for reminders that
+// are still due after the given time.
+func (r *notificationRepository) RestoreCancelledBySchedule(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate string, after time.Time) error {
+	if err := conn(ctx, r.db).
+		Model(&db.NotificationEvent{}).
+		Where("user_id = ? AND status = ? AND template_code = ? AND payload->>'schedule_id' = ? AND payload->>'target_date' = ? AND scheduled_at > ?", userID, constants.NotificationCancelled, constants.TemplateMedBeforeMeal20Min, scheduleID.String(), targetDate, after).
+		Update("status", constants.NotificationPending).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "restore medicine reminder failed", err)
+	}
+	return nil
+}
+
+// DeletePendingBySchedules removes unsent reminders (including ones waiting
+// for a retry) of the given medicine schedules. They are deleted rather than
+// cancelled so the unique (user_id, template_code, scheduled_at) index does
+// not block a later schedule at the same time. Events already PROCESSING are
+// left to finish.
+func (r *notificationRepository) DeletePendingBySchedules(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	if len(scheduleIDs) == 0 {
+		return nil
+	}
+	if err := deletePendingBySchedules(conn(ctx, r.db), userID, scheduleIDs); err != nil {
+		return domain.WrapError(constants.InternalError, "delete medicine reminders failed", err)
+	}
+	return nil
+}
+
+// ReplaceScheduleEvents swaps the unsent reminders of one schedule for events
+// in a single transaction, so the patient never sees both or neither.
+func (r *notificationRepository) ReplaceScheduleEvents(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, events []db.NotificationEvent) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return replaceScheduleEvents(tx, userID, scheduleID, events)
+	})
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "replace medicine reminders failed", err)
+	}
+	return nil
+}
+
+func replaceScheduleEvents(tx *gorm.DB, userID uuid.UUID, scheduleID uuid.UUID, events []db.NotificationEvent) error {
+	if err := deletePendingBySchedules(tx, userID, []uuid.UUID{scheduleID}); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Clauses(notificationEventConflict).CreateInBatches(events, 100).Error
+}
+
+func deletePendingBySchedules(tx *gorm.DB, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	ids := make([]string, 0, len(scheduleIDs))
+	for _, id := range scheduleIDs {
+		ids = append(ids, id.String())
+	}
+	return tx.
+		Where("user_id = ? AND status IN ? AND payload->>'schedule_id' IN ?", userID, []constants.NotificationStatus{constants.NotificationPending, constants.NotificationFailed}, ids).
+		Delete(&db.NotificationEvent{}).Error
+}
+
+var appointmentTemplates = []string{constants.TemplateAppt5Days, constants.TemplateAppt1Day, constants.TemplateApptReminder}
+
 func (r *notificationRepository) CancelPendingByAppointment(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Model(&db.NotificationEvent{}).
-		Where("user_id = ? AND status = ? AND (template_code = ? OR template_code = ?) AND payload->>'appointment_id' = ?", userID, constants.NotificationPending, constants.TemplateAppt5Days, constants.TemplateAppt1Day, appointmentID.String()).
+		Where("user_id = ? AND status = ? AND template_code IN ? AND payload->>'appointment_id' = ?", userID, constants.NotificationPending, appointmentTemplates, appointmentID.String()).
 		Update("status", constants.NotificationCancelled).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "cancel appointment reminders failed", err)
 	}
 	return nil
 }
 
+// ReplaceAppointmentEvents swaps the unsent reminders of an appointment for
+// events. They are deleted rather than cancelled so that a replacement at the
+// same time is not dropped as a duplicate.
+func (r *notificationRepository) ReplaceAppointmentEvents(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID, events []db.NotificationEvent) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("user_id = ? AND status IN ? AND template_code IN ? AND payload->>'appointment_id' = ?", userID, []constants.NotificationStatus{constants.NotificationPending, constants.NotificationFailed}, appointmentTemplates, appointmentID.String()).
+			Delete(&db.NotificationEvent{}).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Clauses(notificationEventConflict).CreateInBatches(events, 100).Error
+	})
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "replace appointment reminders failed", err)
+	}
+	return nil
+}
+
 func (r *notificationRepository) CancelPendingByTemplate(ctx context.Context, userID uuid.UUID, templateCode string) error {
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Model(&db.NotificationEvent{}).
 		Where("user_id = ? AND status = ? AND template_code = ?", userID, constants.NotificationPending, templateCode).
 		Update("status", constants.NotificationCancelled).Error; err != nil {
@@ -131,3 +368,93 @@ func (r *notificationRepository) CancelPendingByTemplate(ctx context.Context, us
 	}
 	return nil
 }
+
+// ReplacePendingByTemplate swaps the user's unsent events of one template for
+// events in a single transaction. Like DeletePendingBySchedules it deletes
+// rather than cancels, so a replacement at the same time is not blocked.
+func (r *notificationRepository) ReplacePendingByTemplate(ctx context.Context, userID uuid.UUID, templateCode string, events []db.NotificationEvent) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("user_id = ? AND status IN ? AND template_code = ?", userID, []constants.NotificationStatus{constants.NotificationPending, constants.NotificationFailed}, templateCode).
+			Delete(&db.NotificationEvent{}).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Clauses(notificationEventConflict).CreateInBatches(events, 100).Error
+	})
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "replace notification events failed", err)
+	}
+	return nil
+}
+
+// inbox selects the user's sent events that are not archived.
+func (r *notificationRepository) inbox(ctx context.Context, userID uuid.UUID) *gorm.DB {
+	return conn(ctx, r.db).
+		Model(&db.NotificationEvent{}).
+		Where("user_id = ? AND status = ? AND sent_at IS NOT NULL AND archived_at IS NULL", userID, constants.NotificationSent)
+}
+
+// ListInbox returns the user's sent events newest first, starting below
+// before when it is set.
+func (r *notificationRepository) ListInbox(ctx context.Context, userID uuid.UUID, before *NotificationInboxCursor, unreadOnly bool, limit int) ([]db.NotificationEvent, error) {
+	query := r.inbox(ctx, userID)
+	if before != nil {
+		query = query.Where("(sent_at, id) < (?, ?)", before.SentAt, before.ID)
+	}
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var items []db.NotificationEvent
+	if err := query.Order("sent_at desc, id desc").Limit(limit).Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list notification inbox failed", err)
+	}
+	return items, nil
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.inbox(ctx, userID).Where("read_at IS NULL").Count(&count).Error; err != nil {
+		return 0, domain.WrapError(constants.InternalError, "count unread notifications failed", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks one inbox item read. An item that is already read keeps its
+// read_at.
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	result := r.inbox(ctx, userID).
+		Where("id = ?", id).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", at))
+	if result.Error != nil {
+		return domain.WrapError(constants.InternalError, "mark notification read failed", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewError(constants.NotificationNotFound, "notification not found")
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread inbox item read and returns how many were.
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error) {
+	result := r.inbox(ctx, userID).Where("read_at IS NULL").Update("read_at", at)
+	if result.Error != nil {
+		return 0, domain.WrapError(constants.InternalError, "mark notifications read failed", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Archive removes an item from the inbox. The event itself is kept.
+func (r *notificationRepository) Archive(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	result := r.inbox(ctx, userID).Where("id = ?", id).Update("archived_at", at)
+	if result.Error != nil {
+		return domain.WrapError(constants.InternalError, "archive notification failed", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewError(constants.NotificationNotFound, "notification not found")
+	}
+	return nil
+}
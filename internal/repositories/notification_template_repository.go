@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+type NotificationTemplateFilter struct {
+	Code     string
+	Locale   string
+	IsActive *bool
+}
+
+type NotificationTemplateRepository interface {
+	List(ctx context.Context, filter NotificationTemplateFilter, page, pageSize int) ([]db.NotificationTemplate, int64, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*db.NotificationTemplate, error)
+	Create(ctx context.Context, tpl *db.NotificationTemplate) error
+	Update(ctx context.Context, id uuid.UUID, updates map[string]any) (*db.NotificationTemplate, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	CountActiveByCode(ctx context.Context, code string, excludeID uuid.UUID) (int64, error)
+}
+
+type notificationTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationTemplateRepository(dbConn *gorm.DB) NotificationTemplateRepository {
+	return &notificationTemplateRepository{db: dbConn}
+}
+
+func (r *notificationTemplateRepository) List(ctx context.Context, filter NotificationTemplateFilter, page, pageSize int) ([]db.NotificationTemplate, int64, error) {
+	query := conn(ctx, r.db).Model(&db.NotificationTemplate{})
+	if filter.Code != "" {
+		query = query.Where("code = ?", filter.Code)
+	}
+	if filter.Locale != "" {
+		query = query.Where("locale = ?", filter.Locale)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "count notification templates failed", err)
+	}
+
+	var items []db.NotificationTemplate
+	if err := query.
+		Order("code asc, locale asc").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&items).Error; err != nil {
+		return nil, 0, domain.WrapError(constants.InternalError, "list notification templates failed", err)
+	}
+	return items, total, nil
+}
+
+func (r *notificationTemplateRepository) FindByID(ctx context.Context, id uuid.UUID) (*db.NotificationTemplate, error) {
+	var tpl db.NotificationTemplate
+	if err := conn(ctx, r.db).First(&tpl, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.NewError(constants.NotificationNotFound, "notification template not found")
+		}
+		return nil, domain.WrapError(constants.InternalError, "find notification template failed", err)
+	}
+	return &tpl, nil
+}
+
+// Create inserts a template. is_active defaults to true in the table, so an
+// inactive template is switched off in the same transaction.
+func (r *notificationTemplateRepository) Create(ctx context.Context, tpl *db.NotificationTemplate) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tpl).Error; err != nil {
+			return err
+		}
+		if tpl.IsActive {
+			return nil
+		}
+		return tx.Model(tpl).Update("is_active", false).Error
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.NewError(constants.NotificationConflict, "notification template already exists for this code and locale")
+		}
+		return domain.WrapError(constants.InternalError, "create notification template failed", err)
+	}
+	return nil
+}
+
+func (r *notificationTemplateRepository) Update(ctx context.Context, id uuid.UUID, updates map[string]any) (*db.NotificationTemplate, error) {
+	var tpl db.NotificationTemplate
+	result := conn(ctx, r.db).
+		Model(&tpl).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, domain.WrapError(constants.InternalError, "update notification template failed", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.NewError(constants.NotificationNotFound, "notification template not found")
+	}
+	return &tpl, nil
+}
+
+func (r *notificationTemplateRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := conn(ctx, r.db).Where("id = ?", id).Delete(&db.NotificationTemplate{})
+	if result.Error != nil {
+		return domain.WrapError(constants.InternalError, "delete notification template failed", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.NewError(constants.NotificationNotFound, "notification template not found")
+	}
+	return nil
+}
+
+// CountActiveByCode counts the active templates of code other than excludeID.
+func (r *notificationTemplateRepository) CountActiveByCode(ctx context.Context, code string, excludeID uuid.UUID) (int64, error) {
+	var count int64
+	if err := conn(ctx, r.db).
+		Model(&db.NotificationTemplate{}).
+		Where("code = ? AND is_active = ? AND id <> ?", code, true, excludeID).
+		Count(&count).Error; err != nil {
+		return 0, domain.WrapError(constants.InternalError, "count notification templates failed", err)
+	}
+	return count, nil
+}
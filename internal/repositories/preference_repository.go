@@ -12,10 +12,17 @@ import (
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 )
 
+// WeeklyReminderUser is a patient due a weekly reminder, with their preferred
+// timezone, if any.
+type WeeklyReminderUser struct {
+	UserID   uuid.UUID
+	Timezone *string
+}
+
 type PreferenceRepository interface {
 	Upsert(ctx context.Context, pref *db.UserPreference) error
 	FindByUserID(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error)
-	ListWeeklyReminderUsers(ctx context.Context) ([]uuid.UUID, error)
+	ListWeeklyReminderUsers(ctx context.Context) ([]WeeklyReminderUser, error)
 }
 
 type preferenceRepository struct {
@@ -27,7 +34,7 @@ func NewPreferenceRepository(dbConn *gorm.DB) PreferenceRepository {
 }
 
 func (r *preferenceRepository) Upsert(ctx context.Context, pref *db.UserPreference) error {
-	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+	if err := conn(ctx, r.db).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "user_id"}},
 		UpdateAll: true,
 	}).Create(pref).Error; err != nil {
@@ -38,7 +45,7 @@ func (r *preferenceRepository) Upsert(ctx context.Context, pref *db.UserPreferen
 
 func (r *preferenceRepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
 	var pref db.UserPreference
-	if err := r.db.WithContext(ctx).First(&pref, "user_id = ?", userID).Error; err != nil {
+	if err := conn(ctx, r.db).First(&pref, "user_id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.UserNotFound, "preferences not found")
 		}
@@ -47,15 +54,15 @@ func (r *preferenceRepository) FindByUserID(ctx context.Context, userID uuid.UUI
 	return &pref, nil
 }
 
-func (r *preferenceRepository) ListWeeklyReminderUsers(ctx context.Context) ([]uuid.UUID, error) {
-	var ids []uuid.UUID
-	if err := r.db.WithContext(ctx).
+func (r *preferenceRepository) ListWeeklyReminderUsers(ctx context.Context) ([]WeeklyReminderUser, error) {
+	var items []WeeklyReminderUser
+	if err := conn(ctx, r.db).
 		Table("users").
-		Select("users.id").
+		Select("users.id AS user_id, user_preferences.timezone").
 		Joins("LEFT JOIN user_preferences ON user_preferences.user_id = users.id").
 		Where("users.role = ? AND users.is_active = ? AND (user_preferences.weekly_reminder_enabled IS NULL OR user_preferences.weekly_reminder_enabled = ?)", constants.RolePatient, true, true).
-		Scan(&ids).Error; err != nil {
+		Scan(&items).Error; err != nil {
 		return nil, domain.WrapError(constants.InternalError, "list reminder users failed", err)
 	}
-	return ids, nil
+	return items, nil
 }
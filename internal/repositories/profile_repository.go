@@ -27,7 +27,7 @@ func NewProfileRepository(dbConn *gorm.DB) ProfileRepository {
 
 func (r *profileRepository) FindByUserID(ctx context.Context, userID uuid.UUID) (*db.UserProfile, error) {
 	var profile db.UserProfile
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&profile).Error; err != nil {
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).First(&profile).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.UserNotFound, "profile not found")
 		}
@@ -37,7 +37,7 @@ func (r *profileRepository) FindByUserID(ctx context.Context, userID uuid.UUID)
 }
 
 func (r *profileRepository) Upsert(ctx context.Context, profile *db.UserProfile) error {
-	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+	if err := conn(ctx, r.db).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "user_id"}},
 		UpdateAll: true,
 	}).Create(profile).Error; err != nil {
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+// TrackedMedicineRow is an active medicine of an active patient whose stock on
+// hand is recorded, with the patient's preferred timezone, if any.
+type TrackedMedicineRow struct {
+	PatientMedicineID uuid.UUID
+	UserID            uuid.UUID
+	Username          string
+	FirstName         *string
+	LastName          *string
+	HN                *string
+	MedicineName      string
+	DosageAmount      string
+	QuantityOnHand    float64
+	PackSize          *float64
+	RefillDate        *time.Time
+	Timezone          *string
+}
+
+type RefillRepository interface {
+	AdjustStock(ctx context.Context, id uuid.UUID, delta float64) (*db.PatientMedicine, error)
+	Restock(ctx context.Context, id uuid.UUID, quantity float64, refillDate time.Time) (*db.PatientMedicine, error)
+	FlagLowSupply(ctx context.Context, id uuid.UUID, event db.NotificationEvent) (bool, error)
+	ListTrackedMedicines(ctx context.Context, patientID *uuid.UUID) ([]TrackedMedicineRow, error)
+	ListSchedulesByMedicines(ctx context.Context, medicineIDs []uuid.UUID) ([]db.MedicineSchedule, error)
+}
+
+type refillRepository struct {
+	db *gorm.DB
+}
+
+func NewRefillRepository(dbConn *gorm.DB) RefillRepository {
+	return &refillRepository{db: dbConn}
+}
+
+// AdjustStock adds delta to the stock on hand, never going below zero, and
+// returns the updated medicine. It returns nil when the medicine does not
+// track stock.
+func (r *refillRepository) AdjustStock(ctx context.Context, id uuid.UUID, delta float64) (*db.PatientMedicine, error) {
+	var items []db.PatientMedicine
+	result := conn(ctx, r.db).
+		Model(&items).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND quantity_on_hand IS NOT NULL", id).
+		Update("quantity_on_hand", gorm.Expr("GREATEST(quantity_on_hand + ?, 0)", delta))
+	if result.Error != nil {
+		return nil, domain.WrapError(constants.InternalError, "adjust medicine stock failed", result.Error)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+// Restock adds quantity to the stock on hand (starting from zero when stock
+// was not tracked yet), records the refill date and re-arms the low-supply
+// reminder.
+func (r *refillRepository) Restock(ctx context.Context, id uuid.UUID, quantity float64, refillDate time.Time) (*db.PatientMedicine, error) {
+	var med db.PatientMedicine
+	result := conn(ctx, r.db).
+		Model(&med).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"quantity_on_hand":       gorm.Expr("COALESCE(quantity_on_hand, 0) + ?", quantity),
+			"refill_date":            refillDate.Format("2006-01-02"),
+			"low_supply_notified_at": nil,
+		})
+	if result.Error != nil {
+		return nil, domain.WrapError(constants.InternalError, "restock medicine failed", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.NewError(constants.MedNotFound, "patient medicine not found")
+	}
+	return &med, nil
+}
+
+// FlagLowSupply marks the medicine as reminded and queues event, unless a
+// reminder was already queued since the last refill. It reports whether the
+// event was queued.
+func (r *refillRepository) FlagLowSupply(ctx context.Context, id uuid.UUID, event db.NotificationEvent) (bool, error) {
+	queued := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&db.PatientMedicine{}).
+			Where("id = ? AND low_supply_notified_at IS NULL", id).
+			UpdateColumn("low_supply_notified_at", event.ScheduledAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Clauses(notificationEventConflict).Create(&event).Error; err != nil {
+			return err
+		}
+		queued = true
+		return nil
+	})
+	if err != nil {
+		return false, domain.WrapError(constants.InternalError, "flag low supply failed", err)
+	}
+	return queued, nil
+}
+
+func (r *refillRepository) ListTrackedMedicines(ctx context.Context, patientID *uuid.UUID) ([]TrackedMedicineRow, error) {
+	query := conn(ctx, r.db).
+		Table("patient_medicines AS pm").
+		Select(`pm.id AS patient_medicine_id, pm.user_id, u.username, p.first_name, p.last_name, p.hn,
+			COALESCE(pm.custom_name, mm.trade_name, mci.display_name, '') AS medicine_name,
+			pm.dosage_amount, pm.quantity_on_hand, pm.pack_size, pm.refill_date, up.timezone`).
+		Joins("JOIN users AS u ON u.id = pm.user_id AND u.deleted_at IS NULL AND u.is_active = ?", true).
+		Joins("LEFT JOIN user_profiles AS p ON p.user_id = u.id").
+		Joins("LEFT JOIN user_preferences AS up ON up.user_id = u.id").
+		Joins("LEFT JOIN medicines_master AS mm ON mm.id = pm.medicine_master_id").
+		Joins("LEFT JOIN medicine_category_items AS mci ON mci.id = pm.category_item_id").
+		Where("pm.deleted_at IS NULL AND pm.is_active = ? AND pm.quantity_on_hand IS NOT NULL", true)
+	if patientID != nil {
+		query = query.Where("pm.user_id = ?", *patientID)
+	}
+
+	var items []TrackedMedicineRow
+	if err := query.Order("pm.user_id asc, pm.created_at asc").Scan(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list tracked medicines failed", err)
+	}
+	return items, nil
+}
+
+func (r *refillRepository) ListSchedulesByMedicines(ctx context.Context, medicineIDs []uuid.UUID) ([]db.MedicineSchedule, error) {
+	if len(medicineIDs) == 0 {
+		return nil, nil
+	}
+	var items []db.MedicineSchedule
+	if err := conn(ctx, r.db).
+		Where("patient_medicine_id IN ?", medicineIDs).
+		Order("time_slot asc").
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list medicine schedules failed", err)
+	}
+	return items, nil
+}
@@ -5,10 +5,14 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -22,6 +26,7 @@ import (
 	"github.com/ParkPawapon/mhp-be/internal/config"
 	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/database/postgres"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 )
 
@@ -33,6 +38,12 @@ func TestMigrationsApplied(t *testing.T) {
 	assertTableExists(t, dbConn, "medicine_categories")
 	assertTableExists(t, dbConn, "notification_events")
 	assertTableExists(t, dbConn, "support_chat_requests")
+	assertTableExists(t, dbConn, "job_runs")
+	assertTableExists(t, dbConn, "intake_history_changes")
+	assertTableExists(t, dbConn, "sync_mutations")
+	assertTableExists(t, dbConn, "sync_changes")
+	assertTableExists(t, dbConn, "escalation_policies")
+	assertTableExists(t, dbConn, "missed_dose_escalations")
 }
 
 func TestUserAndProfileRepositories(t *testing.T) {
@@ -59,6 +70,137 @@ func TestUserAndProfileRepositories(t *testing.T) {
 	if found.FirstName != "A" {
 		t.Fatalf("unexpected profile data")
 	}
+
+	lineID := "U1234"
+	if err := userRepo.UpdateLineUserID(context.Background(), user.ID, &lineID); err != nil {
+		t.Fatalf("link line: %v", err)
+	}
+	linked, err := userRepo.FindByLineUserID(context.Background(), lineID)
+	if err != nil || linked.ID != user.ID {
+		t.Fatalf("find by line user id: %v", err)
+	}
+	other := &db.User{Username: "0800000001", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := userRepo.Create(context.Background(), other); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if err := userRepo.UpdateLineUserID(context.Background(), other.ID, &lineID); err == nil {
+		t.Fatalf("expected conflict when line account already linked")
+	}
+}
+
+func TestAdminRepositoryListPatients(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewAdminRepository(dbConn)
+	hn := "HN0001"
+	citizenID := "1100701234567"
+
+	patient := &db.User{Username: "0820000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	caregiver := &db.User{Username: "0820000001", PasswordHash: "hash", Role: constants.RoleCaregiver, IsActive: true, IsVerified: true}
+	inactive := &db.User{Username: "0820000002", PasswordHash: "hash", Role: constants.RolePatient, IsActive: false, IsVerified: true}
+	for _, user := range []*db.User{patient, caregiver, inactive} {
+		if err := dbConn.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	if err := dbConn.Model(inactive).Update("is_active", false).Error; err != nil {
+		t.Fatalf("deactivate user: %v", err)
+	}
+	profile := &db.UserProfile{UserID: patient.ID, HN: &hn, CitizenID: &citizenID, FirstName: "สมชาย", LastName: "Jaidee", DateOfBirth: time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := dbConn.Create(profile).Error; err != nil {
+		t.Fatalf("create profile: %v", err)
+	}
+	if err := dbConn.Create(&db.CaregiverAssignment{PatientID: patient.ID, CaregiverID: caregiver.ID, Relationship: "CHILD"}).Error; err != nil {
+		t.Fatalf("create assignment: %v", err)
+	}
+
+	active := true
+	cases := []struct {
+		name   string
+		filter PatientListFilter
+		want   int64
+	}{
+		{"all patients", PatientListFilter{}, 2},
+		{"hn", PatientListFilter{Query: "hn0001"}, 1},
+		{"thai name", PatientListFilter{Query: "สมชาย"}, 1},
+		{"latin name", PatientListFilter{Query: "jaid"}, 1},
+		{"phone", PatientListFilter{Query: "0820000002"}, 1},
+		{"citizen id suffix", PatientListFilter{Query: "4567"}, 1},
+		{"citizen id middle", PatientListFilter{Query: "0701"}, 0},
+		{"active", PatientListFilter{IsActive: &active}, 1},
+		{"caregiver", PatientListFilter{CaregiverID: &caregiver.ID}, 1},
+	}
+	for _, tc := range cases {
+		_, total, err := repo.ListPatients(ctx, tc.filter, 1, 20)
+		if err != nil {
+			t.Fatalf("%s: list patients: %v", tc.name, err)
+		}
+		if total != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.want, total)
+		}
+	}
+
+	found, err := repo.FindPatient(ctx, patient.ID)
+	if err != nil {
+		t.Fatalf("find patient: %v", err)
+	}
+	if found.Profile.FirstName != "สมชาย" {
+		t.Fatalf("expected profile preloaded")
+	}
+	if _, err := repo.FindPatient(ctx, caregiver.ID); err == nil {
+		t.Fatalf("expected caregiver to be excluded")
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewAuditRepository(dbConn)
+
+	nurse := &db.User{Username: "nurse01", PasswordHash: "hash", Role: constants.RoleNurse, IsActive: true, IsVerified: true}
+	patient := &db.User{Username: "0830000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	for _, user := range []*db.User{nurse, patient} {
+		if err := dbConn.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	ip := "203.0.113.7"
+	for _, entry := range []*db.AuditLog{
+		{ActorID: &nurse.ID, TargetUserID: &nurse.ID, ActionType: string(constants.AuditStaffLogin), IPAddress: &ip},
+		{ActorID: &nurse.ID, TargetUserID: &patient.ID, ActionType: string(constants.AuditPatientRead), IPAddress: &ip},
+		{ActorID: &patient.ID, TargetUserID: &patient.ID, ActionType: string(constants.AuditLogin)},
+	} {
+		if err := repo.Create(ctx, entry); err != nil {
+			t.Fatalf("create audit log: %v", err)
+		}
+	}
+
+	now := time.Now()
+	cases := []struct {
+		name   string
+		filter AuditLogFilter
+		want   int64
+	}{
+		{"all", AuditLogFilter{}, 3},
+		{"actor", AuditLogFilter{ActorID: &nurse.ID}, 2},
+		{"action", AuditLogFilter{ActionType: string(constants.AuditPatientRead)}, 1},
+		{"range", AuditLogFilter{From: now.Add(-time.Hour), To: now.Add(time.Hour)}, 3},
+		{"before range", AuditLogFilter{To: now.Add(-time.Hour)}, 0},
+	}
+	for _, tc := range cases {
+		items, total, err := repo.List(ctx, tc.filter, 1, 20)
+		if err != nil {
+			t.Fatalf("%s: list audit logs: %v", tc.name, err)
+		}
+		if total != tc.want || int64(len(items)) != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.want, total)
+		}
+	}
 }
 
 func TestNotificationRepository(t *testing.T) {
@@ -82,12 +224,1058 @@ func TestNotificationRepository(t *testing.T) {
 		t.Fatalf("create events: %v", err)
 	}
 
-	due, err := repo.ListDueForUpdate(context.Background(), time.Now().UTC(), 10)
+	now := time.Now().UTC()
+	lease := now.Add(5 * time.Minute)
+	due, err := repo.ClaimDue(context.Background(), now, lease, 10)
+	if err != nil {
+		t.Fatalf("claim due: %v", err)
+	}
+	if len(due) != 1 || due[0].Status != constants.NotificationProcessing || due[0].LockedUntil == nil || due[0].Attempts != 1 {
+		t.Fatalf("expected one claimed event counted as an attempt, got %+v", due)
+	}
+	if again, err := repo.ClaimDue(context.Background(), now, lease, 10); err != nil || len(again) != 0 {
+		t.Fatalf("expected leased event not to be claimed twice, got %d err=%v", len(again), err)
+	}
+
+	errMsg := "fcm unavailable"
+	next := now.Add(time.Hour)
+	if err := repo.UpdateEventDelivery(context.Background(), due[0].ID, NotificationDeliveryUpdate{LockedUntil: *due[0].LockedUntil, Status: constants.NotificationFailed, Attempts: 1, NextAttemptAt: &next, LastError: &errMsg}); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	if err := repo.UpdateEventDelivery(context.Background(), due[0].ID, NotificationDeliveryUpdate{LockedUntil: *due[0].LockedUntil, Status: constants.NotificationSent}); err == nil {
+		t.Fatalf("expected released lease to reject a second update")
+	}
+	if held, err := repo.ClaimDue(context.Background(), now, lease, 10); err != nil || len(held) != 0 {
+		t.Fatalf("expected failed event held back until next_attempt_at, got %d err=%v", len(held), err)
+	}
+	due, err = repo.ClaimDue(context.Background(), next, next.Add(time.Minute), 10)
+	if err != nil || len(due) != 1 || due[0].Attempts != 2 {
+		t.Fatalf("expected failed event due after backoff, got %d err=%v", len(due), err)
+	}
+
+	expired := next.Add(2 * time.Minute)
+	reclaimed, err := repo.ClaimDue(context.Background(), expired, expired.Add(time.Minute), 10)
+	if err != nil || len(reclaimed) != 1 || reclaimed[0].Attempts != 3 {
+		t.Fatalf("expected expired lease to be reclaimed as another attempt, got %d err=%v", len(reclaimed), err)
+	}
+	if err := repo.UpdateEventDelivery(context.Background(), due[0].ID, NotificationDeliveryUpdate{LockedUntil: *due[0].LockedUntil, Status: constants.NotificationSent}); err == nil {
+		t.Fatalf("expected stale lease holder to be rejected")
+	}
+
+	if err := repo.UpdateEventDelivery(context.Background(), reclaimed[0].ID, NotificationDeliveryUpdate{LockedUntil: *reclaimed[0].LockedUntil, Status: constants.NotificationDead, Attempts: 5, LastError: &errMsg}); err != nil {
+		t.Fatalf("dead-letter: %v", err)
+	}
+	failed, total, err := repo.ListEvents(context.Background(), NotificationEventFilter{Statuses: []constants.NotificationStatus{constants.NotificationDead}}, 1, 20)
+	if err != nil || total != 1 || failed[0].LastError == nil {
+		t.Fatalf("list dead events: total=%d err=%v", total, err)
+	}
+
+	requeued, err := repo.Requeue(context.Background(), failed[0].ID)
+	if err != nil || requeued.Status != constants.NotificationPending || requeued.Attempts != 0 {
+		t.Fatalf("requeue: %+v err=%v", requeued, err)
+	}
+	if _, err := repo.Requeue(context.Background(), failed[0].ID); err == nil {
+		t.Fatalf("expected pending event not to be requeued again")
+	}
+
+	// A pending event put back for quiet hours waits for next_attempt_at.
+	at := expired.Add(time.Hour)
+	due, err = repo.ClaimDue(context.Background(), at, at.Add(time.Minute), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("expected requeued event due, got %d err=%v", len(due), err)
+	}
+	quietEnd := at.Add(6 * time.Hour)
+	if err := repo.UpdateEventDelivery(context.Background(), due[0].ID, NotificationDeliveryUpdate{LockedUntil: *due[0].LockedUntil, Status: constants.NotificationPending, NextAttemptAt: &quietEnd}); err != nil {
+		t.Fatalf("defer: %v", err)
+	}
+	if held, err := repo.ClaimDue(context.Background(), at, at.Add(time.Minute), 10); err != nil || len(held) != 0 {
+		t.Fatalf("expected deferred event held back, got %d err=%v", len(held), err)
+	}
+	if due, err := repo.ClaimDue(context.Background(), quietEnd, quietEnd.Add(time.Minute), 10); err != nil || len(due) != 1 {
+		t.Fatalf("expected deferred event due after quiet hours, got %d err=%v", len(due), err)
+	}
+}
+
+func TestNotificationRepositoryReplaceScheduleEvents(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewNotificationRepository(dbConn)
+	user := &db.User{Username: "0830000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	scheduleID := uuid.New()
+	payload := []byte(`{"schedule_id":"` + scheduleID.String() + `","target_date":"2026-01-20"}`)
+	at := time.Date(2026, 1, 20, 1, 0, 0, 0, time.UTC)
+	event := func(when time.Time) db.NotificationEvent {
+		return db.NotificationEvent{UserID: user.ID, TemplateCode: constants.TemplateMedAfterMealNow, ScheduledAt: when, Status: constants.NotificationPending, Payload: payload}
+	}
+	if err := repo.CreateEvents(context.Background(), []db.NotificationEvent{event(at)}); err != nil {
+		t.Fatalf("create events: %v", err)
+	}
+
+	later := at.Add(30 * time.Minute)
+	if err := repo.ReplaceScheduleEvents(context.Background(), user.ID, scheduleID, []db.NotificationEvent{event(later)}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	var events []db.NotificationEvent
+	if err := dbConn.Where("user_id = ?", user.ID).Find(&events).Error; err != nil {
+		t.Fatalf("load events: %v", err)
+	}
+	if len(events) != 1 || !events[0].ScheduledAt.Equal(later) {
+		t.Fatalf("expected only the replacement event, got %+v", events)
+	}
+
+	// Moving back to the original time must not collide with the old row.
+	if err := repo.ReplaceScheduleEvents(context.Background(), user.ID, scheduleID, []db.NotificationEvent{event(at)}); err != nil {
+		t.Fatalf("replace back: %v", err)
+	}
+	if err := repo.DeletePendingBySchedules(context.Background(), user.ID, []uuid.UUID{scheduleID}); err != nil {
+		t.Fatalf("delete pending: %v", err)
+	}
+	var remaining int64
+	if err := dbConn.Model(&db.NotificationEvent{}).Where("user_id = ?", user.ID).Count(&remaining).Error; err != nil || remaining != 0 {
+		t.Fatalf("expected reminders removed, got %d err=%v", remaining, err)
+	}
+}
+
+func TestNotificationRepositoryKeepsRemindersPerSchedule(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewNotificationRepository(dbConn)
+	user := &db.User{Username: "0830000001", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Two medicines in the same time slot.
+	first, second := uuid.New(), uuid.New()
+	at := time.Date(2026, 1, 20, 1, 0, 0, 0, time.UTC)
+	event := func(scheduleID uuid.UUID) db.NotificationEvent {
+		payload := []byte(`{"schedule_id":"` + scheduleID.String() + `","target_date":"2026-01-20"}`)
+		return db.NotificationEvent{UserID: user.ID, TemplateCode: constants.TemplateMedBeforeMeal20Min, ScheduledAt: at, Status: constants.NotificationPending, Payload: payload}
+	}
+	if err := repo.CreateEvents(context.Background(), []db.NotificationEvent{event(first), event(second)}); err != nil {
+		t.Fatalf("create events: %v", err)
+	}
+	if err := repo.CreateEvents(context.Background(), []db.NotificationEvent{event(first)}); err != nil {
+		t.Fatalf("create duplicate: %v", err)
+	}
+	weekly := db.NotificationEvent{UserID: user.ID, TemplateCode: constants.TemplateWeeklyHealthLog, ScheduledAt: at, Status: constants.NotificationPending}
+	if err := repo.CreateEvents(context.Background(), []db.NotificationEvent{weekly, weekly}); err != nil {
+		t.Fatalf("create unscheduled events: %v", err)
+	}
+	var total int64
+	if err := dbConn.Model(&db.NotificationEvent{}).Where("user_id = ?", user.ID).Count(&total).Error; err != nil || total != 3 {
+		t.Fatalf("expected one reminder per medicine plus one weekly event, got %d err=%v", total, err)
+	}
+
+	if err := repo.CancelPendingBySchedule(context.Background(), user.ID, first, "2026-01-20"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := repo.DeletePendingBySchedules(context.Background(), user.ID, []uuid.UUID{first}); err != nil {
+		t.Fatalf("delete pending: %v", err)
+	}
+	var pending []db.NotificationEvent
+	if err := dbConn.Where("user_id = ? AND status = ? AND template_code = ?", user.ID, constants.NotificationPending, constants.TemplateMedBeforeMeal20Min).Find(&pending).Error; err != nil {
+		t.Fatalf("load events: %v", err)
+	}
+	if len(pending) != 1 || !strings.Contains(string(pending[0].Payload), second.String()) {
+		t.Fatalf("expected the other medicine's reminder untouched, got %+v", pending)
+	}
+}
+
+func TestPreferenceRepositoryTimezone(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewPreferenceRepository(dbConn)
+	notifications := NewNotificationRepository(dbConn)
+	user := &db.User{Username: "0840000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	timezone := "America/New_York"
+	if err := repo.Upsert(context.Background(), &db.UserPreference{UserID: user.ID, WeeklyReminderEnabled: false, Timezone: &timezone}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	pref, err := repo.FindByUserID(context.Background(), user.ID)
+	if err != nil || pref.WeeklyReminderEnabled || pref.Timezone == nil || *pref.Timezone != timezone {
+		t.Fatalf("expected weekly reminders off and the timezone stored, got %+v err=%v", pref, err)
+	}
+	users, err := repo.ListWeeklyReminderUsers(context.Background())
+	if err != nil {
+		t.Fatalf("list weekly users: %v", err)
+	}
+	for _, item := range users {
+		if item.UserID == user.ID {
+			t.Fatalf("expected opted-out user to be skipped")
+		}
+	}
+
+	pref.WeeklyReminderEnabled = true
+	if err := repo.Upsert(context.Background(), pref); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	users, err = repo.ListWeeklyReminderUsers(context.Background())
+	if err != nil {
+		t.Fatalf("list weekly users: %v", err)
+	}
+	found := false
+	for _, item := range users {
+		if item.UserID == user.ID {
+			found = item.Timezone != nil && *item.Timezone == timezone
+		}
+	}
+	if !found {
+		t.Fatalf("expected the user with their timezone, got %+v", users)
+	}
+
+	at := time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC)
+	weekly := func(when time.Time) db.NotificationEvent {
+		return db.NotificationEvent{UserID: user.ID, TemplateCode: constants.TemplateWeeklyHealthLog, ScheduledAt: when, Status: constants.NotificationPending, Payload: []byte(`{"type":"weekly_health_log"}`)}
+	}
+	if err := notifications.CreateEvents(context.Background(), []db.NotificationEvent{weekly(at)}); err != nil {
+		t.Fatalf("create events: %v", err)
+	}
+	moved := time.Date(2026, 1, 5, 14, 0, 0, 0, time.UTC)
+	if err := notifications.ReplacePendingByTemplate(context.Background(), user.ID, constants.TemplateWeeklyHealthLog, []db.NotificationEvent{weekly(moved)}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	var events []db.NotificationEvent
+	if err := dbConn.Where("user_id = ?", user.ID).Find(&events).Error; err != nil {
+		t.Fatalf("load events: %v", err)
+	}
+	if len(events) != 1 || !events[0].ScheduledAt.Equal(moved) {
+		t.Fatalf("expected only the moved weekly reminder, got %+v", events)
+	}
+}
+
+func TestPreferenceRepositoryNotificationSettings(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewPreferenceRepository(dbConn)
+	notifications := NewNotificationRepository(dbConn)
+	user := &db.User{Username: "0850000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	start, end := time.Date(0, 1, 1, 22, 0, 0, 0, time.UTC), time.Date(0, 1, 1, 7, 0, 0, 0, time.UTC)
+	lead, language := 10, constants.LocaleEnglish
+	if err := repo.Upsert(context.Background(), &db.UserPreference{
+		UserID:                  user.ID,
+		WeeklyReminderEnabled:   true,
+		Language:                &language,
+		NotificationChannels:    []byte(`["line","sms"]`),
+		QuietHoursStart:         &start,
+		QuietHoursEnd:           &end,
+		AppointmentReminderDays: []byte(`[]`),
+		BeforeMealLeadMinutes:   &lead,
+	}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	pref, err := repo.FindByUserID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if string(pref.AppointmentReminderDays) != "[]" || pref.QuietHoursStart == nil || pref.QuietHoursStart.Hour() != 22 ||
+		pref.QuietHoursEnd == nil || pref.QuietHoursEnd.Hour() != 7 || pref.BeforeMealLeadMinutes == nil || *pref.BeforeMealLeadMinutes != 10 || pref.BeforeMealFollowUpMinutes != nil ||
+		pref.Language == nil || *pref.Language != constants.LocaleEnglish {
+		t.Fatalf("unexpected preferences: %+v", pref)
+	}
+	var channels []string
+	if err := json.Unmarshal(pref.NotificationChannels, &channels); err != nil || len(channels) != 2 || channels[1] != "sms" {
+		t.Fatalf("unexpected channels %s err=%v", pref.NotificationChannels, err)
+	}
+
+	apptID := uuid.New()
+	reminder := func(code string, when time.Time) db.NotificationEvent {
+		return db.NotificationEvent{UserID: user.ID, TemplateCode: code, ScheduledAt: when, Status: constants.NotificationPending, Payload: []byte(`{"appointment_id":"` + apptID.String() + `"}`)}
+	}
+	at := time.Date(2026, 2, 1, 2, 0, 0, 0, time.UTC)
+	if err := notifications.CreateEvents(context.Background(), []db.NotificationEvent{reminder(constants.TemplateAppt5Days, at), reminder(constants.TemplateAppt1Day, at.AddDate(0, 0, 4))}); err != nil {
+		t.Fatalf("create events: %v", err)
+	}
+	if err := notifications.ReplaceAppointmentEvents(context.Background(), user.ID, apptID, []db.NotificationEvent{reminder(constants.TemplateApptReminder, at.AddDate(0, 0, -2)), reminder(constants.TemplateAppt1Day, at.AddDate(0, 0, 4))}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	var events []db.NotificationEvent
+	if err := dbConn.Where("user_id = ?", user.ID).Order("scheduled_at asc").Find(&events).Error; err != nil {
+		t.Fatalf("load events: %v", err)
+	}
+	if len(events) != 2 || events[0].TemplateCode != constants.TemplateApptReminder || events[1].TemplateCode != constants.TemplateAppt1Day {
+		t.Fatalf("expected the replacement reminders only, got %+v", events)
+	}
+	if err := notifications.CancelPendingByAppointment(context.Background(), user.ID, apptID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	var pending int64
+	if err := dbConn.Model(&db.NotificationEvent{}).Where("user_id = ? AND status = ?", user.ID, constants.NotificationPending).Count(&pending).Error; err != nil || pending != 0 {
+		t.Fatalf("expected every appointment reminder cancelled, got %d err=%v", pending, err)
+	}
+}
+
+func TestNotificationRepositoryFindTemplateByLocale(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewNotificationRepository(dbConn)
+	// Migrations seed both locales of the built-in templates.
+	th, err := repo.FindTemplate(context.Background(), constants.TemplateMedAfterMealNow, constants.LocaleThai, constants.LocaleEnglish)
+	if err != nil || th.Locale != constants.LocaleThai {
+		t.Fatalf("expected the Thai template, got %+v err=%v", th, err)
+	}
+	en, err := repo.FindTemplate(context.Background(), constants.TemplateMedAfterMealNow, constants.LocaleEnglish, constants.LocaleThai)
+	if err != nil || en.Locale != constants.LocaleEnglish || en.Body == th.Body {
+		t.Fatalf("expected the English template, got %+v err=%v", en, err)
+	}
+
+	if err := dbConn.Model(&db.NotificationTemplate{}).Where("code = ? AND locale = ?", constants.TemplateMedAfterMealNow, constants.LocaleThai).Update("is_active", false).Error; err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	fallback, err := repo.FindTemplate(context.Background(), constants.TemplateMedAfterMealNow, constants.LocaleThai)
+	if err != nil || fallback.Locale != constants.LocaleEnglish {
+		t.Fatalf("expected a fallback to the English template, got %+v err=%v", fallback, err)
+	}
+	if _, err := repo.FindTemplate(context.Background(), "NO_SUCH_TEMPLATE", constants.LocaleThai); err == nil {
+		t.Fatalf("expected an unknown code to be not found")
+	}
+}
+
+func TestNotificationRepositoryInbox(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewNotificationRepository(dbConn)
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+	for i, id := range []uuid.UUID{userID, otherID} {
+		if err := dbConn.Create(&db.User{ID: id, Username: fmt.Sprintf("083000000%d", i), PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	base := time.Date(2026, 1, 20, 1, 0, 0, 0, time.UTC)
+	sentAt := func(after time.Duration) *time.Time {
+		at := base.Add(after)
+		return &at
+	}
+	title := "Medicine reminder"
+	events := []db.NotificationEvent{
+		{UserID: userID, TemplateCode: constants.TemplateMedAfterMealNow, ScheduledAt: base, Status: constants.NotificationSent, SentAt: sentAt(0), Title: &title},
+		{UserID: userID, TemplateCode: constants.TemplateMedAfterMealNow, ScheduledAt: base.Add(time.Hour), Status: constants.NotificationSent, SentAt: sentAt(time.Hour), Title: &title},
+		{UserID: userID, TemplateCode: constants.TemplateMedAfterMealNow, ScheduledAt: base.Add(2 * time.Hour), Status: constants.NotificationSent, SentAt: sentAt(2 * time.Hour), Title: &title},
+		{UserID: userID, TemplateCode: constants.TemplateMedAfterMealNow, ScheduledAt: base.Add(24 * time.Hour), Status: constants.NotificationPending},
+		{UserID: otherID, TemplateCode: constants.TemplateMedAfterMealNow, ScheduledAt: base, Status: constants.NotificationSent, SentAt: sentAt(0)},
+	}
+	if err := repo.CreateEvents(ctx, events); err != nil {
+		t.Fatalf("create events: %v", err)
+	}
+
+	page, err := repo.ListInbox(ctx, userID, nil, false, 2)
+	if err != nil || len(page) != 2 || page[0].ID != events[2].ID || page[1].ID != events[1].ID || page[0].Title == nil {
+		t.Fatalf("expected the two newest sent events, got %+v err=%v", page, err)
+	}
+	rest, err := repo.ListInbox(ctx, userID, &NotificationInboxCursor{SentAt: *page[1].SentAt, ID: page[1].ID}, false, 2)
+	if err != nil || len(rest) != 1 || rest[0].ID != events[0].ID {
+		t.Fatalf("expected the oldest event after the cursor, got %+v err=%v", rest, err)
+	}
+
+	if count, err := repo.CountUnread(ctx, userID); err != nil || count != 3 {
+		t.Fatalf("expected 3 unread, got %d err=%v", count, err)
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if err := repo.MarkRead(ctx, userID, events[2].ID, now); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+	if err := repo.MarkRead(ctx, userID, events[2].ID, now.Add(time.Hour)); err != nil {
+		t.Fatalf("mark read twice: %v", err)
+	}
+	var stored db.NotificationEvent
+	if err := dbConn.First(&stored, "id = ?", events[2].ID).Error; err != nil || stored.ReadAt == nil || !stored.ReadAt.Equal(now) {
+		t.Fatalf("expected the first read_at kept, got %+v err=%v", stored.ReadAt, err)
+	}
+	if err := repo.MarkRead(ctx, userID, events[3].ID, now); err == nil {
+		t.Fatalf("expected a pending event to be not found")
+	}
+	if err := repo.MarkRead(ctx, userID, events[4].ID, now); err == nil {
+		t.Fatalf("expected another user's event to be not found")
+	}
+	if unread, err := repo.ListInbox(ctx, userID, nil, true, 10); err != nil || len(unread) != 2 {
+		t.Fatalf("expected 2 unread events, got %d err=%v", len(unread), err)
+	}
+
+	if err := repo.Archive(ctx, userID, events[1].ID, now); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if err := repo.Archive(ctx, userID, events[1].ID, now); err == nil {
+		t.Fatalf("expected an archived event to be not found")
+	}
+	if marked, err := repo.MarkAllRead(ctx, userID, now); err != nil || marked != 1 {
+		t.Fatalf("expected one event marked read, got %d err=%v", marked, err)
+	}
+	if count, err := repo.CountUnread(ctx, userID); err != nil || count != 0 {
+		t.Fatalf("expected no unread, got %d err=%v", count, err)
+	}
+	if count, err := repo.CountUnread(ctx, otherID); err != nil || count != 1 {
+		t.Fatalf("expected the other user's event untouched, got %d err=%v", count, err)
+	}
+	if inbox, err := repo.ListInbox(ctx, userID, nil, false, 10); err != nil || len(inbox) != 2 {
+		t.Fatalf("expected the archived event hidden, got %d err=%v", len(inbox), err)
+	}
+}
+
+func TestNotificationTemplateRepositoryCRUD(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewNotificationTemplateRepository(dbConn)
+	ctx := context.Background()
+	draft := &db.NotificationTemplate{Code: "TEST_TEMPLATE", Locale: constants.LocaleThai, Title: "t", Body: "b", IsActive: false}
+	if err := repo.Create(ctx, draft); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	stored, err := repo.FindByID(ctx, draft.ID)
+	if err != nil || stored.IsActive {
+		t.Fatalf("expected an inactive template, got %+v err=%v", stored, err)
+	}
+	err = repo.Create(ctx, &db.NotificationTemplate{Code: "TEST_TEMPLATE", Locale: constants.LocaleThai, Title: "t", Body: "b"})
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.NotificationConflict {
+		t.Fatalf("expected a conflict for a duplicate locale, got %v", err)
+	}
+
+	if count, err := repo.CountActiveByCode(ctx, "TEST_TEMPLATE", uuid.Nil); err != nil || count != 0 {
+		t.Fatalf("expected no active templates, got %d err=%v", count, err)
+	}
+	updated, err := repo.Update(ctx, draft.ID, map[string]any{"title": "new", "is_active": true})
+	if err != nil || updated.Title != "new" || !updated.IsActive || updated.Code != "TEST_TEMPLATE" {
+		t.Fatalf("expected the updated row returned, got %+v err=%v", updated, err)
+	}
+	if count, err := repo.CountActiveByCode(ctx, "TEST_TEMPLATE", uuid.Nil); err != nil || count != 1 {
+		t.Fatalf("expected one active template, got %d err=%v", count, err)
+	}
+	if count, err := repo.CountActiveByCode(ctx, "TEST_TEMPLATE", draft.ID); err != nil || count != 0 {
+		t.Fatalf("expected the excluded template not counted, got %d err=%v", count, err)
+	}
+
+	active := true
+	items, total, err := repo.List(ctx, NotificationTemplateFilter{Code: "TEST_TEMPLATE", IsActive: &active}, 1, 10)
+	if err != nil || total != 1 || len(items) != 1 || items[0].ID != draft.ID {
+		t.Fatalf("expected the filtered template, got %+v total=%d err=%v", items, total, err)
+	}
+
+	if err := repo.Delete(ctx, draft.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, draft.ID); err == nil {
+		t.Fatalf("expected the deleted template to be not found")
+	}
+	if err := repo.Delete(ctx, draft.ID); err == nil {
+		t.Fatalf("expected deleting twice to fail")
+	}
+}
+
+func TestMedicineRepositoryListActiveSchedules(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewMedicineRepository(dbConn)
+	user := &db.User{Username: "0820000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	slot := time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)
+	active := &db.PatientMedicine{UserID: user.ID, DosageAmount: "1", IsActive: true}
+	inactive := &db.PatientMedicine{UserID: user.ID, DosageAmount: "1", IsActive: true}
+	for _, med := range []*db.PatientMedicine{active, inactive} {
+		if err := repo.CreatePatientMedicine(context.Background(), med); err != nil {
+			t.Fatalf("create medicine: %v", err)
+		}
+		if err := repo.CreateSchedule(context.Background(), &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: slot, StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), IntervalDays: 2, WeekdayMask: constants.WeekdayMaskAll}); err != nil {
+			t.Fatalf("create schedule: %v", err)
+		}
+	}
+	if err := repo.UpdatePatientMedicine(context.Background(), inactive.ID, map[string]any{"is_active": false}); err != nil {
+		t.Fatalf("deactivate medicine: %v", err)
+	}
+
+	rows, err := repo.ListActiveSchedules(context.Background(), uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("list active schedules: %v", err)
+	}
+	if len(rows) != 1 || rows[0].UserID != user.ID || rows[0].IntervalDays != 2 || rows[0].StartDate.Format("2006-01-02") != "2026-01-01" || rows[0].Timezone != nil {
+		t.Fatalf("expected only the active medicine's schedule, got %+v", rows)
+	}
+	if next, err := repo.ListActiveSchedules(context.Background(), rows[0].ID, 10); err != nil || len(next) != 0 {
+		t.Fatalf("expected empty next page, got %d err=%v", len(next), err)
+	}
+}
+
+func TestMedicineRepositoryUpdateScheduleWithReminders(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewMedicineRepository(dbConn)
+	user := &db.User{Username: "0820000001", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	med := &db.PatientMedicine{UserID: user.ID, DosageAmount: "1", IsActive: true}
+	if err := repo.CreatePatientMedicine(context.Background(), med); err != nil {
+		t.Fatalf("create medicine: %v", err)
+	}
+	schedule := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	if err := repo.CreateSchedule(context.Background(), schedule); err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+	payload := []byte(`{"schedule_id":"` + schedule.ID.String() + `","target_date":"2026-01-20"}`)
+	reminder := func(userID uuid.UUID, at time.Time) db.NotificationEvent {
+		return db.NotificationEvent{UserID: userID, TemplateCode: constants.TemplateMedAfterMealNow, ScheduledAt: at, Status: constants.NotificationPending, Payload: payload}
+	}
+	at := time.Date(2026, 1, 20, 1, 0, 0, 0, time.UTC)
+	effective := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+	if err := NewNotificationRepository(dbConn).CreateEvents(context.Background(), []db.NotificationEvent{reminder(user.ID, at)}); err != nil {
+		t.Fatalf("create events: %v", err)
+	}
+
+	// A reminder that cannot be stored rolls back the schedule change too.
+	if err := repo.UpdateScheduleWithReminders(context.Background(), schedule.ID, map[string]any{"time_slot": "09:30:00"}, effective, user.ID, []db.NotificationEvent{reminder(uuid.New(), at.Add(90*time.Minute))}); err == nil {
+		t.Fatalf("expected the reminder insert to fail")
+	}
+	stored, err := repo.GetScheduleByID(context.Background(), schedule.ID)
+	if err != nil || stored.TimeSlot.Hour() != 8 {
+		t.Fatalf("expected the schedule unchanged, got %+v err=%v", stored, err)
+	}
+	var events []db.NotificationEvent
+	if err := dbConn.Where("user_id = ?", user.ID).Find(&events).Error; err != nil || len(events) != 1 || !events[0].ScheduledAt.Equal(at) {
+		t.Fatalf("expected the old reminder kept, got %+v err=%v", events, err)
+	}
+
+	later := at.Add(90 * time.Minute)
+	if err := repo.UpdateScheduleWithReminders(context.Background(), schedule.ID, map[string]any{"time_slot": "09:30:00"}, effective, user.ID, []db.NotificationEvent{reminder(user.ID, later)}); err != nil {
+		t.Fatalf("update schedule: %v", err)
+	}
+	stored, err = repo.GetScheduleByID(context.Background(), schedule.ID)
+	if err != nil || stored.TimeSlot.Hour() != 9 {
+		t.Fatalf("expected the new time slot, got %+v err=%v", stored, err)
+	}
+	if err := dbConn.Where("user_id = ?", user.ID).Find(&events).Error; err != nil || len(events) != 1 || !events[0].ScheduledAt.Equal(later) {
+		t.Fatalf("expected only the new reminder, got %+v err=%v", events, err)
+	}
+	if err := repo.UpdateScheduleWithReminders(context.Background(), uuid.New(), map[string]any{"time_slot": "09:30:00"}, effective, user.ID, nil); err == nil {
+		t.Fatalf("expected an unknown schedule to fail")
+	}
+}
+
+func TestMedicineRepositoryListScheduleHistory(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewMedicineRepository(dbConn)
+	ctx := context.Background()
+	user := &db.User{Username: "0820000002", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	med := &db.PatientMedicine{UserID: user.ID, DosageAmount: "1", IsActive: true}
+	if err := repo.CreatePatientMedicine(ctx, med); err != nil {
+		t.Fatalf("create medicine: %v", err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	edited := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), StartDate: start, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	removed := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 12, 0, 0, 0, time.UTC), StartDate: start, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	for _, schedule := range []*db.MedicineSchedule{edited, removed} {
+		if err := repo.CreateSchedule(ctx, schedule); err != nil {
+			t.Fatalf("create schedule: %v", err)
+		}
+	}
+
+	// Two edits on the 10th keep one version: 08:00 through the 9th.
+	for _, slot := range []string{"09:00:00", "09:30:00"} {
+		if err := repo.UpdateSchedule(ctx, edited.ID, map[string]any{"time_slot": slot}, time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("update schedule: %v", err)
+		}
+	}
+	if err := repo.DeleteSchedule(ctx, removed.ID); err != nil {
+		t.Fatalf("delete schedule: %v", err)
+	}
+	if err := repo.UpdatePatientMedicineStatus(ctx, med.ID, map[string]any{"is_active": false}, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("deactivate medicine: %v", err)
+	}
+
+	rows, err := repo.ListScheduleHistory(ctx, user.ID)
 	if err != nil {
-		t.Fatalf("list due: %v", err)
+		t.Fatalf("list schedule history: %v", err)
+	}
+	date := func(value *time.Time) string {
+		if value == nil {
+			return ""
+		}
+		return value.Format("2006-01-02")
+	}
+	want := []struct {
+		scheduleID uuid.UUID
+		slot       string
+		active     bool
+		from, to   string
+		deleted    bool
+	}{
+		{edited.ID, "08:00", true, "", "2026-01-09", false},
+		{edited.ID, "09:30", true, "2026-01-10", "2026-01-14", false},
+		{edited.ID, "09:30", false, "2026-01-15", "", false},
+		{removed.ID, "12:00", true, "", "", true},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %+v", len(want), rows)
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.ScheduleID != w.scheduleID || row.TimeSlot.Format("15:04") != w.slot || row.MedicineActive != w.active ||
+			date(row.ValidFrom) != w.from || date(row.ValidUntil) != w.to || (row.DeletedAt != nil) != w.deleted {
+			t.Fatalf("row %d: expected %+v, got %+v", i, w, row)
+		}
+	}
+
+	current, err := repo.ListSchedulesWithMedicine(ctx, user.ID)
+	if err != nil || len(current) != 1 || current[0].ScheduleID != edited.ID {
+		t.Fatalf("expected only the live schedule, got %+v err=%v", current, err)
+	}
+	if _, err := repo.GetScheduleByID(ctx, removed.ID); err == nil {
+		t.Fatalf("expected a deleted schedule to be hidden")
+	}
+}
+
+func TestIntakeRepositoryCreateMissed(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	medicines := NewMedicineRepository(dbConn)
+	repo := NewIntakeRepository(dbConn)
+	user := &db.User{Username: "0840000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	med := &db.PatientMedicine{UserID: user.ID, DosageAmount: "1", IsActive: true}
+	if err := medicines.CreatePatientMedicine(context.Background(), med); err != nil {
+		t.Fatalf("create medicine: %v", err)
+	}
+	schedule := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	if err := medicines.CreateSchedule(context.Background(), schedule); err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	taken := time.Date(2026, 1, 19, 0, 0, 0, 0, time.UTC)
+	if err := repo.Create(context.Background(), &db.IntakeHistory{UserID: user.ID, ScheduleID: &schedule.ID, TargetDate: taken, Status: constants.MedTaken}); err != nil {
+		t.Fatalf("create intake: %v", err)
+	}
+
+	doses := []MissedDose{
+		{UserID: user.ID, ScheduleID: schedule.ID, TargetDate: taken, DueAt: taken.Add(time.Hour)},
+		{UserID: user.ID, ScheduleID: schedule.ID, TargetDate: taken.AddDate(0, 0, 1), DueAt: taken.AddDate(0, 0, 1).Add(time.Hour)},
+	}
+	inserted, err := repo.CreateMissed(context.Background(), doses)
+	if err != nil || inserted != 1 {
+		t.Fatalf("expected 1 missed row, got %d err=%v", inserted, err)
+	}
+	if again, err := repo.CreateMissed(context.Background(), doses); err != nil || again != 0 {
+		t.Fatalf("expected rerun to insert nothing, got %d err=%v", again, err)
+	}
+	feed, err := NewSyncRepository(dbConn).ListChanges(context.Background(), user.ID, SyncChangeCursor{}, 10)
+	if err != nil || len(feed) != 1 || feed[0].EntityType != constants.SyncEntityIntake {
+		t.Fatalf("expected one MISSED change in the sync feed, got %+v err=%v", feed, err)
+	}
+
+	var missed int64
+	if err := dbConn.Model(&db.IntakeHistory{}).Where("user_id = ? AND status = ?", user.ID, constants.MedMissed).Count(&missed).Error; err != nil || missed != 1 {
+		t.Fatalf("expected one MISSED row, got %d err=%v", missed, err)
+	}
+
+	duplicate := &db.IntakeHistory{UserID: user.ID, ScheduleID: &schedule.ID, TargetDate: taken, Status: constants.MedSkipped}
+	if err := repo.Create(context.Background(), duplicate); err == nil {
+		t.Fatalf("expected duplicate dose to conflict")
+	} else if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.IntakeConflict {
+		t.Fatalf("expected intake conflict, got %v", err)
+	}
+
+	record, err := repo.FindByDose(context.Background(), user.ID, schedule.ID, taken)
+	if err != nil || record == nil || record.Status != constants.MedTaken {
+		t.Fatalf("expected taken dose, got %+v err=%v", record, err)
+	}
+	previous := record.Status
+	record.Status = constants.MedSkipped
+	record.TakenAt = nil
+	record.UpdatedAt = time.Now().UTC()
+	if err := repo.Correct(context.Background(), record, &db.IntakeChange{IntakeID: record.ID, UserID: user.ID, ActorID: &user.ID, Action: constants.IntakeChangeUpdate, PreviousStatus: &previous, NewStatus: &record.Status}); err != nil {
+		t.Fatalf("correct: %v", err)
+	}
+	// A second correction from the same stale read loses: the status is no
+	// longer TAKEN.
+	if err := repo.Correct(context.Background(), record, &db.IntakeChange{IntakeID: record.ID, UserID: user.ID, ActorID: &user.ID, Action: constants.IntakeChangeUpdate, PreviousStatus: &previous, NewStatus: &record.Status}); err == nil {
+		t.Fatalf("expected a stale correction to conflict")
+	} else if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.IntakeConflict {
+		t.Fatalf("expected intake conflict, got %v", err)
+	}
+	if err := repo.Delete(context.Background(), record.ID, &db.IntakeChange{IntakeID: record.ID, UserID: user.ID, ActorID: &user.ID, Action: constants.IntakeChangeDelete, PreviousStatus: &previous}); err == nil {
+		t.Fatalf("expected a stale delete to conflict")
+	}
+	if err := repo.Delete(context.Background(), record.ID, &db.IntakeChange{IntakeID: record.ID, UserID: user.ID, ActorID: &user.ID, Action: constants.IntakeChangeDelete, PreviousStatus: &record.Status}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.GetByID(context.Background(), record.ID); err == nil {
+		t.Fatalf("expected deleted intake to be gone")
+	}
+	changes, err := repo.ListChanges(context.Background(), record.ID)
+	if err != nil || len(changes) != 2 || changes[0].Action != constants.IntakeChangeUpdate || changes[1].Action != constants.IntakeChangeDelete {
+		t.Fatalf("expected update then delete changes, got %+v err=%v", changes, err)
+	}
+}
+
+func TestAdminRepositoryListDoseTimings(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	medicines := NewMedicineRepository(dbConn)
+	intake := NewIntakeRepository(dbConn)
+	user := &db.User{Username: "0860000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	name := "Amlodipine"
+	med := &db.PatientMedicine{UserID: user.ID, CustomName: &name, DosageAmount: "1", IsActive: true}
+	if err := medicines.CreatePatientMedicine(context.Background(), med); err != nil {
+		t.Fatalf("create medicine: %v", err)
+	}
+	morning := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	evening := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 20, 0, 0, 0, time.UTC), StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	for _, schedule := range []*db.MedicineSchedule{morning, evening} {
+		if err := medicines.CreateSchedule(context.Background(), schedule); err != nil {
+			t.Fatalf("create schedule: %v", err)
+		}
+	}
+
+	day := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+	record := func(schedule *db.MedicineSchedule, slot time.Duration, delay time.Duration, timing string) {
+		due := day.Add(slot)
+		taken := due.Add(delay)
+		if err := intake.Create(context.Background(), &db.IntakeHistory{UserID: user.ID, ScheduleID: &schedule.ID, TargetDate: day, Status: constants.MedTaken, DueAt: &due, TakenAt: &taken, Timing: &timing}); err != nil {
+			t.Fatalf("create intake: %v", err)
+		}
+	}
+	record(morning, 8*time.Hour, 11*time.Hour, constants.IntakeTimingLate)
+	record(evening, 20*time.Hour, 90*time.Minute, constants.IntakeTimingLate)
+
+	repo := NewAdminRepository(dbConn)
+	items, total, err := repo.ListDoseTimings(context.Background(), DoseTimingFilter{Timing: constants.IntakeTimingLate, SlotFrom: "05:00", SlotTo: "11:59", MinDelay: 6 * time.Hour}, 1, 20)
+	if err != nil {
+		t.Fatalf("list dose timings: %v", err)
+	}
+	if total != 1 || len(items) != 1 || items[0].MedicineName != name || items[0].UserID != user.ID || items[0].TakenAt.Sub(items[0].DueAt) != 11*time.Hour {
+		t.Fatalf("expected only the late morning dose, got total=%d %+v", total, items)
+	}
+	if _, total, err := repo.ListDoseTimings(context.Background(), DoseTimingFilter{PatientID: &user.ID}, 1, 20); err != nil || total != 2 {
+		t.Fatalf("expected both doses for the patient, got %d err=%v", total, err)
+	}
+}
+
+func TestSyncRepository(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewSyncRepository(dbConn)
+	user := &db.User{Username: "0850000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Two overlapping replays of one mutation: the second waits for the lock
+	// and gets the stored outcome instead of applying again.
+	clientID := uuid.New()
+	var applied int32
+	apply := func(ctx context.Context) (*db.SyncMutation, error) {
+		atomic.AddInt32(&applied, 1)
+		time.Sleep(100 * time.Millisecond)
+		entityID := uuid.New()
+		return &db.SyncMutation{UserID: user.ID, ClientID: clientID, MutationType: constants.SyncEntityHealthRecord, ClientTimestamp: time.Now().UTC(), Status: constants.SyncApplied, EntityID: &entityID}, nil
+	}
+	var wg sync.WaitGroup
+	outcomes := make([]*db.SyncMutation, 2)
+	duplicates := make([]bool, 2)
+	errs := make([]error, 2)
+	for i := range outcomes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outcomes[i], duplicates[i], errs[i] = repo.ApplyMutation(context.Background(), user.ID, clientID, apply)
+		}(i)
+	}
+	wg.Wait()
+	if errs[0] != nil || errs[1] != nil || applied != 1 || duplicates[0] == duplicates[1] || *outcomes[0].EntityID != *outcomes[1].EntityID {
+		t.Fatalf("expected the mutation applied once, got applied=%d duplicates=%v errs=%v", applied, duplicates, errs)
+	}
+
+	failedID := uuid.New()
+	if _, _, err := repo.ApplyMutation(context.Background(), user.ID, failedID, func(ctx context.Context) (*db.SyncMutation, error) {
+		return nil, domain.NewError(constants.InternalError, "down")
+	}); err == nil {
+		t.Fatalf("expected the apply error")
+	}
+	var stored int64
+	if err := dbConn.Model(&db.SyncMutation{}).Where("user_id = ? AND client_id = ?", user.ID, failedID).Count(&stored).Error; err != nil || stored != 0 {
+		t.Fatalf("expected a failed apply not to be stored, got %d err=%v", stored, err)
+	}
+
+	// The write of a mutation commits with its outcome: when the outcome
+	// cannot be stored, or the mutation is rejected, the write is undone.
+	health := NewHealthRepository(dbConn)
+	writeRecord := func(ctx context.Context, mutationType, status string) (*db.SyncMutation, error) {
+		record := &db.HealthRecord{UserID: user.ID, RecordDate: time.Now().UTC(), TimePeriod: "MORNING"}
+		if err := health.CreateHealthRecord(ctx, record); err != nil {
+			return nil, err
+		}
+		return &db.SyncMutation{UserID: user.ID, ClientID: uuid.New(), MutationType: mutationType, ClientTimestamp: time.Now().UTC(), Status: status, EntityID: &record.ID}, nil
+	}
+	if _, _, err := repo.ApplyMutation(context.Background(), user.ID, uuid.New(), func(ctx context.Context) (*db.SyncMutation, error) {
+		return writeRecord(ctx, "HEALTH_RECORD_TYPE_TOO_LONG_FOR_ITS_COLUMN", constants.SyncApplied)
+	}); err == nil {
+		t.Fatalf("expected the outcome insert to fail")
+	}
+	rejectedID := uuid.New()
+	rejected, _, err := repo.ApplyMutation(context.Background(), user.ID, rejectedID, func(ctx context.Context) (*db.SyncMutation, error) {
+		mutation, err := writeRecord(ctx, constants.SyncEntityHealthRecord, constants.SyncRejected)
+		if mutation != nil {
+			mutation.ClientID = rejectedID
+		}
+		return mutation, err
+	})
+	if err != nil || rejected.Status != constants.SyncRejected {
+		t.Fatalf("expected the rejected outcome stored, got %+v err=%v", rejected, err)
+	}
+	var records int64
+	if err := dbConn.Model(&db.HealthRecord{}).Where("user_id = ?", user.ID).Count(&records).Error; err != nil || records != 0 {
+		t.Fatalf("expected no health record without an applied outcome, got %d err=%v", records, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := repo.RecordChange(context.Background(), &db.SyncChange{UserID: user.ID, EntityType: constants.SyncEntityAppointment, EntityID: uuid.New(), Operation: constants.SyncOpUpsert, Payload: []byte(`{"status":"CONFIRMED"}`)}); err != nil {
+			t.Fatalf("record change: %v", err)
+		}
+	}
+	first, err := repo.ListChanges(context.Background(), user.ID, SyncChangeCursor{}, 2)
+	if err != nil || len(first) != 2 || first[0].TxID == 0 {
+		t.Fatalf("expected 2 changes with their transaction ids, got %+v err=%v", first, err)
+	}
+	cursor := SyncChangeCursor{TxID: first[1].TxID, ID: first[1].ID}
+	rest, err := repo.ListChanges(context.Background(), user.ID, cursor, 10)
+	if err != nil || len(rest) != 1 || rest[0].ID <= first[1].ID {
+		t.Fatalf("expected the remaining change after the cursor, got %+v err=%v", rest, err)
+	}
+	cursor = SyncChangeCursor{TxID: rest[0].TxID, ID: rest[0].ID}
+
+	// A change committed while an older transaction is still open is held
+	// back until that transaction commits, so the cursor cannot pass the
+	// older transaction's change.
+	open := dbConn.Begin()
+	if err := NewSyncRepository(open).RecordChange(context.Background(), &db.SyncChange{UserID: user.ID, EntityType: constants.SyncEntityIntake, EntityID: uuid.New(), Operation: constants.SyncOpUpsert}); err != nil {
+		open.Rollback()
+		t.Fatalf("record change in open transaction: %v", err)
+	}
+	if err := repo.RecordChange(context.Background(), &db.SyncChange{UserID: user.ID, EntityType: constants.SyncEntityAppointment, EntityID: uuid.New(), Operation: constants.SyncOpDelete}); err != nil {
+		open.Rollback()
+		t.Fatalf("record change: %v", err)
+	}
+	if held, err := repo.ListChanges(context.Background(), user.ID, cursor, 10); err != nil || len(held) != 0 {
+		open.Rollback()
+		t.Fatalf("expected changes held back behind the open transaction, got %+v err=%v", held, err)
+	}
+	if err := open.Commit().Error; err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	released, err := repo.ListChanges(context.Background(), user.ID, cursor, 10)
+	if err != nil || len(released) != 2 || released[0].EntityType != constants.SyncEntityIntake || released[1].Operation != constants.SyncOpDelete {
+		t.Fatalf("expected both changes in transaction order after the commit, got %+v err=%v", released, err)
+	}
+}
+
+func TestEscalationRepository(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	medicines := NewMedicineRepository(dbConn)
+	intake := NewIntakeRepository(dbConn)
+	repo := NewEscalationRepository(dbConn)
+	patient := &db.User{Username: "0870000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	caregiver := &db.User{Username: "0870000001", PasswordHash: "hash", Role: constants.RoleCaregiver, IsActive: true, IsVerified: true}
+	for _, user := range []*db.User{patient, caregiver} {
+		if err := dbConn.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	if err := dbConn.Create(&db.CaregiverAssignment{PatientID: patient.ID, CaregiverID: caregiver.ID, Relationship: "child"}).Error; err != nil {
+		t.Fatalf("create assignment: %v", err)
+	}
+	med := &db.PatientMedicine{UserID: patient.ID, DosageAmount: "1", IsActive: true}
+	if err := medicines.CreatePatientMedicine(context.Background(), med); err != nil {
+		t.Fatalf("create medicine: %v", err)
+	}
+	schedule := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	if err := medicines.CreateSchedule(context.Background(), schedule); err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	day := time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)
+	takenDue := day.Add(8 * time.Hour)
+	if err := intake.Create(context.Background(), &db.IntakeHistory{UserID: patient.ID, ScheduleID: &schedule.ID, TargetDate: day, Status: constants.MedTaken, DueAt: &takenDue}); err != nil {
+		t.Fatalf("create intake: %v", err)
+	}
+	missed := []MissedDose{
+		{UserID: patient.ID, ScheduleID: schedule.ID, TargetDate: day.AddDate(0, 0, 1), DueAt: takenDue.AddDate(0, 0, 1)},
+		{UserID: patient.ID, ScheduleID: schedule.ID, TargetDate: day.AddDate(0, 0, 2), DueAt: takenDue.AddDate(0, 0, 2)},
+	}
+	if _, err := intake.CreateMissed(context.Background(), missed); err != nil {
+		t.Fatalf("create missed: %v", err)
+	}
+
+	since := day.AddDate(0, 0, -7)
+	candidates, err := repo.ListMissedPatients(context.Background(), since)
+	if err != nil || len(candidates) != 1 || candidates[0].UserID != patient.ID {
+		t.Fatalf("expected the patient as candidate, got %+v err=%v", candidates, err)
+	}
+	doses, err := repo.ListRecentDoses(context.Background(), patient.ID, since)
+	if err != nil || len(doses) != 3 || doses[0].Status != constants.MedMissed || !doses[0].DueAt.Equal(missed[1].DueAt) || doses[2].Status != constants.MedTaken {
+		t.Fatalf("expected doses latest first, got %+v err=%v", doses, err)
+	}
+	caregivers, err := repo.ListCaregiverIDs(context.Background(), patient.ID)
+	if err != nil || len(caregivers) != 1 || caregivers[0] != caregiver.ID {
+		t.Fatalf("expected the caregiver, got %+v err=%v", caregivers, err)
+	}
+
+	escalation := db.MissedDoseEscalation{UserID: patient.ID, Level: constants.EscalationLevelCaregiver, MissedCount: 2, FirstMissedAt: missed[0].DueAt, LastMissedAt: missed[1].DueAt, RecipientCount: 1}
+	event := db.NotificationEvent{UserID: caregiver.ID, TemplateCode: constants.TemplateEscalationMissedCaregiver, ScheduledAt: time.Now().UTC(), Status: constants.NotificationPending, Payload: []byte(`{"type":"missed_dose_escalation"}`)}
+	if err := repo.CreateEscalations(context.Background(), []db.MissedDoseEscalation{escalation}, []db.NotificationEvent{event}); err != nil {
+		t.Fatalf("create escalations: %v", err)
+	}
+	latest, err := repo.FindLatestEscalation(context.Background(), patient.ID, constants.EscalationLevelCaregiver)
+	if err != nil || latest == nil || latest.MissedCount != 2 {
+		t.Fatalf("expected the escalation, got %+v err=%v", latest, err)
+	}
+	if broken, err := repo.HasDoseOutcomeSince(context.Background(), patient.ID, latest.LastMissedAt); err != nil || broken {
+		t.Fatalf("expected the streak to be unbroken, got %v err=%v", broken, err)
+	}
+
+	if err := repo.SavePolicy(context.Background(), &db.EscalationPolicy{UserID: patient.ID, Enabled: false}); err != nil {
+		t.Fatalf("save policy: %v", err)
+	}
+	if policy, err := repo.GetPolicy(context.Background(), patient.ID); err != nil || policy == nil || policy.Enabled {
+		t.Fatalf("expected a disabled policy, got %+v err=%v", policy, err)
+	}
+	if candidates, err := repo.ListMissedPatients(context.Background(), since); err != nil || len(candidates) != 0 {
+		t.Fatalf("expected disabled patient to be excluded, got %+v err=%v", candidates, err)
+	}
+}
+
+func TestRefillRepository(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	medicines := NewMedicineRepository(dbConn)
+	repo := NewRefillRepository(dbConn)
+	user := &db.User{Username: "0880000000", PasswordHash: "hash", Role: constants.RolePatient, IsActive: true, IsVerified: true}
+	if err := dbConn.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	name := "Metformin"
+	quantity, packSize := 3.0, 30.0
+	med := &db.PatientMedicine{UserID: user.ID, CustomName: &name, DosageAmount: "1", QuantityOnHand: &quantity, PackSize: &packSize, IsActive: true}
+	untracked := &db.PatientMedicine{UserID: user.ID, DosageAmount: "1", IsActive: true}
+	for _, item := range []*db.PatientMedicine{med, untracked} {
+		if err := medicines.CreatePatientMedicine(context.Background(), item); err != nil {
+			t.Fatalf("create medicine: %v", err)
+		}
+	}
+	schedule := &db.MedicineSchedule{PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), StartDate: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	if err := medicines.CreateSchedule(context.Background(), schedule); err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	updated, err := repo.AdjustStock(context.Background(), med.ID, -5)
+	if err != nil || updated == nil || updated.QuantityOnHand == nil || *updated.QuantityOnHand != 0 {
+		t.Fatalf("expected stock floored at zero, got %+v err=%v", updated, err)
+	}
+	if updated, err := repo.AdjustStock(context.Background(), untracked.ID, -1); err != nil || updated != nil {
+		t.Fatalf("expected untracked medicine to be skipped, got %+v err=%v", updated, err)
+	}
+
+	event := db.NotificationEvent{UserID: user.ID, TemplateCode: constants.TemplateMedLowSupply, ScheduledAt: time.Now().UTC(), Status: constants.NotificationPending, Payload: []byte(`{"type":"low_supply"}`)}
+	if queued, err := repo.FlagLowSupply(context.Background(), med.ID, event); err != nil || !queued {
+		t.Fatalf("expected reminder queued, got %v err=%v", queued, err)
+	}
+	event.ScheduledAt = event.ScheduledAt.Add(time.Minute)
+	if queued, err := repo.FlagLowSupply(context.Background(), med.ID, event); err != nil || queued {
+		t.Fatalf("expected second reminder suppressed, got %v err=%v", queued, err)
+	}
+
+	rows, err := repo.ListTrackedMedicines(context.Background(), &user.ID)
+	if err != nil || len(rows) != 1 || rows[0].PatientMedicineID != med.ID || rows[0].MedicineName != name || rows[0].Username != user.Username {
+		t.Fatalf("expected only the tracked medicine, got %+v err=%v", rows, err)
+	}
+	schedules, err := repo.ListSchedulesByMedicines(context.Background(), []uuid.UUID{med.ID, untracked.ID})
+	if err != nil || len(schedules) != 1 || schedules[0].ID != schedule.ID {
+		t.Fatalf("expected the schedule, got %+v err=%v", schedules, err)
+	}
+
+	refillDate := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+	restocked, err := repo.Restock(context.Background(), med.ID, 30, refillDate)
+	if err != nil || *restocked.QuantityOnHand != 30 || restocked.LowSupplyNotifiedAt != nil || restocked.RefillDate == nil || !restocked.RefillDate.Equal(refillDate) {
+		t.Fatalf("expected refill to reset the reminder, got %+v err=%v", restocked, err)
+	}
+	if queued, err := repo.FlagLowSupply(context.Background(), med.ID, event); err != nil || !queued {
+		t.Fatalf("expected reminder re-armed after refill, got %v err=%v", queued, err)
+	}
+}
+
+func TestJobRunRepository(t *testing.T) {
+	dbConn, cleanup := setupIntegrationDB(t)
+	defer cleanup()
+
+	repo := NewJobRunRepository(dbConn)
+	run := &db.JobRun{JobName: "test.job", Status: constants.JobRunRunning, StartedAt: time.Now().UTC()}
+	if err := repo.Start(context.Background(), run); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if run.ID == uuid.Nil {
+		t.Fatalf("expected run id")
+	}
+
+	errMsg := "boom"
+	if err := repo.Finish(context.Background(), run.ID, constants.JobRunFailed, time.Now().UTC(), 1500*time.Millisecond, &errMsg); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+
+	var stored db.JobRun
+	if err := dbConn.First(&stored, "id = ?", run.ID).Error; err != nil {
+		t.Fatalf("load run: %v", err)
+	}
+	if stored.Status != constants.JobRunFailed || stored.FinishedAt == nil || stored.DurationMs == nil || *stored.DurationMs != 1500 || stored.Error == nil || *stored.Error != errMsg {
+		t.Fatalf("unexpected run: %+v", stored)
+	}
+
+	old := &db.JobRun{JobName: "test.job", Status: constants.JobRunSucceeded, StartedAt: time.Now().UTC().Add(-31 * 24 * time.Hour)}
+	if err := repo.Start(context.Background(), old); err != nil {
+		t.Fatalf("start old run: %v", err)
+	}
+	deleted, err := repo.DeleteStartedBefore(context.Background(), time.Now().UTC().Add(-30*24*time.Hour))
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected only the old run pruned, got %d err=%v", deleted, err)
 	}
-	if len(due) == 0 {
-		t.Fatalf("expected due events")
+	if err := dbConn.First(&stored, "id = ?", run.ID).Error; err != nil {
+		t.Fatalf("expected the recent run kept: %v", err)
 	}
 }
 
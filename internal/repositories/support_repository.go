@@ -24,7 +24,7 @@ func NewSupportRepository(dbConn *gorm.DB) SupportRepository {
 }
 
 func (r *supportRepository) CreateChatRequest(ctx context.Context, req *db.SupportChatRequest) error {
-	if err := r.db.WithContext(ctx).Create(req).Error; err != nil {
+	if err := conn(ctx, r.db).Create(req).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "create support chat request failed", err)
 	}
 	return nil
@@ -32,12 +32,12 @@ func (r *supportRepository) CreateChatRequest(ctx context.Context, req *db.Suppo
 
 func (r *supportRepository) ListChatRequests(ctx context.Context, page, pageSize int) ([]db.SupportChatRequest, int64, error) {
 	var total int64
-	if err := r.db.WithContext(ctx).Model(&db.SupportChatRequest{}).Count(&total).Error; err != nil {
+	if err := conn(ctx, r.db).Model(&db.SupportChatRequest{}).Count(&total).Error; err != nil {
 		return nil, 0, domain.WrapError(constants.InternalError, "count support chat requests failed", err)
 	}
 
 	var items []db.SupportChatRequest
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Order("created_at desc").
 		Limit(pageSize).
 		Offset((page - 1) * pageSize).
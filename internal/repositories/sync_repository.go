@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+type SyncRepository interface {
+	ApplyMutation(ctx context.Context, userID, clientID uuid.UUID, apply func(ctx context.Context) (*db.SyncMutation, error)) (*db.SyncMutation, bool, error)
+	RecordChange(ctx context.Context, change *db.SyncChange) error
+	ListChanges(ctx context.Context, userID uuid.UUID, after SyncChangeCursor, limit int) ([]db.SyncChange, error)
+}
+
+// SyncChangeCursor is the position of a change in the feed.
+type SyncChangeCursor struct {
+	TxID uint64
+	ID   int64
+}
+
+type syncRepository struct {
+	db *gorm.DB
+}
+
+func NewSyncRepository(dbConn *gorm.DB) SyncRepository {
+	return &syncRepository{db: dbConn}
+}
+
+// errSyncNotApplied rolls back the writes of a mutation apply rejected.
+var errSyncNotApplied = errors.New("sync mutation not applied")
+
+// ApplyMutation applies a mutation at most once per client id. In one
+// transaction it takes an advisory lock on (userID, clientID), looks up the
+// stored outcome, and otherwise calls apply and stores the outcome it returns.
+// apply must do its writes through the repositories with the context it is
+// given, so they commit together with the outcome or not at all. An
+// overlapping replay waits for the lock and then gets the stored outcome with
+// duplicate set. The writes of an outcome other than APPLIED are rolled back,
+// and nothing is stored when apply fails.
+func (r *syncRepository) ApplyMutation(ctx context.Context, userID, clientID uuid.UUID, apply func(ctx context.Context) (*db.SyncMutation, error)) (*db.SyncMutation, bool, error) {
+	var (
+		outcome   *db.SyncMutation
+		duplicate bool
+	)
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", userID.String()+":"+clientID.String()).Error; err != nil {
+			return domain.WrapError(constants.InternalError, "lock sync mutation failed", err)
+		}
+		var stored db.SyncMutation
+		err := tx.Where("user_id = ? AND client_id = ?", userID, clientID).Take(&stored).Error
+		if err == nil {
+			outcome, duplicate = &stored, true
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.WrapError(constants.InternalError, "find sync mutation failed", err)
+		}
+
+		var mutation *db.SyncMutation
+		err = tx.Transaction(func(sp *gorm.DB) error {
+			applied, err := apply(withTx(ctx, sp))
+			if err != nil {
+				return err
+			}
+			mutation = applied
+			if applied.Status != constants.SyncApplied {
+				return errSyncNotApplied
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errSyncNotApplied) {
+			return err
+		}
+		if err := tx.Create(mutation).Error; err != nil {
+			return domain.WrapError(constants.InternalError, "save sync mutation failed", err)
+		}
+		outcome = mutation
+		return nil
+	})
+	if err != nil {
+		if _, ok := domain.AsAppError(err); ok {
+			return nil, false, err
+		}
+		return nil, false, domain.WrapError(constants.InternalError, "apply sync mutation failed", err)
+	}
+	return outcome, duplicate, nil
+}
+
+func (r *syncRepository) RecordChange(ctx context.Context, change *db.SyncChange) error {
+	if err := conn(ctx, r.db).Create(change).Error; err != nil {
+		return domain.WrapError(constants.InternalError, "record sync change failed", err)
+	}
+	return nil
+}
+
+// ListChanges returns a user's changes after the cursor in transaction order.
+// Only changes of transactions older than every transaction still running are
+// returned: a transaction in flight may commit changes that sort before the
+// ones already visible, so reading past them could skip those for good.
+func (r *syncRepository) ListChanges(ctx context.Context, userID uuid.UUID, after SyncChangeCursor, limit int) ([]db.SyncChange, error) {
+	var items []db.SyncChange
+	if err := conn(ctx, r.db).
+		Select("id, txid::text AS txid, user_id, entity_type, entity_id, operation, payload, actor_id, created_at").
+		Where("user_id = ? AND (txid, id) > (?::xid8, ?)", userID, strconv.FormatUint(after.TxID, 10), after.ID).
+		Where("txid < pg_snapshot_xmin(pg_current_snapshot())").
+		Order("txid asc, id asc").
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		return nil, domain.WrapError(constants.InternalError, "list sync changes failed", err)
+	}
+	return items, nil
+}
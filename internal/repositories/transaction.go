@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txContextKey struct{}
+
+// withTx returns a context whose repository calls run in tx. Transactions the
+// repositories open themselves become savepoints of tx.
+func withTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// conn returns the transaction carried by ctx, else dbConn, bound to ctx.
+func conn(ctx context.Context, dbConn *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return dbConn.WithContext(ctx)
+}
@@ -2,10 +2,8 @@ package repositories
 
 import (
 	"context"
-	"errors"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgconn"
 	"gorm.io/gorm"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
@@ -18,6 +16,8 @@ type UserRepository interface {
 	FindByUsername(ctx context.Context, username string) (*db.User, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*db.User, error)
 	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	FindByLineUserID(ctx context.Context, lineUserID string) (*db.User, error)
+	UpdateLineUserID(ctx context.Context, id uuid.UUID, lineUserID *string) error
 }
 
 type userRepository struct {
@@ -29,9 +29,8 @@ func NewUserRepository(dbConn *gorm.DB) UserRepository {
 }
 
 func (r *userRepository) Create(ctx context.Context, user *db.User) error {
-	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+	if err := conn(ctx, r.db).Create(user).Error; err != nil {
+		if isUniqueViolation(err) {
 			return domain.NewError(constants.UserConflict, "user already exists")
 		}
 		return domain.WrapError(constants.InternalError, "create user failed", err)
@@ -41,7 +40,7 @@ func (r *userRepository) Create(ctx context.Context, user *db.User) error {
 
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (*db.User, error) {
 	var user db.User
-	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+	if err := conn(ctx, r.db).Where("username = ?", username).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.UserNotFound, "user not found")
 		}
@@ -52,7 +51,7 @@ func (r *userRepository) FindByUsername(ctx context.Context, username string) (*
 
 func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*db.User, error) {
 	var user db.User
-	if err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
+	if err := conn(ctx, r.db).First(&user, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.NewError(constants.UserNotFound, "user not found")
 		}
@@ -62,8 +61,29 @@ func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*db.User,
 }
 
 func (r *userRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
-	if err := r.db.WithContext(ctx).Model(&db.User{}).Where("id = ?", id).Update("password_hash", passwordHash).Error; err != nil {
+	if err := conn(ctx, r.db).Model(&db.User{}).Where("id = ?", id).Update("password_hash", passwordHash).Error; err != nil {
 		return domain.WrapError(constants.InternalError, "update password failed", err)
 	}
 	return nil
 }
+
+func (r *userRepository) FindByLineUserID(ctx context.Context, lineUserID string) (*db.User, error) {
+	var user db.User
+	if err := conn(ctx, r.db).Where("line_user_id = ?", lineUserID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.NewError(constants.UserNotFound, "user not found")
+		}
+		return nil, domain.WrapError(constants.InternalError, "find user failed", err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) UpdateLineUserID(ctx context.Context, id uuid.UUID, lineUserID *string) error {
+	if err := conn(ctx, r.db).Model(&db.User{}).Where("id = ?", id).Update("line_user_id", lineUserID).Error; err != nil {
+		if isUniqueViolation(err) {
+			return domain.NewError(constants.UserConflict, "line account already linked to another user")
+		}
+		return domain.WrapError(constants.InternalError, "update line user id failed", err)
+	}
+	return nil
+}
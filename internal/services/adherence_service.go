@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+const (
+	defaultAdherenceDays = 30
+	maxAdherenceDays     = 366
+)
+
+type AdherenceService interface {
+	GetReport(ctx context.Context, userID, from, to string) (dto.AdherenceReportResponse, error)
+}
+
+type adherenceService struct {
+	medicines repositories.MedicineRepository
+	intake    repositories.IntakeRepository
+	locations userLocations
+	now       func() time.Time
+}
+
+func NewAdherenceService(medicines repositories.MedicineRepository, intake repositories.IntakeRepository, prefs repositories.PreferenceRepository, timezone string) AdherenceService {
+	return &adherenceService{
+		medicines: medicines,
+		intake:    intake,
+		locations: newUserLocations(prefs, timezone),
+		now:       time.Now,
+	}
+}
+
+// GetReport expands the patient's schedules into expected doses for the
+// range and grades each against intake history. from/to default to the last
+// 30 days ending today in the patient's timezone.
+func (s *adherenceService) GetReport(ctx context.Context, userID, from, to string) (dto.AdherenceReportResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.AdherenceReportResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	fromDate, toDate, err := parseDateRange(from, to)
+	if err != nil {
+		return dto.AdherenceReportResponse{}, err
+	}
+	location, err := s.locations.forUser(ctx, uid)
+	if err != nil {
+		return dto.AdherenceReportResponse{}, err
+	}
+	today := localDate(s.now(), location)
+	if toDate.IsZero() {
+		toDate = today
+	}
+	if fromDate.IsZero() {
+		fromDate = toDate.AddDate(0, 0, -(defaultAdherenceDays - 1))
+	}
+	if fromDate.After(toDate) {
+		return dto.AdherenceReportResponse{}, domain.NewError(constants.ValidationFailed, "to must not be before from")
+	}
+	if int(toDate.Sub(fromDate).Hours()/24)+1 > maxAdherenceDays {
+		return dto.AdherenceReportResponse{}, domain.NewError(constants.ValidationFailed, "date range too long")
+	}
+
+	schedules, err := s.medicines.ListScheduleHistory(ctx, uid)
+	if err != nil {
+		return dto.AdherenceReportResponse{}, err
+	}
+	intakes, err := s.intake.ListHistory(ctx, uid, fromDate, toDate)
+	if err != nil {
+		return dto.AdherenceReportResponse{}, err
+	}
+
+	report := computeAdherence(fromDate, toDate, today, location, schedules, intakes)
+	report.UserID = uid.String()
+	return report, nil
+}
+
+// computeAdherence is the pure part of the engine. Dates are calendar dates
+// encoded as UTC midnight. Each day is read against the schedule versions in
+// effect that day (see expectsDose). Doses dated today only count once
+// recorded, and days after today are never expected.
+func computeAdherence(from, to, today time.Time, location *time.Location, schedules []repositories.ScheduleWithMedicineRow, intakes []db.IntakeHistory) dto.AdherenceReportResponse {
+	type doseKey struct {
+		scheduleID uuid.UUID
+		date       string
+	}
+	latest := make(map[doseKey]db.IntakeHistory, len(intakes))
+	for _, item := range intakes {
+		if item.ScheduleID == nil {
+			continue
+		}
+		key := doseKey{scheduleID: *item.ScheduleID, date: item.TargetDate.Format("2006-01-02")}
+		if prev, ok := latest[key]; !ok || item.CreatedAt.After(prev.CreatedAt) {
+			latest[key] = item
+		}
+	}
+
+	report := dto.AdherenceReportResponse{
+		From:      from.Format("2006-01-02"),
+		To:        to.Format("2006-01-02"),
+		Medicines: []dto.MedicineAdherenceResponse{},
+	}
+
+	medicineIndex := make(map[uuid.UUID]int)
+	for _, row := range schedules {
+		if _, ok := medicineIndex[row.PatientMedicineID]; !ok {
+			medicineIndex[row.PatientMedicineID] = len(report.Medicines)
+			report.Medicines = append(report.Medicines, dto.MedicineAdherenceResponse{
+				PatientMedicineID: row.PatientMedicineID.String(),
+				Name:              row.MedicineName,
+			})
+		}
+	}
+
+	last := to
+	if last.After(today) {
+		last = today
+	}
+
+	streak := 0
+	for day := from; !day.After(last); day = day.AddDate(0, 0, 1) {
+		dateKey := day.Format("2006-01-02")
+		dayExpected, dayTaken := 0, 0
+
+		for _, row := range schedules {
+			if !expectsDose(row, day, location) {
+				continue
+			}
+
+			record, recorded := latest[doseKey{scheduleID: row.ScheduleID, date: dateKey}]
+			if !recorded && day.Equal(today) {
+				continue
+			}
+
+			stats := &report.Medicines[medicineIndex[row.PatientMedicineID]].AdherenceStats
+			stats.Expected++
+			report.Overall.Expected++
+			dayExpected++
+
+			switch {
+			case recorded && record.Status == constants.MedTaken:
+				stats.Taken++
+				report.Overall.Taken++
+				dayTaken++
+				countTiming(stats, record.Timing)
+				countTiming(&report.Overall, record.Timing)
+			case recorded && record.Status == constants.MedSkipped:
+				stats.Skipped++
+				report.Overall.Skipped++
+			default:
+				stats.Missed++
+				report.Overall.Missed++
+			}
+		}
+
+		if dayExpected == 0 {
+			continue
+		}
+		report.DaysExpected++
+		if dayTaken == dayExpected {
+			report.DaysCovered++
+			streak++
+			if streak > report.LongestStreak {
+				report.LongestStreak = streak
+			}
+		} else {
+			streak = 0
+		}
+	}
+	report.CurrentStreak = streak
+
+	for i := range report.Medicines {
+		report.Medicines[i].Percent = percent(report.Medicines[i].Taken, report.Medicines[i].Expected)
+	}
+	report.Overall.Percent = percent(report.Overall.Taken, report.Overall.Expected)
+	report.PDC = percent(report.DaysCovered, report.DaysExpected)
+	return report
+}
+
+func countTiming(stats *dto.AdherenceStats, timing *string) {
+	if timing == nil {
+		return
+	}
+	switch *timing {
+	case constants.IntakeTimingOnTime:
+		stats.OnTime++
+	case constants.IntakeTimingLate:
+		stats.Late++
+	case constants.IntakeTimingEarly:
+		stats.Early++
+	}
+}
+
+// expectsDose reports whether the schedule version in row expects a dose on
+// day: the version is in effect that day, the medicine was active, the
+// recurrence is due, and the day is neither before the schedule was created
+// nor on or after the schedule or its medicine was deleted.
+func expectsDose(row repositories.ScheduleWithMedicineRow, day time.Time, location *time.Location) bool {
+	if row.ValidFrom != nil && day.Before(calendarDate(*row.ValidFrom)) {
+		return false
+	}
+	if row.ValidUntil != nil && day.After(calendarDate(*row.ValidUntil)) {
+		return false
+	}
+	if !row.MedicineActive || day.Before(localDate(row.ScheduleCreatedAt, location)) {
+		return false
+	}
+	if row.DeletedAt != nil && !day.Before(localDate(*row.DeletedAt, location)) {
+		return false
+	}
+	rule := recurrence{start: row.StartDate, end: row.EndDate, intervalDays: row.IntervalDays, weekdayMask: row.WeekdayMask}
+	return rule.occursOn(day)
+}
+
+func localDate(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
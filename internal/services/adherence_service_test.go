@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+func adherenceDate(value string) time.Time {
+	d, _ := time.Parse("2006-01-02", value)
+	return d
+}
+
+func intakeFor(scheduleID uuid.UUID, date string, status constants.MedIntakeStatus) db.IntakeHistory {
+	return db.IntakeHistory{ID: uuid.New(), ScheduleID: &scheduleID, TargetDate: adherenceDate(date), Status: status, CreatedAt: adherenceDate(date).Add(time.Hour)}
+}
+
+func datePtr(value string) *time.Time {
+	d := adherenceDate(value)
+	return &d
+}
+
+func TestComputeAdherence(t *testing.T) {
+	medA, medB := uuid.New(), uuid.New()
+	morning, evening, other := uuid.New(), uuid.New(), uuid.New()
+	created := adherenceDate("2026-01-01")
+
+	schedules := []repositories.ScheduleWithMedicineRow{
+		{ScheduleID: morning, PatientMedicineID: medA, MedicineName: "Amlodipine", MedicineActive: true, ScheduleCreatedAt: created},
+		{ScheduleID: evening, PatientMedicineID: medA, MedicineName: "Amlodipine", MedicineActive: true, ScheduleCreatedAt: created},
+		// Added on the 3rd, so not expected on the 1st and 2nd.
+		{ScheduleID: other, PatientMedicineID: medB, MedicineName: "Metformin", MedicineActive: true, ScheduleCreatedAt: adherenceDate("2026-01-03")},
+	}
+	intakes := []db.IntakeHistory{
+		intakeFor(morning, "2026-01-01", constants.MedTaken),
+		intakeFor(evening, "2026-01-01", constants.MedTaken),
+		intakeFor(morning, "2026-01-02", constants.MedTaken),
+		intakeFor(evening, "2026-01-02", constants.MedSkipped),
+		intakeFor(morning, "2026-01-03", constants.MedTaken),
+		intakeFor(evening, "2026-01-03", constants.MedTaken),
+		intakeFor(other, "2026-01-03", constants.MedTaken),
+		// Today: only the recorded dose counts.
+		intakeFor(morning, "2026-01-04", constants.MedTaken),
+	}
+	// A later correction wins over the earlier MISSED row.
+	missed := intakeFor(other, "2026-01-03", constants.MedMissed)
+	missed.CreatedAt = missed.CreatedAt.Add(-30 * time.Minute)
+	intakes = append(intakes, missed)
+	onTime, late := constants.IntakeTimingOnTime, constants.IntakeTimingLate
+	intakes[0].Timing = &onTime
+	intakes[2].Timing = &late
+	intakes[4].Timing = &late
+
+	report := computeAdherence(adherenceDate("2026-01-01"), adherenceDate("2026-01-10"), adherenceDate("2026-01-04"), time.UTC, schedules, intakes)
+
+	if report.Overall.Expected != 8 || report.Overall.Taken != 7 || report.Overall.Skipped != 1 || report.Overall.Missed != 0 {
+		t.Fatalf("unexpected overall: %+v", report.Overall)
+	}
+	if report.Overall.OnTime != 1 || report.Overall.Late != 2 || report.Overall.Early != 0 || report.Medicines[0].Late != 2 || report.Medicines[1].Late != 0 {
+		t.Fatalf("unexpected timing counts: %+v %+v", report.Overall, report.Medicines)
+	}
+	if report.Overall.Percent != 87.5 {
+		t.Fatalf("unexpected overall percent: %v", report.Overall.Percent)
+	}
+	if len(report.Medicines) != 2 || report.Medicines[0].Expected != 7 || report.Medicines[1].Expected != 1 || report.Medicines[1].Percent != 100 {
+		t.Fatalf("unexpected per-medicine: %+v", report.Medicines)
+	}
+	if report.DaysExpected != 4 || report.DaysCovered != 3 || report.PDC != 75 {
+		t.Fatalf("unexpected pdc: days=%d covered=%d pdc=%v", report.DaysExpected, report.DaysCovered, report.PDC)
+	}
+	if report.CurrentStreak != 2 || report.LongestStreak != 2 {
+		t.Fatalf("unexpected streaks: current=%d longest=%d", report.CurrentStreak, report.LongestStreak)
+	}
+}
+
+func TestComputeAdherenceUnrecordedAndInactive(t *testing.T) {
+	med := uuid.New()
+	schedule := uuid.New()
+	schedules := []repositories.ScheduleWithMedicineRow{
+		// Active through the 2nd, stopped from the 3rd.
+		{ScheduleID: schedule, PatientMedicineID: med, MedicineName: "Aspirin", MedicineActive: true, ScheduleCreatedAt: adherenceDate("2026-01-01"), ValidUntil: datePtr("2026-01-02")},
+		{ScheduleID: schedule, PatientMedicineID: med, MedicineName: "Aspirin", MedicineActive: false, ScheduleCreatedAt: adherenceDate("2026-01-01"), ValidFrom: datePtr("2026-01-03")},
+	}
+
+	report := computeAdherence(adherenceDate("2026-01-01"), adherenceDate("2026-01-05"), adherenceDate("2026-01-10"), time.UTC, schedules, nil)
+
+	if report.Overall.Expected != 2 || report.Overall.Missed != 2 || report.Overall.Percent != 0 {
+		t.Fatalf("unexpected overall: %+v", report.Overall)
+	}
+	if report.CurrentStreak != 0 || report.LongestStreak != 0 || report.PDC != 0 {
+		t.Fatalf("unexpected streaks: %+v", report)
+	}
+}
+
+func TestAdherenceServiceGetReport(t *testing.T) {
+	scheduleID := uuid.New()
+	medicines := &medicineRepoStub{scheduleRows: []repositories.ScheduleWithMedicineRow{
+		{ScheduleID: scheduleID, PatientMedicineID: uuid.New(), MedicineName: "Amlodipine", MedicineActive: true, ScheduleCreatedAt: adherenceDate("2025-01-01")},
+	}}
+	intake := &fakeIntakeRepo{history: []db.IntakeHistory{intakeFor(scheduleID, "2026-01-29", constants.MedTaken)}}
+	svc := NewAdherenceService(medicines, intake, nil, "Asia/Bangkok").(*adherenceService)
+	// 18:00 UTC on the 29th is already the 30th in Bangkok.
+	svc.now = func() time.Time { return time.Date(2026, 1, 29, 18, 0, 0, 0, time.UTC) }
+
+	userID := uuid.New().String()
+	report, err := svc.GetReport(context.Background(), userID, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.From != "2026-01-01" || report.To != "2026-01-30" {
+		t.Fatalf("unexpected default range: %s..%s", report.From, report.To)
+	}
+	if report.Overall.Expected != 29 || report.Overall.Taken != 1 || report.CurrentStreak != 1 {
+		t.Fatalf("unexpected report: %+v", report.Overall)
+	}
+
+	if _, err := svc.GetReport(context.Background(), userID, "2024-01-01", "2026-01-01"); err == nil {
+		t.Fatalf("expected range too long error")
+	}
+	if _, err := svc.GetReport(context.Background(), "bad", "", ""); err == nil {
+		t.Fatalf("expected invalid user_id error")
+	}
+}
+
+func TestComputeAdherenceHonorsRecurrence(t *testing.T) {
+	everyOther, weekdays := uuid.New(), uuid.New()
+	end := adherenceDate("2026-01-08")
+	schedules := []repositories.ScheduleWithMedicineRow{
+		// 2026-01-01 .. 01-08, every other day: 1st, 3rd, 5th, 7th.
+		{ScheduleID: everyOther, PatientMedicineID: uuid.New(), MedicineActive: true, StartDate: adherenceDate("2026-01-01"), EndDate: &end, IntervalDays: 2, WeekdayMask: constants.WeekdayMaskAll, ScheduleCreatedAt: adherenceDate("2026-01-01")},
+		// Mon/Wed/Fri: 5th, 7th, 9th (the 2nd is before creation).
+		{ScheduleID: weekdays, PatientMedicineID: uuid.New(), MedicineActive: true, StartDate: adherenceDate("2026-01-01"), IntervalDays: 1, WeekdayMask: 1<<1 | 1<<3 | 1<<5, ScheduleCreatedAt: adherenceDate("2026-01-04")},
+	}
+
+	report := computeAdherence(adherenceDate("2026-01-01"), adherenceDate("2026-01-09"), adherenceDate("2026-01-10"), time.UTC, schedules, nil)
+	if report.Medicines[0].Expected != 4 || report.Medicines[1].Expected != 3 {
+		t.Fatalf("unexpected expected doses: %+v", report.Medicines)
+	}
+}
+
+func TestComputeAdherenceReadsEachDayAgainstItsVersion(t *testing.T) {
+	med := uuid.New()
+	edited, removed := uuid.New(), uuid.New()
+	created := adherenceDate("2026-01-01")
+	deletedAt := time.Date(2026, 1, 4, 17, 30, 0, 0, time.UTC) // the 5th in Bangkok
+	schedules := []repositories.ScheduleWithMedicineRow{
+		// Daily through the 4th, then moved to Mon/Wed/Fri: 1st-4th, 5th, 7th, 9th.
+		{ScheduleID: edited, PatientMedicineID: med, MedicineActive: true, StartDate: created, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll, ScheduleCreatedAt: created, ValidUntil: datePtr("2026-01-04")},
+		{ScheduleID: edited, PatientMedicineID: med, MedicineActive: true, StartDate: created, IntervalDays: 1, WeekdayMask: 1<<1 | 1<<3 | 1<<5, ScheduleCreatedAt: created, ValidFrom: datePtr("2026-01-05")},
+		// Deleted on the 5th: the 1st-4th still count.
+		{ScheduleID: removed, PatientMedicineID: med, MedicineActive: true, StartDate: created, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll, ScheduleCreatedAt: created, DeletedAt: &deletedAt},
+	}
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+
+	report := computeAdherence(adherenceDate("2026-01-01"), adherenceDate("2026-01-09"), adherenceDate("2026-01-10"), bangkok, schedules, nil)
+	if len(report.Medicines) != 1 || report.Overall.Expected != 11 {
+		t.Fatalf("unexpected expected doses: %+v", report.Overall)
+	}
+	if report.DaysExpected != 7 {
+		t.Fatalf("unexpected days expected: %d", report.DaysExpected)
+	}
+}
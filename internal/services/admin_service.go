@@ -2,37 +2,256 @@ package services
 
 import (
 	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
 )
 
 type AdminService interface {
-	StaffLogin(ctx context.Context, req dto.StaffLoginRequest) (dto.TokenResponse, error)
-	ListPatients(ctx context.Context, page, pageSize int) ([]dto.PatientSummaryResponse, int64, error)
-	GetPatient(ctx context.Context, id string) (dto.PatientDetailResponse, error)
-	ListAdherence(ctx context.Context, patientID, from, to string) ([]dto.IntakeHistoryResponse, error)
+	StaffLogin(ctx context.Context, req dto.StaffLoginRequest, ip string) (dto.TokenResponse, error)
+	ListPatients(ctx context.Context, query dto.PatientListQuery, page, pageSize int) ([]dto.PatientSummaryResponse, int64, error)
+	GetPatient(ctx context.Context, actorID uuid.UUID, viewerRole constants.Role, id string) (dto.PatientDetailResponse, error)
+	ListAdherence(ctx context.Context, patientID, from, to string) (dto.AdherenceReportResponse, error)
+	ListDoseTimings(ctx context.Context, query dto.DoseTimingQuery, page, pageSize int) ([]dto.DoseTimingResponse, int64, error)
 }
 
-type adminService struct{}
+type adminService struct {
+	auth      AuthService
+	repo      repositories.AdminRepository
+	adherence AdherenceService
+	audit     AuditService
+	now       func() time.Time
+}
 
-func NewAdminService() AdminService {
-	return &adminService{}
+func NewAdminService(auth AuthService, repo repositories.AdminRepository, adherence AdherenceService, audit AuditService) AdminService {
+	return &adminService{
+		auth:      auth,
+		repo:      repo,
+		adherence: adherence,
+		audit:     audit,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
 }
 
-func (s *adminService) StaffLogin(ctx context.Context, req dto.StaffLoginRequest) (dto.TokenResponse, error) {
-	return dto.TokenResponse{}, domain.NewError(constants.InternalNotImplemented, "staff login not implemented")
+func (s *adminService) StaffLogin(ctx context.Context, req dto.StaffLoginRequest, ip string) (dto.TokenResponse, error) {
+	return s.auth.StaffLogin(ctx, req, ip)
 }
 
-func (s *adminService) ListPatients(ctx context.Context, page, pageSize int) ([]dto.PatientSummaryResponse, int64, error) {
-	return nil, 0, domain.NewError(constants.InternalNotImplemented, "admin patients not implemented")
+func (s *adminService) ListPatients(ctx context.Context, query dto.PatientListQuery, page, pageSize int) ([]dto.PatientSummaryResponse, int64, error) {
+	filter := repositories.PatientListFilter{
+		Query:    strings.TrimSpace(query.Query),
+		IsActive: query.IsActive,
+	}
+	if caregiverID := strings.TrimSpace(query.CaregiverID); caregiverID != "" {
+		cid, err := uuid.Parse(caregiverID)
+		if err != nil {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid caregiver_id")
+		}
+		filter.CaregiverID = &cid
+	}
+
+	items, total, err := s.repo.ListPatients(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp := make([]dto.PatientSummaryResponse, 0, len(items))
+	for _, item := range items {
+		summary := dto.PatientSummaryResponse{
+			ID:        item.ID.String(),
+			Username:  item.Username,
+			HN:        item.HN,
+			IsActive:  item.IsActive,
+			CreatedAt: item.CreatedAt,
+		}
+		if item.FirstName != nil {
+			summary.FirstName = *item.FirstName
+		}
+		if item.LastName != nil {
+			summary.LastName = *item.LastName
+		}
+		resp = append(resp, summary)
+	}
+	return resp, total, nil
 }
 
-func (s *adminService) GetPatient(ctx context.Context, id string) (dto.PatientDetailResponse, error) {
-	return dto.PatientDetailResponse{}, domain.NewError(constants.InternalNotImplemented, "admin patients not implemented")
+func (s *adminService) GetPatient(ctx context.Context, actorID uuid.UUID, viewerRole constants.Role, id string) (dto.PatientDetailResponse, error) {
+	patientID, err := uuid.Parse(id)
+	if err != nil {
+		return dto.PatientDetailResponse{}, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+
+	user, err := s.repo.FindPatient(ctx, patientID)
+	if err != nil {
+		return dto.PatientDetailResponse{}, err
+	}
+
+	resp := dto.PatientDetailResponse{
+		ID:         user.ID.String(),
+		Username:   user.Username,
+		IsActive:   user.IsActive,
+		Medicines:  []dto.PatientMedicineWithSchedules{},
+		Caregivers: []dto.PatientCaregiverResponse{},
+	}
+	var profile *db.UserProfile
+	if user.Profile.UserID != uuid.Nil {
+		profile = &user.Profile
+	}
+	resp.Profile = toProfileResponse(profile, viewerRole)
+
+	medicines, err := s.repo.ListActiveMedicines(ctx, patientID)
+	if err != nil {
+		return dto.PatientDetailResponse{}, err
+	}
+	medicineIDs := make([]uuid.UUID, 0, len(medicines))
+	for _, med := range medicines {
+		medicineIDs = append(medicineIDs, med.ID)
+	}
+	schedules, err := s.repo.ListSchedulesByMedicineIDs(ctx, medicineIDs)
+	if err != nil {
+		return dto.PatientDetailResponse{}, err
+	}
+	schedulesByMedicine := make(map[uuid.UUID][]dto.MedicineScheduleResponse, len(medicines))
+	for _, schedule := range schedules {
+		schedulesByMedicine[schedule.PatientMedicineID] = append(schedulesByMedicine[schedule.PatientMedicineID], toMedicineScheduleResponse(schedule))
+	}
+	for _, med := range medicines {
+		items := schedulesByMedicine[med.ID]
+		if items == nil {
+			items = []dto.MedicineScheduleResponse{}
+		}
+		resp.Medicines = append(resp.Medicines, dto.PatientMedicineWithSchedules{
+			PatientMedicineResponse: toPatientMedicineResponse(med),
+			Schedules:               items,
+		})
+	}
+
+	now := s.now()
+	appt, err := s.repo.FindNextAppointment(ctx, patientID, now)
+	if err != nil {
+		return dto.PatientDetailResponse{}, err
+	}
+	if appt != nil {
+		next := toAppointmentResponse(*appt)
+		resp.NextAppointment = &next
+	}
+
+	bp, err := s.repo.FindLatestBloodPressure(ctx, patientID)
+	if err != nil {
+		return dto.PatientDetailResponse{}, err
+	}
+	if bp != nil {
+		latest := toHealthRecordResponse(*bp)
+		resp.LatestBP = &latest
+	}
+
+	caregivers, err := s.repo.ListCaregivers(ctx, patientID)
+	if err != nil {
+		return dto.PatientDetailResponse{}, err
+	}
+	for _, caregiver := range caregivers {
+		resp.Caregivers = append(resp.Caregivers, dto.PatientCaregiverResponse{
+			AssignmentID: caregiver.AssignmentID.String(),
+			CaregiverID:  caregiver.CaregiverID.String(),
+			Relationship: caregiver.Relationship,
+			Username:     caregiver.Username,
+			FirstName:    caregiver.FirstName,
+			LastName:     caregiver.LastName,
+		})
+	}
+
+	adherence, err := s.adherence.GetReport(ctx, patientID.String(), "", "")
+	if err != nil {
+		return dto.PatientDetailResponse{}, err
+	}
+	resp.Adherence30d = adherence
+
+	if s.audit != nil {
+		action := constants.AuditPatientRead
+		if viewerRole == constants.RoleAdmin && profile != nil && profile.CitizenID != nil {
+			action = constants.AuditCitizenIDView
+		}
+		s.audit.Record(ctx, domain.AuditEntry{ActorID: &actorID, TargetUserID: &patientID, Action: action})
+	}
+
+	return resp, nil
+}
+
+func (s *adminService) ListAdherence(ctx context.Context, patientID, from, to string) (dto.AdherenceReportResponse, error) {
+	return s.adherence.GetReport(ctx, patientID, from, to)
+}
+
+// ListDoseTimings reports TAKEN doses by timing across patients, e.g. morning
+// doses taken hours late.
+func (s *adminService) ListDoseTimings(ctx context.Context, query dto.DoseTimingQuery, page, pageSize int) ([]dto.DoseTimingResponse, int64, error) {
+	from, to, err := parseDateRange(query.From, query.To)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter := repositories.DoseTimingFilter{
+		Timing:   strings.TrimSpace(query.Timing),
+		From:     from,
+		To:       to,
+		MinDelay: time.Duration(query.MinDelayMinutes) * time.Minute,
+	}
+	if filter.Timing != "" && !isAllowed(filter.Timing, constants.IntakeTimings) {
+		return nil, 0, domain.NewError(constants.ValidationFailed, "invalid timing")
+	}
+	if patientID := strings.TrimSpace(query.PatientID); patientID != "" {
+		pid, err := uuid.Parse(patientID)
+		if err != nil {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid patient_id")
+		}
+		filter.PatientID = &pid
+	}
+	if filter.SlotFrom, err = parseSlotFilter(query.SlotFrom, "slot_from"); err != nil {
+		return nil, 0, err
+	}
+	if filter.SlotTo, err = parseSlotFilter(query.SlotTo, "slot_to"); err != nil {
+		return nil, 0, err
+	}
+
+	items, total, err := s.repo.ListDoseTimings(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp := make([]dto.DoseTimingResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, dto.DoseTimingResponse{
+			IntakeID:     item.IntakeID.String(),
+			UserID:       item.UserID.String(),
+			Username:     item.Username,
+			HN:           item.HN,
+			FirstName:    item.FirstName,
+			LastName:     item.LastName,
+			MedicineName: item.MedicineName,
+			TimeSlot:     item.TimeSlot.Format("15:04"),
+			TargetDate:   item.TargetDate.Format("2006-01-02"),
+			DueAt:        item.DueAt,
+			TakenAt:      item.TakenAt,
+			DelayMinutes: int(item.TakenAt.Sub(item.DueAt).Minutes()),
+			Timing:       item.Timing,
+		})
+	}
+	return resp, total, nil
 }
 
-func (s *adminService) ListAdherence(ctx context.Context, patientID, from, to string) ([]dto.IntakeHistoryResponse, error) {
-	return nil, domain.NewError(constants.InternalNotImplemented, "admin adherence not implemented")
+func parseSlotFilter(value, field string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return "", domain.NewError(constants.ValidationFailed, "invalid "+field)
+	}
+	return parsed.Format("15:04"), nil
 }
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type adminRepoStub struct {
+	filter     repositories.PatientListFilter
+	patient    *db.User
+	medicines  []db.PatientMedicine
+	schedules  []db.MedicineSchedule
+	appt       *db.Appointment
+	bp         *db.HealthRecord
+	caregivers []repositories.PatientCaregiverRow
+	timings    []repositories.DoseTimingRow
+	timingArgs repositories.DoseTimingFilter
+}
+
+func (s *adminRepoStub) ListPatients(ctx context.Context, filter repositories.PatientListFilter, page, pageSize int) ([]repositories.PatientRow, int64, error) {
+	s.filter = filter
+	first := "Somchai"
+	return []repositories.PatientRow{{ID: uuid.New(), Username: "0800000000", IsActive: true, FirstName: &first}}, 1, nil
+}
+func (s *adminRepoStub) FindPatient(ctx context.Context, id uuid.UUID) (*db.User, error) {
+	return s.patient, nil
+}
+func (s *adminRepoStub) ListActiveMedicines(ctx context.Context, userID uuid.UUID) ([]db.PatientMedicine, error) {
+	return s.medicines, nil
+}
+func (s *adminRepoStub) ListSchedulesByMedicineIDs(ctx context.Context, medicineIDs []uuid.UUID) ([]db.MedicineSchedule, error) {
+	return s.schedules, nil
+}
+func (s *adminRepoStub) FindNextAppointment(ctx context.Context, userID uuid.UUID, after time.Time) (*db.Appointment, error) {
+	return s.appt, nil
+}
+func (s *adminRepoStub) FindLatestBloodPressure(ctx context.Context, userID uuid.UUID) (*db.HealthRecord, error) {
+	return s.bp, nil
+}
+func (s *adminRepoStub) ListCaregivers(ctx context.Context, patientID uuid.UUID) ([]repositories.PatientCaregiverRow, error) {
+	return s.caregivers, nil
+}
+func (s *adminRepoStub) ListDoseTimings(ctx context.Context, filter repositories.DoseTimingFilter, page, pageSize int) ([]repositories.DoseTimingRow, int64, error) {
+	s.timingArgs = filter
+	return s.timings, int64(len(s.timings)), nil
+}
+
+type adherenceServiceStub struct {
+	userID   string
+	from, to string
+}
+
+func (s *adherenceServiceStub) GetReport(ctx context.Context, userID, from, to string) (dto.AdherenceReportResponse, error) {
+	s.userID, s.from, s.to = userID, from, to
+	return dto.AdherenceReportResponse{UserID: userID, Overall: dto.AdherenceStats{Expected: 10, Taken: 7, Percent: 70}}, nil
+}
+
+func TestAdminServiceListPatientsFilters(t *testing.T) {
+	repo := &adminRepoStub{}
+	svc := NewAdminService(nil, repo, &adherenceServiceStub{}, nil)
+
+	if _, _, err := svc.ListPatients(context.Background(), dto.PatientListQuery{CaregiverID: "bad"}, 1, 20); err == nil {
+		t.Fatalf("expected invalid caregiver_id error")
+	}
+
+	active := true
+	caregiverID := uuid.New()
+	items, total, err := svc.ListPatients(context.Background(), dto.PatientListQuery{Query: " 1234 ", IsActive: &active, CaregiverID: caregiverID.String()}, 1, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 1 || len(items) != 1 || items[0].FirstName != "Somchai" || items[0].LastName != "" {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	if repo.filter.Query != "1234" || repo.filter.IsActive == nil || !*repo.filter.IsActive {
+		t.Fatalf("unexpected filter: %+v", repo.filter)
+	}
+	if repo.filter.CaregiverID == nil || *repo.filter.CaregiverID != caregiverID {
+		t.Fatalf("expected caregiver filter")
+	}
+}
+
+func TestAdminServiceListDoseTimings(t *testing.T) {
+	due := time.Date(2026, 1, 20, 1, 0, 0, 0, time.UTC)
+	repo := &adminRepoStub{timings: []repositories.DoseTimingRow{{
+		IntakeID:     uuid.New(),
+		UserID:       uuid.New(),
+		MedicineName: "Amlodipine",
+		TimeSlot:     time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+		TargetDate:   time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC),
+		DueAt:        due,
+		TakenAt:      due.Add(11*time.Hour + 30*time.Minute),
+		Timing:       constants.IntakeTimingLate,
+	}}}
+	svc := NewAdminService(nil, repo, &adherenceServiceStub{}, nil)
+
+	for _, query := range []dto.DoseTimingQuery{{Timing: "SOMETIMES"}, {SlotFrom: "8am"}, {PatientID: "bad"}, {From: "2026-01-20", To: "2026-01-01"}} {
+		if _, _, err := svc.ListDoseTimings(context.Background(), query, 1, 20); err == nil {
+			t.Fatalf("expected validation error for %+v", query)
+		}
+	}
+
+	items, total, err := svc.ListDoseTimings(context.Background(), dto.DoseTimingQuery{Timing: "LATE", SlotFrom: "05:00", SlotTo: "11:59", MinDelayMinutes: 360}, 1, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.timingArgs.Timing != constants.IntakeTimingLate || repo.timingArgs.SlotFrom != "05:00" || repo.timingArgs.SlotTo != "11:59" || repo.timingArgs.MinDelay != 6*time.Hour {
+		t.Fatalf("unexpected filter: %+v", repo.timingArgs)
+	}
+	if total != 1 || len(items) != 1 || items[0].DelayMinutes != 690 || items[0].TimeSlot != "08:00" || items[0].TargetDate != "2026-01-20" {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+}
+
+func TestAdminServiceGetPatientAggregate(t *testing.T) {
+	patientID := uuid.New()
+	nurseID, adminID := uuid.New(), uuid.New()
+	medID := uuid.New()
+	citizenID := "1234567890123"
+	systolic, diastolic := 128, 82
+	caregiverFirst := "Malee"
+
+	repo := &adminRepoStub{
+		patient: &db.User{
+			ID:       patientID,
+			Username: "0800000000",
+			Role:     constants.RolePatient,
+			IsActive: true,
+			Profile:  db.UserProfile{UserID: patientID, FirstName: "Somchai", LastName: "Jaidee", CitizenID: &citizenID},
+		},
+		medicines: []db.PatientMedicine{{ID: medID, UserID: patientID, DosageAmount: "1", IsActive: true}},
+		schedules: []db.MedicineSchedule{
+			{ID: uuid.New(), PatientMedicineID: medID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)},
+			{ID: uuid.New(), PatientMedicineID: medID, TimeSlot: time.Date(0, 1, 1, 20, 0, 0, 0, time.UTC)},
+		},
+		appt:       &db.Appointment{ID: uuid.New(), UserID: patientID, Title: "Follow-up", Status: constants.ApptPending},
+		bp:         &db.HealthRecord{ID: uuid.New(), UserID: patientID, SystolicBP: &systolic, DiastolicBP: &diastolic},
+		caregivers: []repositories.PatientCaregiverRow{{AssignmentID: uuid.New(), CaregiverID: uuid.New(), Relationship: "CHILD", Username: "0811111111", FirstName: &caregiverFirst}},
+	}
+	adherence := &adherenceServiceStub{}
+	audit := &auditRecorderStub{}
+	svc := NewAdminService(nil, repo, adherence, audit).(*adminService)
+	svc.now = func() time.Time { return time.Date(2026, 1, 30, 15, 0, 0, 0, time.UTC) }
+
+	resp, err := svc.GetPatient(context.Background(), nurseID, constants.RoleNurse, patientID.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Profile.FirstName != "Somchai" || resp.Profile.CitizenID == nil || *resp.Profile.CitizenID == citizenID {
+		t.Fatalf("expected masked citizen id for nurse: %+v", resp.Profile)
+	}
+	if len(resp.Medicines) != 1 || len(resp.Medicines[0].Schedules) != 2 || resp.Medicines[0].Schedules[0].TimeSlot != "08:00" {
+		t.Fatalf("unexpected medicines: %+v", resp.Medicines)
+	}
+	if resp.NextAppointment == nil || resp.NextAppointment.Title != "Follow-up" {
+		t.Fatalf("expected next appointment")
+	}
+	if resp.LatestBP == nil || *resp.LatestBP.SystolicBP != 128 {
+		t.Fatalf("expected latest bp")
+	}
+	if len(resp.Caregivers) != 1 || resp.Caregivers[0].Relationship != "CHILD" {
+		t.Fatalf("unexpected caregivers: %+v", resp.Caregivers)
+	}
+	if resp.Adherence30d.Overall.Percent != 70 || adherence.userID != patientID.String() || adherence.from != "" || adherence.to != "" {
+		t.Fatalf("expected default adherence window from engine: %+v", resp.Adherence30d)
+	}
+
+	if len(audit.entries) != 1 || audit.entries[0].Action != constants.AuditPatientRead || *audit.entries[0].ActorID != nurseID || *audit.entries[0].TargetUserID != patientID {
+		t.Fatalf("expected patient read audit for nurse: %+v", audit.entries)
+	}
+
+	resp, err = svc.GetPatient(context.Background(), adminID, constants.RoleAdmin, patientID.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Profile.CitizenID == nil || *resp.Profile.CitizenID != citizenID {
+		t.Fatalf("expected full citizen id for admin")
+	}
+	if len(audit.entries) != 2 || audit.entries[1].Action != constants.AuditCitizenIDView || *audit.entries[1].ActorID != adminID {
+		t.Fatalf("expected citizen id view audit for admin: %+v", audit.entries)
+	}
+}
+
+func TestAdminServiceGetPatientWithoutProfile(t *testing.T) {
+	patientID := uuid.New()
+	repo := &adminRepoStub{patient: &db.User{ID: patientID, Username: "0800000000", Role: constants.RolePatient}}
+	svc := NewAdminService(nil, repo, &adherenceServiceStub{}, nil)
+
+	resp, err := svc.GetPatient(context.Background(), uuid.New(), constants.RoleAdmin, patientID.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Profile.DateOfBirth != "" || resp.Medicines == nil || resp.Caregivers == nil {
+		t.Fatalf("unexpected empty aggregate: %+v", resp)
+	}
+	if resp.NextAppointment != nil || resp.LatestBP != nil {
+		t.Fatalf("expected empty optional sections")
+	}
+
+	if _, err := svc.GetPatient(context.Background(), uuid.New(), constants.RoleAdmin, "bad"); err == nil {
+		t.Fatalf("expected invalid id error")
+	}
+}
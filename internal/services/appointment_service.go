@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,15 +23,18 @@ type AppointmentService interface {
 	DeleteAppointment(ctx context.Context, id string) error
 	CreateNurseVisitNote(ctx context.Context, appointmentID, nurseID string, req dto.CreateNurseVisitNoteRequest) error
 	ListVisitHistory(ctx context.Context, userID string) ([]dto.VisitHistoryItem, error)
+	RescheduleReminders(ctx context.Context, userID uuid.UUID) error
 }
 
 type appointmentService struct {
-	repo   repositories.AppointmentRepository
-	notify NotificationService
+	repo    repositories.AppointmentRepository
+	notify  NotificationService
+	changes repositories.SyncRepository
+	now     func() time.Time
 }
 
-func NewAppointmentService(repo repositories.AppointmentRepository, notify NotificationService) AppointmentService {
-	return &appointmentService{repo: repo, notify: notify}
+func NewAppointmentService(repo repositories.AppointmentRepository, notify NotificationService, changes repositories.SyncRepository) AppointmentService {
+	return &appointmentService{repo: repo, notify: notify, changes: changes, now: time.Now}
 }
 
 func (s *appointmentService) ListAppointments(ctx context.Context, userID string) ([]dto.AppointmentResponse, error) {
@@ -46,17 +50,7 @@ func (s *appointmentService) ListAppointments(ctx context.Context, userID string
 
 	resp := make([]dto.AppointmentResponse, 0, len(items))
 	for _, item := range items {
-		resp = append(resp, dto.AppointmentResponse{
-			ID:           item.ID.String(),
-			UserID:       item.UserID.String(),
-			Title:        item.Title,
-			ApptType:     item.ApptType,
-			ApptDateTime: item.ApptDateTime,
-			LocationName: item.LocationName,
-			SlipImageURL: item.SlipImageURL,
-			Status:       item.Status,
-			CreatedAt:    item.CreatedAt,
-		})
+		resp = append(resp, toAppointmentResponse(item))
 	}
 	return resp, nil
 }
@@ -90,17 +84,7 @@ func (s *appointmentService) CreateAppointment(ctx context.Context, userID strin
 		_ = s.notify.ScheduleAppointmentReminders(ctx, appt)
 	}
 
-	return dto.AppointmentResponse{
-		ID:           appt.ID.String(),
-		UserID:       appt.UserID.String(),
-		Title:        appt.Title,
-		ApptType:     appt.ApptType,
-		ApptDateTime: appt.ApptDateTime,
-		LocationName: appt.LocationName,
-		SlipImageURL: appt.SlipImageURL,
-		Status:       appt.Status,
-		CreatedAt:    appt.CreatedAt,
-	}, nil
+	return toAppointmentResponse(*appt), nil
 }
 
 func (s *appointmentService) UpdateStatus(ctx context.Context, id string, req dto.UpdateAppointmentStatusRequest) error {
@@ -117,6 +101,8 @@ func (s *appointmentService) UpdateStatus(ctx context.Context, id string, req dt
 	if err := s.repo.UpdateStatus(ctx, apptID, req.Status); err != nil {
 		return err
 	}
+	appt.Status = req.Status
+	recordSyncChange(ctx, s.changes, appt.UserID, constants.SyncEntityAppointment, appt.ID, constants.SyncOpUpsert, toAppointmentResponse(*appt), nil)
 
 	if req.Status == constants.ApptCancelled && s.notify != nil {
 		_ = s.notify.CancelAppointmentReminders(ctx, appt.UserID, appt.ID)
@@ -138,12 +124,37 @@ func (s *appointmentService) DeleteAppointment(ctx context.Context, id string) e
 	if err := s.repo.DeleteAppointment(ctx, apptID); err != nil {
 		return err
 	}
+	recordSyncChange(ctx, s.changes, appt.UserID, constants.SyncEntityAppointment, appt.ID, constants.SyncOpDelete, nil, nil)
 	if s.notify != nil {
 		_ = s.notify.CancelAppointmentReminders(ctx, appt.UserID, appt.ID)
 	}
 	return nil
 }
 
+// RescheduleReminders re-plans the reminders of the patient's upcoming
+// appointments, e.g. after they changed their reminder lead times.
+func (s *appointmentService) RescheduleReminders(ctx context.Context, userID uuid.UUID) error {
+	if s.notify == nil {
+		return nil
+	}
+	items, err := s.repo.ListAppointments(ctx, userID)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	var errs []error
+	for i := range items {
+		appt := &items[i]
+		if appt.Status == constants.ApptCancelled || appt.Status == constants.ApptCompleted || !appt.ApptDateTime.After(now) {
+			continue
+		}
+		if err := s.notify.RescheduleAppointmentReminders(ctx, appt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (s *appointmentService) CreateNurseVisitNote(ctx context.Context, appointmentID, nurseID string, req dto.CreateNurseVisitNoteRequest) error {
 	apptID, err := uuid.Parse(appointmentID)
 	if err != nil {
@@ -155,7 +166,8 @@ func (s *appointmentService) CreateNurseVisitNote(ctx context.Context, appointme
 		return domain.NewError(constants.ValidationFailed, "invalid nurse_id")
 	}
 
-	if _, err := s.repo.FindByID(ctx, apptID); err != nil {
+	appt, err := s.repo.FindByID(ctx, apptID)
+	if err != nil {
 		return err
 	}
 
@@ -176,7 +188,23 @@ func (s *appointmentService) CreateNurseVisitNote(ctx context.Context, appointme
 		NextActionPlan:    req.NextActionPlan,
 	}
 
-	return s.repo.CreateNurseVisitNote(ctx, note)
+	if err := s.repo.CreateNurseVisitNote(ctx, note); err != nil {
+		return err
+	}
+
+	recordSyncChange(ctx, s.changes, appt.UserID, constants.SyncEntityVisitNote, note.ID, constants.SyncOpUpsert, dto.VisitHistoryItem{
+		AppointmentID:     appt.ID.String(),
+		VisitNoteID:       note.ID.String(),
+		ApptDateTime:      appt.ApptDateTime,
+		Title:             appt.Title,
+		LocationName:      appt.LocationName,
+		NurseID:           nID.String(),
+		VisitDetails:      note.VisitDetails,
+		VitalSignsSummary: req.VitalSignsSummary,
+		NextActionPlan:    note.NextActionPlan,
+		CreatedAt:         note.CreatedAt,
+	}, &nID)
+	return nil
 }
 
 func (s *appointmentService) ListVisitHistory(ctx context.Context, userID string) ([]dto.VisitHistoryItem, error) {
@@ -218,3 +246,17 @@ func parseRFC3339(value string) (time.Time, error) {
 	}
 	return time.Parse(time.RFC3339Nano, value)
 }
+
+func toAppointmentResponse(appt db.Appointment) dto.AppointmentResponse {
+	return dto.AppointmentResponse{
+		ID:           appt.ID.String(),
+		UserID:       appt.UserID.String(),
+		Title:        appt.Title,
+		ApptType:     appt.ApptType,
+		ApptDateTime: appt.ApptDateTime,
+		LocationName: appt.LocationName,
+		SlipImageURL: appt.SlipImageURL,
+		Status:       appt.Status,
+		CreatedAt:    appt.CreatedAt,
+	}
+}
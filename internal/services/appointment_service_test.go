@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -15,11 +16,12 @@ import (
 )
 
 type appointmentRepoStub struct {
-	appointment *db.Appointment
+	appointment  *db.Appointment
+	appointments []db.Appointment
 }
 
 func (s *appointmentRepoStub) ListAppointments(ctx context.Context, userID uuid.UUID) ([]db.Appointment, error) {
-	panic("not used")
+	return s.appointments, nil
 }
 func (s *appointmentRepoStub) CreateAppointment(ctx context.Context, appt *db.Appointment) error {
 	appt.ID = uuid.New()
@@ -54,18 +56,35 @@ func (s *appointmentRepoStub) ListVisitHistory(ctx context.Context, userID uuid.
 }
 
 type notificationCancelStub struct {
-	cancelled bool
+	cancelled   bool
+	rescheduled []uuid.UUID
 }
 
-func (s *notificationCancelStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, mealTiming *string, timeSlot time.Time) error {
+func (s *notificationCancelStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
 	panic("not used")
 }
 func (s *notificationCancelStub) CancelMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
 	panic("not used")
 }
+func (s *notificationCancelStub) RestoreMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
+	return nil
+}
+func (s *notificationCancelStub) RescheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+	panic("not used")
+}
+func (s *notificationCancelStub) BuildMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) ([]db.NotificationEvent, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) CancelMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	panic("not used")
+}
 func (s *notificationCancelStub) ScheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
 	return nil
 }
+func (s *notificationCancelStub) RescheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
+	s.rescheduled = append(s.rescheduled, appt.ID)
+	return nil
+}
 func (s *notificationCancelStub) CancelAppointmentReminders(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
 	s.cancelled = true
 	return nil
@@ -82,10 +101,37 @@ func (s *notificationCancelStub) ProcessDue(ctx context.Context) error {
 func (s *notificationCancelStub) CancelWeeklyReminders(ctx context.Context, userID uuid.UUID) error {
 	panic("not used")
 }
+func (s *notificationCancelStub) RescheduleWeeklyReminder(ctx context.Context, userID uuid.UUID) error {
+	panic("not used")
+}
+func (s *notificationCancelStub) ListFailedEvents(ctx context.Context, page, pageSize int, status, userID, templateCode string) ([]dto.NotificationEventResponse, int64, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) RequeueEvent(ctx context.Context, id string) (dto.NotificationEventResponse, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) ListInbox(ctx context.Context, userID uuid.UUID, cursor string, limit int, unreadOnly bool) (dto.NotificationInboxResponse, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) UnreadCount(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) MarkRead(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) MarkAllRead(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) ArchiveInboxItem(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationCancelStub) Channels() []string {
+	panic("not used")
+}
 
 func TestCreateAppointmentValidation(t *testing.T) {
 	repo := &appointmentRepoStub{}
-	svc := NewAppointmentService(repo, nil)
+	svc := NewAppointmentService(repo, nil, nil)
 
 	_, err := svc.CreateAppointment(context.Background(), uuid.New().String(), dto.CreateAppointmentRequest{
 		Title:        "Visit",
@@ -100,7 +146,7 @@ func TestCreateAppointmentValidation(t *testing.T) {
 func TestUpdateStatusCancelsWhenCancelled(t *testing.T) {
 	repo := &appointmentRepoStub{appointment: &db.Appointment{ID: uuid.New(), UserID: uuid.New(), ApptType: constants.ApptHospital}}
 	notify := &notificationCancelStub{}
-	svc := NewAppointmentService(repo, notify)
+	svc := NewAppointmentService(repo, notify, nil)
 
 	if err := svc.UpdateStatus(context.Background(), repo.appointment.ID.String(), dto.UpdateAppointmentStatusRequest{Status: constants.ApptCancelled}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -110,10 +156,31 @@ func TestUpdateStatusCancelsWhenCancelled(t *testing.T) {
 	}
 }
 
+func TestUpdateStatusPublishesSyncChange(t *testing.T) {
+	repo := &appointmentRepoStub{appointment: &db.Appointment{ID: uuid.New(), UserID: uuid.New(), ApptType: constants.ApptHospital, Status: constants.ApptPending}}
+	changes := &syncRepoStub{}
+	svc := NewAppointmentService(repo, nil, changes)
+
+	if err := svc.UpdateStatus(context.Background(), repo.appointment.ID.String(), dto.UpdateAppointmentStatusRequest{Status: constants.ApptConfirmed}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes.changes) != 1 {
+		t.Fatalf("expected one sync change, got %d", len(changes.changes))
+	}
+	change := changes.changes[0]
+	if change.UserID != repo.appointment.UserID || change.EntityType != constants.SyncEntityAppointment || change.Operation != constants.SyncOpUpsert {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	var payload dto.AppointmentResponse
+	if err := json.Unmarshal(change.Payload, &payload); err != nil || payload.Status != constants.ApptConfirmed {
+		t.Fatalf("expected confirmed appointment payload, got %s err=%v", change.Payload, err)
+	}
+}
+
 func TestDeleteAppointmentCancels(t *testing.T) {
 	repo := &appointmentRepoStub{appointment: &db.Appointment{ID: uuid.New(), UserID: uuid.New(), ApptType: constants.ApptHospital}}
 	notify := &notificationCancelStub{}
-	svc := NewAppointmentService(repo, notify)
+	svc := NewAppointmentService(repo, notify, nil)
 
 	if err := svc.DeleteAppointment(context.Background(), repo.appointment.ID.String()); err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -122,3 +189,23 @@ func TestDeleteAppointmentCancels(t *testing.T) {
 		t.Fatalf("expected reminders cancelled")
 	}
 }
+
+func TestRescheduleRemindersCoversUpcomingAppointments(t *testing.T) {
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	upcoming := db.Appointment{ID: uuid.New(), ApptType: constants.ApptHospital, Status: constants.ApptConfirmed, ApptDateTime: now.AddDate(0, 0, 3)}
+	repo := &appointmentRepoStub{appointments: []db.Appointment{
+		upcoming,
+		{ID: uuid.New(), ApptType: constants.ApptHospital, Status: constants.ApptCancelled, ApptDateTime: now.AddDate(0, 0, 3)},
+		{ID: uuid.New(), ApptType: constants.ApptHospital, Status: constants.ApptPending, ApptDateTime: now.AddDate(0, 0, -3)},
+	}}
+	notify := &notificationCancelStub{}
+	svc := NewAppointmentService(repo, notify, nil).(*appointmentService)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.RescheduleReminders(context.Background(), uuid.New()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notify.rescheduled) != 1 || notify.rescheduled[0] != upcoming.ID {
+		t.Fatalf("expected only the upcoming appointment replanned, got %v", notify.rescheduled)
+	}
+}
@@ -2,22 +2,114 @@ package services
 
 import (
 	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
 )
 
+// maxUserAgentLength keeps a hostile User-Agent header from bloating the trail.
+const maxUserAgentLength = 512
+
 type AuditService interface {
+	Record(ctx context.Context, entry domain.AuditEntry)
 	ListAuditLogs(ctx context.Context, page, pageSize int, from, to, actorID, actionType string) ([]dto.AuditLogResponse, int64, error)
 }
 
-type auditService struct{}
+type auditService struct {
+	repo     repositories.AuditRepository
+	location *time.Location
+	logger   *zap.Logger
+}
 
-func NewAuditService() AuditService {
-	return &auditService{}
+func NewAuditService(repo repositories.AuditRepository, timezone string, logger *zap.Logger) AuditService {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	return &auditService{repo: repo, location: location, logger: logger}
 }
 
+// Record writes an audit entry, taking IP address and user agent from the
+// request metadata on ctx. Failures are logged rather than returned so that
+// auditing never blocks the audited operation.
+func (s *auditService) Record(ctx context.Context, entry domain.AuditEntry) {
+	meta := domain.RequestMetaFrom(ctx)
+	record := &db.AuditLog{
+		ActorID:      entry.ActorID,
+		TargetUserID: entry.TargetUserID,
+		ActionType:   string(entry.Action),
+		IPAddress:    trimOrNil(&meta.IPAddress),
+	}
+	if ua := strings.TrimSpace(meta.UserAgent); ua != "" {
+		if len(ua) > maxUserAgentLength {
+			ua = ua[:maxUserAgentLength]
+		}
+		record.UserAgent = &ua
+	}
+
+	if err := s.repo.Create(ctx, record); err != nil && s.logger != nil {
+		s.logger.Error("audit log write failed",
+			zap.String("action_type", record.ActionType),
+			zap.Stringp("actor_id", stringPtr(entry.ActorID)),
+			zap.Stringp("target_user_id", stringPtr(entry.TargetUserID)),
+			zap.Error(err),
+		)
+	}
+}
+
+// ListAuditLogs filters by calendar dates in the configured timezone; to is
+// inclusive.
 func (s *auditService) ListAuditLogs(ctx context.Context, page, pageSize int, from, to, actorID, actionType string) ([]dto.AuditLogResponse, int64, error) {
-	return nil, 0, domain.NewError(constants.InternalNotImplemented, "audit logs not implemented")
+	fromDate, toDate, err := parseDateRange(from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := repositories.AuditLogFilter{}
+	if !fromDate.IsZero() {
+		filter.From = time.Date(fromDate.Year(), fromDate.Month(), fromDate.Day(), 0, 0, 0, 0, s.location)
+	}
+	if !toDate.IsZero() {
+		filter.To = time.Date(toDate.Year(), toDate.Month(), toDate.Day()+1, 0, 0, 0, 0, s.location)
+	}
+	if actorID = strings.TrimSpace(actorID); actorID != "" {
+		aid, err := uuid.Parse(actorID)
+		if err != nil {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid actor_id")
+		}
+		filter.ActorID = &aid
+	}
+	if actionType = strings.ToUpper(strings.TrimSpace(actionType)); actionType != "" {
+		if !isAllowed(actionType, constants.AuditActions) {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid action_type")
+		}
+		filter.ActionType = actionType
+	}
+
+	items, total, err := s.repo.List(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp := make([]dto.AuditLogResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, dto.AuditLogResponse{
+			ID:           item.ID.String(),
+			ActorID:      stringPtr(item.ActorID),
+			TargetUserID: stringPtr(item.TargetUserID),
+			ActionType:   item.ActionType,
+			IPAddress:    item.IPAddress,
+			UserAgent:    item.UserAgent,
+			Timestamp:    item.Timestamp,
+		})
+	}
+	return resp, total, nil
 }
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type auditRepoStub struct {
+	created []db.AuditLog
+	filter  repositories.AuditLogFilter
+	items   []db.AuditLog
+}
+
+func (s *auditRepoStub) Create(ctx context.Context, entry *db.AuditLog) error {
+	s.created = append(s.created, *entry)
+	return nil
+}
+
+func (s *auditRepoStub) List(ctx context.Context, filter repositories.AuditLogFilter, page, pageSize int) ([]db.AuditLog, int64, error) {
+	s.filter = filter
+	return s.items, int64(len(s.items)), nil
+}
+
+type auditRecorderStub struct {
+	entries []domain.AuditEntry
+}
+
+func (s *auditRecorderStub) Record(ctx context.Context, entry domain.AuditEntry) {
+	s.entries = append(s.entries, entry)
+}
+
+func (s *auditRecorderStub) ListAuditLogs(ctx context.Context, page, pageSize int, from, to, actorID, actionType string) ([]dto.AuditLogResponse, int64, error) {
+	return nil, 0, nil
+}
+
+func TestAuditServiceRecordUsesRequestMeta(t *testing.T) {
+	repo := &auditRepoStub{}
+	svc := NewAuditService(repo, "Asia/Bangkok", nil)
+
+	actorID, targetID := uuid.New(), uuid.New()
+	ctx := domain.WithRequestMeta(context.Background(), domain.RequestMeta{
+		IPAddress: "203.0.113.7",
+		UserAgent: strings.Repeat("a", maxUserAgentLength+10),
+	})
+	svc.Record(ctx, domain.AuditEntry{ActorID: &actorID, TargetUserID: &targetID, Action: constants.AuditPatientRead})
+
+	if len(repo.created) != 1 {
+		t.Fatalf("expected one audit row, got %d", len(repo.created))
+	}
+	row := repo.created[0]
+	if row.ActionType != "PATIENT_READ" || *row.ActorID != actorID || *row.TargetUserID != targetID {
+		t.Fatalf("unexpected row: %+v", row)
+	}
+	if row.IPAddress == nil || *row.IPAddress != "203.0.113.7" {
+		t.Fatalf("expected ip address from request meta")
+	}
+	if row.UserAgent == nil || len(*row.UserAgent) != maxUserAgentLength {
+		t.Fatalf("expected truncated user agent")
+	}
+
+	svc.Record(context.Background(), domain.AuditEntry{Action: constants.AuditLogin})
+	if repo.created[1].IPAddress != nil || repo.created[1].UserAgent != nil {
+		t.Fatalf("expected empty meta without request context")
+	}
+}
+
+func TestAuditServiceListFilters(t *testing.T) {
+	actorID := uuid.New()
+	repo := &auditRepoStub{items: []db.AuditLog{{ID: uuid.New(), ActorID: &actorID, ActionType: "LOGIN"}}}
+	svc := NewAuditService(repo, "Asia/Bangkok", nil)
+
+	items, total, err := svc.ListAuditLogs(context.Background(), 1, 20, "2026-01-01", "2026-01-31", actorID.String(), "login")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 1 || len(items) != 1 || items[0].ActorID == nil || *items[0].ActorID != actorID.String() {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	if !repo.filter.From.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, bangkok)) || !repo.filter.To.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, bangkok)) {
+		t.Fatalf("unexpected range: %v - %v", repo.filter.From, repo.filter.To)
+	}
+	if repo.filter.ActorID == nil || *repo.filter.ActorID != actorID || repo.filter.ActionType != "LOGIN" {
+		t.Fatalf("unexpected filter: %+v", repo.filter)
+	}
+
+	for _, tc := range []struct{ from, to, actor, action string }{
+		{from: "bad"},
+		{from: "2026-02-01", to: "2026-01-01"},
+		{actor: "bad"},
+		{action: "DELETE_EVERYTHING"},
+	} {
+		if _, _, err := svc.ListAuditLogs(context.Background(), 1, 20, tc.from, tc.to, tc.actor, tc.action); err == nil {
+			t.Fatalf("expected validation error for %+v", tc)
+		}
+	}
+}
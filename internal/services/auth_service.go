@@ -25,6 +25,7 @@ type AuthService interface {
 	VerifyOTP(ctx context.Context, phone, refCode, otpCode, purpose string) error
 	Register(ctx context.Context, req dto.RegisterRequest) (dto.TokenResponse, error)
 	Login(ctx context.Context, req dto.LoginRequest) (dto.TokenResponse, error)
+	StaffLogin(ctx context.Context, req dto.StaffLoginRequest, ip string) (dto.TokenResponse, error)
 	ForgotPasswordRequestOTP(ctx context.Context, phone, ip string) (dto.RequestOTPResponse, error)
 	ForgotPasswordConfirm(ctx context.Context, req dto.ForgotPasswordConfirmRequest) error
 	Refresh(ctx context.Context, refreshToken string) (dto.TokenResponse, error)
@@ -37,15 +38,17 @@ type authService struct {
 	userRepo repositories.UserRepository
 	redis    *redis.Client
 	sms      SmsSender
+	audit    AuditService
 }
 
-func NewAuthService(cfg config.Config, authRepo repositories.AuthRepository, userRepo repositories.UserRepository, redisClient *redis.Client, sms SmsSender) AuthService {
+func NewAuthService(cfg config.Config, authRepo repositories.AuthRepository, userRepo repositories.UserRepository, redisClient *redis.Client, sms SmsSender, audit AuditService) AuthService {
 	return &authService{
 		cfg:      cfg,
 		authRepo: authRepo,
 		userRepo: userRepo,
 		redis:    redisClient,
 		sms:      sms,
+		audit:    audit,
 	}
 }
 
@@ -151,6 +154,42 @@ func (s *authService) Login(ctx context.Context, req dto.LoginRequest) (dto.Toke
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		return dto.TokenResponse{}, domain.NewError(constants.AuthInvalidCredentials, "invalid credentials")
 	}
+	s.recordLogin(ctx, user.ID, constants.AuditLogin)
+	return s.issueTokens(ctx, user.ID, user.Role)
+}
+
+func (s *authService) StaffLogin(ctx context.Context, req dto.StaffLoginRequest, ip string) (dto.TokenResponse, error) {
+	username := strings.TrimSpace(req.Username)
+	if username == "" || req.Password == "" {
+		return dto.TokenResponse{}, domain.NewError(constants.ValidationFailed, "username and password required")
+	}
+
+	if err := s.checkStaffLoginAllowed(ctx, username, ip); err != nil {
+		return dto.TokenResponse{}, err
+	}
+
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.UserNotFound {
+			return dto.TokenResponse{}, err
+		}
+		return dto.TokenResponse{}, s.recordStaffLoginFailure(ctx, username, ip)
+	}
+	if user.Role != constants.RoleNurse && user.Role != constants.RoleAdmin {
+		return dto.TokenResponse{}, s.recordStaffLoginFailure(ctx, username, ip)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return dto.TokenResponse{}, s.recordStaffLoginFailure(ctx, username, ip)
+	}
+	if !user.IsActive {
+		return dto.TokenResponse{}, domain.NewError(constants.AuthForbidden, "account inactive")
+	}
+
+	if err := s.redis.Del(ctx, staffLoginFailKey(username)).Err(); err != nil {
+		return dto.TokenResponse{}, domain.WrapError(constants.InternalError, "reset login attempts failed", err)
+	}
+
+	s.recordLogin(ctx, user.ID, constants.AuditStaffLogin)
 	return s.issueTokens(ctx, user.ID, user.Role)
 }
 
@@ -256,6 +295,13 @@ func (s *authService) rotateTokens(ctx context.Context, userID uuid.UUID, role c
 	return s.issueTokens(ctx, userID, role)
 }
 
+func (s *authService) recordLogin(ctx context.Context, userID uuid.UUID, action constants.AuditAction) {
+	if s.audit == nil {
+		return
+	}
+	s.audit.Record(ctx, domain.AuditEntry{ActorID: &userID, TargetUserID: &userID, Action: action})
+}
+
 func (s *authService) storeRefreshSession(ctx context.Context, userID uuid.UUID, sessionID string, refreshToken string) error {
 	key := refreshSessionKey(userID, sessionID)
 	hash := utils.HashToken(refreshToken)
@@ -293,12 +339,9 @@ func (s *authService) checkRateLimit(ctx context.Context, phone, ip string) erro
 }
 
 func (s *authService) rateLimitKey(ctx context.Context, key string, limit int) error {
-	count, err := s.redis.Incr(ctx, key).Result()
+	count, err := s.incrWithWindow(ctx, key, s.cfg.RateLimit.Window)
 	if err != nil {
-		return domain.WrapError(constants.InternalError, "rate limit failed", err)
-	}
-	if count == 1 {
-		_ = s.redis.Expire(ctx, key, s.cfg.RateLimit.Window).Err()
+		return err
 	}
 	if count > int64(limit) {
 		return domain.NewError(constants.RateLimited, "rate limited")
@@ -306,6 +349,65 @@ func (s *authService) rateLimitKey(ctx context.Context, key string, limit int) e
 	return nil
 }
 
+func (s *authService) checkStaffLoginAllowed(ctx context.Context, username, ip string) error {
+	locked, err := s.redis.Exists(ctx, staffLoginLockKey(username)).Result()
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "login lock lookup failed", err)
+	}
+	if locked > 0 {
+		return domain.NewError(constants.AuthAccountLocked, "account temporarily locked")
+	}
+	if ip == "" {
+		return nil
+	}
+	count, err := s.redis.Get(ctx, staffLoginIPFailKey(ip)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return domain.WrapError(constants.InternalError, "login attempts lookup failed", err)
+	}
+	if count >= int64(s.cfg.RateLimit.StaffLoginPerIP) {
+		return domain.NewError(constants.RateLimited, "too many failed login attempts")
+	}
+	return nil
+}
+
+// recordStaffLoginFailure bumps the per-username and per-IP failure counters
+// and locks the username once it reaches the configured attempt limit. It
+// always returns the error the caller should surface.
+func (s *authService) recordStaffLoginFailure(ctx context.Context, username, ip string) error {
+	count, err := s.incrWithWindow(ctx, staffLoginFailKey(username), s.cfg.RateLimit.StaffLoginWindow)
+	if err != nil {
+		return err
+	}
+	if ip != "" {
+		if _, err := s.incrWithWindow(ctx, staffLoginIPFailKey(ip), s.cfg.RateLimit.StaffLoginWindow); err != nil {
+			return err
+		}
+	}
+
+	if count >= int64(s.cfg.RateLimit.StaffLoginMaxAttempts) {
+		pipe := s.redis.TxPipeline()
+		pipe.Set(ctx, staffLoginLockKey(username), "1", s.cfg.RateLimit.StaffLockoutDuration)
+		pipe.Del(ctx, staffLoginFailKey(username))
+		if _, err := pipe.Exec(ctx); err != nil {
+			return domain.WrapError(constants.InternalError, "lock account failed", err)
+		}
+		return domain.NewError(constants.AuthAccountLocked, "account temporarily locked")
+	}
+
+	return domain.NewError(constants.AuthInvalidCredentials, "invalid credentials")
+}
+
+func (s *authService) incrWithWindow(ctx context.Context, key string, window time.Duration) (int64, error) {
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, domain.WrapError(constants.InternalError, "rate limit failed", err)
+	}
+	if count == 1 {
+		_ = s.redis.Expire(ctx, key, window).Err()
+	}
+	return count, nil
+}
+
 func (s *authService) requireOTPVerified(ctx context.Context, purpose, phone, refCode string) error {
 	key := verifiedOTPKey(purpose, phone, refCode)
 	val, err := s.redis.Get(ctx, key).Result()
@@ -322,3 +424,15 @@ func verifiedOTPKey(purpose, phone, refCode string) string {
 func refreshSessionKey(userID uuid.UUID, sessionID string) string {
 	return fmt.Sprintf("auth:refresh:%s:%s", userID.String(), sessionID)
 }
+
+func staffLoginFailKey(username string) string {
+	return fmt.Sprintf("auth:staff:fail:user:%s", strings.ToLower(username))
+}
+
+func staffLoginIPFailKey(ip string) string {
+	return fmt.Sprintf("auth:staff:fail:ip:%s", ip)
+}
+
+func staffLoginLockKey(username string) string {
+	return fmt.Sprintf("auth:staff:lock:%s", strings.ToLower(username))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/alicebob/miniredis/v2"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/ParkPawapon/mhp-be/internal/config"
 	"github.com/ParkPawapon/mhp-be/internal/constants"
@@ -58,6 +59,12 @@ func (userRepoStubAuth) FindByID(ctx context.Context, id uuid.UUID) (*db.User, e
 func (userRepoStubAuth) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
 	return nil
 }
+func (userRepoStubAuth) FindByLineUserID(ctx context.Context, lineUserID string) (*db.User, error) {
+	return nil, domain.NewError(constants.UserNotFound, "user not found")
+}
+func (userRepoStubAuth) UpdateLineUserID(ctx context.Context, id uuid.UUID, lineUserID *string) error {
+	return nil
+}
 
 type smsSenderStub struct{}
 
@@ -79,7 +86,7 @@ func newTestAuthService(t *testing.T) (AuthService, *authRepoStub, *redis.Client
 		RateLimit: config.RateLimitConfig{OTPPerPhone: 1, OTPPerIP: 1, Window: time.Minute},
 	}
 	repo := &authRepoStub{}
-	svc := NewAuthService(cfg, repo, userRepoStubAuth{}, rdb, smsSenderStub{})
+	svc := NewAuthService(cfg, repo, userRepoStubAuth{}, rdb, smsSenderStub{}, nil)
 	return svc, repo, rdb
 }
 
@@ -142,3 +149,123 @@ func TestForgotPasswordConfirmUsesOTP(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+type staffUserRepoStub struct {
+	userRepoStubAuth
+	users map[string]*db.User
+}
+
+func (s staffUserRepoStub) FindByUsername(ctx context.Context, username string) (*db.User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return nil, domain.NewError(constants.UserNotFound, "user not found")
+	}
+	return user, nil
+}
+
+func newTestStaffAuthService(t *testing.T, users ...*db.User) (AuthService, *redis.Client) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	cfg := config.Config{
+		JWT: config.JWTConfig{Issuer: "test", Secret: "test-secret-123456789012345678901234567890", AccessTTL: 15 * time.Minute, RefreshTTL: 24 * time.Hour},
+		RateLimit: config.RateLimitConfig{
+			StaffLoginMaxAttempts: 3,
+			StaffLoginPerIP:       5,
+			StaffLoginWindow:      time.Minute,
+			StaffLockoutDuration:  time.Minute,
+		},
+	}
+	repo := staffUserRepoStub{users: map[string]*db.User{}}
+	for _, user := range users {
+		repo.users[user.Username] = user
+	}
+	return NewAuthService(cfg, &authRepoStub{}, repo, rdb, smsSenderStub{}, nil), rdb
+}
+
+func newStaffUser(t *testing.T, username string, role constants.Role, active bool) *db.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	return &db.User{ID: uuid.New(), Username: username, PasswordHash: string(hash), Role: role, IsActive: active}
+}
+
+func TestStaffLoginIssuesSession(t *testing.T) {
+	nurse := newStaffUser(t, "nurse01", constants.RoleNurse, true)
+	svc, rdb := newTestStaffAuthService(t, nurse)
+
+	resp, err := svc.StaffLogin(context.Background(), dto.StaffLoginRequest{Username: "nurse01", Password: "secret"}, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Fatalf("expected tokens")
+	}
+	keys, _ := rdb.Keys(context.Background(), "auth:refresh:"+nurse.ID.String()+":*").Result()
+	if len(keys) != 1 {
+		t.Fatalf("expected refresh session stored, got %v", keys)
+	}
+	if _, err := svc.Refresh(context.Background(), resp.RefreshToken); err != nil {
+		t.Fatalf("expected refresh to work: %v", err)
+	}
+}
+
+func TestStaffLoginRejectsPatientAndInactive(t *testing.T) {
+	patient := newStaffUser(t, "0800000000", constants.RolePatient, true)
+	inactive := newStaffUser(t, "admin01", constants.RoleAdmin, false)
+	svc, _ := newTestStaffAuthService(t, patient, inactive)
+
+	_, err := svc.StaffLogin(context.Background(), dto.StaffLoginRequest{Username: "0800000000", Password: "secret"}, "")
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.AuthInvalidCredentials {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+
+	_, err = svc.StaffLogin(context.Background(), dto.StaffLoginRequest{Username: "admin01", Password: "secret"}, "")
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.AuthForbidden {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+}
+
+func TestStaffLoginLocksAfterRepeatedFailures(t *testing.T) {
+	admin := newStaffUser(t, "admin01", constants.RoleAdmin, true)
+	svc, _ := newTestStaffAuthService(t, admin)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.StaffLogin(ctx, dto.StaffLoginRequest{Username: "admin01", Password: "wrong"}, "10.0.0.1")
+		if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.AuthInvalidCredentials {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i, err)
+		}
+	}
+	_, err := svc.StaffLogin(ctx, dto.StaffLoginRequest{Username: "admin01", Password: "wrong"}, "10.0.0.1")
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.AuthAccountLocked {
+		t.Fatalf("expected account locked, got %v", err)
+	}
+
+	_, err = svc.StaffLogin(ctx, dto.StaffLoginRequest{Username: "admin01", Password: "secret"}, "10.0.0.2")
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.AuthAccountLocked {
+		t.Fatalf("expected lock to hold for correct password, got %v", err)
+	}
+}
+
+func TestStaffLoginLimitsFailuresPerIP(t *testing.T) {
+	svc, _ := newTestStaffAuthService(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		username := "unknown" + string(rune('a'+i))
+		if _, err := svc.StaffLogin(ctx, dto.StaffLoginRequest{Username: username, Password: "x"}, "10.0.0.9"); err == nil {
+			t.Fatalf("expected failure")
+		}
+	}
+	_, err := svc.StaffLogin(ctx, dto.StaffLoginRequest{Username: "another", Password: "x"}, "10.0.0.9")
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.RateLimited {
+		t.Fatalf("expected rate limited, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type EscalationService interface {
+	EscalateMissedDoses(ctx context.Context) (int, error)
+	GetPolicy(ctx context.Context, patientID string) (dto.EscalationPolicyResponse, error)
+	UpdatePolicy(ctx context.Context, actorID uuid.UUID, patientID string, req dto.UpdateEscalationPolicyRequest) (dto.EscalationPolicyResponse, error)
+}
+
+type escalationService struct {
+	repo  repositories.EscalationRepository
+	users repositories.UserRepository
+	cfg   config.EscalationConfig
+	now   func() time.Time
+}
+
+func NewEscalationService(repo repositories.EscalationRepository, users repositories.UserRepository, cfg config.EscalationConfig) EscalationService {
+	return &escalationService{
+		repo:  repo,
+		users: users,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+type escalationRecipient struct {
+	userID uuid.UUID
+	level  string
+}
+
+// EscalateMissedDoses notifies caregivers, then the patient's nurse, once a
+// patient's latest recorded doses are a run of MISSED doses reaching the
+// level's threshold. Each level fires once per run; taking or skipping a dose
+// ends the run. A recipient gets one event per level listing every patient
+// escalated to them in this pass.
+func (s *escalationService) EscalateMissedDoses(ctx context.Context) (int, error) {
+	if !s.cfg.Enabled {
+		return 0, nil
+	}
+
+	now := s.now().UTC()
+	since := now.AddDate(0, 0, -s.cfg.LookbackDays)
+	candidates, err := s.repo.ListMissedPatients(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+
+	var escalations []db.MissedDoseEscalation
+	var recipients []escalationRecipient
+	digests := make(map[escalationRecipient][]string)
+	for _, candidate := range candidates {
+		doses, err := s.repo.ListRecentDoses(ctx, candidate.UserID, since)
+		if err != nil {
+			return 0, err
+		}
+		count, first, last := missedStreak(doses)
+		if count == 0 {
+			continue
+		}
+
+		caregiverThreshold, nurseThreshold := s.thresholds(candidate.CaregiverThreshold, candidate.NurseThreshold)
+		levels := []struct {
+			level     string
+			threshold int
+		}{
+			{constants.EscalationLevelCaregiver, caregiverThreshold},
+			{constants.EscalationLevelNurse, nurseThreshold},
+		}
+		for _, level := range levels {
+			if count < level.threshold {
+				continue
+			}
+			escalated, err := s.alreadyEscalated(ctx, candidate.UserID, level.level)
+			if err != nil {
+				return 0, err
+			}
+			if escalated {
+				continue
+			}
+			targets, err := s.levelRecipients(ctx, candidate, level.level)
+			if err != nil {
+				return 0, err
+			}
+			if len(targets) == 0 {
+				continue
+			}
+
+			escalations = append(escalations, db.MissedDoseEscalation{
+				UserID:         candidate.UserID,
+				Level:          level.level,
+				MissedCount:    count,
+				FirstMissedAt:  first,
+				LastMissedAt:   last,
+				RecipientCount: len(targets),
+			})
+			for _, target := range targets {
+				key := escalationRecipient{userID: target, level: level.level}
+				if _, ok := digests[key]; !ok {
+					recipients = append(recipients, key)
+				}
+				digests[key] = append(digests[key], candidate.UserID.String())
+			}
+		}
+	}
+
+	events := make([]db.NotificationEvent, 0, len(recipients))
+	for _, recipient := range recipients {
+		templateCode := constants.TemplateEscalationMissedCaregiver
+		if recipient.level == constants.EscalationLevelNurse {
+			templateCode = constants.TemplateEscalationMissedNurse
+		}
+		// Only ids go into the payload: it is pushed through FCM and APNs, so
+		// names and HNs stay on the server behind the patient endpoints.
+		payloadBytes, _ := json.Marshal(map[string]any{
+			"type":          "missed_dose_escalation",
+			"level":         recipient.level,
+			"patient_ids":   digests[recipient],
+			"patient_count": len(digests[recipient]),
+		})
+		events = append(events, db.NotificationEvent{
+			UserID:       recipient.userID,
+			TemplateCode: templateCode,
+			ScheduledAt:  now,
+			Status:       constants.NotificationPending,
+			Payload:      datatypes.JSON(payloadBytes),
+		})
+	}
+
+	if err := s.repo.CreateEscalations(ctx, escalations, events); err != nil {
+		return 0, err
+	}
+	return len(escalations), nil
+}
+
+// missedStreak counts the MISSED doses at the head of doses (latest first) and
+// returns when the first and last of them were due.
+func missedStreak(doses []repositories.DoseOutcomeRow) (int, time.Time, time.Time) {
+	var count int
+	var first, last time.Time
+	for _, dose := range doses {
+		if dose.Status != constants.MedMissed {
+			break
+		}
+		if count == 0 {
+			last = dose.DueAt
+		}
+		first = dose.DueAt
+		count++
+	}
+	return count, first, last
+}
+
+// alreadyEscalated reports whether level was notified during the current run
+// of missed doses, i.e. no dose was taken or skipped since the last escalation.
+func (s *escalationService) alreadyEscalated(ctx context.Context, userID uuid.UUID, level string) (bool, error) {
+	latest, err := s.repo.FindLatestEscalation(ctx, userID, level)
+	if err != nil || latest == nil {
+		return false, err
+	}
+	broken, err := s.repo.HasDoseOutcomeSince(ctx, userID, latest.LastMissedAt)
+	if err != nil {
+		return false, err
+	}
+	return !broken, nil
+}
+
+func (s *escalationService) levelRecipients(ctx context.Context, candidate repositories.EscalationCandidate, level string) ([]uuid.UUID, error) {
+	if level == constants.EscalationLevelCaregiver {
+		return s.repo.ListCaregiverIDs(ctx, candidate.UserID)
+	}
+	if candidate.NurseID != nil {
+		return []uuid.UUID{*candidate.NurseID}, nil
+	}
+	nurseID, err := s.repo.FindLatestVisitNurse(ctx, candidate.UserID)
+	if err != nil || nurseID == nil {
+		return nil, err
+	}
+	return []uuid.UUID{*nurseID}, nil
+}
+
+func (s *escalationService) thresholds(caregiver, nurse *int) (int, int) {
+	caregiverThreshold, nurseThreshold := s.cfg.CaregiverThreshold, s.cfg.NurseThreshold
+	if caregiver != nil {
+		caregiverThreshold = *caregiver
+	}
+	if nurse != nil {
+		nurseThreshold = *nurse
+	}
+	return caregiverThreshold, nurseThreshold
+}
+
+func (s *escalationService) GetPolicy(ctx context.Context, patientID string) (dto.EscalationPolicyResponse, error) {
+	pid, err := s.patientID(ctx, patientID)
+	if err != nil {
+		return dto.EscalationPolicyResponse{}, err
+	}
+	policy, err := s.repo.GetPolicy(ctx, pid)
+	if err != nil {
+		return dto.EscalationPolicyResponse{}, err
+	}
+	return s.toPolicyResponse(pid, policy), nil
+}
+
+// UpdatePolicy replaces the patient's policy; omitted thresholds and nurse_id
+// fall back to the defaults.
+func (s *escalationService) UpdatePolicy(ctx context.Context, actorID uuid.UUID, patientID string, req dto.UpdateEscalationPolicyRequest) (dto.EscalationPolicyResponse, error) {
+	pid, err := s.patientID(ctx, patientID)
+	if err != nil {
+		return dto.EscalationPolicyResponse{}, err
+	}
+	for _, threshold := range []*int{req.CaregiverThreshold, req.NurseThreshold} {
+		if threshold != nil && (*threshold < 1 || *threshold > constants.MaxEscalationThreshold) {
+			return dto.EscalationPolicyResponse{}, domain.NewError(constants.ValidationFailed, "thresholds must be between 1 and 50")
+		}
+	}
+	if caregiver, nurse := s.thresholds(req.CaregiverThreshold, req.NurseThreshold); nurse < caregiver {
+		return dto.EscalationPolicyResponse{}, domain.NewError(constants.ValidationFailed, "nurse_threshold must not be below caregiver_threshold")
+	}
+
+	policy := &db.EscalationPolicy{
+		UserID:             pid,
+		Enabled:            true,
+		CaregiverThreshold: req.CaregiverThreshold,
+		NurseThreshold:     req.NurseThreshold,
+		UpdatedBy:          &actorID,
+		UpdatedAt:          s.now().UTC(),
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	if req.NurseID != nil {
+		nurseID, err := uuid.Parse(*req.NurseID)
+		if err != nil {
+			return dto.EscalationPolicyResponse{}, domain.NewError(constants.ValidationFailed, "invalid nurse_id")
+		}
+		nurse, err := s.users.FindByID(ctx, nurseID)
+		if err != nil {
+			return dto.EscalationPolicyResponse{}, err
+		}
+		if nurse.Role != constants.RoleNurse || !nurse.IsActive {
+			return dto.EscalationPolicyResponse{}, domain.NewError(constants.ValidationFailed, "nurse_id must be an active nurse")
+		}
+		policy.NurseID = &nurseID
+	}
+
+	if err := s.repo.SavePolicy(ctx, policy); err != nil {
+		return dto.EscalationPolicyResponse{}, err
+	}
+	return s.toPolicyResponse(pid, policy), nil
+}
+
+func (s *escalationService) patientID(ctx context.Context, value string) (uuid.UUID, error) {
+	pid, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+	user, err := s.users.FindByID(ctx, pid)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if user.Role != constants.RolePatient {
+		return uuid.Nil, domain.NewError(constants.UserNotFound, "patient not found")
+	}
+	return pid, nil
+}
+
+func (s *escalationService) toPolicyResponse(userID uuid.UUID, policy *db.EscalationPolicy) dto.EscalationPolicyResponse {
+	if policy == nil {
+		return dto.EscalationPolicyResponse{
+			UserID:             userID.String(),
+			Enabled:            true,
+			CaregiverThreshold: s.cfg.CaregiverThreshold,
+			NurseThreshold:     s.cfg.NurseThreshold,
+			IsDefault:          true,
+		}
+	}
+	caregiverThreshold, nurseThreshold := s.thresholds(policy.CaregiverThreshold, policy.NurseThreshold)
+	updatedAt := policy.UpdatedAt
+	return dto.EscalationPolicyResponse{
+		UserID:             userID.String(),
+		Enabled:            policy.Enabled,
+		CaregiverThreshold: caregiverThreshold,
+		NurseThreshold:     nurseThreshold,
+		NurseID:            stringPtr(policy.NurseID),
+		UpdatedBy:          stringPtr(policy.UpdatedBy),
+		UpdatedAt:          &updatedAt,
+	}
+}
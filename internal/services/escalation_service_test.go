@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type escalationRepoStub struct {
+	candidates   []repositories.EscalationCandidate
+	doses        map[uuid.UUID][]repositories.DoseOutcomeRow
+	caregivers   map[uuid.UUID][]uuid.UUID
+	nurses       map[uuid.UUID]uuid.UUID
+	outcomeSince map[uuid.UUID]bool
+	escalations  []db.MissedDoseEscalation
+	events       []db.NotificationEvent
+	policy       *db.EscalationPolicy
+}
+
+func (s *escalationRepoStub) GetPolicy(ctx context.Context, userID uuid.UUID) (*db.EscalationPolicy, error) {
+	return s.policy, nil
+}
+func (s *escalationRepoStub) SavePolicy(ctx context.Context, policy *db.EscalationPolicy) error {
+	s.policy = policy
+	return nil
+}
+func (s *escalationRepoStub) ListMissedPatients(ctx context.Context, since time.Time) ([]repositories.EscalationCandidate, error) {
+	return s.candidates, nil
+}
+func (s *escalationRepoStub) ListRecentDoses(ctx context.Context, userID uuid.UUID, since time.Time) ([]repositories.DoseOutcomeRow, error) {
+	return s.doses[userID], nil
+}
+func (s *escalationRepoStub) FindLatestEscalation(ctx context.Context, userID uuid.UUID, level string) (*db.MissedDoseEscalation, error) {
+	for i := len(s.escalations) - 1; i >= 0; i-- {
+		if s.escalations[i].UserID == userID && s.escalations[i].Level == level {
+			return &s.escalations[i], nil
+		}
+	}
+	return nil, nil
+}
+func (s *escalationRepoStub) HasDoseOutcomeSince(ctx context.Context, userID uuid.UUID, after time.Time) (bool, error) {
+	return s.outcomeSince[userID], nil
+}
+func (s *escalationRepoStub) ListCaregiverIDs(ctx context.Context, patientID uuid.UUID) ([]uuid.UUID, error) {
+	return s.caregivers[patientID], nil
+}
+func (s *escalationRepoStub) FindLatestVisitNurse(ctx context.Context, patientID uuid.UUID) (*uuid.UUID, error) {
+	if nurseID, ok := s.nurses[patientID]; ok {
+		return &nurseID, nil
+	}
+	return nil, nil
+}
+func (s *escalationRepoStub) CreateEscalations(ctx context.Context, escalations []db.MissedDoseEscalation, events []db.NotificationEvent) error {
+	s.escalations = append(s.escalations, escalations...)
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func dosesDue(now time.Time, statuses ...constants.MedIntakeStatus) []repositories.DoseOutcomeRow {
+	rows := make([]repositories.DoseOutcomeRow, 0, len(statuses))
+	for i, status := range statuses {
+		rows = append(rows, repositories.DoseOutcomeRow{Status: status, DueAt: now.Add(-time.Duration(i+1) * 12 * time.Hour)})
+	}
+	return rows
+}
+
+func TestEscalateMissedDosesByLevel(t *testing.T) {
+	now := time.Date(2026, 1, 20, 12, 0, 0, 0, time.UTC)
+	patientA, patientB, patientD := uuid.New(), uuid.New(), uuid.New()
+	caregiver1, caregiver2, nurse := uuid.New(), uuid.New(), uuid.New()
+	two := 2
+	repo := &escalationRepoStub{
+		candidates: []repositories.EscalationCandidate{
+			{UserID: patientA},
+			// Policy needs two misses in a row; the streak is one.
+			{UserID: patientB, CaregiverThreshold: &two},
+			{UserID: patientD},
+		},
+		doses: map[uuid.UUID][]repositories.DoseOutcomeRow{
+			patientA: dosesDue(now, constants.MedMissed, constants.MedMissed, constants.MedMissed, constants.MedTaken),
+			patientB: dosesDue(now, constants.MedMissed, constants.MedTaken, constants.MedMissed),
+			patientD: dosesDue(now, constants.MedMissed, constants.MedSkipped),
+		},
+		caregivers: map[uuid.UUID][]uuid.UUID{
+			patientA: {caregiver1, caregiver2},
+			patientB: {caregiver2},
+			patientD: {caregiver1},
+		},
+		nurses: map[uuid.UUID]uuid.UUID{patientA: nurse, patientD: nurse},
+	}
+	svc := NewEscalationService(repo, nil, config.EscalationConfig{Enabled: true, CaregiverThreshold: 1, NurseThreshold: 3, LookbackDays: 7}).(*escalationService)
+	svc.now = func() time.Time { return now }
+
+	escalated, err := svc.EscalateMissedDoses(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if escalated != 3 || len(repo.escalations) != 3 {
+		t.Fatalf("expected caregiver+nurse for A and caregiver for D, got %d: %+v", escalated, repo.escalations)
+	}
+	if nurseEscalation := repo.escalations[1]; nurseEscalation.Level != constants.EscalationLevelNurse || nurseEscalation.MissedCount != 3 || !nurseEscalation.LastMissedAt.Equal(now.Add(-12*time.Hour)) || !nurseEscalation.FirstMissedAt.Equal(now.Add(-36*time.Hour)) {
+		t.Fatalf("unexpected nurse escalation: %+v", nurseEscalation)
+	}
+
+	type digest struct {
+		Level        string   `json:"level"`
+		PatientIDs   []string `json:"patient_ids"`
+		PatientCount int      `json:"patient_count"`
+	}
+	got := map[uuid.UUID]digest{}
+	for _, event := range repo.events {
+		var payload digest
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		wantTemplate := constants.TemplateEscalationMissedCaregiver
+		if payload.Level == constants.EscalationLevelNurse {
+			wantTemplate = constants.TemplateEscalationMissedNurse
+		}
+		if event.TemplateCode != wantTemplate || !event.ScheduledAt.Equal(now) || event.Status != constants.NotificationPending {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		got[event.UserID] = payload
+	}
+	if len(repo.events) != 3 || len(got[caregiver1].PatientIDs) != 2 || got[caregiver1].PatientCount != 2 || len(got[caregiver2].PatientIDs) != 1 || len(got[nurse].PatientIDs) != 1 || got[nurse].PatientIDs[0] != patientA.String() {
+		t.Fatalf("expected one digest per recipient, got %+v", got)
+	}
+	// Payloads are pushed through FCM and APNs, so they carry no patient details.
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(repo.events[0].Payload, &keys); err != nil || len(keys) != 4 || keys["patient_ids"] == nil || keys["patient_count"] == nil {
+		t.Fatalf("expected only type, level, patient_ids and patient_count, got %s", repo.events[0].Payload)
+	}
+
+	// The same streak is not escalated twice.
+	repo.doses[patientA] = dosesDue(now, constants.MedMissed, constants.MedMissed, constants.MedMissed, constants.MedMissed)
+	if escalated, err := svc.EscalateMissedDoses(context.Background()); err != nil || escalated != 0 {
+		t.Fatalf("expected no repeat escalation, got %d err=%v", escalated, err)
+	}
+
+	// Once a dose is taken, a new streak escalates again.
+	repo.outcomeSince = map[uuid.UUID]bool{patientD: true}
+	if escalated, err := svc.EscalateMissedDoses(context.Background()); err != nil || escalated != 1 {
+		t.Fatalf("expected a new streak to escalate, got %d err=%v", escalated, err)
+	}
+}
+
+func TestUpdateEscalationPolicy(t *testing.T) {
+	patientID, nurseID, caregiverID, actorID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	users := &lineUserRepoStub{users: map[uuid.UUID]*db.User{
+		patientID:   {ID: patientID, Role: constants.RolePatient, IsActive: true},
+		nurseID:     {ID: nurseID, Role: constants.RoleNurse, IsActive: true},
+		caregiverID: {ID: caregiverID, Role: constants.RoleCaregiver, IsActive: true},
+	}}
+	repo := &escalationRepoStub{}
+	svc := NewEscalationService(repo, users, config.EscalationConfig{Enabled: true, CaregiverThreshold: 1, NurseThreshold: 3})
+
+	resp, err := svc.GetPolicy(context.Background(), patientID.String())
+	if err != nil || !resp.IsDefault || resp.CaregiverThreshold != 1 || resp.NurseThreshold != 3 {
+		t.Fatalf("expected default policy, got %+v err=%v", resp, err)
+	}
+	if _, err := svc.GetPolicy(context.Background(), nurseID.String()); err == nil {
+		t.Fatalf("expected non-patient to be rejected")
+	}
+
+	two, one := 2, 1
+	caregiver := caregiverID.String()
+	for _, req := range []dto.UpdateEscalationPolicyRequest{
+		{CaregiverThreshold: &two, NurseThreshold: &one},
+		{NurseID: &caregiver},
+	} {
+		if _, err := svc.UpdatePolicy(context.Background(), actorID, patientID.String(), req); err == nil {
+			t.Fatalf("expected %+v to be rejected", req)
+		}
+	}
+
+	disabled := false
+	nurse := nurseID.String()
+	resp, err = svc.UpdatePolicy(context.Background(), actorID, patientID.String(), dto.UpdateEscalationPolicyRequest{Enabled: &disabled, CaregiverThreshold: &two, NurseID: &nurse})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.IsDefault || resp.Enabled || resp.CaregiverThreshold != 2 || resp.NurseThreshold != 3 || resp.NurseID == nil || *resp.NurseID != nurse {
+		t.Fatalf("unexpected policy: %+v", resp)
+	}
+	if repo.policy == nil || repo.policy.NurseThreshold != nil || *repo.policy.UpdatedBy != actorID {
+		t.Fatalf("expected omitted threshold to stay on the default: %+v", repo.policy)
+	}
+}
@@ -2,10 +2,31 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+const (
+	minSystolicBP   = 60
+	maxSystolicBP   = 260
+	minDiastolicBP  = 30
+	maxDiastolicBP  = 160
+	minPulseRate    = 30
+	maxPulseRate    = 220
+	minWeightKG     = 1.0
+	maxWeightKG     = 400.0
+	maxStressLevel  = 10
+	maxExerciseMins = 24 * 60
 )
 
 type HealthService interface {
@@ -15,24 +36,200 @@ type HealthService interface {
 	ListDailyAssessments(ctx context.Context, userID, from, to string) ([]dto.DailyAssessmentResponse, error)
 }
 
-type healthService struct{}
+type healthService struct {
+	repo repositories.HealthRepository
+}
 
-func NewHealthService() HealthService {
-	return &healthService{}
+func NewHealthService(repo repositories.HealthRepository) HealthService {
+	return &healthService{repo: repo}
 }
 
 func (s *healthService) CreateHealthRecord(ctx context.Context, userID string, req dto.CreateHealthRecordRequest) (dto.HealthRecordResponse, error) {
-	return dto.HealthRecordResponse{}, domain.NewError(constants.InternalNotImplemented, "health records not implemented")
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.HealthRecordResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	recordDate, err := time.Parse("2006-01-02", strings.TrimSpace(req.RecordDate))
+	if err != nil {
+		return dto.HealthRecordResponse{}, domain.NewError(constants.ValidationFailed, "invalid record_date")
+	}
+
+	timePeriod := strings.ToUpper(strings.TrimSpace(req.TimePeriod))
+	if !isAllowed(timePeriod, constants.HealthTimePeriods) {
+		return dto.HealthRecordResponse{}, domain.NewError(constants.HealthInvalid, "invalid time_period")
+	}
+
+	if err := validateHealthMeasurements(req); err != nil {
+		return dto.HealthRecordResponse{}, err
+	}
+
+	record := &db.HealthRecord{
+		UserID:      uid,
+		RecordDate:  recordDate.UTC(),
+		TimePeriod:  timePeriod,
+		SystolicBP:  req.SystolicBP,
+		DiastolicBP: req.DiastolicBP,
+		PulseRate:   req.PulseRate,
+		WeightKG:    req.WeightKG,
+	}
+
+	if err := s.repo.CreateHealthRecord(ctx, record); err != nil {
+		return dto.HealthRecordResponse{}, err
+	}
+
+	return toHealthRecordResponse(*record), nil
 }
 
 func (s *healthService) ListHealthRecords(ctx context.Context, userID, from, to string) ([]dto.HealthRecordResponse, error) {
-	return nil, domain.NewError(constants.InternalNotImplemented, "health records not implemented")
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	fromDate, toDate, err := parseDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.ListHealthRecords(ctx, uid, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.HealthRecordResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, toHealthRecordResponse(item))
+	}
+	return resp, nil
 }
 
 func (s *healthService) CreateDailyAssessment(ctx context.Context, userID string, req dto.CreateDailyAssessmentRequest) (dto.DailyAssessmentResponse, error) {
-	return dto.DailyAssessmentResponse{}, domain.NewError(constants.InternalNotImplemented, "daily assessments not implemented")
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.DailyAssessmentResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	logDate, err := time.Parse("2006-01-02", strings.TrimSpace(req.LogDate))
+	if err != nil {
+		return dto.DailyAssessmentResponse{}, domain.NewError(constants.ValidationFailed, "invalid log_date")
+	}
+
+	exerciseMinutes := 0
+	if req.ExerciseMinutes != nil {
+		if *req.ExerciseMinutes < 0 || *req.ExerciseMinutes > maxExerciseMins {
+			return dto.DailyAssessmentResponse{}, domain.NewError(constants.HealthInvalid, "exercise_minutes out of range")
+		}
+		exerciseMinutes = *req.ExerciseMinutes
+	}
+	if req.StressLevel != nil && (*req.StressLevel < 0 || *req.StressLevel > maxStressLevel) {
+		return dto.DailyAssessmentResponse{}, domain.NewError(constants.HealthInvalid, "stress_level out of range")
+	}
+
+	var symptoms datatypes.JSON
+	if req.Symptoms != nil {
+		payload, err := json.Marshal(req.Symptoms)
+		if err != nil {
+			return dto.DailyAssessmentResponse{}, domain.NewError(constants.ValidationFailed, "invalid symptoms")
+		}
+		symptoms = payload
+	}
+
+	assessment := &db.DailyAssessment{
+		UserID:          uid,
+		LogDate:         logDate.UTC(),
+		ExerciseMinutes: exerciseMinutes,
+		SleepQuality:    trimOrNil(req.SleepQuality),
+		StressLevel:     req.StressLevel,
+		DietCompliance:  trimOrNil(req.DietCompliance),
+		Symptoms:        symptoms,
+		Note:            trimOrNil(req.Note),
+	}
+
+	if err := s.repo.CreateDailyAssessment(ctx, assessment); err != nil {
+		return dto.DailyAssessmentResponse{}, err
+	}
+
+	return toDailyAssessmentResponse(*assessment), nil
 }
 
 func (s *healthService) ListDailyAssessments(ctx context.Context, userID, from, to string) ([]dto.DailyAssessmentResponse, error) {
-	return nil, domain.NewError(constants.InternalNotImplemented, "daily assessments not implemented")
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	fromDate, toDate, err := parseDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.repo.ListDailyAssessments(ctx, uid, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.DailyAssessmentResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, toDailyAssessmentResponse(item))
+	}
+	return resp, nil
+}
+
+func validateHealthMeasurements(req dto.CreateHealthRecordRequest) error {
+	if req.SystolicBP == nil && req.DiastolicBP == nil && req.PulseRate == nil && req.WeightKG == nil {
+		return domain.NewError(constants.HealthInvalid, "at least one measurement required")
+	}
+	if (req.SystolicBP == nil) != (req.DiastolicBP == nil) {
+		return domain.NewError(constants.HealthInvalid, "systolic_bp and diastolic_bp must be provided together")
+	}
+	if req.SystolicBP != nil {
+		if *req.SystolicBP < minSystolicBP || *req.SystolicBP > maxSystolicBP {
+			return domain.NewError(constants.HealthInvalid, "systolic_bp out of range")
+		}
+		if *req.DiastolicBP < minDiastolicBP || *req.DiastolicBP > maxDiastolicBP {
+			return domain.NewError(constants.HealthInvalid, "diastolic_bp out of range")
+		}
+		if *req.DiastolicBP >= *req.SystolicBP {
+			return domain.NewError(constants.HealthInvalid, "diastolic_bp must be lower than systolic_bp")
+		}
+	}
+	if req.PulseRate != nil && (*req.PulseRate < minPulseRate || *req.PulseRate > maxPulseRate) {
+		return domain.NewError(constants.HealthInvalid, "pulse_rate out of range")
+	}
+	if req.WeightKG != nil && (*req.WeightKG < minWeightKG || *req.WeightKG > maxWeightKG) {
+		return domain.NewError(constants.HealthInvalid, "weight_kg out of range")
+	}
+	return nil
+}
+
+func toHealthRecordResponse(record db.HealthRecord) dto.HealthRecordResponse {
+	return dto.HealthRecordResponse{
+		ID:          record.ID.String(),
+		RecordDate:  record.RecordDate.Format("2006-01-02"),
+		TimePeriod:  record.TimePeriod,
+		SystolicBP:  record.SystolicBP,
+		DiastolicBP: record.DiastolicBP,
+		PulseRate:   record.PulseRate,
+		WeightKG:    record.WeightKG,
+		CreatedAt:   record.CreatedAt,
+	}
+}
+
+func toDailyAssessmentResponse(item db.DailyAssessment) dto.DailyAssessmentResponse {
+	var symptoms any
+	if len(item.Symptoms) > 0 {
+		_ = json.Unmarshal(item.Symptoms, &symptoms)
+	}
+	return dto.DailyAssessmentResponse{
+		ID:              item.ID.String(),
+		LogDate:         item.LogDate.Format("2006-01-02"),
+		ExerciseMinutes: item.ExerciseMinutes,
+		SleepQuality:    item.SleepQuality,
+		StressLevel:     item.StressLevel,
+		DietCompliance:  item.DietCompliance,
+		Symptoms:        symptoms,
+		Note:            item.Note,
+		CreatedAt:       item.CreatedAt,
+	}
 }
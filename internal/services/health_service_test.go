@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type healthRepoStub struct {
+	record     *db.HealthRecord
+	assessment *db.DailyAssessment
+	from, to   time.Time
+	createErr  error
+}
+
+func (s *healthRepoStub) CreateHealthRecord(ctx context.Context, record *db.HealthRecord) error {
+	record.ID = uuid.New()
+	s.record = record
+	return nil
+}
+func (s *healthRepoStub) ListHealthRecords(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.HealthRecord, error) {
+	s.from, s.to = from, to
+	return []db.HealthRecord{}, nil
+}
+func (s *healthRepoStub) CreateDailyAssessment(ctx context.Context, assessment *db.DailyAssessment) error {
+	if s.createErr != nil {
+		return s.createErr
+	}
+	assessment.ID = uuid.New()
+	s.assessment = assessment
+	return nil
+}
+func (s *healthRepoStub) ListDailyAssessments(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.DailyAssessment, error) {
+	s.from, s.to = from, to
+	return []db.DailyAssessment{{ID: uuid.New(), LogDate: from, Symptoms: []byte(`{"dizzy":true}`)}}, nil
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestHealthServiceCreateRecordValidation(t *testing.T) {
+	svc := NewHealthService(&healthRepoStub{})
+	userID := uuid.New().String()
+	weight := 600.0
+
+	cases := []struct {
+		name string
+		req  dto.CreateHealthRecordRequest
+	}{
+		{"bad date", dto.CreateHealthRecordRequest{RecordDate: "2024/01/01", TimePeriod: "MORNING", PulseRate: intPtr(70)}},
+		{"bad period", dto.CreateHealthRecordRequest{RecordDate: "2024-01-01", TimePeriod: "NOON", PulseRate: intPtr(70)}},
+		{"no measurement", dto.CreateHealthRecordRequest{RecordDate: "2024-01-01", TimePeriod: "MORNING"}},
+		{"systolic only", dto.CreateHealthRecordRequest{RecordDate: "2024-01-01", TimePeriod: "MORNING", SystolicBP: intPtr(120)}},
+		{"systolic range", dto.CreateHealthRecordRequest{RecordDate: "2024-01-01", TimePeriod: "MORNING", SystolicBP: intPtr(400), DiastolicBP: intPtr(80)}},
+		{"diastolic above systolic", dto.CreateHealthRecordRequest{RecordDate: "2024-01-01", TimePeriod: "MORNING", SystolicBP: intPtr(90), DiastolicBP: intPtr(100)}},
+		{"pulse range", dto.CreateHealthRecordRequest{RecordDate: "2024-01-01", TimePeriod: "MORNING", PulseRate: intPtr(10)}},
+		{"weight range", dto.CreateHealthRecordRequest{RecordDate: "2024-01-01", TimePeriod: "MORNING", WeightKG: &weight}},
+	}
+	for _, tc := range cases {
+		if _, err := svc.CreateHealthRecord(context.Background(), userID, tc.req); err == nil {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestHealthServiceCreateRecord(t *testing.T) {
+	repo := &healthRepoStub{}
+	svc := NewHealthService(repo)
+
+	resp, err := svc.CreateHealthRecord(context.Background(), uuid.New().String(), dto.CreateHealthRecordRequest{
+		RecordDate:  "2024-01-01",
+		TimePeriod:  " before_bed ",
+		SystolicBP:  intPtr(130),
+		DiastolicBP: intPtr(85),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.TimePeriod != constants.HealthTimePeriodBeforeBed || resp.RecordDate != "2024-01-01" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if repo.record == nil || repo.record.TimePeriod != constants.HealthTimePeriodBeforeBed {
+		t.Fatalf("expected normalized time_period stored")
+	}
+}
+
+func TestHealthServiceCreateDailyAssessment(t *testing.T) {
+	repo := &healthRepoStub{}
+	svc := NewHealthService(repo)
+	userID := uuid.New().String()
+
+	if _, err := svc.CreateDailyAssessment(context.Background(), userID, dto.CreateDailyAssessmentRequest{LogDate: "2024-01-01", StressLevel: intPtr(11)}); err == nil {
+		t.Fatalf("expected stress_level error")
+	}
+	if _, err := svc.CreateDailyAssessment(context.Background(), userID, dto.CreateDailyAssessmentRequest{LogDate: "2024-01-01", ExerciseMinutes: intPtr(-1)}); err == nil {
+		t.Fatalf("expected exercise_minutes error")
+	}
+
+	resp, err := svc.CreateDailyAssessment(context.Background(), userID, dto.CreateDailyAssessmentRequest{
+		LogDate:  "2024-01-01",
+		Symptoms: map[string]any{"headache": true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(repo.assessment.Symptoms) != `{"headache":true}` {
+		t.Fatalf("unexpected symptoms stored: %s", repo.assessment.Symptoms)
+	}
+	if symptoms, ok := resp.Symptoms.(map[string]any); !ok || symptoms["headache"] != true {
+		t.Fatalf("unexpected symptoms response: %#v", resp.Symptoms)
+	}
+
+	repo.createErr = domain.NewError(constants.HealthConflict, "daily assessment already exists for log_date")
+	_, err = svc.CreateDailyAssessment(context.Background(), userID, dto.CreateDailyAssessmentRequest{LogDate: "2024-01-01"})
+	var appErr *domain.AppError
+	if !errors.As(err, &appErr) || appErr.Code != constants.HealthConflict {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestHealthServiceListDateRange(t *testing.T) {
+	repo := &healthRepoStub{}
+	svc := NewHealthService(repo)
+	userID := uuid.New().String()
+
+	if _, err := svc.ListHealthRecords(context.Background(), userID, "2024-02-01", "2024-01-01"); err == nil {
+		t.Fatalf("expected range error")
+	}
+	if _, err := svc.ListHealthRecords(context.Background(), userID, "bad", ""); err == nil {
+		t.Fatalf("expected invalid from error")
+	}
+
+	items, err := svc.ListDailyAssessments(context.Background(), userID, "2024-01-01", "2024-01-31")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.from.Format("2006-01-02") != "2024-01-01" || repo.to.Format("2006-01-02") != "2024-01-31" {
+		t.Fatalf("unexpected range passed: %v %v", repo.from, repo.to)
+	}
+	if len(items) != 1 || items[0].Symptoms == nil {
+		t.Fatalf("expected symptoms decoded")
+	}
+}
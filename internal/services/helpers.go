@@ -1,6 +1,13 @@
 package services
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+)
 
 func stringPtr(id *uuid.UUID) *string {
 	if id == nil {
@@ -18,3 +25,27 @@ func isAllowed(value string, allowed []string) bool {
 	}
 	return false
 }
+
+// parseDateRange parses optional YYYY-MM-DD from/to filters. Empty values yield zero times.
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	var fromDate time.Time
+	var toDate time.Time
+	if from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return time.Time{}, time.Time{}, domain.NewError(constants.ValidationFailed, "invalid from")
+		}
+		fromDate = parsed.UTC()
+	}
+	if to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return time.Time{}, time.Time{}, domain.NewError(constants.ValidationFailed, "invalid to")
+		}
+		toDate = parsed.UTC()
+	}
+	if !fromDate.IsZero() && !toDate.IsZero() && toDate.Before(fromDate) {
+		return time.Time{}, time.Time{}, domain.NewError(constants.ValidationFailed, "to must not be before from")
+	}
+	return fromDate, toDate, nil
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/ParkPawapon/mhp-be/internal/config"
 	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/domain"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
@@ -15,18 +17,49 @@ import (
 
 type IntakeService interface {
 	CreateIntake(ctx context.Context, userID string, req dto.CreateIntakeRequest) (dto.IntakeHistoryResponse, error)
+	UpdateIntake(ctx context.Context, userID string, id string, req dto.UpdateIntakeRequest) (dto.IntakeHistoryResponse, error)
+	DeleteIntake(ctx context.Context, userID string, id string) error
+	ListChanges(ctx context.Context, userID string, id string) ([]dto.IntakeChangeResponse, error)
 	ListHistory(ctx context.Context, userID string, from, to string) ([]dto.IntakeHistoryResponse, error)
+	GetToday(ctx context.Context, userID string, date string) (dto.TodayChecklistResponse, error)
+	MarkMissedDoses(ctx context.Context) (int64, error)
 }
 
+const missedDoseBatchSize = 500
+
+// takenAtClockSkew tolerates device clocks running slightly ahead of the
+// server when a client reports taken_at.
+const takenAtClockSkew = 5 * time.Minute
+
+// maxCorrectionAttempts bounds how often a correction is re-applied after
+// losing a race with another change to the same record.
+const maxCorrectionAttempts = 3
+
 type intakeService struct {
-	repo   repositories.IntakeRepository
-	notify NotificationService
+	repo      repositories.IntakeRepository
+	medicines repositories.MedicineRepository
+	notify    NotificationService
+	refills   RefillService
+	cfg       config.IntakeConfig
+	locations userLocations
+	now       func() time.Time
 }
 
-func NewIntakeService(repo repositories.IntakeRepository, notify NotificationService) IntakeService {
-	return &intakeService{repo: repo, notify: notify}
+func NewIntakeService(repo repositories.IntakeRepository, medicines repositories.MedicineRepository, prefs repositories.PreferenceRepository, notify NotificationService, refills RefillService, cfg config.IntakeConfig, timezone string) IntakeService {
+	return &intakeService{
+		repo:      repo,
+		medicines: medicines,
+		notify:    notify,
+		refills:   refills,
+		cfg:       cfg,
+		locations: newUserLocations(prefs, timezone),
+		now:       time.Now,
+	}
 }
 
+// CreateIntake records a dose. A scheduled dose has at most one record per
+// target date, so repeating the call corrects the existing record instead of
+// adding another one.
 func (s *intakeService) CreateIntake(ctx context.Context, userID string, req dto.CreateIntakeRequest) (dto.IntakeHistoryResponse, error) {
 	uid, err := uuid.Parse(userID)
 	if err != nil {
@@ -39,6 +72,10 @@ func (s *intakeService) CreateIntake(ctx context.Context, userID string, req dto
 	}
 	targetDate = targetDate.UTC()
 
+	if !isAllowed(string(req.Status), constants.IntakeStatuses) {
+		return dto.IntakeHistoryResponse{}, domain.NewError(constants.ValidationFailed, "invalid status")
+	}
+
 	var scheduleID *uuid.UUID
 	if req.ScheduleID != nil {
 		id, err := uuid.Parse(*req.ScheduleID)
@@ -46,11 +83,32 @@ func (s *intakeService) CreateIntake(ctx context.Context, userID string, req dto
 			return dto.IntakeHistoryResponse{}, domain.NewError(constants.ValidationFailed, "invalid schedule_id")
 		}
 		scheduleID = &id
+		if err := s.checkScheduleOwner(ctx, uid, id); err != nil {
+			return dto.IntakeHistoryResponse{}, err
+		}
 	}
 
-	var takenAt *time.Time
-	if req.Status == constants.MedTaken {
-		now := time.Now().UTC()
+	dueAt, err := s.dueAt(ctx, uid, scheduleID, targetDate)
+	if err != nil {
+		return dto.IntakeHistoryResponse{}, err
+	}
+	takenAt, err := s.parseTakenAt(req.TakenAt, req.Status, dueAt)
+	if err != nil {
+		return dto.IntakeHistoryResponse{}, err
+	}
+
+	if scheduleID != nil {
+		existing, err := s.repo.FindByDose(ctx, uid, *scheduleID, targetDate)
+		if err != nil {
+			return dto.IntakeHistoryResponse{}, err
+		}
+		if existing != nil {
+			return s.correct(ctx, uid, existing, req.Status, req.SkipReason, takenAt)
+		}
+	}
+
+	if takenAt == nil && req.Status == constants.MedTaken {
+		now := s.now().UTC()
 		takenAt = &now
 	}
 
@@ -59,52 +117,378 @@ func (s *intakeService) CreateIntake(ctx context.Context, userID string, req dto
 		ScheduleID: scheduleID,
 		TargetDate: targetDate,
 		TakenAt:    takenAt,
+		DueAt:      dueAt,
+		Timing:     s.classifyTiming(dueAt, takenAt),
 		Status:     req.Status,
 		SkipReason: req.SkipReason,
 	}
 
 	if err := s.repo.Create(ctx, record); err != nil {
+		// A concurrent request recorded the same dose first.
+		if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.IntakeConflict && scheduleID != nil {
+			existing, findErr := s.repo.FindByDose(ctx, uid, *scheduleID, targetDate)
+			if findErr != nil || existing == nil {
+				return dto.IntakeHistoryResponse{}, err
+			}
+			return s.correct(ctx, uid, existing, req.Status, req.SkipReason, takenAt)
+		}
 		return dto.IntakeHistoryResponse{}, err
 	}
 
 	if req.Status == constants.MedTaken && scheduleID != nil && s.notify != nil {
 		_ = s.notify.CancelMedicineAfterMealReminder(ctx, uid, *scheduleID, targetDate)
 	}
+	if req.Status == constants.MedTaken {
+		s.consumeStock(ctx, uid, scheduleID, 1)
+	}
 
-	return dto.IntakeHistoryResponse{
-		ID:         record.ID.String(),
-		UserID:     record.UserID.String(),
-		ScheduleID: stringPtr(scheduleID),
-		TargetDate: record.TargetDate.Format("2006-01-02"),
-		TakenAt:    record.TakenAt,
-		Status:     record.Status,
-		SkipReason: record.SkipReason,
-		CreatedAt:  record.CreatedAt,
-	}, nil
+	return toIntakeResponse(*record), nil
 }
 
-func (s *intakeService) ListHistory(ctx context.Context, userID string, from, to string) ([]dto.IntakeHistoryResponse, error) {
+// UpdateIntake corrects the status of the caller's own record within the
+// correction window.
+func (s *intakeService) UpdateIntake(ctx context.Context, userID string, id string, req dto.UpdateIntakeRequest) (dto.IntakeHistoryResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.IntakeHistoryResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+	if !isAllowed(string(req.Status), constants.IntakeStatuses) {
+		return dto.IntakeHistoryResponse{}, domain.NewError(constants.ValidationFailed, "invalid status")
+	}
+
+	record, err := s.ownedIntake(ctx, uid, id)
+	if err != nil {
+		return dto.IntakeHistoryResponse{}, err
+	}
+	if record.DueAt == nil {
+		if record.DueAt, err = s.dueAt(ctx, record.UserID, record.ScheduleID, record.TargetDate); err != nil {
+			return dto.IntakeHistoryResponse{}, err
+		}
+	}
+	takenAt, err := s.parseTakenAt(req.TakenAt, req.Status, record.DueAt)
+	if err != nil {
+		return dto.IntakeHistoryResponse{}, err
+	}
+	return s.correct(ctx, uid, record, req.Status, req.SkipReason, takenAt)
+}
+
+// DeleteIntake removes the caller's own record within the correction window;
+// the removed values are kept in the change history.
+func (s *intakeService) DeleteIntake(ctx context.Context, userID string, id string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	record, err := s.ownedIntake(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCorrectionWindow(record); err != nil {
+		return err
+	}
+
+	change := &db.IntakeChange{
+		IntakeID:           record.ID,
+		UserID:             record.UserID,
+		ActorID:            &uid,
+		Action:             constants.IntakeChangeDelete,
+		PreviousStatus:     &record.Status,
+		PreviousTakenAt:    record.TakenAt,
+		PreviousSkipReason: record.SkipReason,
+	}
+	if err := s.repo.Delete(ctx, record.ID, change); err != nil {
+		return err
+	}
+
+	if record.Status == constants.MedTaken && record.ScheduleID != nil && s.notify != nil {
+		_ = s.notify.RestoreMedicineAfterMealReminder(ctx, record.UserID, *record.ScheduleID, record.TargetDate)
+	}
+	if record.Status == constants.MedTaken {
+		s.consumeStock(ctx, record.UserID, record.ScheduleID, -1)
+	}
+	return nil
+}
+
+// ListChanges returns the correction history of one of userID's records.
+func (s *intakeService) ListChanges(ctx context.Context, userID string, id string) ([]dto.IntakeChangeResponse, error) {
 	uid, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, domain.NewError(constants.ValidationFailed, "invalid user_id")
 	}
+	intakeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
 
-	var fromDate time.Time
-	var toDate time.Time
-	if from != "" {
-		if parsed, err := time.Parse("2006-01-02", from); err == nil {
-			fromDate = parsed.UTC()
-		} else {
-			return nil, domain.NewError(constants.ValidationFailed, "invalid from")
+	items, err := s.repo.ListChanges(ctx, intakeID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.IntakeChangeResponse, 0, len(items))
+	for _, item := range items {
+		// Changes outlive deleted records, so ownership is checked per row.
+		if item.UserID != uid {
+			continue
 		}
+		resp = append(resp, dto.IntakeChangeResponse{
+			ID:                 item.ID.String(),
+			IntakeID:           item.IntakeID.String(),
+			ActorID:            stringPtr(item.ActorID),
+			Action:             item.Action,
+			PreviousStatus:     item.PreviousStatus,
+			NewStatus:          item.NewStatus,
+			PreviousTakenAt:    item.PreviousTakenAt,
+			NewTakenAt:         item.NewTakenAt,
+			PreviousSkipReason: item.PreviousSkipReason,
+			NewSkipReason:      item.NewSkipReason,
+			CreatedAt:          item.CreatedAt,
+		})
 	}
-	if to != "" {
-		if parsed, err := time.Parse("2006-01-02", to); err == nil {
-			toDate = parsed.UTC()
-		} else {
-			return nil, domain.NewError(constants.ValidationFailed, "invalid to")
+	return resp, nil
+}
+
+func (s *intakeService) ownedIntake(ctx context.Context, userID uuid.UUID, id string) (*db.IntakeHistory, error) {
+	intakeID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+	record, err := s.repo.GetByID(ctx, intakeID)
+	if err != nil {
+		return nil, err
+	}
+	if record.UserID != userID {
+		return nil, domain.NewError(constants.IntakeNotFound, "intake not found")
+	}
+	return record, nil
+}
+
+func (s *intakeService) checkCorrectionWindow(record *db.IntakeHistory) error {
+	if s.cfg.CorrectionWindow > 0 && s.now().After(record.CreatedAt.Add(s.cfg.CorrectionWindow)) {
+		return domain.NewError(constants.IntakeCorrectionClosed, "correction window has closed")
+	}
+	return nil
+}
+
+// correct moves record to status, logging the change and keeping the
+// after-meal reminder in step with whether the dose is TAKEN. takenAt, when
+// set, replaces the recorded time. Repeating the current values is a no-op.
+// When another request changed the record first, the correction is applied
+// again to the record as it now stands, so two identical corrections racing
+// each other take effect once.
+func (s *intakeService) correct(ctx context.Context, actorID uuid.UUID, record *db.IntakeHistory, status constants.MedIntakeStatus, skipReason *string, takenAt *time.Time) (dto.IntakeHistoryResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := s.correctOnce(ctx, actorID, record, status, skipReason, takenAt)
+		appErr, ok := domain.AsAppError(err)
+		if !ok || appErr.Code != constants.IntakeConflict || attempt == maxCorrectionAttempts {
+			return resp, err
+		}
+		if record, err = s.repo.GetByID(ctx, record.ID); err != nil {
+			return dto.IntakeHistoryResponse{}, err
 		}
 	}
+}
+
+func (s *intakeService) correctOnce(ctx context.Context, actorID uuid.UUID, record *db.IntakeHistory, status constants.MedIntakeStatus, skipReason *string, takenAt *time.Time) (dto.IntakeHistoryResponse, error) {
+	if record.Status == status && equalStringPtr(record.SkipReason, skipReason) && (takenAt == nil || equalTimePtr(record.TakenAt, takenAt)) {
+		return toIntakeResponse(*record), nil
+	}
+	if err := s.checkCorrectionWindow(record); err != nil {
+		return dto.IntakeHistoryResponse{}, err
+	}
+
+	previous := *record
+	now := s.now().UTC()
+	switch {
+	case status != constants.MedTaken:
+		record.TakenAt = nil
+	case takenAt != nil:
+		record.TakenAt = takenAt
+	case previous.Status != constants.MedTaken:
+		record.TakenAt = &now
+	}
+	if record.DueAt == nil {
+		due, err := s.dueAt(ctx, record.UserID, record.ScheduleID, record.TargetDate)
+		if err != nil {
+			return dto.IntakeHistoryResponse{}, err
+		}
+		record.DueAt = due
+	}
+	record.Timing = s.classifyTiming(record.DueAt, record.TakenAt)
+	record.Status = status
+	record.SkipReason = skipReason
+	record.UpdatedAt = now
+
+	change := &db.IntakeChange{
+		IntakeID:           record.ID,
+		UserID:             record.UserID,
+		ActorID:            &actorID,
+		Action:             constants.IntakeChangeUpdate,
+		PreviousStatus:     &previous.Status,
+		NewStatus:          &record.Status,
+		PreviousTakenAt:    previous.TakenAt,
+		NewTakenAt:         record.TakenAt,
+		PreviousSkipReason: previous.SkipReason,
+		NewSkipReason:      record.SkipReason,
+	}
+	if err := s.repo.Correct(ctx, record, change); err != nil {
+		return dto.IntakeHistoryResponse{}, err
+	}
+
+	if record.ScheduleID != nil && s.notify != nil {
+		switch {
+		case status == constants.MedTaken && previous.Status != constants.MedTaken:
+			_ = s.notify.CancelMedicineAfterMealReminder(ctx, record.UserID, *record.ScheduleID, record.TargetDate)
+		case status != constants.MedTaken && previous.Status == constants.MedTaken:
+			_ = s.notify.RestoreMedicineAfterMealReminder(ctx, record.UserID, *record.ScheduleID, record.TargetDate)
+		}
+	}
+	switch {
+	case status == constants.MedTaken && previous.Status != constants.MedTaken:
+		s.consumeStock(ctx, record.UserID, record.ScheduleID, 1)
+	case status != constants.MedTaken && previous.Status == constants.MedTaken:
+		s.consumeStock(ctx, record.UserID, record.ScheduleID, -1)
+	}
+	return toIntakeResponse(*record), nil
+}
+
+// consumeStock keeps the medicine's stock on hand in step with a dose becoming
+// TAKEN (doses 1) or no longer TAKEN (doses -1). Stock is advisory, so a
+// failure here does not fail the intake.
+func (s *intakeService) consumeStock(ctx context.Context, userID uuid.UUID, scheduleID *uuid.UUID, doses float64) {
+	if scheduleID == nil || s.refills == nil {
+		return
+	}
+	_ = s.refills.ConsumeDose(ctx, userID, *scheduleID, doses)
+}
+
+// checkScheduleOwner rejects a schedule of another patient as if the dose did
+// not exist, so intake cannot be recorded against someone else's medicine.
+func (s *intakeService) checkScheduleOwner(ctx context.Context, userID, scheduleID uuid.UUID) error {
+	if s.medicines == nil {
+		return nil
+	}
+	schedule, err := s.medicines.GetScheduleByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+	med, err := s.medicines.GetPatientMedicineByID(ctx, schedule.PatientMedicineID)
+	if err != nil {
+		return err
+	}
+	if med.UserID != userID {
+		return domain.NewError(constants.IntakeNotFound, "medicine schedule not found")
+	}
+	return nil
+}
+
+func toIntakeResponse(item db.IntakeHistory) dto.IntakeHistoryResponse {
+	return dto.IntakeHistoryResponse{
+		ID:         item.ID.String(),
+		UserID:     item.UserID.String(),
+		ScheduleID: stringPtr(item.ScheduleID),
+		TargetDate: item.TargetDate.Format("2006-01-02"),
+		TakenAt:    item.TakenAt,
+		DueAt:      item.DueAt,
+		Timing:     item.Timing,
+		Status:     item.Status,
+		SkipReason: item.SkipReason,
+		CreatedAt:  item.CreatedAt,
+	}
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// dueAt returns when a scheduled dose was due on targetDate in the patient's
+// timezone, or nil for doses without a schedule.
+func (s *intakeService) dueAt(ctx context.Context, userID uuid.UUID, scheduleID *uuid.UUID, targetDate time.Time) (*time.Time, error) {
+	if scheduleID == nil || s.medicines == nil {
+		return nil, nil
+	}
+	schedule, err := s.medicines.GetScheduleByID(ctx, *scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	location, err := s.locations.forUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	due := doseDueAt(targetDate, schedule.TimeSlot, location).UTC()
+	return &due, nil
+}
+
+// parseTakenAt validates a client-reported taken_at: RFC3339, only for TAKEN
+// doses, not in the future and within MaxTakenOffset of the dose's time slot.
+func (s *intakeService) parseTakenAt(value *string, status constants.MedIntakeStatus, dueAt *time.Time) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if status != constants.MedTaken {
+		return nil, domain.NewError(constants.ValidationFailed, "taken_at is only allowed for TAKEN")
+	}
+	takenAt, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid taken_at")
+	}
+	takenAt = takenAt.UTC()
+	if takenAt.After(s.now().Add(takenAtClockSkew)) {
+		return nil, domain.NewError(constants.ValidationFailed, "taken_at must not be in the future")
+	}
+	if dueAt != nil && s.cfg.MaxTakenOffset > 0 {
+		offset := takenAt.Sub(*dueAt)
+		if offset > s.cfg.MaxTakenOffset || offset < -s.cfg.MaxTakenOffset {
+			return nil, domain.WithDetails(domain.NewError(constants.ValidationFailed, "taken_at is too far from the scheduled time"),
+				map[string]any{"due_at": dueAt.Format(time.RFC3339)})
+		}
+	}
+	return &takenAt, nil
+}
+
+// classifyTiming compares a TAKEN dose with when it was due; doses without
+// both times have no classification.
+func (s *intakeService) classifyTiming(dueAt, takenAt *time.Time) *string {
+	if dueAt == nil || takenAt == nil {
+		return nil
+	}
+	timing := constants.IntakeTimingOnTime
+	switch offset := takenAt.Sub(*dueAt); {
+	case offset > s.cfg.OnTimeWindow:
+		timing = constants.IntakeTimingLate
+	case offset < -s.cfg.OnTimeWindow:
+		timing = constants.IntakeTimingEarly
+	}
+	return &timing
+}
+
+// doseDueAt is the local time slot of a dose on day, a calendar date encoded
+// as UTC midnight.
+func doseDueAt(day, timeSlot time.Time, location *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), timeSlot.Hour(), timeSlot.Minute(), 0, 0, location)
+}
+
+func (s *intakeService) ListHistory(ctx context.Context, userID string, from, to string) ([]dto.IntakeHistoryResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	fromDate, toDate, err := parseDateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
 
 	items, err := s.repo.ListHistory(ctx, uid, fromDate, toDate)
 	if err != nil {
@@ -113,16 +497,144 @@ func (s *intakeService) ListHistory(ctx context.Context, userID string, from, to
 
 	resp := make([]dto.IntakeHistoryResponse, 0, len(items))
 	for _, item := range items {
-		resp = append(resp, dto.IntakeHistoryResponse{
-			ID:         item.ID.String(),
-			UserID:     item.UserID.String(),
-			ScheduleID: stringPtr(item.ScheduleID),
-			TargetDate: item.TargetDate.Format("2006-01-02"),
-			TakenAt:    item.TakenAt,
-			Status:     item.Status,
-			SkipReason: item.SkipReason,
-			CreatedAt:  item.CreatedAt,
-		})
+		resp = append(resp, toIntakeResponse(item))
 	}
 	return resp, nil
 }
+
+// GetToday lists the doses expected on date (the patient's local today when
+// empty) with their latest intake status; unrecorded doses are PENDING.
+func (s *intakeService) GetToday(ctx context.Context, userID string, date string) (dto.TodayChecklistResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.TodayChecklistResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+
+	location, err := s.locations.forUser(ctx, uid)
+	if err != nil {
+		return dto.TodayChecklistResponse{}, err
+	}
+	day := localDate(s.now(), location)
+	if date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return dto.TodayChecklistResponse{}, domain.NewError(constants.ValidationFailed, "invalid date")
+		}
+		day = parsed.UTC()
+	}
+
+	schedules, err := s.medicines.ListScheduleHistory(ctx, uid)
+	if err != nil {
+		return dto.TodayChecklistResponse{}, err
+	}
+	intakes, err := s.repo.ListHistory(ctx, uid, day, day)
+	if err != nil {
+		return dto.TodayChecklistResponse{}, err
+	}
+
+	latest := make(map[uuid.UUID]db.IntakeHistory, len(intakes))
+	for _, item := range intakes {
+		if item.ScheduleID == nil {
+			continue
+		}
+		if prev, ok := latest[*item.ScheduleID]; !ok || item.CreatedAt.After(prev.CreatedAt) {
+			latest[*item.ScheduleID] = item
+		}
+	}
+
+	items := make([]dto.TodayDoseItem, 0, len(schedules))
+	for _, row := range schedules {
+		if !expectsDose(row, day, location) {
+			continue
+		}
+
+		item := dto.TodayDoseItem{
+			ScheduleID:        row.ScheduleID.String(),
+			PatientMedicineID: row.PatientMedicineID.String(),
+			MedicineName:      row.MedicineName,
+			DosageAmount:      row.DosageAmount,
+			Instruction:       row.Instruction,
+			TimeSlot:          row.TimeSlot.Format("15:04"),
+			MealTiming:        row.MealTiming,
+			Status:            constants.MedPending,
+		}
+		if record, ok := latest[row.ScheduleID]; ok {
+			item.Status = record.Status
+			item.IntakeID = stringPtr(&record.ID)
+			item.TakenAt = record.TakenAt
+			item.Timing = record.Timing
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].TimeSlot < items[j].TimeSlot })
+
+	return dto.TodayChecklistResponse{
+		UserID: uid.String(),
+		Date:   day.Format("2006-01-02"),
+		Items:  items,
+	}, nil
+}
+
+// MarkMissedDoses records MISSED for every expected dose of an active schedule
+// whose time slot passed more than the grace window ago without an intake
+// record. Doses are evaluated on the patient's local calendar, and only the
+// last MissedLookbackDays days are considered. Reruns insert nothing new.
+func (s *intakeService) MarkMissedDoses(ctx context.Context) (int64, error) {
+	if s.medicines == nil {
+		return 0, nil
+	}
+
+	now := s.now()
+	var total int64
+	after := uuid.Nil
+	for {
+		rows, err := s.medicines.ListActiveSchedules(ctx, after, missedDoseBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		doses := make([]repositories.MissedDose, 0, len(rows))
+		for _, row := range rows {
+			doses = append(doses, s.overdueDoses(row, s.locations.resolve(row.Timezone), now)...)
+		}
+		inserted, err := s.repo.CreateMissed(ctx, doses)
+		if err != nil {
+			return total, err
+		}
+		total += inserted
+
+		if len(rows) < missedDoseBatchSize {
+			return total, nil
+		}
+		after = rows[len(rows)-1].ID
+	}
+}
+
+func (s *intakeService) overdueDoses(row repositories.ActiveScheduleRow, location *time.Location, now time.Time) []repositories.MissedDose {
+	lookback := s.cfg.MissedLookbackDays
+	if lookback < 0 {
+		lookback = 0
+	}
+	grace := s.cfg.MissedGrace
+	if grace < 0 {
+		grace = 0
+	}
+
+	rule := scheduleRecurrence(row.MedicineSchedule)
+	today := localDate(now, location)
+
+	var doses []repositories.MissedDose
+	for i := lookback; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i)
+		if !rule.occursOn(day) {
+			continue
+		}
+		due := doseDueAt(day, row.TimeSlot, location)
+		// Doses due before the schedule existed were never expected.
+		if due.Before(row.CreatedAt) || now.Before(due.Add(grace)) {
+			continue
+		}
+		doses = append(doses, repositories.MissedDose{UserID: row.UserID, ScheduleID: row.ID, TargetDate: day, DueAt: due.UTC()})
+	}
+	return doses
+}
@@ -7,32 +7,91 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ParkPawapon/mhp-be/internal/config"
 	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
 	"github.com/ParkPawapon/mhp-be/internal/repositories"
 )
 
 type fakeIntakeRepo struct {
-	created *db.IntakeHistory
+	created   *db.IntakeHistory
+	createErr error
+	existing  *db.IntakeHistory
+	// hiddenLookups makes the first FindByDose calls miss existing, as when
+	// a concurrent request inserts the dose.
+	hiddenLookups int
+	history       []db.IntakeHistory
+	missed        []repositories.MissedDose
+	corrected     *db.IntakeHistory
+	// raced makes the next Correct lose to a concurrent request that stored
+	// raced first.
+	raced   *db.IntakeHistory
+	deleted uuid.UUID
+	changes []db.IntakeChange
 }
 
 func (f *fakeIntakeRepo) Create(ctx context.Context, intake *db.IntakeHistory) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
 	f.created = intake
 	return nil
 }
 
+func (f *fakeIntakeRepo) GetByID(ctx context.Context, id uuid.UUID) (*db.IntakeHistory, error) {
+	if f.existing == nil || f.existing.ID != id {
+		return nil, domain.NewError(constants.IntakeNotFound, "intake not found")
+	}
+	return f.existing, nil
+}
+
+func (f *fakeIntakeRepo) FindByDose(ctx context.Context, userID, scheduleID uuid.UUID, targetDate time.Time) (*db.IntakeHistory, error) {
+	if f.hiddenLookups > 0 {
+		f.hiddenLookups--
+		return nil, nil
+	}
+	return f.existing, nil
+}
+
+func (f *fakeIntakeRepo) Correct(ctx context.Context, intake *db.IntakeHistory, change *db.IntakeChange) error {
+	if f.raced != nil {
+		f.existing, f.raced = f.raced, nil
+		return domain.NewError(constants.IntakeConflict, "intake was changed by another request")
+	}
+	f.corrected = intake
+	f.changes = append(f.changes, *change)
+	return nil
+}
+
+func (f *fakeIntakeRepo) Delete(ctx context.Context, id uuid.UUID, change *db.IntakeChange) error {
+	f.deleted = id
+	f.changes = append(f.changes, *change)
+	return nil
+}
+
+func (f *fakeIntakeRepo) ListChanges(ctx context.Context, intakeID uuid.UUID) ([]db.IntakeChange, error) {
+	return f.changes, nil
+}
+
 func (f *fakeIntakeRepo) ListHistory(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]db.IntakeHistory, error) {
-	return nil, nil
+	return f.history, nil
+}
+
+func (f *fakeIntakeRepo) CreateMissed(ctx context.Context, doses []repositories.MissedDose) (int64, error) {
+	f.missed = append(f.missed, doses...)
+	return int64(len(doses)), nil
 }
 
 type fakeNotificationService struct {
-	cancelCalled bool
-	gotSchedule  uuid.UUID
-	gotDate      time.Time
+	cancelCalled  bool
+	restoreCalled bool
+	gotSchedule   uuid.UUID
+	gotDate       time.Time
 }
 
-func (f *fakeNotificationService) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, mealTiming *string, timeSlot time.Time) error {
+func (f *fakeNotificationService) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
 	return nil
 }
 
@@ -42,10 +101,28 @@ func (f *fakeNotificationService) CancelMedicineAfterMealReminder(ctx context.Co
 	f.gotDate = targetDate
 	return nil
 }
+func (f *fakeNotificationService) RestoreMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
+	f.restoreCalled = true
+	f.gotSchedule = scheduleID
+	f.gotDate = targetDate
+	return nil
+}
+func (f *fakeNotificationService) RescheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+	panic("not used")
+}
+func (f *fakeNotificationService) BuildMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) ([]db.NotificationEvent, error) {
+	panic("not used")
+}
+func (f *fakeNotificationService) CancelMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	panic("not used")
+}
 
 func (f *fakeNotificationService) ScheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
 	return nil
 }
+func (f *fakeNotificationService) RescheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
+	return nil
+}
 
 func (f *fakeNotificationService) CancelAppointmentReminders(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
 	return nil
@@ -66,13 +143,40 @@ func (f *fakeNotificationService) ProcessDue(ctx context.Context) error {
 func (f *fakeNotificationService) CancelWeeklyReminders(ctx context.Context, userID uuid.UUID) error {
 	return nil
 }
+func (f *fakeNotificationService) RescheduleWeeklyReminder(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+func (f *fakeNotificationService) ListFailedEvents(ctx context.Context, page, pageSize int, status, userID, templateCode string) ([]dto.NotificationEventResponse, int64, error) {
+	return nil, 0, nil
+}
+func (f *fakeNotificationService) RequeueEvent(ctx context.Context, id string) (dto.NotificationEventResponse, error) {
+	return dto.NotificationEventResponse{}, nil
+}
+func (f *fakeNotificationService) ListInbox(ctx context.Context, userID uuid.UUID, cursor string, limit int, unreadOnly bool) (dto.NotificationInboxResponse, error) {
+	return dto.NotificationInboxResponse{}, nil
+}
+func (f *fakeNotificationService) UnreadCount(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (f *fakeNotificationService) MarkRead(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (f *fakeNotificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (f *fakeNotificationService) ArchiveInboxItem(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (f *fakeNotificationService) Channels() []string {
+	panic("not used")
+}
 
 var _ repositories.IntakeRepository = (*fakeIntakeRepo)(nil)
 
 func TestCreateIntakeCancelsAfterMealReminderWhenTaken(t *testing.T) {
 	repo := &fakeIntakeRepo{}
 	notify := &fakeNotificationService{}
-	svc := NewIntakeService(repo, notify)
+	svc := NewIntakeService(repo, nil, nil, notify, nil, config.IntakeConfig{}, "UTC")
 
 	scheduleID := uuid.New().String()
 	userID := uuid.New().String()
@@ -100,3 +204,487 @@ func TestCreateIntakeCancelsAfterMealReminderWhenTaken(t *testing.T) {
 		t.Fatalf("expected target date 2026-01-20, got %s", notify.gotDate.Format("2006-01-02"))
 	}
 }
+
+func newTakenIntake(userID uuid.UUID, createdAt time.Time) *db.IntakeHistory {
+	scheduleID := uuid.New()
+	takenAt := createdAt
+	return &db.IntakeHistory{
+		ID:         uuid.New(),
+		UserID:     userID,
+		ScheduleID: &scheduleID,
+		TargetDate: time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC),
+		TakenAt:    &takenAt,
+		Status:     constants.MedTaken,
+		CreatedAt:  createdAt,
+	}
+}
+
+func TestCreateIntakeRepeatedPostCorrectsExistingDose(t *testing.T) {
+	now := time.Date(2026, 1, 20, 3, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	existing := newTakenIntake(userID, now.Add(-time.Hour))
+	repo := &fakeIntakeRepo{existing: existing}
+	notify := &fakeNotificationService{}
+	svc := NewIntakeService(repo, nil, nil, notify, nil, config.IntakeConfig{CorrectionWindow: 24 * time.Hour}, "UTC").(*intakeService)
+	svc.now = func() time.Time { return now }
+
+	scheduleID := existing.ScheduleID.String()
+	req := dto.CreateIntakeRequest{ScheduleID: &scheduleID, TargetDate: "2026-01-20", Status: constants.MedTaken}
+	resp, err := svc.CreateIntake(context.Background(), userID.String(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ID != existing.ID.String() || repo.created != nil || repo.corrected != nil || notify.cancelCalled {
+		t.Fatalf("expected repeated TAKEN to be a no-op, got %+v", resp)
+	}
+
+	reason := "felt dizzy"
+	req.Status = constants.MedSkipped
+	req.SkipReason = &reason
+	resp, err = svc.CreateIntake(context.Background(), userID.String(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.created != nil || repo.corrected == nil || resp.Status != constants.MedSkipped || resp.TakenAt != nil {
+		t.Fatalf("expected existing dose corrected to SKIPPED, got %+v", resp)
+	}
+	if len(repo.changes) != 1 {
+		t.Fatalf("expected one change, got %d", len(repo.changes))
+	}
+	change := repo.changes[0]
+	if change.Action != constants.IntakeChangeUpdate || *change.PreviousStatus != constants.MedTaken || *change.NewStatus != constants.MedSkipped || change.PreviousTakenAt == nil || change.ActorID == nil || *change.ActorID != userID {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	if !notify.restoreCalled || notify.gotSchedule != *existing.ScheduleID {
+		t.Fatalf("expected after-meal reminder restored")
+	}
+}
+
+func TestCreateIntakeConcurrentDuplicateFallsBackToCorrection(t *testing.T) {
+	userID := uuid.New()
+	existing := newTakenIntake(userID, time.Now())
+	existing.Status = constants.MedMissed
+	existing.TakenAt = nil
+	repo := &fakeIntakeRepo{
+		existing:      existing,
+		hiddenLookups: 1,
+		createErr:     domain.NewError(constants.IntakeConflict, "intake already recorded for this dose"),
+	}
+	notify := &fakeNotificationService{}
+	svc := NewIntakeService(repo, nil, nil, notify, nil, config.IntakeConfig{CorrectionWindow: time.Hour}, "UTC")
+
+	scheduleID := existing.ScheduleID.String()
+	resp, err := svc.CreateIntake(context.Background(), userID.String(), dto.CreateIntakeRequest{ScheduleID: &scheduleID, TargetDate: "2026-01-20", Status: constants.MedTaken})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ID != existing.ID.String() || resp.Status != constants.MedTaken || resp.TakenAt == nil {
+		t.Fatalf("expected MISSED dose corrected to TAKEN, got %+v", resp)
+	}
+	if !notify.cancelCalled {
+		t.Fatalf("expected after-meal reminder cancelled")
+	}
+}
+
+type fakeRefillService struct {
+	consumed []float64
+}
+
+func (f *fakeRefillService) ConsumeDose(ctx context.Context, userID, scheduleID uuid.UUID, doses float64) error {
+	f.consumed = append(f.consumed, doses)
+	return nil
+}
+func (f *fakeRefillService) Refill(ctx context.Context, actorID uuid.UUID, role constants.Role, id string, req dto.RefillPatientMedicineRequest) (dto.PatientMedicineResponse, error) {
+	panic("not used")
+}
+func (f *fakeRefillService) ListRefillsNeeded(ctx context.Context, query dto.RefillListQuery, page, pageSize int) ([]dto.PatientRefillResponse, int64, error) {
+	panic("not used")
+}
+
+func TestIntakeStockFollowsTakenDoses(t *testing.T) {
+	now := time.Date(2026, 1, 20, 3, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	repo := &fakeIntakeRepo{}
+	refills := &fakeRefillService{}
+	svc := NewIntakeService(repo, nil, nil, nil, refills, config.IntakeConfig{CorrectionWindow: 24 * time.Hour}, "UTC").(*intakeService)
+	svc.now = func() time.Time { return now }
+
+	scheduleID := uuid.NewString()
+	req := dto.CreateIntakeRequest{ScheduleID: &scheduleID, TargetDate: "2026-01-20", Status: constants.MedTaken}
+	if _, err := svc.CreateIntake(context.Background(), userID.String(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	repo.existing = repo.created
+	repo.existing.ID = uuid.New()
+	repo.existing.CreatedAt = now
+	req.Status = constants.MedSkipped
+	if _, err := svc.CreateIntake(context.Background(), userID.String(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Status = constants.MedTaken
+	if _, err := svc.CreateIntake(context.Background(), userID.String(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.DeleteIntake(context.Background(), userID.String(), repo.existing.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []float64{1, -1, 1, -1}
+	if len(refills.consumed) != len(want) {
+		t.Fatalf("expected stock changes %v, got %v", want, refills.consumed)
+	}
+	for i := range want {
+		if refills.consumed[i] != want[i] {
+			t.Fatalf("expected stock changes %v, got %v", want, refills.consumed)
+		}
+	}
+}
+
+func TestUpdateIntakeReappliesCorrectionAfterLosingRace(t *testing.T) {
+	now := time.Date(2026, 1, 20, 3, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	scheduleID := uuid.New()
+	record := &db.IntakeHistory{ID: uuid.New(), UserID: userID, ScheduleID: &scheduleID, TargetDate: now, Status: constants.MedSkipped, CreatedAt: now}
+	taken := *record
+	taken.Status = constants.MedTaken
+	taken.TakenAt = &now
+	repo := &fakeIntakeRepo{existing: record, raced: &taken}
+	refills := &fakeRefillService{}
+	svc := NewIntakeService(repo, nil, nil, nil, refills, config.IntakeConfig{CorrectionWindow: 24 * time.Hour}, "UTC").(*intakeService)
+	svc.now = func() time.Time { return now }
+
+	// Another SKIPPED -> TAKEN correction won; this one finds the dose
+	// already TAKEN and changes nothing.
+	resp, err := svc.UpdateIntake(context.Background(), userID.String(), record.ID.String(), dto.UpdateIntakeRequest{Status: constants.MedTaken})
+	if err != nil || resp.Status != constants.MedTaken {
+		t.Fatalf("expected the dose TAKEN, got %+v err=%v", resp, err)
+	}
+	if len(refills.consumed) != 0 || len(repo.changes) != 0 {
+		t.Fatalf("expected no second stock change or history row, got stock=%v changes=%d", refills.consumed, len(repo.changes))
+	}
+}
+
+func TestUpdateIntakeEnforcesOwnerAndWindow(t *testing.T) {
+	now := time.Date(2026, 1, 20, 3, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	existing := newTakenIntake(userID, now.Add(-25*time.Hour))
+	repo := &fakeIntakeRepo{existing: existing}
+	svc := NewIntakeService(repo, nil, nil, &fakeNotificationService{}, nil, config.IntakeConfig{CorrectionWindow: 24 * time.Hour}, "UTC").(*intakeService)
+	svc.now = func() time.Time { return now }
+
+	req := dto.UpdateIntakeRequest{Status: constants.MedSkipped}
+	_, err := svc.UpdateIntake(context.Background(), uuid.NewString(), existing.ID.String(), req)
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.IntakeNotFound {
+		t.Fatalf("expected not found for another user, got %v", err)
+	}
+
+	_, err = svc.UpdateIntake(context.Background(), userID.String(), existing.ID.String(), req)
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.IntakeCorrectionClosed {
+		t.Fatalf("expected correction window closed, got %v", err)
+	}
+
+	_, err = svc.UpdateIntake(context.Background(), userID.String(), existing.ID.String(), dto.UpdateIntakeRequest{Status: constants.MedPending})
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.ValidationFailed {
+		t.Fatalf("expected invalid status, got %v", err)
+	}
+	if repo.corrected != nil {
+		t.Fatalf("expected no correction")
+	}
+}
+
+func TestDeleteIntakeKeepsChangeAndRestoresReminder(t *testing.T) {
+	now := time.Date(2026, 1, 20, 3, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	existing := newTakenIntake(userID, now.Add(-time.Hour))
+	repo := &fakeIntakeRepo{existing: existing}
+	notify := &fakeNotificationService{}
+	svc := NewIntakeService(repo, nil, nil, notify, nil, config.IntakeConfig{CorrectionWindow: 24 * time.Hour}, "UTC").(*intakeService)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.DeleteIntake(context.Background(), userID.String(), existing.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.deleted != existing.ID || len(repo.changes) != 1 || repo.changes[0].Action != constants.IntakeChangeDelete || *repo.changes[0].PreviousStatus != constants.MedTaken {
+		t.Fatalf("expected delete with change log, got %+v", repo.changes)
+	}
+	if !notify.restoreCalled {
+		t.Fatalf("expected after-meal reminder restored")
+	}
+
+	changes, err := svc.ListChanges(context.Background(), userID.String(), existing.ID.String())
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("expected change history, got %d err=%v", len(changes), err)
+	}
+	if others, _ := svc.ListChanges(context.Background(), uuid.NewString(), existing.ID.String()); len(others) != 0 {
+		t.Fatalf("expected no changes for another user")
+	}
+}
+
+func TestMarkMissedDosesAfterGraceWindow(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	userID := uuid.New()
+	morning := db.MedicineSchedule{
+		ID:           uuid.New(),
+		TimeSlot:     time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+		IntervalDays: 1,
+		WeekdayMask:  constants.WeekdayMaskAll,
+		CreatedAt:    time.Date(2026, 1, 1, 0, 0, 0, 0, loc),
+	}
+	evening := db.MedicineSchedule{
+		ID:           uuid.New(),
+		TimeSlot:     time.Date(0, 1, 1, 20, 0, 0, 0, time.UTC),
+		IntervalDays: 1,
+		WeekdayMask:  constants.WeekdayMaskAll,
+		CreatedAt:    time.Date(2026, 1, 1, 0, 0, 0, 0, loc),
+	}
+	everyOtherDay := db.MedicineSchedule{
+		ID:           uuid.New(),
+		TimeSlot:     time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+		StartDate:    time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC),
+		IntervalDays: 2,
+		WeekdayMask:  constants.WeekdayMaskAll,
+		CreatedAt:    time.Date(2026, 1, 1, 0, 0, 0, 0, loc),
+	}
+	newSchedule := db.MedicineSchedule{
+		ID:           uuid.New(),
+		TimeSlot:     time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+		IntervalDays: 1,
+		WeekdayMask:  constants.WeekdayMaskAll,
+		CreatedAt:    time.Date(2026, 1, 20, 9, 0, 0, 0, loc),
+	}
+	medicines := &medicineRepoStub{activeRows: []repositories.ActiveScheduleRow{
+		{MedicineSchedule: morning, UserID: userID},
+		{MedicineSchedule: evening, UserID: userID},
+		{MedicineSchedule: everyOtherDay, UserID: userID},
+		{MedicineSchedule: newSchedule, UserID: userID},
+	}}
+	repo := &fakeIntakeRepo{}
+	svc := NewIntakeService(repo, medicines, nil, nil, nil, config.IntakeConfig{MissedGrace: 2 * time.Hour, MissedLookbackDays: 2}, "Asia/Bangkok").(*intakeService)
+	svc.now = func() time.Time { return time.Date(2026, 1, 20, 10, 30, 0, 0, loc) }
+
+	marked, err := svc.MarkMissedDoses(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, dose := range repo.missed {
+		if dose.UserID != userID {
+			t.Fatalf("unexpected user %s", dose.UserID)
+		}
+		got[dose.ScheduleID.String()+"/"+dose.TargetDate.Format("2006-01-02")] = true
+	}
+	want := []string{
+		morning.ID.String() + "/2026-01-18",
+		morning.ID.String() + "/2026-01-19",
+		morning.ID.String() + "/2026-01-20",
+		evening.ID.String() + "/2026-01-18",
+		evening.ID.String() + "/2026-01-19",
+		everyOtherDay.ID.String() + "/2026-01-18",
+		everyOtherDay.ID.String() + "/2026-01-20",
+	}
+	if marked != int64(len(want)) || len(got) != len(want) {
+		t.Fatalf("expected %d missed doses, got %d: %v", len(want), marked, got)
+	}
+	for _, key := range want {
+		if !got[key] {
+			t.Fatalf("expected missed dose %s, got %v", key, got)
+		}
+	}
+}
+
+func TestIntakeUsesPatientTimezone(t *testing.T) {
+	bangkokUser, newYorkUser := uuid.New(), uuid.New()
+	newYork := "America/New_York"
+	prefs := preferenceRepoStub{find: func(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
+		if userID == newYorkUser {
+			return &db.UserPreference{UserID: userID, Timezone: &newYork}, nil
+		}
+		return nil, domain.NewError(constants.UserNotFound, "preferences not found")
+	}}
+	daily := db.MedicineSchedule{
+		ID:           uuid.New(),
+		TimeSlot:     time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+		StartDate:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		IntervalDays: 1,
+		WeekdayMask:  constants.WeekdayMaskAll,
+		CreatedAt:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	medicines := &medicineRepoStub{schedule: &daily, activeRows: []repositories.ActiveScheduleRow{
+		{MedicineSchedule: daily, UserID: bangkokUser},
+		{MedicineSchedule: daily, UserID: newYorkUser, Timezone: &newYork},
+	}}
+	repo := &fakeIntakeRepo{}
+	svc := NewIntakeService(repo, medicines, prefs, nil, nil, config.IntakeConfig{MissedGrace: 2 * time.Hour}, "Asia/Bangkok").(*intakeService)
+	// 03:30 UTC: 10:30 on the 20th in Bangkok, 22:30 on the 19th in New York.
+	svc.now = func() time.Time { return time.Date(2026, 1, 20, 3, 30, 0, 0, time.UTC) }
+
+	if _, err := svc.MarkMissedDoses(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[uuid.UUID]repositories.MissedDose{}
+	for _, dose := range repo.missed {
+		got[dose.UserID] = dose
+	}
+	if dose := got[bangkokUser]; dose.TargetDate.Format("2006-01-02") != "2026-01-20" || !dose.DueAt.Equal(time.Date(2026, 1, 20, 1, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the Bangkok dose of the 20th, got %+v", dose)
+	}
+	if dose := got[newYorkUser]; dose.TargetDate.Format("2006-01-02") != "2026-01-19" || !dose.DueAt.Equal(time.Date(2026, 1, 19, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the New York dose of the 19th, got %+v", dose)
+	}
+
+	medicines.patientMedicine = &db.PatientMedicine{ID: daily.PatientMedicineID, UserID: newYorkUser}
+	sid := daily.ID.String()
+	resp, err := svc.CreateIntake(context.Background(), newYorkUser.String(), dto.CreateIntakeRequest{ScheduleID: &sid, TargetDate: "2026-01-19", Status: constants.MedSkipped})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.DueAt == nil || !resp.DueAt.Equal(time.Date(2026, 1, 19, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected due_at at 08:00 New York time, got %v", resp.DueAt)
+	}
+
+	today, err := svc.GetToday(context.Background(), newYorkUser.String(), "")
+	if err != nil || today.Date != "2026-01-19" {
+		t.Fatalf("expected the New York calendar day, got %q err=%v", today.Date, err)
+	}
+}
+
+func TestGetTodayChecklist(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	userID := uuid.New()
+	medicineID := uuid.New()
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, loc)
+	row := func(slot int, intervalDays int) repositories.ScheduleWithMedicineRow {
+		return repositories.ScheduleWithMedicineRow{
+			ScheduleID:        uuid.New(),
+			PatientMedicineID: medicineID,
+			MedicineName:      "Amlodipine",
+			DosageAmount:      "1 tab",
+			MedicineActive:    true,
+			TimeSlot:          time.Date(0, 1, 1, slot, 0, 0, 0, time.UTC),
+			StartDate:         time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			IntervalDays:      intervalDays,
+			WeekdayMask:       constants.WeekdayMaskAll,
+			ScheduleCreatedAt: created,
+		}
+	}
+	// The evening dose moved from 19:00 to 20:00 on the 16th.
+	evening := row(20, 1)
+	evening.ValidFrom = datePtr("2026-01-16")
+	earlierEvening := evening
+	earlierEvening.TimeSlot = time.Date(0, 1, 1, 19, 0, 0, 0, time.UTC)
+	earlierEvening.ValidFrom, earlierEvening.ValidUntil = nil, datePtr("2026-01-15")
+	morning := row(8, 1)
+	notDue := row(12, 2)
+	medicines := &medicineRepoStub{scheduleRows: []repositories.ScheduleWithMedicineRow{earlierEvening, evening, morning, notDue}}
+
+	takenAt := time.Date(2026, 1, 20, 1, 5, 0, 0, time.UTC)
+	repo := &fakeIntakeRepo{history: []db.IntakeHistory{
+		{ID: uuid.New(), UserID: userID, ScheduleID: &morning.ScheduleID, Status: constants.MedSkipped, CreatedAt: takenAt.Add(-time.Hour)},
+		{ID: uuid.New(), UserID: userID, ScheduleID: &morning.ScheduleID, Status: constants.MedTaken, TakenAt: &takenAt, CreatedAt: takenAt},
+	}}
+	svc := NewIntakeService(repo, medicines, nil, nil, nil, config.IntakeConfig{}, "Asia/Bangkok").(*intakeService)
+	svc.now = func() time.Time { return time.Date(2026, 1, 20, 6, 0, 0, 0, time.UTC) }
+
+	resp, err := svc.GetToday(context.Background(), userID.String(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Date != "2026-01-20" || len(resp.Items) != 2 {
+		t.Fatalf("expected 2 doses on 2026-01-20, got %s %+v", resp.Date, resp.Items)
+	}
+	first, second := resp.Items[0], resp.Items[1]
+	if first.TimeSlot != "08:00" || first.Status != constants.MedTaken || first.IntakeID == nil || first.DosageAmount != "1 tab" {
+		t.Fatalf("expected morning dose taken, got %+v", first)
+	}
+	if second.TimeSlot != "20:00" || second.Status != constants.MedPending || second.IntakeID != nil {
+		t.Fatalf("expected evening dose pending, got %+v", second)
+	}
+
+	past, err := svc.GetToday(context.Background(), userID.String(), "2026-01-10")
+	if err != nil || len(past.Items) != 2 || past.Items[1].TimeSlot != "19:00" {
+		t.Fatalf("expected the evening dose at its earlier time on 2026-01-10, got %+v err=%v", past.Items, err)
+	}
+
+	if _, err := svc.GetToday(context.Background(), userID.String(), "20-01-2026"); err == nil {
+		t.Fatalf("expected invalid date error")
+	}
+}
+
+func TestCreateIntakeClassifiesClientTakenAt(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	scheduleID := uuid.New()
+	owner := uuid.New()
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: owner}
+	medicines := &medicineRepoStub{patientMedicine: med, schedule: &db.MedicineSchedule{ID: scheduleID, PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)}}
+	repo := &fakeIntakeRepo{}
+	svc := NewIntakeService(repo, medicines, nil, nil, nil, config.IntakeConfig{OnTimeWindow: time.Hour, MaxTakenOffset: 24 * time.Hour}, "Asia/Bangkok").(*intakeService)
+	svc.now = func() time.Time { return time.Date(2026, 1, 20, 21, 0, 0, 0, loc) }
+	sid := scheduleID.String()
+	userID := owner.String()
+
+	cases := []struct {
+		takenAt string
+		timing  string
+	}{
+		{"2026-01-20T08:40:00+07:00", constants.IntakeTimingOnTime},
+		{"2026-01-20T20:15:00+07:00", constants.IntakeTimingLate},
+		{"2026-01-20T06:30:00+07:00", constants.IntakeTimingEarly},
+	}
+	for _, tc := range cases {
+		takenAt := tc.takenAt
+		resp, err := svc.CreateIntake(context.Background(), userID, dto.CreateIntakeRequest{ScheduleID: &sid, TargetDate: "2026-01-20", Status: constants.MedTaken, TakenAt: &takenAt})
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tc.takenAt, err)
+		}
+		if resp.Timing == nil || *resp.Timing != tc.timing {
+			t.Fatalf("expected %s for %s, got %v", tc.timing, tc.takenAt, resp.Timing)
+		}
+		if resp.DueAt == nil || !resp.DueAt.Equal(time.Date(2026, 1, 20, 8, 0, 0, 0, loc)) {
+			t.Fatalf("expected due_at at the local time slot, got %v", resp.DueAt)
+		}
+	}
+
+	for _, bad := range []string{"yesterday", "2026-01-20T21:30:00+07:00", "2026-01-22T08:00:00+07:00", "2026-01-19T07:00:00+07:00"} {
+		takenAt := bad
+		if _, err := svc.CreateIntake(context.Background(), userID, dto.CreateIntakeRequest{ScheduleID: &sid, TargetDate: "2026-01-20", Status: constants.MedTaken, TakenAt: &takenAt}); err == nil {
+			t.Fatalf("expected taken_at %s to be rejected", bad)
+		}
+	}
+	takenAt := "2026-01-20T08:00:00+07:00"
+	if _, err := svc.CreateIntake(context.Background(), userID, dto.CreateIntakeRequest{ScheduleID: &sid, TargetDate: "2026-01-20", Status: constants.MedSkipped, TakenAt: &takenAt}); err == nil {
+		t.Fatalf("expected taken_at on a SKIPPED dose to be rejected")
+	}
+
+	// Another patient's schedule is treated as not found.
+	_, err := svc.CreateIntake(context.Background(), uuid.NewString(), dto.CreateIntakeRequest{ScheduleID: &sid, TargetDate: "2026-01-20", Status: constants.MedTaken})
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.IntakeNotFound {
+		t.Fatalf("expected INTAKE_NOT_FOUND for another patient's schedule, got %v", err)
+	}
+}
+
+func TestUpdateIntakeReclassifiesCorrectedTakenAt(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	userID := uuid.New()
+	scheduleID := uuid.New()
+	due := time.Date(2026, 1, 20, 8, 0, 0, 0, loc)
+	loggedAt := time.Date(2026, 1, 20, 20, 0, 0, 0, loc)
+	late := constants.IntakeTimingLate
+	existing := &db.IntakeHistory{ID: uuid.New(), UserID: userID, ScheduleID: &scheduleID, TargetDate: time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC), Status: constants.MedTaken, TakenAt: &loggedAt, DueAt: &due, Timing: &late, CreatedAt: loggedAt}
+	repo := &fakeIntakeRepo{existing: existing}
+	svc := NewIntakeService(repo, nil, nil, nil, nil, config.IntakeConfig{CorrectionWindow: 24 * time.Hour, OnTimeWindow: time.Hour, MaxTakenOffset: 24 * time.Hour}, "Asia/Bangkok").(*intakeService)
+	svc.now = func() time.Time { return loggedAt.Add(time.Minute) }
+
+	takenAt := "2026-01-20T08:10:00+07:00"
+	resp, err := svc.UpdateIntake(context.Background(), userID.String(), existing.ID.String(), dto.UpdateIntakeRequest{Status: constants.MedTaken, TakenAt: &takenAt})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Timing == nil || *resp.Timing != constants.IntakeTimingOnTime || !resp.TakenAt.Equal(due.Add(10*time.Minute)) {
+		t.Fatalf("expected corrected dose to be ON_TIME, got %+v", resp)
+	}
+	if len(repo.changes) != 1 || !repo.changes[0].PreviousTakenAt.Equal(loggedAt) {
+		t.Fatalf("expected the taken_at correction to be logged: %+v", repo.changes)
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// lineMessagingClient is a minimal LINE Messaging API client for push and
+// reply messages.
+type lineMessagingClient struct {
+	baseURL     string
+	accessToken string
+	client      *http.Client
+}
+
+func newLineMessagingClient(baseURL, accessToken string, client *http.Client) *lineMessagingClient {
+	return &lineMessagingClient{
+		baseURL:     strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		accessToken: strings.TrimSpace(accessToken),
+		client:      client,
+	}
+}
+
+func (c *lineMessagingClient) Push(ctx context.Context, to string, messages []any) error {
+	return c.post(ctx, "/v2/bot/message/push", map[string]any{"to": to, "messages": messages})
+}
+
+func (c *lineMessagingClient) Reply(ctx context.Context, replyToken string, messages []any) error {
+	return c.post(ctx, "/v2/bot/message/reply", map[string]any{"replyToken": replyToken, "messages": messages})
+}
+
+func (c *lineMessagingClient) post(ctx context.Context, path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("line error status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return nil
+}
+
+func lineTextMessage(text string) map[string]any {
+	return map[string]any{"type": "text", "text": text}
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+	"github.com/ParkPawapon/mhp-be/internal/utils"
+)
+
+const lineLinkStateLength = 32
+
+const (
+	lineReplyNotLinked = "not_linked"
+	lineReplyFailed    = "failed"
+	lineReplyTaken     = "taken"
+	lineReplySkipped   = "skipped"
+)
+
+// lineReplies are the postback answers in each locale.
+var lineReplies = map[string]map[string]string{
+	constants.LocaleThai: {
+		lineReplyNotLinked: "กรุณาเชื่อมบัญชี LINE ในแอปก่อน",
+		lineReplyFailed:    "ขออภัย ไม่สามารถบันทึกได้ กรุณาลองอีกครั้งในแอป",
+		lineReplyTaken:     "บันทึกแล้ว: ทานยาแล้ว",
+		lineReplySkipped:   "บันทึกแล้ว: ข้ามยามื้อนี้",
+	},
+	constants.LocaleEnglish: {
+		lineReplyNotLinked: "Please link your LINE account in the app first.",
+		lineReplyFailed:    "Sorry, we could not record this. Please try again in the app.",
+		lineReplyTaken:     "Recorded: taken.",
+		lineReplySkipped:   "Recorded: skipped.",
+	},
+}
+
+type LineService interface {
+	CreateLinkURL(ctx context.Context, actorID uuid.UUID) (dto.LineLinkURLResponse, error)
+	Link(ctx context.Context, actorID uuid.UUID, req dto.LineLinkRequest) (dto.LineLinkResponse, error)
+	Unlink(ctx context.Context, actorID uuid.UUID) error
+	HandleWebhook(ctx context.Context, signature string, body []byte) error
+}
+
+type lineService struct {
+	cfg           config.LineConfig
+	users         repositories.UserRepository
+	prefs         repositories.PreferenceRepository
+	redis         *redis.Client
+	intake        IntakeService
+	client        *http.Client
+	messaging     *lineMessagingClient
+	defaultLocale string
+	logger        *zap.Logger
+}
+
+// NewLineService answers postbacks in the user's language, else defaultLocale
+// (NOTIFICATION_DEFAULT_LOCALE), else Thai.
+func NewLineService(cfg config.LineConfig, users repositories.UserRepository, prefs repositories.PreferenceRepository, redisClient *redis.Client, intake IntakeService, defaultLocale string, logger *zap.Logger) LineService {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.LinkStateTTL <= 0 {
+		cfg.LinkStateTTL = 10 * time.Minute
+	}
+	if defaultLocale == "" {
+		defaultLocale = constants.LocaleThai
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	svc := &lineService{
+		cfg:           cfg,
+		users:         users,
+		prefs:         prefs,
+		redis:         redisClient,
+		intake:        intake,
+		client:        client,
+		defaultLocale: defaultLocale,
+		logger:        logger,
+	}
+	if cfg.MessagingEnabled() {
+		svc.messaging = newLineMessagingClient(cfg.MessagingBaseURL, cfg.ChannelAccessToken, client)
+	}
+	return svc
+}
+
+// CreateLinkURL starts LINE Login for the actor. The returned state is bound
+// to the actor and must come back with the authorization code.
+func (s *lineService) CreateLinkURL(ctx context.Context, actorID uuid.UUID) (dto.LineLinkURLResponse, error) {
+	if !s.cfg.LoginEnabled() {
+		return dto.LineLinkURLResponse{}, domain.NewError(constants.InternalNotImplemented, "line login not configured")
+	}
+
+	state, err := utils.RandomRefCode(lineLinkStateLength)
+	if err != nil {
+		return dto.LineLinkURLResponse{}, domain.WrapError(constants.InternalError, "generate state failed", err)
+	}
+	if err := s.redis.Set(ctx, lineLinkStateKey(state), actorID.String(), s.cfg.LinkStateTTL).Err(); err != nil {
+		return dto.LineLinkURLResponse{}, domain.WrapError(constants.InternalError, "store link state failed", err)
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", s.cfg.LoginChannelID)
+	query.Set("redirect_uri", s.cfg.LoginRedirectURI)
+	query.Set("state", state)
+	query.Set("scope", "openid profile")
+	query.Set("bot_prompt", "aggressive")
+
+	return dto.LineLinkURLResponse{
+		AuthorizeURL: strings.TrimSpace(s.cfg.LoginAuthorizeURL) + "?" + query.Encode(),
+		State:        state,
+	}, nil
+}
+
+func (s *lineService) Link(ctx context.Context, actorID uuid.UUID, req dto.LineLinkRequest) (dto.LineLinkResponse, error) {
+	if !s.cfg.LoginEnabled() {
+		return dto.LineLinkResponse{}, domain.NewError(constants.InternalNotImplemented, "line login not configured")
+	}
+
+	owner, err := s.redis.GetDel(ctx, lineLinkStateKey(req.State)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return dto.LineLinkResponse{}, domain.NewError(constants.ValidationFailed, "invalid state")
+		}
+		return dto.LineLinkResponse{}, domain.WrapError(constants.InternalError, "load link state failed", err)
+	}
+	if owner != actorID.String() {
+		return dto.LineLinkResponse{}, domain.NewError(constants.ValidationFailed, "invalid state")
+	}
+
+	idToken, err := s.exchangeCode(ctx, req.Code)
+	if err != nil {
+		return dto.LineLinkResponse{}, err
+	}
+	lineUserID, err := s.verifyIDToken(ctx, idToken)
+	if err != nil {
+		return dto.LineLinkResponse{}, err
+	}
+
+	if err := s.users.UpdateLineUserID(ctx, actorID, &lineUserID); err != nil {
+		return dto.LineLinkResponse{}, err
+	}
+	return dto.LineLinkResponse{Linked: true}, nil
+}
+
+func (s *lineService) Unlink(ctx context.Context, actorID uuid.UUID) error {
+	return s.users.UpdateLineUserID(ctx, actorID, nil)
+}
+
+func (s *lineService) exchangeCode(ctx context.Context, code string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.cfg.LoginRedirectURI)
+	form.Set("client_id", s.cfg.LoginChannelID)
+	form.Set("client_secret", s.cfg.LoginChannelSecret)
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := s.postLoginForm(ctx, "/oauth2/v2.1/token", form, &token); err != nil {
+		return "", err
+	}
+	if token.IDToken == "" {
+		return "", domain.NewError(constants.InternalUnavailable, "line login returned no id_token")
+	}
+	return token.IDToken, nil
+}
+
+func (s *lineService) verifyIDToken(ctx context.Context, idToken string) (string, error) {
+	form := url.Values{}
+	form.Set("id_token", idToken)
+	form.Set("client_id", s.cfg.LoginChannelID)
+
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	if err := s.postLoginForm(ctx, "/oauth2/v2.1/verify", form, &claims); err != nil {
+		return "", err
+	}
+	if claims.Sub == "" {
+		return "", domain.NewError(constants.InternalUnavailable, "line login returned no user id")
+	}
+	return claims.Sub, nil
+}
+
+// postLoginForm maps LINE's 400 responses (bad or reused code, invalid token)
+// to a validation error; anything else is treated as LINE being unavailable.
+func (s *lineService) postLoginForm(ctx context.Context, path string, form url.Values, dst any) error {
+	urlStr := strings.TrimRight(strings.TrimSpace(s.cfg.LoginBaseURL), "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, strings.NewReader(form.Encode()))
+	if err != nil {
+		return domain.WrapError(constants.InternalError, "build line login request failed", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return domain.WrapError(constants.InternalUnavailable, "line login request failed", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 8192))
+	if resp.StatusCode == http.StatusBadRequest {
+		return domain.NewError(constants.ValidationFailed, "line authorization failed")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return domain.WrapError(constants.InternalUnavailable, "line login request failed", fmt.Errorf("status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw))))
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return domain.WrapError(constants.InternalUnavailable, "decode line login response failed", err)
+	}
+	return nil
+}
+
+type lineWebhookPayload struct {
+	Events []lineWebhookEvent `json:"events"`
+}
+
+type lineWebhookEvent struct {
+	Type       string `json:"type"`
+	ReplyToken string `json:"replyToken"`
+	Source     struct {
+		UserID string `json:"userId"`
+	} `json:"source"`
+	Postback struct {
+		Data string `json:"data"`
+	} `json:"postback"`
+}
+
+// HandleWebhook verifies the channel signature and records intake for
+// Taken/Skip postbacks. Per-event failures are logged and answered with a
+// reply message; only an invalid request is returned as an error, since LINE
+// retries any non-2xx response.
+func (s *lineService) HandleWebhook(ctx context.Context, signature string, body []byte) error {
+	if !s.validSignature(signature, body) {
+		return domain.NewError(constants.AuthUnauthorized, "invalid signature")
+	}
+
+	var payload lineWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return domain.NewError(constants.ValidationFailed, "invalid webhook payload")
+	}
+
+	for _, event := range payload.Events {
+		if event.Type != "postback" {
+			continue
+		}
+		reply := s.handlePostback(ctx, event)
+		if reply == "" || event.ReplyToken == "" || s.messaging == nil {
+			continue
+		}
+		if err := s.messaging.Reply(ctx, event.ReplyToken, []any{lineTextMessage(reply)}); err != nil && s.logger != nil {
+			s.logger.Warn("line reply failed", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *lineService) handlePostback(ctx context.Context, event lineWebhookEvent) string {
+	data, err := url.ParseQuery(event.Postback.Data)
+	if err != nil || data.Get("action") != linePostbackIntake {
+		return ""
+	}
+	status := constants.MedIntakeStatus(data.Get("status"))
+	if status != constants.MedTaken && status != constants.MedSkipped {
+		return ""
+	}
+
+	user, err := s.users.FindByLineUserID(ctx, event.Source.UserID)
+	if err != nil {
+		if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.UserNotFound {
+			return lineReplies[s.defaultLocale][lineReplyNotLinked]
+		}
+		s.logPostbackError(event, err)
+		return lineReplies[s.defaultLocale][lineReplyFailed]
+	}
+	replies := lineReplies[s.locale(ctx, user.ID)]
+
+	scheduleID := data.Get("schedule_id")
+	if _, err := s.intake.CreateIntake(ctx, user.ID.String(), dto.CreateIntakeRequest{
+		ScheduleID: &scheduleID,
+		TargetDate: data.Get("target_date"),
+		Status:     status,
+	}); err != nil {
+		s.logPostbackError(event, err)
+		return replies[lineReplyFailed]
+	}
+
+	if status == constants.MedTaken {
+		return replies[lineReplyTaken]
+	}
+	return replies[lineReplySkipped]
+}
+
+// locale is the user's language preference, else the default locale. A failed
+// lookup falls back to the default rather than dropping the reply.
+func (s *lineService) locale(ctx context.Context, userID uuid.UUID) string {
+	if s.prefs != nil {
+		if pref, err := s.prefs.FindByUserID(ctx, userID); err == nil {
+			if language := notificationSettingsOf(pref).language; lineReplies[language] != nil {
+				return language
+			}
+		}
+	}
+	return s.defaultLocale
+}
+
+func (s *lineService) validSignature(signature string, body []byte) bool {
+	secret := strings.TrimSpace(s.cfg.ChannelSecret)
+	if secret == "" || signature == "" {
+		return false
+	}
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func (s *lineService) logPostbackError(event lineWebhookEvent, err error) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.Warn("line postback failed", zap.String("line_user_id", event.Source.UserID), zap.String("data", event.Postback.Data), zap.Error(err))
+}
+
+func lineLinkStateKey(state string) string {
+	return fmt.Sprintf("line:link:state:%s", state)
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type lineUserRepoStub struct {
+	users map[uuid.UUID]*db.User
+}
+
+func (s *lineUserRepoStub) Create(ctx context.Context, user *db.User) error { return nil }
+func (s *lineUserRepoStub) FindByUsername(ctx context.Context, username string) (*db.User, error) {
+	return nil, domain.NewError(constants.UserNotFound, "user not found")
+}
+func (s *lineUserRepoStub) FindByID(ctx context.Context, id uuid.UUID) (*db.User, error) {
+	if user, ok := s.users[id]; ok {
+		return user, nil
+	}
+	return nil, domain.NewError(constants.UserNotFound, "user not found")
+}
+func (s *lineUserRepoStub) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	return nil
+}
+func (s *lineUserRepoStub) FindByLineUserID(ctx context.Context, lineUserID string) (*db.User, error) {
+	for _, user := range s.users {
+		if user.LineUserID != nil && *user.LineUserID == lineUserID {
+			return user, nil
+		}
+	}
+	return nil, domain.NewError(constants.UserNotFound, "user not found")
+}
+func (s *lineUserRepoStub) UpdateLineUserID(ctx context.Context, id uuid.UUID, lineUserID *string) error {
+	user, ok := s.users[id]
+	if !ok {
+		return domain.NewError(constants.UserNotFound, "user not found")
+	}
+	user.LineUserID = lineUserID
+	return nil
+}
+
+type intakeServiceStub struct {
+	created []dto.CreateIntakeRequest
+	userIDs []string
+}
+
+func (s *intakeServiceStub) CreateIntake(ctx context.Context, userID string, req dto.CreateIntakeRequest) (dto.IntakeHistoryResponse, error) {
+	s.userIDs = append(s.userIDs, userID)
+	s.created = append(s.created, req)
+	return dto.IntakeHistoryResponse{ID: uuid.NewString(), Status: req.Status}, nil
+}
+func (s *intakeServiceStub) UpdateIntake(ctx context.Context, userID string, id string, req dto.UpdateIntakeRequest) (dto.IntakeHistoryResponse, error) {
+	panic("not used")
+}
+func (s *intakeServiceStub) DeleteIntake(ctx context.Context, userID string, id string) error {
+	panic("not used")
+}
+func (s *intakeServiceStub) ListChanges(ctx context.Context, userID string, id string) ([]dto.IntakeChangeResponse, error) {
+	panic("not used")
+}
+func (s *intakeServiceStub) ListHistory(ctx context.Context, userID string, from, to string) ([]dto.IntakeHistoryResponse, error) {
+	return nil, nil
+}
+func (s *intakeServiceStub) GetToday(ctx context.Context, userID string, date string) (dto.TodayChecklistResponse, error) {
+	return dto.TodayChecklistResponse{}, nil
+}
+func (s *intakeServiceStub) MarkMissedDoses(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+type lineStubServer struct {
+	mu       sync.Mutex
+	pushes   []map[string]any
+	replies  []map[string]any
+	codes    []string
+	verified []string
+}
+
+func (s *lineStubServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/v2.1/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		s.mu.Lock()
+		s.codes = append(s.codes, r.Form.Get("code"))
+		s.mu.Unlock()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("client_secret") != "login-secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "id_token": "id-token"})
+	})
+	mux.HandleFunc("/oauth2/v2.1/verify", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		s.mu.Lock()
+		s.verified = append(s.verified, r.Form.Get("id_token"))
+		s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"sub": "U1234", "aud": r.Form.Get("client_id")})
+	})
+	mux.HandleFunc("/v2/bot/message/push", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		s.pushes = append(s.pushes, body)
+		s.mu.Unlock()
+	})
+	mux.HandleFunc("/v2/bot/message/reply", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		s.replies = append(s.replies, body)
+		s.mu.Unlock()
+	})
+	return mux
+}
+
+func newTestLineConfig(baseURL string) config.LineConfig {
+	return config.LineConfig{
+		MessagingBaseURL:   baseURL,
+		ChannelAccessToken: "channel-token",
+		ChannelSecret:      "channel-secret",
+		LoginBaseURL:       baseURL,
+		LoginAuthorizeURL:  "https://access.line.me/oauth2/v2.1/authorize",
+		LoginChannelID:     "1650000000",
+		LoginChannelSecret: "login-secret",
+		LoginRedirectURI:   "https://app.example.com/line/callback",
+		LinkStateTTL:       time.Minute,
+	}
+}
+
+func signLineBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestLineServiceLinkFlow(t *testing.T) {
+	stub := &lineStubServer{}
+	server := httptest.NewServer(stub.handler())
+	defer server.Close()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	actorID, otherID := uuid.New(), uuid.New()
+	users := &lineUserRepoStub{users: map[uuid.UUID]*db.User{actorID: {ID: actorID}, otherID: {ID: otherID}}}
+	svc := NewLineService(newTestLineConfig(server.URL), users, preferenceRepoStub{}, rdb, &intakeServiceStub{}, "", nil)
+	ctx := context.Background()
+
+	link, err := svc.CreateLinkURL(ctx, actorID)
+	if err != nil {
+		t.Fatalf("create link url: %v", err)
+	}
+	authorize, _ := url.Parse(link.AuthorizeURL)
+	query := authorize.Query()
+	if query.Get("state") != link.State || query.Get("client_id") != "1650000000" || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorize url: %s", link.AuthorizeURL)
+	}
+
+	if _, err := svc.Link(ctx, otherID, dto.LineLinkRequest{Code: "good-code", State: link.State}); !hasCode(err, constants.ValidationFailed) {
+		t.Fatalf("expected state bound to the requesting user, got %v", err)
+	}
+	if _, err := svc.Link(ctx, actorID, dto.LineLinkRequest{Code: "good-code", State: link.State}); !hasCode(err, constants.ValidationFailed) {
+		t.Fatalf("expected state to be single use, got %v", err)
+	}
+
+	link, _ = svc.CreateLinkURL(ctx, actorID)
+	if _, err := svc.Link(ctx, actorID, dto.LineLinkRequest{Code: "bad-code", State: link.State}); !hasCode(err, constants.ValidationFailed) {
+		t.Fatalf("expected rejected code to be a validation error, got %v", err)
+	}
+
+	link, _ = svc.CreateLinkURL(ctx, actorID)
+	resp, err := svc.Link(ctx, actorID, dto.LineLinkRequest{Code: "good-code", State: link.State})
+	if err != nil || !resp.Linked {
+		t.Fatalf("expected linked, got %+v err=%v", resp, err)
+	}
+	if lineID := users.users[actorID].LineUserID; lineID == nil || *lineID != "U1234" {
+		t.Fatalf("expected line user id stored, got %v", lineID)
+	}
+	if len(stub.verified) != 1 || stub.verified[0] != "id-token" {
+		t.Fatalf("expected id token verified once, got %v", stub.verified)
+	}
+
+	if err := svc.Unlink(ctx, actorID); err != nil || users.users[actorID].LineUserID != nil {
+		t.Fatalf("expected unlink to clear line user id: %v", err)
+	}
+
+	unconfigured := NewLineService(config.LineConfig{}, users, preferenceRepoStub{}, rdb, &intakeServiceStub{}, "", nil)
+	if _, err := unconfigured.CreateLinkURL(ctx, actorID); !hasCode(err, constants.InternalNotImplemented) {
+		t.Fatalf("expected not implemented without login config, got %v", err)
+	}
+}
+
+func TestLineServiceWebhookRecordsIntake(t *testing.T) {
+	stub := &lineStubServer{}
+	server := httptest.NewServer(stub.handler())
+	defer server.Close()
+
+	lineID := "U1234"
+	userID := uuid.New()
+	users := &lineUserRepoStub{users: map[uuid.UUID]*db.User{userID: {ID: userID, LineUserID: &lineID}}}
+	intake := &intakeServiceStub{}
+	english := constants.LocaleEnglish
+	prefs := preferenceRepoStub{find: func(ctx context.Context, id uuid.UUID) (*db.UserPreference, error) {
+		return &db.UserPreference{UserID: id, Language: &english}, nil
+	}}
+	cfg := newTestLineConfig(server.URL)
+	svc := NewLineService(cfg, users, prefs, nil, intake, "", nil)
+
+	postback := func(source, data string) map[string]any {
+		return map[string]any{
+			"type":       "postback",
+			"replyToken": "reply-" + source,
+			"source":     map[string]any{"type": "user", "userId": source},
+			"postback":   map[string]any{"data": data},
+		}
+	}
+	scheduleID := uuid.NewString()
+	taken := url.Values{"action": {"intake"}, "schedule_id": {scheduleID}, "target_date": {"2026-01-20"}, "status": {"TAKEN"}}
+	body, _ := json.Marshal(map[string]any{"events": []any{
+		postback(lineID, taken.Encode()),
+		postback("U-unknown", taken.Encode()),
+		postback(lineID, "action=other"),
+		map[string]any{"type": "follow", "replyToken": "r", "source": map[string]any{"userId": lineID}},
+	}})
+
+	if err := svc.HandleWebhook(context.Background(), "bm90LXZhbGlk", body); !hasCode(err, constants.AuthUnauthorized) {
+		t.Fatalf("expected invalid signature to be rejected, got %v", err)
+	}
+	if len(intake.created) != 0 {
+		t.Fatalf("no intake must be recorded for unsigned requests")
+	}
+
+	if err := svc.HandleWebhook(context.Background(), signLineBody(cfg.ChannelSecret, body), body); err != nil {
+		t.Fatalf("unexpected webhook error: %v", err)
+	}
+	if len(intake.created) != 1 || intake.userIDs[0] != userID.String() {
+		t.Fatalf("expected one intake for the linked user, got %+v", intake.userIDs)
+	}
+	got := intake.created[0]
+	if got.ScheduleID == nil || *got.ScheduleID != scheduleID || got.TargetDate != "2026-01-20" || got.Status != constants.MedTaken {
+		t.Fatalf("unexpected intake request: %+v", got)
+	}
+	if len(stub.replies) != 2 {
+		t.Fatalf("expected replies for both intake postbacks, got %d", len(stub.replies))
+	}
+	replyText := func(reply map[string]any) string {
+		return reply["messages"].([]any)[0].(map[string]any)["text"].(string)
+	}
+	if got := replyText(stub.replies[0]); got != "Recorded: taken." {
+		t.Fatalf("expected reply in the user's language, got %q", got)
+	}
+	if got := replyText(stub.replies[1]); got != lineReplies[constants.LocaleThai][lineReplyNotLinked] {
+		t.Fatalf("expected unlinked account answered in the default locale, got %q", got)
+	}
+}
+
+func TestLineNotificationSenderAddsIntakeButtons(t *testing.T) {
+	stub := &lineStubServer{}
+	server := httptest.NewServer(stub.handler())
+	defer server.Close()
+
+	lineID := "U1234"
+	linkedID, unlinkedID := uuid.New(), uuid.New()
+	users := &lineUserRepoStub{users: map[uuid.UUID]*db.User{
+		linkedID:   {ID: linkedID, LineUserID: &lineID},
+		unlinkedID: {ID: unlinkedID},
+	}}
+	sender, err := NewLineNotificationSender(newTestLineConfig(server.URL), users, nil)
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+
+	msg := NotificationMessage{Title: "Medicine time", Body: "Take your dose for 2026-01-20", Data: map[string]string{"schedule_id": "abc", "target_date": "2026-01-20"}}
+	event := db.NotificationEvent{ID: uuid.New(), UserID: unlinkedID, TemplateCode: "MED_AFTER_MEAL_NOW"}
+	if err := sender.Send(context.Background(), event, msg); err != nil || len(stub.pushes) != 0 {
+		t.Fatalf("expected no push for unlinked user, err=%v pushes=%d", err, len(stub.pushes))
+	}
+
+	event.UserID = linkedID
+	if err := sender.Send(context.Background(), event, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stub.pushes) != 1 || stub.pushes[0]["to"] != lineID {
+		t.Fatalf("expected push to linked account, got %+v", stub.pushes)
+	}
+	message := stub.pushes[0]["messages"].([]any)[0].(map[string]any)
+	if message["type"] != "flex" || message["altText"] != "Medicine time: Take your dose for 2026-01-20" {
+		t.Fatalf("unexpected message: %+v", message)
+	}
+	footer := message["contents"].(map[string]any)["footer"].(map[string]any)
+	buttons := footer["contents"].([]any)
+	action := buttons[1].(map[string]any)["action"].(map[string]any)
+	data, _ := url.ParseQuery(action["data"].(string))
+	if len(buttons) != 2 || data.Get("status") != "SKIPPED" || data.Get("schedule_id") != "abc" {
+		t.Fatalf("unexpected postback buttons: %+v", buttons)
+	}
+
+	noButtons := lineFlexMessage(NotificationMessage{Title: "Appointment", Body: "Tomorrow"})
+	if _, ok := noButtons["contents"].(map[string]any)["footer"]; ok {
+		t.Fatalf("expected no buttons without schedule data")
+	}
+}
+
+func hasCode(err error, code string) bool {
+	appErr, ok := domain.AsAppError(err)
+	return ok && appErr.Code == code
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -21,20 +23,27 @@ type MedicineService interface {
 	UpdatePatientMedicine(ctx context.Context, id string, req dto.UpdatePatientMedicineRequest) error
 	DeletePatientMedicine(ctx context.Context, id string) error
 	CreateSchedule(ctx context.Context, patientMedicineID string, req dto.CreateMedicineScheduleRequest) (dto.MedicineScheduleResponse, error)
+	UpdateSchedule(ctx context.Context, actorID uuid.UUID, role constants.Role, id string, req dto.UpdateMedicineScheduleRequest) (dto.MedicineScheduleResponse, error)
 	DeleteSchedule(ctx context.Context, id string) error
 	ListCategories(ctx context.Context) ([]dto.MedicineCategoryResponse, error)
 	ListCategoryItems(ctx context.Context, categoryID string) ([]dto.MedicineCategoryItemResponse, error)
 	GetDosageOptions(ctx context.Context) []string
 	GetMealTimingOptions(ctx context.Context) []string
+	EnsureReminderHorizon(ctx context.Context) error
+	RescheduleReminders(ctx context.Context, userID uuid.UUID) error
 }
 
+const reminderHorizonBatchSize = 500
+
 type medicineService struct {
-	repo   repositories.MedicineRepository
-	notify NotificationService
+	repo      repositories.MedicineRepository
+	notify    NotificationService
+	locations userLocations
+	now       func() time.Time
 }
 
-func NewMedicineService(repo repositories.MedicineRepository, notify NotificationService) MedicineService {
-	return &medicineService{repo: repo, notify: notify}
+func NewMedicineService(repo repositories.MedicineRepository, prefs repositories.PreferenceRepository, notify NotificationService, timezone string) MedicineService {
+	return &medicineService{repo: repo, notify: notify, locations: newUserLocations(prefs, timezone), now: time.Now}
 }
 
 func (s *medicineService) ListMaster(ctx context.Context, page, pageSize int) ([]dto.MedicineMasterResponse, int64, error) {
@@ -109,6 +118,11 @@ func (s *medicineService) CreatePatientMedicine(ctx context.Context, userID stri
 		return dto.PatientMedicineResponse{}, domain.NewError(constants.ValidationFailed, "dosage_amount required")
 	}
 
+	refillDate, err := parseRefillDate(req.RefillDate)
+	if err != nil {
+		return dto.PatientMedicineResponse{}, err
+	}
+
 	med := &db.PatientMedicine{
 		UserID:           uid,
 		MedicineMasterID: masterID,
@@ -118,6 +132,9 @@ func (s *medicineService) CreatePatientMedicine(ctx context.Context, userID stri
 		Instruction:      trimOrNil(req.Instruction),
 		Indication:       trimOrNil(req.Indication),
 		MyDrugImageURL:   trimOrNil(req.MyDrugImageURL),
+		QuantityOnHand:   req.QuantityOnHand,
+		PackSize:         req.PackSize,
+		RefillDate:       refillDate,
 		IsActive:         true,
 	}
 
@@ -125,19 +142,7 @@ func (s *medicineService) CreatePatientMedicine(ctx context.Context, userID stri
 		return dto.PatientMedicineResponse{}, err
 	}
 
-	return dto.PatientMedicineResponse{
-		ID:               med.ID.String(),
-		UserID:           med.UserID.String(),
-		MedicineMasterID: stringPtr(masterID),
-		CategoryItemID:   stringPtr(categoryItemID),
-		CustomName:       med.CustomName,
-		DosageAmount:     med.DosageAmount,
-		Instruction:      med.Instruction,
-		Indication:       med.Indication,
-		MyDrugImageURL:   med.MyDrugImageURL,
-		IsActive:         med.IsActive,
-		CreatedAt:        med.CreatedAt,
-	}, nil
+	return toPatientMedicineResponse(*med), nil
 }
 
 func (s *medicineService) ListPatientMedicines(ctx context.Context, userID string) ([]dto.PatientMedicineResponse, error) {
@@ -153,19 +158,7 @@ func (s *medicineService) ListPatientMedicines(ctx context.Context, userID strin
 
 	resp := make([]dto.PatientMedicineResponse, 0, len(items))
 	for _, med := range items {
-		resp = append(resp, dto.PatientMedicineResponse{
-			ID:               med.ID.String(),
-			UserID:           med.UserID.String(),
-			MedicineMasterID: stringPtr(med.MedicineMasterID),
-			CategoryItemID:   stringPtr(med.CategoryItemID),
-			CustomName:       med.CustomName,
-			DosageAmount:     med.DosageAmount,
-			Instruction:      med.Instruction,
-			Indication:       med.Indication,
-			MyDrugImageURL:   med.MyDrugImageURL,
-			IsActive:         med.IsActive,
-			CreatedAt:        med.CreatedAt,
-		})
+		resp = append(resp, toPatientMedicineResponse(med))
 	}
 	return resp, nil
 }
@@ -196,6 +189,25 @@ func (s *medicineService) UpdatePatientMedicine(ctx context.Context, id string,
 	if req.MyDrugImageURL != nil {
 		updates["my_drug_image_url"] = trimString(req.MyDrugImageURL)
 	}
+	if req.QuantityOnHand != nil {
+		// A recount starts a new supply cycle, so a later shortfall is
+		// reminded again.
+		updates["quantity_on_hand"] = *req.QuantityOnHand
+		updates["low_supply_notified_at"] = nil
+	}
+	if req.PackSize != nil {
+		updates["pack_size"] = *req.PackSize
+	}
+	if req.RefillDate != nil {
+		refillDate, err := parseRefillDate(req.RefillDate)
+		if err != nil {
+			return err
+		}
+		updates["refill_date"] = nil
+		if refillDate != nil {
+			updates["refill_date"] = refillDate.Format("2006-01-02")
+		}
+	}
 	if req.IsActive != nil {
 		updates["is_active"] = *req.IsActive
 	}
@@ -203,7 +215,52 @@ func (s *medicineService) UpdatePatientMedicine(ctx context.Context, id string,
 		return domain.NewError(constants.ValidationFailed, "no fields to update")
 	}
 
-	return s.repo.UpdatePatientMedicine(ctx, medID, updates)
+	if req.IsActive == nil {
+		return s.repo.UpdatePatientMedicine(ctx, medID, updates)
+	}
+
+	medicine, err := s.repo.GetPatientMedicineByID(ctx, medID)
+	if err != nil {
+		return err
+	}
+	if medicine.IsActive == *req.IsActive {
+		return s.repo.UpdatePatientMedicine(ctx, medID, updates)
+	}
+
+	// The new status applies from the patient's today; earlier days keep the
+	// old one.
+	location, err := s.locations.forUser(ctx, medicine.UserID)
+	if err != nil {
+		return err
+	}
+	effectiveFrom := localDate(s.now(), location)
+	if s.notify == nil {
+		return s.repo.UpdatePatientMedicineStatus(ctx, medID, updates, effectiveFrom)
+	}
+
+	schedules, err := s.repo.ListSchedulesByMedicine(ctx, medID)
+	if err != nil {
+		return err
+	}
+
+	// Stop reminders before deactivating so a failure leaves the medicine
+	// active rather than silently reminding for a stopped drug.
+	if !*req.IsActive {
+		if err := s.notify.CancelMedicineReminders(ctx, medicine.UserID, scheduleIDs(schedules)); err != nil {
+			return err
+		}
+		return s.repo.UpdatePatientMedicineStatus(ctx, medID, updates, effectiveFrom)
+	}
+
+	if err := s.repo.UpdatePatientMedicineStatus(ctx, medID, updates, effectiveFrom); err != nil {
+		return err
+	}
+	for _, schedule := range schedules {
+		if err := s.notify.ScheduleMedicineReminders(ctx, medicine.UserID, schedule); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *medicineService) DeletePatientMedicine(ctx context.Context, id string) error {
@@ -211,6 +268,20 @@ func (s *medicineService) DeletePatientMedicine(ctx context.Context, id string)
 	if err != nil {
 		return domain.NewError(constants.ValidationFailed, "invalid id")
 	}
+
+	if s.notify != nil {
+		medicine, err := s.repo.GetPatientMedicineByID(ctx, medID)
+		if err != nil {
+			return err
+		}
+		schedules, err := s.repo.ListSchedulesByMedicine(ctx, medID)
+		if err != nil {
+			return err
+		}
+		if err := s.notify.CancelMedicineReminders(ctx, medicine.UserID, scheduleIDs(schedules)); err != nil {
+			return err
+		}
+	}
 	return s.repo.DeletePatientMedicine(ctx, medID)
 }
 
@@ -235,10 +306,20 @@ func (s *medicineService) CreateSchedule(ctx context.Context, patientMedicineID
 		return dto.MedicineScheduleResponse{}, domain.NewError(constants.ValidationFailed, "invalid meal_timing")
 	}
 
+	location, err := s.locations.forUser(ctx, medicine.UserID)
+	if err != nil {
+		return dto.MedicineScheduleResponse{}, err
+	}
 	schedule := &db.MedicineSchedule{
 		PatientMedicineID: medicine.ID,
 		TimeSlot:          timeSlot,
 		MealTiming:        mealTiming,
+		StartDate:         localDate(s.now(), location),
+		IntervalDays:      1,
+		WeekdayMask:       constants.WeekdayMaskAll,
+	}
+	if err := applyRecurrence(schedule, req.ScheduleRecurrenceRequest, map[string]any{}); err != nil {
+		return dto.MedicineScheduleResponse{}, err
 	}
 
 	if err := s.repo.CreateSchedule(ctx, schedule); err != nil {
@@ -246,16 +327,10 @@ func (s *medicineService) CreateSchedule(ctx context.Context, patientMedicineID
 	}
 
 	if s.notify != nil {
-		_ = s.notify.ScheduleMedicineReminders(ctx, medicine.UserID, schedule.ID, schedule.MealTiming, schedule.TimeSlot)
+		_ = s.notify.ScheduleMedicineReminders(ctx, medicine.UserID, *schedule)
 	}
 
-	return dto.MedicineScheduleResponse{
-		ID:                schedule.ID.String(),
-		PatientMedicineID: schedule.PatientMedicineID.String(),
-		TimeSlot:          schedule.TimeSlot.Format("15:04"),
-		MealTiming:        schedule.MealTiming,
-		CreatedAt:         schedule.CreatedAt,
-	}, nil
+	return toMedicineScheduleResponse(*schedule), nil
 }
 
 func (s *medicineService) DeleteSchedule(ctx context.Context, id string) error {
@@ -263,9 +338,91 @@ func (s *medicineService) DeleteSchedule(ctx context.Context, id string) error {
 	if err != nil {
 		return domain.NewError(constants.ValidationFailed, "invalid id")
 	}
+
+	if s.notify != nil {
+		schedule, err := s.repo.GetScheduleByID(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		medicine, err := s.repo.GetPatientMedicineByID(ctx, schedule.PatientMedicineID)
+		if err != nil {
+			return err
+		}
+		if err := s.notify.CancelMedicineReminders(ctx, medicine.UserID, []uuid.UUID{schedule.ID}); err != nil {
+			return err
+		}
+	}
 	return s.repo.DeleteSchedule(ctx, scheduleID)
 }
 
+// UpdateSchedule changes the time, meal timing or recurrence of a schedule
+// from the patient's today, keeping the old version for earlier days, and
+// replaces its unsent reminders. An empty meal_timing or end_date clears it.
+func (s *medicineService) UpdateSchedule(ctx context.Context, actorID uuid.UUID, role constants.Role, id string, req dto.UpdateMedicineScheduleRequest) (dto.MedicineScheduleResponse, error) {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return dto.MedicineScheduleResponse{}, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+
+	schedule, err := s.repo.GetScheduleByID(ctx, scheduleID)
+	if err != nil {
+		return dto.MedicineScheduleResponse{}, err
+	}
+	medicine, err := s.repo.GetPatientMedicineByID(ctx, schedule.PatientMedicineID)
+	if err != nil {
+		return dto.MedicineScheduleResponse{}, err
+	}
+	if role == constants.RolePatient && medicine.UserID != actorID {
+		return dto.MedicineScheduleResponse{}, domain.NewError(constants.MedNotFound, "medicine schedule not found")
+	}
+
+	updates := map[string]any{}
+	if req.TimeSlot != nil {
+		timeSlot, err := time.Parse("15:04", strings.TrimSpace(*req.TimeSlot))
+		if err != nil {
+			return dto.MedicineScheduleResponse{}, domain.NewError(constants.ValidationFailed, "invalid time_slot")
+		}
+		schedule.TimeSlot = timeSlot
+		updates["time_slot"] = timeSlot.Format("15:04:05")
+	}
+	if req.MealTiming != nil {
+		mealTiming := trimOrNil(req.MealTiming)
+		if mealTiming != nil && !isAllowed(*mealTiming, constants.MealTimingOptions) {
+			return dto.MedicineScheduleResponse{}, domain.NewError(constants.ValidationFailed, "invalid meal_timing")
+		}
+		schedule.MealTiming = mealTiming
+		updates["meal_timing"] = mealTiming
+	}
+	if err := applyRecurrence(schedule, req.ScheduleRecurrenceRequest, updates); err != nil {
+		return dto.MedicineScheduleResponse{}, err
+	}
+	if len(updates) == 0 {
+		return dto.MedicineScheduleResponse{}, domain.NewError(constants.ValidationFailed, "no fields to update")
+	}
+
+	location, err := s.locations.forUser(ctx, medicine.UserID)
+	if err != nil {
+		return dto.MedicineScheduleResponse{}, err
+	}
+	effectiveFrom := localDate(s.now(), location)
+
+	if s.notify != nil && medicine.IsActive {
+		reminders, err := s.notify.BuildMedicineReminders(ctx, medicine.UserID, *schedule)
+		if err != nil {
+			return dto.MedicineScheduleResponse{}, err
+		}
+		if err := s.repo.UpdateScheduleWithReminders(ctx, scheduleID, updates, effectiveFrom, medicine.UserID, reminders); err != nil {
+			return dto.MedicineScheduleResponse{}, err
+		}
+		return toMedicineScheduleResponse(*schedule), nil
+	}
+
+	if err := s.repo.UpdateSchedule(ctx, scheduleID, updates, effectiveFrom); err != nil {
+		return dto.MedicineScheduleResponse{}, err
+	}
+	return toMedicineScheduleResponse(*schedule), nil
+}
+
 func (s *medicineService) ListCategories(ctx context.Context) ([]dto.MedicineCategoryResponse, error) {
 	items, err := s.repo.ListCategories(ctx)
 	if err != nil {
@@ -334,3 +491,181 @@ func trimString(value *string) *string {
 	}
 	return &trimmed
 }
+
+// parseRefillDate reads an optional YYYY-MM-DD date; empty means none.
+func parseRefillDate(value *string) (*time.Time, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(*value))
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid refill_date")
+	}
+	return &date, nil
+}
+
+func toPatientMedicineResponse(med db.PatientMedicine) dto.PatientMedicineResponse {
+	var refillDate *string
+	if med.RefillDate != nil {
+		value := med.RefillDate.Format("2006-01-02")
+		refillDate = &value
+	}
+	return dto.PatientMedicineResponse{
+		ID:               med.ID.String(),
+		UserID:           med.UserID.String(),
+		MedicineMasterID: stringPtr(med.MedicineMasterID),
+		CategoryItemID:   stringPtr(med.CategoryItemID),
+		CustomName:       med.CustomName,
+		DosageAmount:     med.DosageAmount,
+		Instruction:      med.Instruction,
+		Indication:       med.Indication,
+		MyDrugImageURL:   med.MyDrugImageURL,
+		QuantityOnHand:   med.QuantityOnHand,
+		PackSize:         med.PackSize,
+		RefillDate:       refillDate,
+		IsActive:         med.IsActive,
+		CreatedAt:        med.CreatedAt,
+	}
+}
+
+func toMedicineScheduleResponse(schedule db.MedicineSchedule) dto.MedicineScheduleResponse {
+	var endDate *string
+	if schedule.EndDate != nil {
+		value := schedule.EndDate.Format("2006-01-02")
+		endDate = &value
+	}
+	intervalDays := schedule.IntervalDays
+	if intervalDays <= 0 {
+		intervalDays = 1
+	}
+	return dto.MedicineScheduleResponse{
+		ID:                schedule.ID.String(),
+		PatientMedicineID: schedule.PatientMedicineID.String(),
+		TimeSlot:          schedule.TimeSlot.Format("15:04"),
+		MealTiming:        schedule.MealTiming,
+		StartDate:         schedule.StartDate.Format("2006-01-02"),
+		EndDate:           endDate,
+		IntervalDays:      intervalDays,
+		Weekdays:          weekdaysFromMask(schedule.WeekdayMask),
+		CreatedAt:         schedule.CreatedAt,
+	}
+}
+
+// applyRecurrence copies the provided recurrence fields onto schedule and
+// records the column changes in updates.
+func applyRecurrence(schedule *db.MedicineSchedule, req dto.ScheduleRecurrenceRequest, updates map[string]any) error {
+	if req.StartDate != nil {
+		start, err := time.Parse("2006-01-02", strings.TrimSpace(*req.StartDate))
+		if err != nil {
+			return domain.NewError(constants.ValidationFailed, "invalid start_date")
+		}
+		schedule.StartDate = start
+		updates["start_date"] = start.Format("2006-01-02")
+	}
+	if req.EndDate != nil {
+		value := strings.TrimSpace(*req.EndDate)
+		if value == "" {
+			schedule.EndDate = nil
+			updates["end_date"] = nil
+		} else {
+			end, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return domain.NewError(constants.ValidationFailed, "invalid end_date")
+			}
+			schedule.EndDate = &end
+			updates["end_date"] = end.Format("2006-01-02")
+		}
+	}
+	if req.IntervalDays != nil {
+		if *req.IntervalDays < 1 || *req.IntervalDays > constants.MaxIntervalDays {
+			return domain.NewError(constants.ValidationFailed, "invalid interval_days")
+		}
+		schedule.IntervalDays = *req.IntervalDays
+		updates["interval_days"] = *req.IntervalDays
+	}
+	if req.Weekdays != nil {
+		mask, err := weekdayMask(req.Weekdays)
+		if err != nil {
+			return err
+		}
+		schedule.WeekdayMask = mask
+		updates["weekday_mask"] = mask
+	}
+	if schedule.EndDate != nil && calendarDate(*schedule.EndDate).Before(calendarDate(schedule.StartDate)) {
+		return domain.NewError(constants.ValidationFailed, "end_date must not be before start_date")
+	}
+	return nil
+}
+
+// EnsureReminderHorizon keeps reminders for every active schedule materialized
+// for NOTIFICATION_SCHEDULE_DAYS ahead. Existing events are left alone by the
+// (user_id, template_code, scheduled_at) unique index, so reruns are cheap and
+// a cancelled reminder is not recreated.
+func (s *medicineService) EnsureReminderHorizon(ctx context.Context) error {
+	if s.notify == nil {
+		return nil
+	}
+
+	var errs []error
+	after := uuid.Nil
+	for {
+		rows, err := s.repo.ListActiveSchedules(ctx, after, reminderHorizonBatchSize)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		for _, row := range rows {
+			if err := s.notify.ScheduleMedicineReminders(ctx, row.UserID, row.MedicineSchedule); err != nil {
+				errs = append(errs, fmt.Errorf("schedule %s: %w", row.ID, err))
+			}
+		}
+		if len(rows) < reminderHorizonBatchSize {
+			return errors.Join(errs...)
+		}
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		after = rows[len(rows)-1].ID
+	}
+}
+
+// RescheduleReminders replans the unsent reminders of every schedule of the
+// patient's active medicines, e.g. after the patient changed timezone.
+func (s *medicineService) RescheduleReminders(ctx context.Context, userID uuid.UUID) error {
+	if s.notify == nil {
+		return nil
+	}
+	rows, err := s.repo.ListSchedulesWithMedicine(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, row := range rows {
+		if !row.MedicineActive {
+			continue
+		}
+		schedule := db.MedicineSchedule{
+			ID:                row.ScheduleID,
+			PatientMedicineID: row.PatientMedicineID,
+			TimeSlot:          row.TimeSlot,
+			MealTiming:        row.MealTiming,
+			StartDate:         row.StartDate,
+			EndDate:           row.EndDate,
+			IntervalDays:      row.IntervalDays,
+			WeekdayMask:       row.WeekdayMask,
+			CreatedAt:         row.ScheduleCreatedAt,
+		}
+		if err := s.notify.RescheduleMedicineReminders(ctx, userID, schedule); err != nil {
+			errs = append(errs, fmt.Errorf("schedule %s: %w", row.ScheduleID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func scheduleIDs(schedules []db.MedicineSchedule) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(schedules))
+	for _, schedule := range schedules {
+		ids = append(ids, schedule.ID)
+	}
+	return ids
+}
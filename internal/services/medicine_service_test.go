@@ -11,14 +11,25 @@ import (
 	"github.com/ParkPawapon/mhp-be/internal/domain"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
 )
 
 type medicineRepoStub struct {
+	scheduleRows    []repositories.ScheduleWithMedicineRow
+	activeRows      []repositories.ActiveScheduleRow
+	activePages     int
 	master          *db.MedicineMaster
 	categoryItem    *db.MedicineCategoryItem
 	patientMedicine *db.PatientMedicine
 	createdMedicine *db.PatientMedicine
 	createdSchedule *db.MedicineSchedule
+	schedule        *db.MedicineSchedule
+	schedules       []db.MedicineSchedule
+	medicineUpdates map[string]any
+	scheduleUpdates map[string]any
+	effectiveFrom   time.Time
+	reminders       []db.NotificationEvent
+	deleted         bool
 }
 
 func (s *medicineRepoStub) ListMaster(ctx context.Context, page, pageSize int) ([]db.MedicineMaster, int64, error) {
@@ -46,10 +57,17 @@ func (s *medicineRepoStub) GetPatientMedicineByID(ctx context.Context, id uuid.U
 	return s.patientMedicine, nil
 }
 func (s *medicineRepoStub) UpdatePatientMedicine(ctx context.Context, id uuid.UUID, updates map[string]any) error {
-	panic("not used")
+	s.medicineUpdates = updates
+	return nil
+}
+func (s *medicineRepoStub) UpdatePatientMedicineStatus(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time) error {
+	s.medicineUpdates = updates
+	s.effectiveFrom = effectiveFrom
+	return nil
 }
 func (s *medicineRepoStub) DeletePatientMedicine(ctx context.Context, id uuid.UUID) error {
-	panic("not used")
+	s.deleted = true
+	return nil
 }
 func (s *medicineRepoStub) CreateSchedule(ctx context.Context, schedule *db.MedicineSchedule) error {
 	s.createdSchedule = schedule
@@ -58,10 +76,50 @@ func (s *medicineRepoStub) CreateSchedule(ctx context.Context, schedule *db.Medi
 	return nil
 }
 func (s *medicineRepoStub) GetScheduleByID(ctx context.Context, id uuid.UUID) (*db.MedicineSchedule, error) {
-	panic("not used")
+	if s.schedule == nil {
+		return nil, domain.NewError(constants.MedNotFound, "not found")
+	}
+	return s.schedule, nil
+}
+func (s *medicineRepoStub) ListSchedulesByMedicine(ctx context.Context, patientMedicineID uuid.UUID) ([]db.MedicineSchedule, error) {
+	return s.schedules, nil
+}
+func (s *medicineRepoStub) UpdateSchedule(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time) error {
+	s.scheduleUpdates = updates
+	s.effectiveFrom = effectiveFrom
+	return nil
+}
+func (s *medicineRepoStub) UpdateScheduleWithReminders(ctx context.Context, id uuid.UUID, updates map[string]any, effectiveFrom time.Time, userID uuid.UUID, reminders []db.NotificationEvent) error {
+	s.scheduleUpdates = updates
+	s.effectiveFrom = effectiveFrom
+	s.reminders = reminders
+	return nil
 }
 func (s *medicineRepoStub) DeleteSchedule(ctx context.Context, id uuid.UUID) error {
-	panic("not used")
+	s.deleted = true
+	return nil
+}
+func (s *medicineRepoStub) ListSchedulesWithMedicine(ctx context.Context, userID uuid.UUID) ([]repositories.ScheduleWithMedicineRow, error) {
+	return s.scheduleRows, nil
+}
+func (s *medicineRepoStub) ListScheduleHistory(ctx context.Context, userID uuid.UUID) ([]repositories.ScheduleWithMedicineRow, error) {
+	return s.scheduleRows, nil
+}
+func (s *medicineRepoStub) ListActiveSchedules(ctx context.Context, afterID uuid.UUID, limit int) ([]repositories.ActiveScheduleRow, error) {
+	s.activePages++
+	start := 0
+	if afterID != uuid.Nil {
+		for i, row := range s.activeRows {
+			if row.ID == afterID {
+				start = i + 1
+			}
+		}
+	}
+	end := start + limit
+	if end > len(s.activeRows) {
+		end = len(s.activeRows)
+	}
+	return s.activeRows[start:end], nil
 }
 func (s *medicineRepoStub) ListCategories(ctx context.Context) ([]db.MedicineCategory, error) {
 	panic("not used")
@@ -77,19 +135,47 @@ func (s *medicineRepoStub) GetCategoryItemByID(ctx context.Context, id uuid.UUID
 }
 
 type notificationScheduleStub struct {
-	called bool
+	called      bool
+	scheduled   []uuid.UUID
+	rescheduled []uuid.UUID
+	cancelled   []uuid.UUID
+	failFor     uuid.UUID
 }
 
-func (s *notificationScheduleStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, mealTiming *string, timeSlot time.Time) error {
+func (s *notificationScheduleStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
 	s.called = true
+	if schedule.ID == s.failFor {
+		return domain.NewError(constants.InternalError, "insert failed")
+	}
+	s.scheduled = append(s.scheduled, schedule.ID)
 	return nil
 }
 func (s *notificationScheduleStub) CancelMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
 	panic("not used")
 }
+func (s *notificationScheduleStub) RestoreMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
+	return nil
+}
+func (s *notificationScheduleStub) RescheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+	s.rescheduled = append(s.rescheduled, schedule.ID)
+	return nil
+}
+func (s *notificationScheduleStub) BuildMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) ([]db.NotificationEvent, error) {
+	if schedule.ID == s.failFor {
+		return nil, domain.NewError(constants.InternalError, "load settings failed")
+	}
+	return []db.NotificationEvent{{UserID: userID, TemplateCode: constants.TemplateMedBeforeMeal5Min}}, nil
+}
+func (s *notificationScheduleStub) CancelMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	s.cancelled = append(s.cancelled, scheduleIDs...)
+	return nil
+}
 func (s *notificationScheduleStub) ScheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
 	panic("not used")
 }
+func (s *notificationScheduleStub) RescheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
+	panic("not used")
+}
 func (s *notificationScheduleStub) CancelAppointmentReminders(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
 	panic("not used")
 }
@@ -105,10 +191,37 @@ func (s *notificationScheduleStub) ProcessDue(ctx context.Context) error {
 func (s *notificationScheduleStub) CancelWeeklyReminders(ctx context.Context, userID uuid.UUID) error {
 	panic("not used")
 }
+func (s *notificationScheduleStub) RescheduleWeeklyReminder(ctx context.Context, userID uuid.UUID) error {
+	panic("not used")
+}
+func (s *notificationScheduleStub) ListFailedEvents(ctx context.Context, page, pageSize int, status, userID, templateCode string) ([]dto.NotificationEventResponse, int64, error) {
+	panic("not used")
+}
+func (s *notificationScheduleStub) RequeueEvent(ctx context.Context, id string) (dto.NotificationEventResponse, error) {
+	panic("not used")
+}
+func (s *notificationScheduleStub) ListInbox(ctx context.Context, userID uuid.UUID, cursor string, limit int, unreadOnly bool) (dto.NotificationInboxResponse, error) {
+	panic("not used")
+}
+func (s *notificationScheduleStub) UnreadCount(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationScheduleStub) MarkRead(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationScheduleStub) MarkAllRead(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationScheduleStub) ArchiveInboxItem(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	panic("not used")
+}
+func (s *notificationScheduleStub) Channels() []string {
+	panic("not used")
+}
 
 func TestCreatePatientMedicineRequiresSource(t *testing.T) {
 	repo := &medicineRepoStub{}
-	svc := NewMedicineService(repo, nil)
+	svc := NewMedicineService(repo, nil, nil, "UTC")
 
 	_, err := svc.CreatePatientMedicine(context.Background(), uuid.New().String(), dto.CreatePatientMedicineRequest{
 		DosageAmount: "1",
@@ -129,7 +242,7 @@ func TestCreatePatientMedicineFromCategoryItem(t *testing.T) {
 			DefaultDosageText: &dosage,
 		},
 	}
-	svc := NewMedicineService(repo, nil)
+	svc := NewMedicineService(repo, nil, nil, "UTC")
 
 	resp, err := svc.CreatePatientMedicine(context.Background(), uuid.New().String(), dto.CreatePatientMedicineRequest{
 		CategoryItemID: &[]string{itemID.String()}[0],
@@ -150,7 +263,7 @@ func TestCreateScheduleValidatesMealTiming(t *testing.T) {
 	medID := uuid.New()
 	repo := &medicineRepoStub{patientMedicine: &db.PatientMedicine{ID: medID, UserID: uuid.New()}}
 	notify := &notificationScheduleStub{}
-	svc := NewMedicineService(repo, notify)
+	svc := NewMedicineService(repo, nil, notify, "UTC")
 
 	_, err := svc.CreateSchedule(context.Background(), medID.String(), dto.CreateMedicineScheduleRequest{
 		TimeSlot:   "08:00",
@@ -171,3 +284,218 @@ func TestCreateScheduleValidatesMealTiming(t *testing.T) {
 		t.Fatalf("expected notification schedule called")
 	}
 }
+
+func TestCreateScheduleRecurrence(t *testing.T) {
+	medID := uuid.New()
+	repo := &medicineRepoStub{patientMedicine: &db.PatientMedicine{ID: medID, UserID: uuid.New()}}
+	svc := NewMedicineService(repo, nil, &notificationScheduleStub{}, "UTC")
+	impl := svc.(*medicineService)
+	impl.now = func() time.Time { return time.Date(2026, 1, 10, 3, 0, 0, 0, time.UTC) }
+
+	resp, err := svc.CreateSchedule(context.Background(), medID.String(), dto.CreateMedicineScheduleRequest{TimeSlot: "08:00"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StartDate != "2026-01-10" || resp.IntervalDays != 1 || len(resp.Weekdays) != 7 || resp.EndDate != nil {
+		t.Fatalf("unexpected default recurrence: %+v", resp)
+	}
+
+	interval := 2
+	resp, err = svc.CreateSchedule(context.Background(), medID.String(), dto.CreateMedicineScheduleRequest{
+		TimeSlot: "08:00",
+		ScheduleRecurrenceRequest: dto.ScheduleRecurrenceRequest{
+			StartDate:    strPtr("2026-01-12"),
+			EndDate:      strPtr("2026-01-18"),
+			IntervalDays: &interval,
+			Weekdays:     []string{"mon", "WED", "FRI"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.createdSchedule.WeekdayMask != 1<<1|1<<3|1<<5 || repo.createdSchedule.IntervalDays != 2 {
+		t.Fatalf("unexpected stored recurrence: %+v", repo.createdSchedule)
+	}
+	if resp.EndDate == nil || *resp.EndDate != "2026-01-18" || len(resp.Weekdays) != 3 || resp.Weekdays[0] != "MON" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	zero := 0
+	for _, req := range []dto.ScheduleRecurrenceRequest{
+		{StartDate: strPtr("2026-01-12"), EndDate: strPtr("2026-01-11")},
+		{StartDate: strPtr("12/01/2026")},
+		{IntervalDays: &zero},
+		{Weekdays: []string{}},
+		{Weekdays: []string{"XYZ"}},
+	} {
+		if _, err := svc.CreateSchedule(context.Background(), medID.String(), dto.CreateMedicineScheduleRequest{TimeSlot: "08:00", ScheduleRecurrenceRequest: req}); err == nil {
+			t.Fatalf("expected validation error for %+v", req)
+		}
+	}
+}
+
+func TestDeleteScheduleCancelsReminders(t *testing.T) {
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: uuid.New(), IsActive: true}
+	schedule := &db.MedicineSchedule{ID: uuid.New(), PatientMedicineID: med.ID}
+	repo := &medicineRepoStub{patientMedicine: med, schedule: schedule}
+	notify := &notificationScheduleStub{}
+	svc := NewMedicineService(repo, nil, notify, "UTC")
+
+	if err := svc.DeleteSchedule(context.Background(), schedule.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !repo.deleted || len(notify.cancelled) != 1 || notify.cancelled[0] != schedule.ID {
+		t.Fatalf("expected schedule reminders cancelled, got %v", notify.cancelled)
+	}
+}
+
+func TestUpdatePatientMedicineActiveTogglesReminders(t *testing.T) {
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: uuid.New(), IsActive: true}
+	schedules := []db.MedicineSchedule{{ID: uuid.New(), PatientMedicineID: med.ID}, {ID: uuid.New(), PatientMedicineID: med.ID}}
+	repo := &medicineRepoStub{patientMedicine: med, schedules: schedules}
+	notify := &notificationScheduleStub{}
+	svc := NewMedicineService(repo, nil, notify, "Asia/Bangkok")
+	svc.(*medicineService).now = func() time.Time { return time.Date(2026, 1, 9, 20, 0, 0, 0, time.UTC) }
+
+	inactive := false
+	if err := svc.UpdatePatientMedicine(context.Background(), med.ID.String(), dto.UpdatePatientMedicineRequest{IsActive: &inactive}); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if len(notify.cancelled) != 2 || len(notify.scheduled) != 0 {
+		t.Fatalf("expected both schedules cancelled, got cancelled=%d scheduled=%d", len(notify.cancelled), len(notify.scheduled))
+	}
+	if want := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC); !repo.effectiveFrom.Equal(want) {
+		t.Fatalf("expected the status to change from the patient's today %v, got %v", want, repo.effectiveFrom)
+	}
+
+	repo.effectiveFrom = time.Time{}
+	stillActive, instruction := true, "after breakfast"
+	if err := svc.UpdatePatientMedicine(context.Background(), med.ID.String(), dto.UpdatePatientMedicineRequest{IsActive: &stillActive, Instruction: &instruction}); err != nil {
+		t.Fatalf("unchanged status: %v", err)
+	}
+	if !repo.effectiveFrom.IsZero() {
+		t.Fatalf("expected no new version when the status does not change")
+	}
+
+	med.IsActive = false
+	active := true
+	if err := svc.UpdatePatientMedicine(context.Background(), med.ID.String(), dto.UpdatePatientMedicineRequest{IsActive: &active}); err != nil {
+		t.Fatalf("reactivate: %v", err)
+	}
+	if len(notify.scheduled) != 2 {
+		t.Fatalf("expected reminders regenerated, got %d", len(notify.scheduled))
+	}
+}
+
+func TestUpdatePatientMedicineStock(t *testing.T) {
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: uuid.New(), IsActive: true}
+	repo := &medicineRepoStub{patientMedicine: med}
+	svc := NewMedicineService(repo, nil, nil, "UTC")
+
+	quantity, packSize := 28.0, 14.0
+	refillDate := "2026-01-20"
+	if err := svc.UpdatePatientMedicine(context.Background(), med.ID.String(), dto.UpdatePatientMedicineRequest{QuantityOnHand: &quantity, PackSize: &packSize, RefillDate: &refillDate}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.medicineUpdates["quantity_on_hand"] != 28.0 || repo.medicineUpdates["pack_size"] != 14.0 || repo.medicineUpdates["refill_date"] != "2026-01-20" {
+		t.Fatalf("unexpected updates: %+v", repo.medicineUpdates)
+	}
+	if value, ok := repo.medicineUpdates["low_supply_notified_at"]; !ok || value != nil {
+		t.Fatalf("expected a recount to re-arm the low-supply reminder: %+v", repo.medicineUpdates)
+	}
+
+	invalid := "20/01/2026"
+	if err := svc.UpdatePatientMedicine(context.Background(), med.ID.String(), dto.UpdatePatientMedicineRequest{RefillDate: &invalid}); err == nil {
+		t.Fatalf("expected invalid refill_date to be rejected")
+	}
+}
+
+func TestDeletePatientMedicineCancelsReminders(t *testing.T) {
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: uuid.New(), IsActive: true}
+	repo := &medicineRepoStub{patientMedicine: med, schedules: []db.MedicineSchedule{{ID: uuid.New(), PatientMedicineID: med.ID}}}
+	notify := &notificationScheduleStub{}
+	svc := NewMedicineService(repo, nil, notify, "UTC")
+
+	if err := svc.DeletePatientMedicine(context.Background(), med.ID.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !repo.deleted || len(notify.cancelled) != 1 {
+		t.Fatalf("expected reminders cancelled before delete")
+	}
+}
+
+func TestUpdateScheduleReplacesReminders(t *testing.T) {
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: uuid.New(), IsActive: true}
+	schedule := &db.MedicineSchedule{ID: uuid.New(), PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), MealTiming: strPtr(constants.MealTimingBeforeMeal)}
+	repo := &medicineRepoStub{patientMedicine: med, schedule: schedule}
+	notify := &notificationScheduleStub{}
+	svc := NewMedicineService(repo, nil, notify, "UTC")
+
+	if _, err := svc.UpdateSchedule(context.Background(), med.UserID, constants.RolePatient, schedule.ID.String(), dto.UpdateMedicineScheduleRequest{}); err == nil {
+		t.Fatalf("expected empty update to fail")
+	}
+	if _, err := svc.UpdateSchedule(context.Background(), med.UserID, constants.RolePatient, schedule.ID.String(), dto.UpdateMedicineScheduleRequest{TimeSlot: strPtr("25:00")}); err == nil {
+		t.Fatalf("expected invalid time_slot to fail")
+	}
+
+	resp, err := svc.UpdateSchedule(context.Background(), med.UserID, constants.RolePatient, schedule.ID.String(), dto.UpdateMedicineScheduleRequest{TimeSlot: strPtr("09:30"), MealTiming: strPtr("")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.TimeSlot != "09:30" || resp.MealTiming != nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if repo.scheduleUpdates["time_slot"] != "09:30:00" {
+		t.Fatalf("unexpected updates: %+v", repo.scheduleUpdates)
+	}
+	if len(repo.reminders) != 1 || repo.reminders[0].UserID != med.UserID {
+		t.Fatalf("expected reminders replaced with the schedule, got %+v", repo.reminders)
+	}
+
+	// Reminders that cannot be built leave the schedule unchanged.
+	repo.scheduleUpdates, repo.reminders = nil, nil
+	notify.failFor = schedule.ID
+	if _, err := svc.UpdateSchedule(context.Background(), med.UserID, constants.RolePatient, schedule.ID.String(), dto.UpdateMedicineScheduleRequest{TimeSlot: strPtr("11:00")}); err == nil || repo.scheduleUpdates != nil {
+		t.Fatalf("expected the update to fail without changes, got %+v err=%v", repo.scheduleUpdates, err)
+	}
+
+	// Another patient cannot see or change the schedule; staff can.
+	repo.scheduleUpdates, notify.failFor = nil, uuid.Nil
+	_, err = svc.UpdateSchedule(context.Background(), uuid.New(), constants.RolePatient, schedule.ID.String(), dto.UpdateMedicineScheduleRequest{TimeSlot: strPtr("11:00")})
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.MedNotFound || repo.scheduleUpdates != nil {
+		t.Fatalf("expected another patient's schedule not found, got %v", err)
+	}
+	if _, err := svc.UpdateSchedule(context.Background(), uuid.New(), constants.RoleNurse, schedule.ID.String(), dto.UpdateMedicineScheduleRequest{TimeSlot: strPtr("11:00")}); err != nil {
+		t.Fatalf("expected a nurse to update the schedule: %v", err)
+	}
+
+	repo.reminders = nil
+	med.IsActive = false
+	if _, err := svc.UpdateSchedule(context.Background(), med.UserID, constants.RolePatient, schedule.ID.String(), dto.UpdateMedicineScheduleRequest{TimeSlot: strPtr("10:00")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.scheduleUpdates["time_slot"] != "10:00:00" || repo.reminders != nil {
+		t.Fatalf("expected no reminders for an inactive medicine")
+	}
+}
+
+func TestEnsureReminderHorizonPagesAllSchedules(t *testing.T) {
+	rows := make([]repositories.ActiveScheduleRow, reminderHorizonBatchSize+3)
+	for i := range rows {
+		rows[i] = repositories.ActiveScheduleRow{MedicineSchedule: db.MedicineSchedule{ID: uuid.New(), TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)}, UserID: uuid.New()}
+	}
+	repo := &medicineRepoStub{activeRows: rows}
+	notify := &notificationScheduleStub{failFor: rows[1].ID}
+	svc := NewMedicineService(repo, nil, notify, "UTC")
+
+	err := svc.EnsureReminderHorizon(context.Background())
+	if err == nil {
+		t.Fatalf("expected per-schedule failure to be reported")
+	}
+	if repo.activePages != 2 {
+		t.Fatalf("expected 2 pages, got %d", repo.activePages)
+	}
+	if len(notify.scheduled) != len(rows)-1 {
+		t.Fatalf("expected remaining schedules to be materialized, got %d", len(notify.scheduled))
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+// ListInbox pages through the user's sent notifications, newest first. Pass
+// next_cursor back as cursor to continue; it is null on the last page.
+func (s *notificationService) ListInbox(ctx context.Context, userID uuid.UUID, cursor string, limit int, unreadOnly bool) (dto.NotificationInboxResponse, error) {
+	var before *repositories.NotificationInboxCursor
+	if cursor = strings.TrimSpace(cursor); cursor != "" {
+		parsed, ok := decodeInboxCursor(cursor)
+		if !ok {
+			return dto.NotificationInboxResponse{}, domain.NewError(constants.ValidationFailed, "invalid cursor")
+		}
+		before = &parsed
+	}
+	if limit <= 0 {
+		limit = constants.DefaultInboxLimit
+	}
+	if limit > constants.MaxInboxLimit {
+		limit = constants.MaxInboxLimit
+	}
+
+	items, err := s.repo.ListInbox(ctx, userID, before, unreadOnly, limit+1)
+	if err != nil {
+		return dto.NotificationInboxResponse{}, err
+	}
+
+	resp := dto.NotificationInboxResponse{Items: make([]dto.NotificationInboxItem, 0, len(items))}
+	if len(items) > limit {
+		items = items[:limit]
+		resp.HasMore = true
+	}
+	var settings *notificationSettings
+	for _, item := range items {
+		var msg NotificationMessage
+		if item.Title != nil {
+			msg.Title = *item.Title
+		}
+		if item.Body != nil {
+			msg.Body = *item.Body
+		}
+		if item.Title == nil && item.Body == nil {
+			if settings == nil {
+				loaded, err := s.locations.settingsFor(ctx, userID)
+				if err != nil {
+					return dto.NotificationInboxResponse{}, err
+				}
+				settings = &loaded
+			}
+			msg = s.renderSentEvent(ctx, item, *settings)
+		}
+		resp.Items = append(resp.Items, toNotificationInboxItem(item, msg))
+	}
+	if resp.HasMore {
+		next := encodeInboxCursor(items[len(items)-1])
+		resp.NextCursor = &next
+	}
+	return resp, nil
+}
+
+// renderSentEvent renders an event sent before messages were stored with the
+// current template. An event that no longer renders is listed without a title
+// and body.
+func (s *notificationService) renderSentEvent(ctx context.Context, event db.NotificationEvent, settings notificationSettings) NotificationMessage {
+	msg, err := s.renderEvent(ctx, event, settings)
+	if err != nil {
+		return NotificationMessage{}
+	}
+	return msg
+}
+
+func (s *notificationService) UnreadCount(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	count, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return dto.NotificationUnreadCountResponse{}, err
+	}
+	return dto.NotificationUnreadCountResponse{UnreadCount: count}, nil
+}
+
+// MarkRead marks one of the user's inbox items read and returns the new
+// unread count.
+func (s *notificationService) MarkRead(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return dto.NotificationUnreadCountResponse{}, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+	if err := s.repo.MarkRead(ctx, userID, eventID, s.now().UTC()); err != nil {
+		return dto.NotificationUnreadCountResponse{}, err
+	}
+	return s.UnreadCount(ctx, userID)
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	if _, err := s.repo.MarkAllRead(ctx, userID, s.now().UTC()); err != nil {
+		return dto.NotificationUnreadCountResponse{}, err
+	}
+	return s.UnreadCount(ctx, userID)
+}
+
+// ArchiveInboxItem removes an item from the user's inbox and unread count and
+// returns the new unread count.
+func (s *notificationService) ArchiveInboxItem(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return dto.NotificationUnreadCountResponse{}, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+	if err := s.repo.Archive(ctx, userID, eventID, s.now().UTC()); err != nil {
+		return dto.NotificationUnreadCountResponse{}, err
+	}
+	return s.UnreadCount(ctx, userID)
+}
+
+// encodeInboxCursor makes an opaque cursor from the sent time and id of the
+// last item of a page.
+func encodeInboxCursor(event db.NotificationEvent) string {
+	var sentAt time.Time
+	if event.SentAt != nil {
+		sentAt = *event.SentAt
+	}
+	raw := sentAt.UTC().Format(time.RFC3339Nano) + "|" + event.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeInboxCursor(cursor string) (repositories.NotificationInboxCursor, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return repositories.NotificationInboxCursor{}, false
+	}
+	sentAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return repositories.NotificationInboxCursor{}, false
+	}
+	parsedAt, err := time.Parse(time.RFC3339Nano, sentAt)
+	if err != nil {
+		return repositories.NotificationInboxCursor{}, false
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return repositories.NotificationInboxCursor{}, false
+	}
+	return repositories.NotificationInboxCursor{SentAt: parsedAt, ID: parsedID}, true
+}
+
+func toNotificationInboxItem(event db.NotificationEvent, msg NotificationMessage) dto.NotificationInboxItem {
+	item := dto.NotificationInboxItem{
+		ID:           event.ID.String(),
+		TemplateCode: event.TemplateCode,
+		Title:        msg.Title,
+		Body:         msg.Body,
+		ReadAt:       event.ReadAt,
+	}
+	if len(event.Payload) > 0 {
+		item.Payload = json.RawMessage(event.Payload)
+	}
+	if event.SentAt != nil {
+		item.SentAt = *event.SentAt
+	}
+	return item
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+const quietHoursLayout = "15:04"
+
+var (
+	notificationChannels            = []string{constants.NotificationChannelPush, constants.NotificationChannelLine, constants.NotificationChannelSMS}
+	defaultNotificationChannels     = []string{constants.NotificationChannelPush, constants.NotificationChannelLine}
+	defaultAppointmentReminderDays  = []int{5, 1}
+	appointmentTemplatesByDaysAhead = map[int]string{5: constants.TemplateAppt5Days, 1: constants.TemplateAppt1Day}
+)
+
+// notificationSettings is a user's notification preferences with the defaults
+// applied. Quiet hours are minutes after local midnight; start after end spans
+// midnight. An empty language follows NOTIFICATION_DEFAULT_LOCALE.
+type notificationSettings struct {
+	location           *time.Location
+	language           string
+	channels           []string
+	quietHours         bool
+	quietStart         int
+	quietEnd           int
+	appointmentDays    []int
+	beforeMealLead     time.Duration
+	beforeMealFollowUp time.Duration
+}
+
+// notificationSettingsOf applies the defaults to pref, which is nil when the
+// user saved no preferences. The location is left for the caller to resolve.
+func notificationSettingsOf(pref *db.UserPreference) notificationSettings {
+	settings := notificationSettings{
+		channels:           defaultNotificationChannels,
+		appointmentDays:    defaultAppointmentReminderDays,
+		beforeMealLead:     constants.DefaultBeforeMealLeadMinutes * time.Minute,
+		beforeMealFollowUp: constants.DefaultBeforeMealFollowUpMinutes * time.Minute,
+	}
+	if pref == nil {
+		return settings
+	}
+
+	var channels []string
+	if len(pref.NotificationChannels) > 0 && json.Unmarshal(pref.NotificationChannels, &channels) == nil && len(channels) > 0 {
+		settings.channels = channels
+	}
+	var days []int
+	if len(pref.AppointmentReminderDays) > 0 && json.Unmarshal(pref.AppointmentReminderDays, &days) == nil {
+		settings.appointmentDays = days
+	}
+	if pref.QuietHoursStart != nil && pref.QuietHoursEnd != nil {
+		settings.quietHours = true
+		settings.quietStart = minuteOfDay(*pref.QuietHoursStart)
+		settings.quietEnd = minuteOfDay(*pref.QuietHoursEnd)
+	}
+	if pref.Language != nil {
+		settings.language = *pref.Language
+	}
+	if pref.BeforeMealLeadMinutes != nil {
+		settings.beforeMealLead = time.Duration(*pref.BeforeMealLeadMinutes) * time.Minute
+	}
+	if pref.BeforeMealFollowUpMinutes != nil {
+		settings.beforeMealFollowUp = time.Duration(*pref.BeforeMealFollowUpMinutes) * time.Minute
+	}
+	return settings
+}
+
+// settingsFor loads the user's notification settings in their location.
+func (l userLocations) settingsFor(ctx context.Context, userID uuid.UUID) (notificationSettings, error) {
+	pref, err := l.preference(ctx, userID)
+	if err != nil {
+		return notificationSettings{}, err
+	}
+	settings := notificationSettingsOf(pref)
+	settings.location = l.fallback
+	if pref != nil {
+		settings.location = l.resolve(pref.Timezone)
+	}
+	return settings, nil
+}
+
+// quietUntil reports whether at falls within the quiet hours and, if so, when
+// they end.
+func (s notificationSettings) quietUntil(at time.Time) (time.Time, bool) {
+	if !s.quietHours {
+		return time.Time{}, false
+	}
+	local := at.In(s.location)
+	minute := minuteOfDay(local)
+	quiet := minute >= s.quietStart && minute < s.quietEnd
+	if s.quietStart > s.quietEnd {
+		quiet = minute >= s.quietStart || minute < s.quietEnd
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+	until := time.Date(local.Year(), local.Month(), local.Day(), s.quietEnd/60, s.quietEnd%60, 0, 0, s.location)
+	if !until.After(local) {
+		until = time.Date(local.Year(), local.Month(), local.Day()+1, s.quietEnd/60, s.quietEnd%60, 0, 0, s.location)
+	}
+	return until.UTC(), true
+}
+
+// appointmentTemplate keeps the dedicated templates for the default lead times
+// and uses the generic one, which reads days_before, for the rest.
+func appointmentTemplate(daysBefore int) string {
+	if code, ok := appointmentTemplatesByDaysAhead[daysBefore]; ok {
+		return code
+	}
+	return constants.TemplateApptReminder
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+// NotificationMessage is a notification rendered for its recipient: the
+// template's title and body filled in, and the data handed to the client app.
+type NotificationMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// errNotificationRender marks a template that cannot be rendered for an event,
+// such as a syntax error, an unknown variable or a deleted schedule. Retrying
+// does not help.
+var errNotificationRender = errors.New("notification render failed")
+
+var (
+	thaiMonths      = []string{"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน", "กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม"}
+	thaiMonthsShort = []string{"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.", "ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค."}
+
+	// mealNames are breakfast, lunch and dinner; mealTimingLabels put the meal
+	// of a dose time in place of {meal}.
+	mealNames = map[string][]string{
+		constants.LocaleThai:    {"เช้า", "กลางวัน", "เย็น"},
+		constants.LocaleEnglish: {"breakfast", "lunch", "dinner"},
+	}
+	mealTimingLabels = map[string]map[string]string{
+		constants.LocaleThai: {
+			constants.MealTimingBeforeMeal:           "ก่อนอาหาร{meal}",
+			constants.MealTimingAfterMeal:            "หลังอาหาร{meal}",
+			constants.MealTimingAfterMealImmediately: "หลังอาหาร{meal}ทันที",
+			constants.MealTimingBeforeBed:            "ก่อนนอน",
+			constants.MealTimingUntilFinished:        "ทานติดต่อกันจนหมด",
+			constants.MealTimingNoMilk:               "ห้ามทานพร้อมนม",
+		},
+		constants.LocaleEnglish: {
+			constants.MealTimingBeforeMeal:           "before {meal}",
+			constants.MealTimingAfterMeal:            "after {meal}",
+			constants.MealTimingAfterMealImmediately: "right after {meal}",
+			constants.MealTimingBeforeBed:            "at bedtime",
+			constants.MealTimingUntilFinished:        "until finished",
+			constants.MealTimingNoMilk:               "not with milk",
+		},
+	}
+)
+
+// notificationRenderer turns an event into a NotificationMessage. Templates are
+// Go text/template; the variables are the template data and event payload,
+// plus what the payload's ids resolve to:
+//
+//   - schedule_id: medicine_name, dosage, meal_timing and, with target_date,
+//     dose_at
+//   - appointment_id: appointment_title, appointment_location and appointment_at
+//
+// Dates in the payload (target_date, run_out_date) become times, and date,
+// fulldate and time format times in the recipient's locale and location; Thai
+// dates use the Buddhist era.
+type notificationRenderer struct {
+	medicines    repositories.MedicineRepository
+	appointments repositories.AppointmentRepository
+}
+
+func newNotificationRenderer(medicines repositories.MedicineRepository, appointments repositories.AppointmentRepository) notificationRenderer {
+	return notificationRenderer{medicines: medicines, appointments: appointments}
+}
+
+func (r notificationRenderer) render(ctx context.Context, event db.NotificationEvent, tpl db.NotificationTemplate, locale string, location *time.Location) (NotificationMessage, error) {
+	values := map[string]any{}
+	if len(tpl.Data) > 0 {
+		_ = json.Unmarshal(tpl.Data, &values)
+	}
+	if len(event.Payload) > 0 {
+		var payload map[string]any
+		if err := json.Unmarshal(event.Payload, &payload); err == nil {
+			for k, v := range payload {
+				values[k] = v
+			}
+		}
+	}
+
+	data := make(map[string]string, len(values)+2)
+	for k, v := range values {
+		// An escalation digest can list more patients than fit in a push
+		// payload; apps read the ids from the inbox item instead.
+		if k == "patient_ids" {
+			continue
+		}
+		data[k] = pushValueString(v)
+	}
+	data["template_code"] = event.TemplateCode
+	data["notification_event_id"] = event.ID.String()
+
+	vars, err := r.variables(ctx, values, locale, location)
+	if err != nil {
+		return NotificationMessage{}, err
+	}
+	title, err := executeNotificationTemplate("title", tpl.Title, vars, locale, location)
+	if err != nil {
+		return NotificationMessage{}, err
+	}
+	body, err := executeNotificationTemplate("body", tpl.Body, vars, locale, location)
+	if err != nil {
+		return NotificationMessage{}, err
+	}
+	return NotificationMessage{Title: title, Body: body, Data: data}, nil
+}
+
+// variables resolves the payload ids into the values templates read. An id
+// that no longer resolves is a render error.
+func (r notificationRenderer) variables(ctx context.Context, values map[string]any, locale string, location *time.Location) (map[string]any, error) {
+	vars := make(map[string]any, len(values)+4)
+	for k, v := range values {
+		vars[k] = v
+	}
+	for _, key := range []string{"target_date", "run_out_date"} {
+		if value, ok := vars[key].(string); ok {
+			if date, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+				vars[key] = date
+			}
+		}
+	}
+
+	if value, ok := values["schedule_id"].(string); ok && r.medicines != nil {
+		if err := r.resolveSchedule(ctx, vars, value, locale, location); err != nil {
+			return nil, err
+		}
+	}
+	if value, ok := values["appointment_id"].(string); ok && r.appointments != nil {
+		if err := r.resolveAppointment(ctx, vars, value, location); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+func (r notificationRenderer) resolveSchedule(ctx context.Context, vars map[string]any, value, locale string, location *time.Location) error {
+	scheduleID, err := uuid.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%w: invalid schedule_id", errNotificationRender)
+	}
+	schedule, err := r.medicines.GetScheduleByID(ctx, scheduleID)
+	if err != nil {
+		return renderLookupError(err, constants.MedNotFound)
+	}
+	med, err := r.medicines.GetPatientMedicineByID(ctx, schedule.PatientMedicineID)
+	if err != nil {
+		return renderLookupError(err, constants.MedNotFound)
+	}
+
+	dosage := med.DosageAmount
+	if med.MedicineMasterID != nil && len(strings.Fields(dosage)) == 1 {
+		if _, ok := parseDosageAmount(dosage); ok {
+			if master, err := r.medicines.GetMasterByID(ctx, *med.MedicineMasterID); err == nil && master.DosageUnit != "" {
+				dosage += " " + master.DosageUnit
+			}
+		}
+	}
+
+	vars["medicine_name"] = medicineDisplayName(ctx, r.medicines, *med)
+	vars["dosage"] = dosage
+	vars["meal_timing"] = mealTimingLabel(locale, schedule.MealTiming, schedule.TimeSlot)
+	if day, ok := vars["target_date"].(time.Time); ok {
+		vars["dose_at"] = time.Date(day.Year(), day.Month(), day.Day(), schedule.TimeSlot.Hour(), schedule.TimeSlot.Minute(), 0, 0, location)
+	}
+	return nil
+}
+
+func (r notificationRenderer) resolveAppointment(ctx context.Context, vars map[string]any, value string, location *time.Location) error {
+	appointmentID, err := uuid.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%w: invalid appointment_id", errNotificationRender)
+	}
+	appt, err := r.appointments.FindByID(ctx, appointmentID)
+	if err != nil {
+		return renderLookupError(err, constants.ApptNotFound)
+	}
+	vars["appointment_title"] = appt.Title
+	vars["appointment_location"] = ""
+	if appt.LocationName != nil {
+		vars["appointment_location"] = *appt.LocationName
+	}
+	vars["appointment_at"] = appt.ApptDateTime.In(location)
+	return nil
+}
+
+// renderLookupError makes a missing record a render error and leaves other
+// errors retryable.
+func renderLookupError(err error, notFound string) error {
+	if appErr, ok := domain.AsAppError(err); ok && appErr.Code == notFound {
+		return fmt.Errorf("%w: %s", errNotificationRender, appErr.Message)
+	}
+	return err
+}
+
+func parseNotificationTemplate(name, text, locale string, location *time.Location) (*template.Template, error) {
+	tpl, err := template.New(name).Funcs(notificationFuncs(locale, location)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotificationRender, err)
+	}
+	return tpl, nil
+}
+
+func executeNotificationTemplate(name, text string, vars map[string]any, locale string, location *time.Location) (string, error) {
+	tpl, err := parseNotificationTemplate(name, text, locale, location)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("%w: %v", errNotificationRender, err)
+	}
+	return out.String(), nil
+}
+
+func notificationFuncs(locale string, location *time.Location) template.FuncMap {
+	return template.FuncMap{
+		"date": func(v any) (string, error) {
+			t, err := templateTime(v, location)
+			if err != nil {
+				return "", err
+			}
+			return formatLocalDate(t, locale, false), nil
+		},
+		"fulldate": func(v any) (string, error) {
+			t, err := templateTime(v, location)
+			if err != nil {
+				return "", err
+			}
+			return formatLocalDate(t, locale, true), nil
+		},
+		"time": func(v any) (string, error) {
+			t, err := templateTime(v, location)
+			if err != nil {
+				return "", err
+			}
+			return formatLocalTime(t, locale), nil
+		},
+	}
+}
+
+// templateTime accepts a time or a YYYY-MM-DD or RFC 3339 string.
+func templateTime(v any, location *time.Location) (time.Time, error) {
+	switch value := v.(type) {
+	case time.Time:
+		return value.In(location), nil
+	case string:
+		if t, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t.In(location), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not a date: %v", v)
+}
+
+// formatLocalDate writes "20 ม.ค. 2569" or "20 Jan 2026", or with full month
+// names when long is set. Thai years are in the Buddhist era.
+func formatLocalDate(t time.Time, locale string, long bool) string {
+	if locale != constants.LocaleThai {
+		if long {
+			return t.Format("2 January 2006")
+		}
+		return t.Format("2 Jan 2006")
+	}
+	months := thaiMonthsShort
+	if long {
+		months = thaiMonths
+	}
+	return fmt.Sprintf("%d %s %d", t.Day(), months[t.Month()-1], t.Year()+543)
+}
+
+func formatLocalTime(t time.Time, locale string) string {
+	if locale == constants.LocaleThai {
+		return t.Format("15:04") + " น."
+	}
+	return t.Format("15:04")
+}
+
+// mealTimingLabel describes when a dose is taken, such as "after breakfast".
+// The meal follows the dose time: before 11:00 breakfast, before 16:00 lunch,
+// otherwise dinner.
+func mealTimingLabel(locale string, timing *string, slot time.Time) string {
+	if timing == nil {
+		return ""
+	}
+	labels, ok := mealTimingLabels[locale]
+	if !ok {
+		labels = mealTimingLabels[constants.LocaleEnglish]
+	}
+	label := labels[*timing]
+	if !strings.Contains(label, "{meal}") {
+		return label
+	}
+	meals, ok := mealNames[locale]
+	if !ok {
+		meals = mealNames[constants.LocaleEnglish]
+	}
+	meal := meals[2]
+	switch {
+	case slot.Hour() < 11:
+		meal = meals[0]
+	case slot.Hour() < 16:
+		meal = meals[1]
+	}
+	return strings.ReplaceAll(label, "{meal}", meal)
+}
+
+func pushValueString(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	default:
+		raw, _ := json.Marshal(value)
+		return string(raw)
+	}
+}
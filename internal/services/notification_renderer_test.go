@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+func TestNotificationRendererResolvesPayload(t *testing.T) {
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	masterID := uuid.New()
+	med := &db.PatientMedicine{ID: uuid.New(), MedicineMasterID: &masterID, DosageAmount: "1"}
+	timing := constants.MealTimingAfterMeal
+	schedule := &db.MedicineSchedule{ID: uuid.New(), PatientMedicineID: med.ID, TimeSlot: time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC), MealTiming: &timing}
+	location := "Outpatient building 2"
+	appt := &db.Appointment{ID: uuid.New(), Title: "Cardiology follow-up", LocationName: &location, ApptDateTime: time.Date(2026, 1, 21, 2, 30, 0, 0, time.UTC)}
+	renderer := newNotificationRenderer(
+		&medicineRepoStub{patientMedicine: med, schedule: schedule, master: &db.MedicineMaster{ID: masterID, TradeName: "Amlodipine 5mg", DosageUnit: "tablet"}},
+		&appointmentRepoStub{appointment: appt},
+	)
+
+	dose := db.NotificationEvent{ID: uuid.New(), TemplateCode: constants.TemplateMedAfterMealNow, Payload: datatypes.JSON(`{"schedule_id":"` + schedule.ID.String() + `","target_date":"2026-01-20"}`)}
+	msg, err := renderer.render(context.Background(), dose, db.NotificationTemplate{
+		Title: "Medicine reminder",
+		Body:  "Time to take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} at {{time .dose_at}} on {{date .target_date}}.",
+		Data:  datatypes.JSON(`{"screen":"intake"}`),
+	}, constants.LocaleEnglish, bangkok)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Body != "Time to take Amlodipine 5mg 1 tablet after breakfast at 08:00 on 20 Jan 2026." {
+		t.Fatalf("unexpected body: %q", msg.Body)
+	}
+	if msg.Data["screen"] != "intake" || msg.Data["target_date"] != "2026-01-20" || msg.Data["notification_event_id"] != dose.ID.String() {
+		t.Fatalf("unexpected data: %+v", msg.Data)
+	}
+
+	reminder := db.NotificationEvent{ID: uuid.New(), TemplateCode: constants.TemplateApptReminder, Payload: datatypes.JSON(`{"appointment_id":"` + appt.ID.String() + `","days_before":3}`)}
+	msg, err = renderer.render(context.Background(), reminder, db.NotificationTemplate{
+		Title: "เตือนนัดหมาย",
+		Body:  "อีก {{.days_before}} วันมีนัด {{.appointment_title}} วันที่ {{fulldate .appointment_at}} เวลา {{time .appointment_at}}{{with .appointment_location}} ที่ {{.}}{{end}}",
+	}, constants.LocaleThai, bangkok)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.Body != "อีก 3 วันมีนัด Cardiology follow-up วันที่ 21 มกราคม 2569 เวลา 09:30 น. ที่ Outpatient building 2" {
+		t.Fatalf("unexpected Thai body: %q", msg.Body)
+	}
+
+	// Escalation patient ids are kept out of the push data.
+	escalation := db.NotificationEvent{ID: uuid.New(), TemplateCode: constants.TemplateEscalationMissedNurse, Payload: datatypes.JSON(`{"type":"missed_dose_escalation","patient_ids":["` + uuid.NewString() + `","` + uuid.NewString() + `"],"patient_count":2}`)}
+	msg, err = renderer.render(context.Background(), escalation, db.NotificationTemplate{Title: "Missed doses", Body: "{{.patient_count}} patients missed doses"}, constants.LocaleEnglish, bangkok)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := msg.Data["patient_ids"]; ok || msg.Body != "2 patients missed doses" || msg.Data["patient_count"] != "2" {
+		t.Fatalf("unexpected escalation message: %q %+v", msg.Body, msg.Data)
+	}
+
+	// Unknown variables, syntax errors and deleted records cannot be retried.
+	if _, err := renderer.render(context.Background(), dose, db.NotificationTemplate{Title: "t", Body: "{{.medicine}}"}, constants.LocaleEnglish, bangkok); !errors.Is(err, errNotificationRender) {
+		t.Fatalf("expected render error for an unknown variable, got %v", err)
+	}
+	if _, err := renderer.render(context.Background(), dose, db.NotificationTemplate{Title: "{{.medicine_name", Body: "b"}, constants.LocaleEnglish, bangkok); !errors.Is(err, errNotificationRender) {
+		t.Fatalf("expected render error for a syntax error, got %v", err)
+	}
+	deleted := newNotificationRenderer(&medicineRepoStub{}, nil)
+	if _, err := deleted.render(context.Background(), dose, db.NotificationTemplate{Title: "t", Body: "b"}, constants.LocaleEnglish, bangkok); !errors.Is(err, errNotificationRender) {
+		t.Fatalf("expected render error for a deleted schedule, got %v", err)
+	}
+}
+
+func TestFormatLocalDate(t *testing.T) {
+	day := time.Date(2026, 3, 5, 14, 5, 0, 0, time.UTC)
+	cases := []struct {
+		locale string
+		long   bool
+		want   string
+	}{
+		{constants.LocaleThai, false, "5 มี.ค. 2569"},
+		{constants.LocaleThai, true, "5 มีนาคม 2569"},
+		{constants.LocaleEnglish, false, "5 Mar 2026"},
+		{constants.LocaleEnglish, true, "5 March 2026"},
+	}
+	for _, tc := range cases {
+		if got := formatLocalDate(day, tc.locale, tc.long); got != tc.want {
+			t.Fatalf("%s long=%v: expected %q, got %q", tc.locale, tc.long, tc.want, got)
+		}
+	}
+	if got := formatLocalTime(day, constants.LocaleThai); got != "14:05 น." {
+		t.Fatalf("unexpected Thai time: %q", got)
+	}
+
+	timing := constants.MealTimingBeforeMeal
+	if got := mealTimingLabel(constants.LocaleThai, &timing, time.Date(0, 1, 1, 18, 0, 0, 0, time.UTC)); got != "ก่อนอาหารเย็น" {
+		t.Fatalf("unexpected meal timing: %q", got)
+	}
+}
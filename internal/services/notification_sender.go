@@ -2,21 +2,27 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"go.uber.org/zap"
 
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
 )
 
 type NotificationSender interface {
-	Send(ctx context.Context, event db.NotificationEvent, template db.NotificationTemplate) error
+	Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error
 }
 
 type ConsoleNotificationSender struct {
 	Logger *zap.Logger
 }
 
-func (s ConsoleNotificationSender) Send(ctx context.Context, event db.NotificationEvent, template db.NotificationTemplate) error {
+func (s ConsoleNotificationSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
 	if s.Logger == nil {
 		return nil
 	}
@@ -24,3 +30,103 @@ func (s ConsoleNotificationSender) Send(ctx context.Context, event db.Notificati
 	s.Logger.Info("notification send", zap.String("request_id", "job"), zap.String("user_id", event.UserID.String()), zap.String("template_code", event.TemplateCode), zap.Time("scheduled_at", event.ScheduledAt))
 	return nil
 }
+
+// ChannelSender is a sender for one notification channel, such as
+// constants.NotificationChannelPush.
+type ChannelSender struct {
+	Channel string
+	Sender  NotificationSender
+}
+
+// errNoNotificationChannel means none of the channels a delivery may use is
+// configured, so nothing can be delivered until the configuration changes.
+var errNoNotificationChannel = errors.New("no configured notification channel")
+
+// MultiNotificationSender delivers through every channel. It fails only when
+// all channels fail, so one unavailable channel does not cause the others to
+// resend on retry, and when it has no channel at all.
+type MultiNotificationSender []ChannelSender
+
+func (s MultiNotificationSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
+	if len(s) == 0 {
+		return errNoNotificationChannel
+	}
+	var errs []error
+	for _, channel := range s {
+		if err := channel.Sender.Send(ctx, event, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(s) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// SendVia delivers through the given channels only, with the same failure
+// rules as Send: none of them being configured is an error.
+func (s MultiNotificationSender) SendVia(ctx context.Context, event db.NotificationEvent, msg NotificationMessage, channels []string) error {
+	var selected MultiNotificationSender
+	for _, channel := range s {
+		if isAllowed(channel.Channel, channels) {
+			selected = append(selected, channel)
+		}
+	}
+	return selected.Send(ctx, event, msg)
+}
+
+// Channels lists the configured channels.
+func (s MultiNotificationSender) Channels() []string {
+	channels := make([]string, 0, len(s))
+	for _, channel := range s {
+		channels = append(channels, channel.Channel)
+	}
+	return channels
+}
+
+// NewConfiguredNotificationSender builds the sender selected by PUSH_PROVIDER,
+// adding LINE when a channel access token is configured and SMS when
+// SMS_PROVIDER is thaibulksms. It returns nil when every channel is disabled.
+func NewConfiguredNotificationSender(cfg config.Config, tokens repositories.DeviceTokenRepository, users repositories.UserRepository, logger *zap.Logger) (NotificationSender, error) {
+	var senders MultiNotificationSender
+
+	provider := strings.ToLower(strings.TrimSpace(cfg.Push.Provider))
+	switch provider {
+	case "", "console":
+		senders = append(senders, ChannelSender{Channel: constants.NotificationChannelPush, Sender: ConsoleNotificationSender{Logger: logger}})
+	case "disabled", "none":
+	case "push":
+		push, err := NewPushNotificationSender(cfg.Push, tokens, logger)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, ChannelSender{Channel: constants.NotificationChannelPush, Sender: push})
+	default:
+		return nil, fmt.Errorf("unsupported push provider: %s", cfg.Push.Provider)
+	}
+
+	if cfg.Line.MessagingEnabled() {
+		line, err := NewLineNotificationSender(cfg.Line, users, logger)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, ChannelSender{Channel: constants.NotificationChannelLine, Sender: line})
+	}
+
+	if strings.EqualFold(strings.TrimSpace(cfg.SMS.Provider), "thaibulksms") {
+		client, err := NewThaiBulkSMSSender(cfg.SMS.ThaiBulkSMS, logger)
+		if err != nil {
+			return nil, err
+		}
+		sms, err := NewSmsNotificationSender(client, users)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, ChannelSender{Channel: constants.NotificationChannelSMS, Sender: sms})
+	}
+
+	if len(senders) == 0 {
+		return nil, nil
+	}
+	return senders, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+)
+
+// apnsTokenRefresh is below Apple's one hour limit on provider token age.
+const apnsTokenRefresh = 50 * time.Minute
+
+// apnsClient sends through the APNs provider API using token-based (.p8)
+// authentication.
+type apnsClient struct {
+	baseURL string
+	keyID   string
+	teamID  string
+	topic   string
+	key     *ecdsa.PrivateKey
+	client  *http.Client
+	now     func() time.Time
+
+	mu       sync.Mutex
+	jwtToken string
+	issuedAt time.Time
+
+	// hostVerified is set once APNs accepted a message, proving baseURL
+	// matches the app's environment. Until then BadDeviceToken may mean a
+	// sandbox/production mix-up rather than a bad token.
+	hostVerified atomic.Bool
+}
+
+func newAPNsClient(cfg config.APNsConfig, client *http.Client) (*apnsClient, error) {
+	raw, err := os.ReadFile(strings.TrimSpace(cfg.KeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("read apns key: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse apns key: %w", err)
+	}
+
+	return &apnsClient{
+		baseURL: strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/"),
+		keyID:   strings.TrimSpace(cfg.KeyID),
+		teamID:  strings.TrimSpace(cfg.TeamID),
+		topic:   strings.TrimSpace(cfg.Topic),
+		key:     key,
+		client:  client,
+		now:     time.Now,
+	}, nil
+}
+
+func (c *apnsClient) Send(ctx context.Context, deviceToken string, msg NotificationMessage) error {
+	providerToken, err := c.providerToken()
+	if err != nil {
+		return err
+	}
+
+	payload := make(map[string]any, len(msg.Data)+1)
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	payload["aps"] = map[string]any{
+		"alert": map[string]string{
+			"title": msg.Title,
+			"body":  msg.Body,
+		},
+		"sound": "default",
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	urlStr := c.baseURL + "/3/device/" + url.PathEscape(deviceToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", c.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusOK {
+		c.hostVerified.Store(true)
+		return nil
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(raw, &apnsErr)
+	switch {
+	case resp.StatusCode == http.StatusGone, apnsErr.Reason == "Unregistered",
+		apnsErr.Reason == "BadDeviceToken" && c.hostVerified.Load():
+		return fmt.Errorf("%w: apns status=%d reason=%s", errPushTokenUnregistered, resp.StatusCode, apnsErr.Reason)
+	case apnsErr.Reason == "ExpiredProviderToken":
+		c.resetToken()
+	}
+	return fmt.Errorf("apns error status=%d reason=%s", resp.StatusCode, apnsErr.Reason)
+}
+
+func (c *apnsClient) providerToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.jwtToken != "" && now.Sub(c.issuedAt) < apnsTokenRefresh {
+		return c.jwtToken, nil
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": c.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = c.keyID
+	signed, err := token.SignedString(c.key)
+	if err != nil {
+		return "", fmt.Errorf("sign apns token: %w", err)
+	}
+
+	c.jwtToken = signed
+	c.issuedAt = now
+	return c.jwtToken, nil
+}
+
+func (c *apnsClient) resetToken() {
+	c.mu.Lock()
+	c.jwtToken = ""
+	c.mu.Unlock()
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+)
+
+const (
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmDefaultTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+type fcmServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type fcmErrorResponse struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// fcmClient sends through FCM HTTP v1, authenticating with an OAuth2 access
+// token obtained from a service account JWT assertion.
+type fcmClient struct {
+	baseURL     string
+	projectID   string
+	tokenURL    string
+	clientEmail string
+	key         *rsa.PrivateKey
+	client      *http.Client
+	now         func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func newFCMClient(cfg config.FCMConfig, client *http.Client) (*fcmClient, error) {
+	raw, err := os.ReadFile(strings.TrimSpace(cfg.CredentialsFile))
+	if err != nil {
+		return nil, fmt.Errorf("read fcm credentials: %w", err)
+	}
+	var account fcmServiceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("parse fcm credentials: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("fcm credentials require client_email and private_key")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse fcm private key: %w", err)
+	}
+
+	tokenURL := strings.TrimSpace(cfg.TokenURL)
+	if tokenURL == "" {
+		tokenURL = account.TokenURI
+	}
+	if tokenURL == "" {
+		tokenURL = fcmDefaultTokenURL
+	}
+
+	return &fcmClient{
+		baseURL:     strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/"),
+		projectID:   strings.TrimSpace(cfg.ProjectID),
+		tokenURL:    tokenURL,
+		clientEmail: account.ClientEmail,
+		key:         key,
+		client:      client,
+		now:         time.Now,
+	}, nil
+}
+
+func (c *fcmClient) Send(ctx context.Context, deviceToken string, msg NotificationMessage) error {
+	accessToken, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"token": deviceToken,
+			"notification": map[string]string{
+				"title": msg.Title,
+				"body":  msg.Body,
+			},
+			"data":    msg.Data,
+			"android": map[string]string{"priority": "HIGH"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	urlStr := c.baseURL + "/v1/projects/" + url.PathEscape(c.projectID) + "/messages:send"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlStr, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var fcmErr fcmErrorResponse
+	_ = json.Unmarshal(raw, &fcmErr)
+	// FCM also answers 404 for a wrong project or base URL, so only the
+	// UNREGISTERED error code means the token itself is gone.
+	if fcmErr.hasErrorCode("UNREGISTERED") {
+		return fmt.Errorf("%w: fcm status=%d %s", errPushTokenUnregistered, resp.StatusCode, fcmErr.Error.Status)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		c.resetToken()
+	}
+	return fmt.Errorf("fcm error status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
+
+func (e fcmErrorResponse) hasErrorCode(code string) bool {
+	for _, detail := range e.Error.Details {
+		if detail.ErrorCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// token returns a cached access token, exchanging a fresh assertion a minute
+// before the current one expires.
+func (c *fcmClient) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.accessToken != "" && now.Before(c.expiresAt) {
+		return c.accessToken, nil
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   c.clientEmail,
+		"scope": fcmScope,
+		"aud":   c.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(c.key)
+	if err != nil {
+		return "", fmt.Errorf("sign fcm assertion: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fcm token error status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(raw, &token); err != nil || token.AccessToken == "" {
+		return "", errors.New("fcm token response missing access_token")
+	}
+	if token.ExpiresIn <= 0 {
+		token.ExpiresIn = 3600
+	}
+
+	c.accessToken = token.AccessToken
+	c.expiresAt = now.Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.accessToken, nil
+}
+
+func (c *fcmClient) resetToken() {
+	c.mu.Lock()
+	c.accessToken = ""
+	c.mu.Unlock()
+}
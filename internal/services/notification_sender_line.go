@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+const (
+	linePostbackIntake = "intake"
+	lineAltTextMax     = 400
+)
+
+// LineNotificationSender pushes notifications to users who linked a LINE
+// account. Medicine reminders carry Taken/Skip postback buttons that the LINE
+// webhook turns into intake records.
+type LineNotificationSender struct {
+	users  repositories.UserRepository
+	client *lineMessagingClient
+	logger *zap.Logger
+}
+
+func NewLineNotificationSender(cfg config.LineConfig, users repositories.UserRepository, logger *zap.Logger) (*LineNotificationSender, error) {
+	if !cfg.MessagingEnabled() {
+		return nil, errors.New("line channel access token required")
+	}
+	if users == nil {
+		return nil, errors.New("user repository required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &LineNotificationSender{
+		users:  users,
+		client: newLineMessagingClient(cfg.MessagingBaseURL, cfg.ChannelAccessToken, &http.Client{Timeout: cfg.Timeout}),
+		logger: logger,
+	}, nil
+}
+
+// Send is a no-op for users without a linked LINE account.
+func (s *LineNotificationSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
+	user, err := s.users.FindByID(ctx, event.UserID)
+	if err != nil {
+		if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.UserNotFound {
+			return nil
+		}
+		return err
+	}
+	if user.LineUserID == nil || *user.LineUserID == "" {
+		return nil
+	}
+
+	return s.client.Push(ctx, *user.LineUserID, []any{lineFlexMessage(msg)})
+}
+
+func lineFlexMessage(msg NotificationMessage) map[string]any {
+	altText := msg.Title
+	if msg.Body != "" {
+		altText += ": " + msg.Body
+	}
+	if runes := []rune(altText); len(runes) > lineAltTextMax {
+		altText = string(runes[:lineAltTextMax])
+	}
+
+	bubble := map[string]any{
+		"type": "bubble",
+		"body": map[string]any{
+			"type":   "box",
+			"layout": "vertical",
+			"contents": []any{
+				map[string]any{"type": "text", "text": msg.Title, "weight": "bold", "size": "lg", "wrap": true},
+				map[string]any{"type": "text", "text": msg.Body, "wrap": true, "margin": "md"},
+			},
+		},
+	}
+
+	scheduleID, targetDate := msg.Data["schedule_id"], msg.Data["target_date"]
+	if scheduleID != "" && targetDate != "" {
+		bubble["footer"] = map[string]any{
+			"type":    "box",
+			"layout":  "horizontal",
+			"spacing": "md",
+			"contents": []any{
+				lineIntakeButton("Taken", "primary", scheduleID, targetDate, constants.MedTaken),
+				lineIntakeButton("Skip", "secondary", scheduleID, targetDate, constants.MedSkipped),
+			},
+		}
+	}
+
+	return map[string]any{"type": "flex", "altText": altText, "contents": bubble}
+}
+
+func lineIntakeButton(label, style, scheduleID, targetDate string, status constants.MedIntakeStatus) map[string]any {
+	data := url.Values{}
+	data.Set("action", linePostbackIntake)
+	data.Set("schedule_id", scheduleID)
+	data.Set("target_date", targetDate)
+	data.Set("status", string(status))
+	return map[string]any{
+		"type":  "button",
+		"style": style,
+		"action": map[string]any{
+			"type":        "postback",
+			"label":       label,
+			"data":        data.Encode(),
+			"displayText": label,
+		},
+	}
+}
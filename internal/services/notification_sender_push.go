@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+// errPushTokenUnregistered is wrapped by push clients when the provider reports
+// that a device token is no longer valid.
+var errPushTokenUnregistered = errors.New("push token unregistered")
+
+type pushClient interface {
+	Send(ctx context.Context, deviceToken string, msg NotificationMessage) error
+}
+
+// PushNotificationSender delivers a notification to every active
+// device token of the user: android and web tokens through FCM, ios tokens
+// through APNs.
+type PushNotificationSender struct {
+	tokens repositories.DeviceTokenRepository
+	fcm    pushClient
+	apns   pushClient
+	logger *zap.Logger
+}
+
+func NewPushNotificationSender(cfg config.PushConfig, tokens repositories.DeviceTokenRepository, logger *zap.Logger) (*PushNotificationSender, error) {
+	if tokens == nil {
+		return nil, errors.New("device token repository required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+
+	sender := &PushNotificationSender{tokens: tokens, logger: logger}
+	if cfg.FCM.Enabled() {
+		client, err := newFCMClient(cfg.FCM, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		sender.fcm = client
+	}
+	if cfg.APNs.Enabled() {
+		client, err := newAPNsClient(cfg.APNs, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		sender.apns = client
+	}
+	if sender.fcm == nil && sender.apns == nil {
+		return nil, errors.New("push sender requires fcm or apns configuration")
+	}
+	return sender, nil
+}
+
+// Send succeeds when at least one device accepted the message, or when the
+// user has no deliverable devices. Tokens the provider reports as
+// unregistered are deactivated.
+func (s *PushNotificationSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
+	tokens, err := s.tokens.ListActiveByUser(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	delivered := 0
+	var errs []error
+	for _, token := range tokens {
+		client := s.clientFor(token.Platform)
+		if client == nil {
+			continue
+		}
+
+		err := client.Send(ctx, token.Token, msg)
+		switch {
+		case err == nil:
+			delivered++
+		case errors.Is(err, errPushTokenUnregistered):
+			if err := s.tokens.Deactivate(ctx, token.ID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if s.logger != nil {
+				s.logger.Info("device token deactivated", zap.String("user_id", event.UserID.String()), zap.String("device_token_id", token.ID.String()), zap.String("platform", token.Platform))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s device %s: %w", token.Platform, token.ID, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if delivered == 0 {
+		return errors.Join(errs...)
+	}
+	if s.logger != nil {
+		s.logger.Warn("notification partially delivered", zap.String("user_id", event.UserID.String()), zap.String("template_code", event.TemplateCode), zap.Int("delivered", delivered), zap.Error(errors.Join(errs...)))
+	}
+	return nil
+}
+
+func (s *PushNotificationSender) clientFor(platform string) pushClient {
+	switch strings.ToLower(platform) {
+	case "android", "web":
+		return s.fcm
+	case "ios":
+		return s.apns
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+type pushTokenRepoStub struct {
+	tokens      []db.DeviceToken
+	deactivated []uuid.UUID
+}
+
+func (s *pushTokenRepoStub) Save(ctx context.Context, token *db.DeviceToken) error { return nil }
+func (s *pushTokenRepoStub) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]db.DeviceToken, error) {
+	return s.tokens, nil
+}
+func (s *pushTokenRepoStub) Deactivate(ctx context.Context, id uuid.UUID) error {
+	s.deactivated = append(s.deactivated, id)
+	var active []db.DeviceToken
+	for _, token := range s.tokens {
+		if token.ID != id {
+			active = append(active, token)
+		}
+	}
+	s.tokens = active
+	return nil
+}
+
+type pushStubServer struct {
+	mu          sync.Mutex
+	tokenCalls  int
+	fcmMessages []map[string]any
+	apnsPaths   []string
+	apnsBodies  []map[string]any
+	apnsHeaders []http.Header
+}
+
+func (s *pushStubServer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.tokenCalls++
+		s.mu.Unlock()
+		if err := r.ParseForm(); err != nil || r.Form.Get("assertion") == "" {
+			t.Errorf("expected jwt assertion")
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "ya29.test", "expires_in": 3600})
+	})
+	mux.HandleFunc("/v1/projects/demo/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		message := body["message"].(map[string]any)
+		s.mu.Lock()
+		s.fcmMessages = append(s.fcmMessages, message)
+		s.mu.Unlock()
+		if message["token"] == "android-stale" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+			return
+		}
+		if message["token"] == "android-misrouted" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","message":"Requested entity was not found."}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+	})
+	mux.HandleFunc("/3/device/", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		s.apnsPaths = append(s.apnsPaths, r.URL.Path)
+		s.apnsBodies = append(s.apnsBodies, body)
+		s.apnsHeaders = append(s.apnsHeaders, r.Header.Clone())
+		s.mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "ios-stale") {
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"reason":"Unregistered"}`))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "ios-bad") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "ios-broken") {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"reason":"InternalServerError"}`))
+			return
+		}
+	})
+	return mux
+}
+
+func newTestPushConfig(t *testing.T, baseURL string) config.PushConfig {
+	t.Helper()
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	rsaDER, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	account, _ := json.Marshal(map[string]string{
+		"client_email": "push@demo.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaDER})),
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+	credentialsFile := filepath.Join(dir, "fcm.json")
+	if err := os.WriteFile(credentialsFile, account, 0o600); err != nil {
+		t.Fatalf("write credentials: %v", err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key: %v", err)
+	}
+	ecDER, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	keyFile := filepath.Join(dir, "apns.p8")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER}), 0o600); err != nil {
+		t.Fatalf("write apns key: %v", err)
+	}
+
+	return config.PushConfig{
+		FCM: config.FCMConfig{BaseURL: baseURL, ProjectID: "demo", CredentialsFile: credentialsFile, TokenURL: baseURL + "/token"},
+		APNs: config.APNsConfig{
+			BaseURL: baseURL,
+			KeyID:   "KEY123",
+			TeamID:  "TEAM123",
+			Topic:   "com.example.smartcare",
+			KeyFile: keyFile,
+		},
+	}
+}
+
+func TestPushNotificationSenderFansOutAndDeactivates(t *testing.T) {
+	stub := &pushStubServer{}
+	server := httptest.NewServer(stub.handler(t))
+	defer server.Close()
+
+	androidID, staleAndroidID, iosID, staleIOSID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	repo := &pushTokenRepoStub{tokens: []db.DeviceToken{
+		{ID: androidID, Platform: "android", Token: "android-ok"},
+		{ID: staleAndroidID, Platform: "android", Token: "android-stale"},
+		{ID: iosID, Platform: "ios", Token: "ios-ok"},
+		{ID: staleIOSID, Platform: "ios", Token: "ios-stale"},
+		{ID: uuid.New(), Platform: "symbian", Token: "ignored"},
+	}}
+	sender, err := NewPushNotificationSender(newTestPushConfig(t, server.URL), repo, nil)
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+
+	event := db.NotificationEvent{ID: uuid.New(), UserID: uuid.New(), TemplateCode: "MED_AFTER_MEAL_NOW"}
+	msg := NotificationMessage{
+		Title: "Medicine time",
+		Body:  "Take your dose for 2026-01-20",
+		Data:  map[string]string{"screen": "intake", "schedule_id": "abc", "template_code": event.TemplateCode, "notification_event_id": event.ID.String()},
+	}
+
+	if err := sender.Send(context.Background(), event, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sender.Send(context.Background(), event, msg); err != nil {
+		t.Fatalf("unexpected error on second send: %v", err)
+	}
+
+	if stub.tokenCalls != 1 {
+		t.Fatalf("expected cached access token, got %d token calls", stub.tokenCalls)
+	}
+	if len(stub.fcmMessages) != 3 || len(stub.apnsPaths) != 3 {
+		t.Fatalf("expected fan-out to both providers, got fcm=%d apns=%d", len(stub.fcmMessages), len(stub.apnsPaths))
+	}
+
+	fcm := stub.fcmMessages[0]
+	notification := fcm["notification"].(map[string]any)
+	data := fcm["data"].(map[string]any)
+	if notification["body"] != "Take your dose for 2026-01-20" || data["screen"] != "intake" || data["schedule_id"] != "abc" || data["notification_event_id"] != event.ID.String() {
+		t.Fatalf("unexpected fcm message: %+v", fcm)
+	}
+
+	if stub.apnsPaths[0] != "/3/device/ios-ok" {
+		t.Fatalf("unexpected apns path: %s", stub.apnsPaths[0])
+	}
+	headers := stub.apnsHeaders[0]
+	if headers.Get("apns-topic") != "com.example.smartcare" || headers.Get("apns-push-type") != "alert" || !strings.HasPrefix(headers.Get("Authorization"), "bearer ") {
+		t.Fatalf("unexpected apns headers: %+v", headers)
+	}
+	alert := stub.apnsBodies[0]["aps"].(map[string]any)["alert"].(map[string]any)
+	if alert["title"] != "Medicine time" || stub.apnsBodies[0]["template_code"] != "MED_AFTER_MEAL_NOW" {
+		t.Fatalf("unexpected apns body: %+v", stub.apnsBodies[0])
+	}
+
+	if len(repo.deactivated) != 2 || repo.deactivated[0] != staleAndroidID || repo.deactivated[1] != staleIOSID {
+		t.Fatalf("expected stale tokens deactivated once, got %v", repo.deactivated)
+	}
+}
+
+func TestPushNotificationSenderFailsWhenNothingDelivered(t *testing.T) {
+	stub := &pushStubServer{}
+	server := httptest.NewServer(stub.handler(t))
+	defer server.Close()
+
+	cfg := newTestPushConfig(t, server.URL)
+	cfg.FCM = config.FCMConfig{}
+	repo := &pushTokenRepoStub{tokens: []db.DeviceToken{{ID: uuid.New(), Platform: "ios", Token: "ios-broken"}}}
+	sender, err := NewPushNotificationSender(cfg, repo, nil)
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+
+	event := db.NotificationEvent{ID: uuid.New(), UserID: uuid.New(), TemplateCode: "APPT_1D"}
+	if err := sender.Send(context.Background(), event, NotificationMessage{Title: "t", Body: "b"}); err == nil {
+		t.Fatalf("expected error when no device accepted the message")
+	}
+	if len(repo.deactivated) != 0 {
+		t.Fatalf("transient errors must not deactivate tokens")
+	}
+
+	repo.tokens = nil
+	if err := sender.Send(context.Background(), event, NotificationMessage{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("expected no error without devices: %v", err)
+	}
+
+	if _, err := NewPushNotificationSender(config.PushConfig{}, repo, nil); err == nil {
+		t.Fatalf("expected configuration error")
+	}
+}
+
+func TestPushNotificationSenderKeepsTokensOnAmbiguousErrors(t *testing.T) {
+	stub := &pushStubServer{}
+	server := httptest.NewServer(stub.handler(t))
+	defer server.Close()
+
+	badIOSID := uuid.New()
+	repo := &pushTokenRepoStub{tokens: []db.DeviceToken{
+		{ID: uuid.New(), Platform: "android", Token: "android-misrouted"},
+		{ID: badIOSID, Platform: "ios", Token: "ios-bad"},
+		{ID: uuid.New(), Platform: "ios", Token: "ios-ok"},
+	}}
+	sender, err := NewPushNotificationSender(newTestPushConfig(t, server.URL), repo, nil)
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+
+	event := db.NotificationEvent{ID: uuid.New(), UserID: uuid.New(), TemplateCode: "APPT_1D"}
+	if err := sender.Send(context.Background(), event, NotificationMessage{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A 404 without UNREGISTERED is a project or URL problem, and BadDeviceToken
+	// before any accepted message may be a sandbox/production mix-up.
+	if len(repo.deactivated) != 0 {
+		t.Fatalf("expected tokens kept on ambiguous errors, got %v", repo.deactivated)
+	}
+
+	if err := sender.Send(context.Background(), event, NotificationMessage{Title: "t", Body: "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.deactivated) != 1 || repo.deactivated[0] != badIOSID {
+		t.Fatalf("expected BadDeviceToken deactivated once APNs accepted a message, got %v", repo.deactivated)
+	}
+}
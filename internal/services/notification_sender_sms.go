@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+// SmsNotificationSender texts notifications to the phone number a patient
+// registered with, which is their username.
+type SmsNotificationSender struct {
+	users repositories.UserRepository
+	sms   SmsMessageSender
+}
+
+func NewSmsNotificationSender(sms SmsMessageSender, users repositories.UserRepository) (*SmsNotificationSender, error) {
+	if sms == nil {
+		return nil, errors.New("sms sender required")
+	}
+	if users == nil {
+		return nil, errors.New("user repository required")
+	}
+	return &SmsNotificationSender{users: users, sms: sms}, nil
+}
+
+// Send is a no-op for users whose username is not a phone number.
+func (s *SmsNotificationSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
+	user, err := s.users.FindByID(ctx, event.UserID)
+	if err != nil {
+		if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.UserNotFound {
+			return nil
+		}
+		return err
+	}
+	if !isPhoneNumber(user.Username) {
+		return nil
+	}
+
+	text := msg.Title
+	if msg.Body != "" {
+		text += ": " + msg.Body
+	}
+	return s.sms.SendMessage(ctx, user.Username, text)
+}
+
+func isPhoneNumber(value string) bool {
+	digits := strings.TrimPrefix(value, "+")
+	if len(digits) < 9 || len(digits) > 15 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -3,12 +3,14 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/datatypes"
-	"gorm.io/gorm"
 
 	"github.com/ParkPawapon/mhp-be/internal/config"
 	"github.com/ParkPawapon/mhp-be/internal/constants"
@@ -19,57 +21,132 @@ import (
 )
 
 type NotificationService interface {
-	ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, mealTiming *string, timeSlot time.Time) error
+	ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error
+	RescheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error
+	BuildMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) ([]db.NotificationEvent, error)
+	CancelMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error
 	CancelMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error
+	RestoreMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error
 	ScheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error
+	RescheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error
 	CancelAppointmentReminders(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error
 	ListUpcoming(ctx context.Context, userID string, from, to string) ([]dto.NotificationUpcomingItem, error)
 	EnsureWeeklyReminders(ctx context.Context) error
 	ProcessDue(ctx context.Context) error
 	CancelWeeklyReminders(ctx context.Context, userID uuid.UUID) error
+	RescheduleWeeklyReminder(ctx context.Context, userID uuid.UUID) error
+	ListFailedEvents(ctx context.Context, page, pageSize int, status, userID, templateCode string) ([]dto.NotificationEventResponse, int64, error)
+	RequeueEvent(ctx context.Context, id string) (dto.NotificationEventResponse, error)
+	ListInbox(ctx context.Context, userID uuid.UUID, cursor string, limit int, unreadOnly bool) (dto.NotificationInboxResponse, error)
+	UnreadCount(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error)
+	MarkRead(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error)
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error)
+	ArchiveInboxItem(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error)
+	Channels() []string
+}
+
+const maxLastErrorLength = 1000
+
+var failedNotificationStatuses = []string{string(constants.NotificationFailed), string(constants.NotificationDead)}
+
+// urgentTemplates are delivered during quiet hours too: dose reminders fire at
+// times the patient chose, and escalations ask for timely follow-up.
+var urgentTemplates = map[string]bool{
+	constants.TemplateMedBeforeMeal5Min:         true,
+	constants.TemplateMedBeforeMeal20Min:        true,
+	constants.TemplateMedAfterMealNow:           true,
+	constants.TemplateEscalationMissedCaregiver: true,
+	constants.TemplateEscalationMissedNurse:     true,
+}
+
+// channelNotificationSender is implemented by senders that can limit a
+// delivery to the channels the recipient chose.
+type channelNotificationSender interface {
+	SendVia(ctx context.Context, event db.NotificationEvent, msg NotificationMessage, channels []string) error
+	Channels() []string
 }
 
 type notificationService struct {
-	cfg      config.NotificationConfig
-	db       *gorm.DB
-	repo     repositories.NotificationRepository
-	prefs    repositories.PreferenceRepository
-	sender   NotificationSender
-	logger   *zap.Logger
-	location *time.Location
-	now      func() time.Time
-}
-
-func NewNotificationService(cfg config.NotificationConfig, dbConn *gorm.DB, repo repositories.NotificationRepository, prefs repositories.PreferenceRepository, sender NotificationSender, logger *zap.Logger) NotificationService {
-	location, err := time.LoadLocation(cfg.Timezone)
+	cfg       config.NotificationConfig
+	repo      repositories.NotificationRepository
+	prefs     repositories.PreferenceRepository
+	sender    NotificationSender
+	renderer  notificationRenderer
+	logger    *zap.Logger
+	locations userLocations
+	now       func() time.Time
+}
+
+func NewNotificationService(cfg config.NotificationConfig, repo repositories.NotificationRepository, prefs repositories.PreferenceRepository, medicines repositories.MedicineRepository, appointments repositories.AppointmentRepository, sender NotificationSender, logger *zap.Logger) NotificationService {
+	return &notificationService{
+		cfg:       cfg,
+		repo:      repo,
+		prefs:     prefs,
+		sender:    sender,
+		renderer:  newNotificationRenderer(medicines, appointments),
+		logger:    logger,
+		locations: newUserLocations(prefs, cfg.Timezone),
+		now:       time.Now,
+	}
+}
+
+func (s *notificationService) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+	settings, err := s.locations.settingsFor(ctx, userID)
 	if err != nil {
-		location = time.UTC
+		return err
 	}
-	return &notificationService{
-		cfg:      cfg,
-		db:       dbConn,
-		repo:     repo,
-		prefs:    prefs,
-		sender:   sender,
-		logger:   logger,
-		location: location,
-		now:      time.Now,
+	return s.repo.CreateEvents(ctx, s.buildMedicineReminders(userID, schedule, settings))
+}
+
+// RescheduleMedicineReminders replaces the unsent reminders of a schedule
+// after its time, meal timing or recurrence, or the patient's timezone or
+// before-meal lead times, changed.
+func (s *notificationService) RescheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+	events, err := s.BuildMedicineReminders(ctx, userID, schedule)
+	if err != nil {
+		return err
 	}
+	return s.repo.ReplaceScheduleEvents(ctx, userID, schedule.ID, events)
 }
 
-func (s *notificationService) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, mealTiming *string, timeSlot time.Time) error {
+// BuildMedicineReminders returns the reminders RescheduleMedicineReminders
+// would store, for callers that store them in their own transaction.
+func (s *notificationService) BuildMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) ([]db.NotificationEvent, error) {
+	settings, err := s.locations.settingsFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildMedicineReminders(userID, schedule, settings), nil
+}
+
+// CancelMedicineReminders drops the unsent reminders of stopped schedules.
+func (s *notificationService) CancelMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	return s.repo.DeletePendingBySchedules(ctx, userID, scheduleIDs)
+}
+
+// buildMedicineReminders covers the days of the next ScheduleDays, in the
+// patient's location, on which the schedule's recurrence is due. Before-meal
+// doses get a reminder ahead of the dose time and a follow-up after it, at the
+// patient's lead times.
+func (s *notificationService) buildMedicineReminders(userID uuid.UUID, schedule db.MedicineSchedule, settings notificationSettings) []db.NotificationEvent {
+	location := settings.location
+	scheduleID, mealTiming, timeSlot := schedule.ID, schedule.MealTiming, schedule.TimeSlot
+	rule := scheduleRecurrence(schedule)
 	days := s.cfg.ScheduleDays
 	if days <= 0 {
 		days = 7
 	}
 
 	nowUTC := s.now().UTC()
-	nowLocal := nowUTC.In(s.location)
+	nowLocal := nowUTC.In(location)
 	events := make([]db.NotificationEvent, 0, days*2)
 
 	for i := 0; i < days; i++ {
 		date := nowLocal.AddDate(0, 0, i)
-		localTime := time.Date(date.Year(), date.Month(), date.Day(), timeSlot.Hour(), timeSlot.Minute(), timeSlot.Second(), 0, s.location)
+		if !rule.occursOn(date) {
+			continue
+		}
+		localTime := time.Date(date.Year(), date.Month(), date.Day(), timeSlot.Hour(), timeSlot.Minute(), timeSlot.Second(), 0, location)
 		dateKey := date.Format("2006-01-02")
 
 		payload := map[string]any{
@@ -85,8 +162,8 @@ func (s *notificationService) ScheduleMedicineReminders(ctx context.Context, use
 
 		switch timing {
 		case constants.MealTimingBeforeMeal:
-			beforeTime := localTime.Add(-5 * time.Minute).UTC()
-			afterTime := localTime.Add(20 * time.Minute).UTC()
+			beforeTime := localTime.Add(-settings.beforeMealLead).UTC()
+			afterTime := localTime.Add(settings.beforeMealFollowUp).UTC()
 			if beforeTime.After(nowUTC) {
 				events = append(events, db.NotificationEvent{
 					UserID:       userID,
@@ -118,50 +195,76 @@ func (s *notificationService) ScheduleMedicineReminders(ctx context.Context, use
 			}
 		}
 	}
-
-	return s.repo.CreateEvents(ctx, events)
+	return events
 }
 
+// CancelMedicineAfterMealReminder cancels the follow-up reminder of a dose
+// once it is TAKEN. targetDate is a calendar date encoded as UTC midnight.
 func (s *notificationService) CancelMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
-	dateKey := targetDate.In(s.location).Format("2006-01-02")
+	dateKey := targetDate.Format("2006-01-02")
 	return s.repo.CancelPendingBySchedule(ctx, userID, scheduleID, dateKey)
 }
 
+// RestoreMedicineAfterMealReminder re-enables a reminder cancelled by
+// CancelMedicineAfterMealReminder once the dose is no longer TAKEN. Reminders
+// whose time has already passed stay cancelled.
+func (s *notificationService) RestoreMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
+	dateKey := targetDate.Format("2006-01-02")
+	return s.repo.RestoreCancelledBySchedule(ctx, userID, scheduleID, dateKey, s.now().UTC())
+}
+
 func (s *notificationService) ScheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
-	if appt == nil {
+	if appt == nil || appt.ApptType != constants.ApptHospital {
 		return nil
 	}
-	if appt.ApptType != constants.ApptHospital {
+	events, err := s.buildAppointmentReminders(ctx, appt)
+	if err != nil {
+		return err
+	}
+	return s.repo.CreateEvents(ctx, events)
+}
+
+// RescheduleAppointmentReminders replaces the unsent reminders of an
+// appointment after the patient changed their reminder lead times.
+func (s *notificationService) RescheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
+	if appt == nil || appt.ApptType != constants.ApptHospital {
 		return nil
 	}
+	events, err := s.buildAppointmentReminders(ctx, appt)
+	if err != nil {
+		return err
+	}
+	return s.repo.ReplaceAppointmentEvents(ctx, appt.UserID, appt.ID, events)
+}
+
+// buildAppointmentReminders remind the patient the given numbers of days
+// before a hospital appointment, 5 and 1 by default.
+func (s *notificationService) buildAppointmentReminders(ctx context.Context, appt *db.Appointment) ([]db.NotificationEvent, error) {
+	settings, err := s.locations.settingsFor(ctx, appt.UserID)
+	if err != nil {
+		return nil, err
+	}
 
 	nowUTC := s.now().UTC()
-	payload := map[string]any{"appointment_id": appt.ID.String()}
-	payloadBytes, _ := json.Marshal(payload)
-
-	reminders := []struct {
-		Code string
-		Days int
-	}{
-		{Code: constants.TemplateAppt5Days, Days: -5},
-		{Code: constants.TemplateAppt1Day, Days: -1},
-	}
-
-	events := make([]db.NotificationEvent, 0, len(reminders))
-	for _, reminder := range reminders {
-		scheduled := appt.ApptDateTime.AddDate(0, 0, reminder.Days).UTC()
-		if scheduled.After(nowUTC) {
-			events = append(events, db.NotificationEvent{
-				UserID:       appt.UserID,
-				TemplateCode: reminder.Code,
-				ScheduledAt:  scheduled,
-				Status:       constants.NotificationPending,
-				Payload:      datatypes.JSON(payloadBytes),
-			})
+	events := make([]db.NotificationEvent, 0, len(settings.appointmentDays))
+	for _, days := range settings.appointmentDays {
+		scheduled := appt.ApptDateTime.AddDate(0, 0, -days).UTC()
+		if !scheduled.After(nowUTC) {
+			continue
 		}
+		payloadBytes, _ := json.Marshal(map[string]any{
+			"appointment_id": appt.ID.String(),
+			"days_before":    days,
+		})
+		events = append(events, db.NotificationEvent{
+			UserID:       appt.UserID,
+			TemplateCode: appointmentTemplate(days),
+			ScheduledAt:  scheduled,
+			Status:       constants.NotificationPending,
+			Payload:      datatypes.JSON(payloadBytes),
+		})
 	}
-
-	return s.repo.CreateEvents(ctx, events)
+	return events, nil
 }
 
 func (s *notificationService) CancelAppointmentReminders(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
@@ -208,6 +311,8 @@ func (s *notificationService) ListUpcoming(ctx context.Context, userID string, f
 	return resp, nil
 }
 
+// EnsureWeeklyReminders queues every opted-in patient's next weekly reminder
+// at WeeklyReminderHour:WeeklyReminderMinute on Monday in their own timezone.
 func (s *notificationService) EnsureWeeklyReminders(ctx context.Context) error {
 	if s.prefs == nil {
 		return nil
@@ -220,65 +325,332 @@ func (s *notificationService) EnsureWeeklyReminders(ctx context.Context) error {
 		return nil
 	}
 
-	localNow := s.now().In(s.location)
-	scheduledLocal := nextWeeklyTime(localNow, s.cfg.WeeklyReminderHour, s.cfg.WeeklyReminderMinute)
-	scheduledUTC := scheduledLocal.UTC()
-
-	payloadBytes, _ := json.Marshal(map[string]any{"type": "weekly_health_log"})
 	events := make([]db.NotificationEvent, 0, len(users))
-	for _, userID := range users {
-		events = append(events, db.NotificationEvent{
-			UserID:       userID,
-			TemplateCode: constants.TemplateWeeklyHealthLog,
-			ScheduledAt:  scheduledUTC,
-			Status:       constants.NotificationPending,
-			Payload:      datatypes.JSON(payloadBytes),
-		})
+	for _, user := range users {
+		events = append(events, s.weeklyReminder(user.UserID, s.locations.resolve(user.Timezone)))
 	}
 
 	return s.repo.CreateEvents(ctx, events)
 }
 
-func (s *notificationService) ProcessDue(ctx context.Context) error {
-	if s.db == nil {
+// RescheduleWeeklyReminder moves the user's unsent weekly reminder to the next
+// slot in their current timezone, e.g. after they changed it.
+func (s *notificationService) RescheduleWeeklyReminder(ctx context.Context, userID uuid.UUID) error {
+	if s.prefs == nil {
 		return nil
 	}
+	enabled := true
+	var timezone *string
+	pref, err := s.prefs.FindByUserID(ctx, userID)
+	if err == nil {
+		enabled, timezone = pref.WeeklyReminderEnabled, pref.Timezone
+	} else if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.UserNotFound {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+	event := s.weeklyReminder(userID, s.locations.resolve(timezone))
+	return s.repo.ReplacePendingByTemplate(ctx, userID, constants.TemplateWeeklyHealthLog, []db.NotificationEvent{event})
+}
+
+func (s *notificationService) weeklyReminder(userID uuid.UUID, location *time.Location) db.NotificationEvent {
+	localNow := s.now().In(location)
+	scheduled := nextWeeklyTime(localNow, s.cfg.WeeklyReminderHour, s.cfg.WeeklyReminderMinute)
+	payloadBytes, _ := json.Marshal(map[string]any{"type": "weekly_health_log"})
+	return db.NotificationEvent{
+		UserID:       userID,
+		TemplateCode: constants.TemplateWeeklyHealthLog,
+		ScheduledAt:  scheduled.UTC(),
+		Status:       constants.NotificationPending,
+		Payload:      datatypes.JSON(payloadBytes),
+	}
+}
+
+// ProcessDue claims a batch of due events under a lease, sends them with a
+// bounded pool outside any transaction, and records each result. Events left
+// PROCESSING by a crashed worker are reclaimed once their lease expires, so
+// several replicas can run this concurrently.
+func (s *notificationService) ProcessDue(ctx context.Context) error {
 	batchSize := s.cfg.JobBatchSize
 	if batchSize <= 0 {
 		batchSize = 100
 	}
+	lease := s.cfg.LeaseDuration
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+	concurrency := s.cfg.WorkerConcurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
 
 	nowUTC := s.now().UTC()
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		repo := s.repo.WithTx(tx)
-		events, err := repo.ListDueForUpdate(ctx, nowUTC, batchSize)
-		if err != nil {
-			return err
-		}
-		for _, event := range events {
-			tpl, err := repo.FindTemplateByCode(ctx, event.TemplateCode)
-			if err != nil {
-				_ = repo.UpdateEventStatus(ctx, event.ID, constants.NotificationFailed, nil)
-				continue
-			}
+	events, err := s.repo.ClaimDue(ctx, nowUTC, nowUTC.Add(lease), batchSize)
+	if err != nil {
+		return err
+	}
 
-			status := constants.NotificationSent
-			sentAt := s.now().UTC()
-			if s.sender != nil {
-				if err := s.sender.Send(ctx, event, *tpl); err != nil {
-					status = constants.NotificationFailed
-					if s.logger != nil {
-						s.logger.Warn("notification send failed", zap.String("request_id", "job"), zap.String("user_id", event.UserID.String()), zap.String("template_code", event.TemplateCode), zap.Error(err))
-					}
-				}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, concurrency)
+	)
+	for _, event := range events {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(event db.NotificationEvent) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := s.deliver(ctx, event); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
 			}
+		}(event)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
 
-			if err := repo.UpdateEventStatus(ctx, event.ID, status, &sentAt); err != nil {
-				return err
-			}
+// deliver renders one claimed event in the recipient's language, sends it
+// through their channels and records the attempt. During the recipient's quiet
+// hours, events other than urgentTemplates are put back until the quiet hours
+// end. Send failures are retried with exponential backoff until MaxAttempts,
+// then the event is dead-lettered; a missing or inactive template, or one that
+// cannot be rendered, is dead-lettered straight away since retrying cannot fix
+// it. The claim already counted the attempt, and an event claimed past
+// MaxAttempts (its lease expired every time) is dead-lettered without sending.
+func (s *notificationService) deliver(ctx context.Context, event db.NotificationEvent) error {
+	if event.Attempts > s.maxAttempts() {
+		return s.recordFailure(ctx, event, errors.New("delivery did not finish before the lease expired"), true)
+	}
+	settings, err := s.locations.settingsFor(ctx, event.UserID)
+	if err != nil {
+		return s.recordFailure(ctx, event, err, false)
+	}
+	if until, quiet := settings.quietUntil(s.now()); quiet && !urgentTemplates[event.TemplateCode] {
+		return s.recordDelivery(ctx, event, repositories.NotificationDeliveryUpdate{
+			Status: constants.NotificationPending,
+			// Putting the event back for quiet hours is not an attempt.
+			Attempts:      event.Attempts - 1,
+			NextAttemptAt: &until,
+			LastError:     event.LastError,
+		})
+	}
+
+	msg, err := s.renderEvent(ctx, event, settings)
+	if err != nil {
+		return s.recordFailure(ctx, event, err, permanentRenderError(err))
+	}
+
+	if err := s.send(ctx, event, msg, settings.channels); err != nil {
+		return s.recordFailure(ctx, event, err, errors.Is(err, errNoNotificationChannel))
+	}
+
+	sentAt := s.now().UTC()
+	return s.recordDelivery(ctx, event, repositories.NotificationDeliveryUpdate{
+		Status:   constants.NotificationSent,
+		Attempts: event.Attempts,
+		SentAt:   &sentAt,
+		Title:    &msg.Title,
+		Body:     &msg.Body,
+	})
+}
+
+// renderEvent renders an event with the user's template in their language,
+// else the default locale, else any active locale.
+func (s *notificationService) renderEvent(ctx context.Context, event db.NotificationEvent, settings notificationSettings) (NotificationMessage, error) {
+	tpl, err := s.repo.FindTemplate(ctx, event.TemplateCode, s.locale(settings), s.defaultLocale())
+	if err != nil {
+		return NotificationMessage{}, err
+	}
+	return s.renderer.render(ctx, event, *tpl, tpl.Locale, settings.location)
+}
+
+// permanentRenderError reports whether retrying renderEvent cannot help: the
+// template is missing or inactive, or cannot be rendered for the event.
+func permanentRenderError(err error) bool {
+	if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.NotificationNotFound {
+		return true
+	}
+	return errors.Is(err, errNotificationRender)
+}
+
+// locale is the user's language, else NOTIFICATION_DEFAULT_LOCALE.
+func (s *notificationService) locale(settings notificationSettings) string {
+	if settings.language != "" {
+		return settings.language
+	}
+	return s.defaultLocale()
+}
+
+func (s *notificationService) defaultLocale() string {
+	if s.cfg.DefaultLocale != "" {
+		return s.cfg.DefaultLocale
+	}
+	return constants.LocaleThai
+}
+
+// Channels lists the channels users can choose. Every channel is listed when
+// the sender cannot pick channels or notifications are disabled.
+func (s *notificationService) Channels() []string {
+	if sender, ok := s.sender.(channelNotificationSender); ok {
+		return sender.Channels()
+	}
+	return notificationChannels
+}
+
+func (s *notificationService) send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage, channels []string) error {
+	if s.sender == nil {
+		return nil
+	}
+	if sender, ok := s.sender.(channelNotificationSender); ok {
+		return sender.SendVia(ctx, event, msg, channels)
+	}
+	return s.sender.Send(ctx, event, msg)
+}
+
+func (s *notificationService) maxAttempts() int {
+	if s.cfg.MaxAttempts > 0 {
+		return s.cfg.MaxAttempts
+	}
+	return 5
+}
+
+func (s *notificationService) recordFailure(ctx context.Context, event db.NotificationEvent, cause error, permanent bool) error {
+	attempts := event.Attempts
+	lastError := cause.Error()
+	if runes := []rune(lastError); len(runes) > maxLastErrorLength {
+		lastError = string(runes[:maxLastErrorLength])
+	}
+	update := repositories.NotificationDeliveryUpdate{
+		Status:    constants.NotificationDead,
+		Attempts:  attempts,
+		LastError: &lastError,
+	}
+
+	if !permanent && attempts < s.maxAttempts() {
+		next := s.now().UTC().Add(s.retryDelay(attempts))
+		update.Status = constants.NotificationFailed
+		update.NextAttemptAt = &next
+	}
+
+	if s.logger != nil {
+		fields := []zap.Field{
+			zap.String("request_id", "job"),
+			zap.String("notification_event_id", event.ID.String()),
+			zap.String("user_id", event.UserID.String()),
+			zap.String("template_code", event.TemplateCode),
+			zap.Int("attempts", attempts),
+			zap.Error(cause),
+		}
+		if update.Status == constants.NotificationDead {
+			s.logger.Error("notification dead-lettered", fields...)
+		} else {
+			s.logger.Warn("notification send failed", append(fields, zap.Timep("next_attempt_at", update.NextAttemptAt))...)
+		}
+	}
+
+	return s.recordDelivery(ctx, event, update)
+}
+
+// recordDelivery releases the event's lease. A lost lease means another worker
+// reclaimed the event after ours expired; its result wins, so ours is dropped.
+func (s *notificationService) recordDelivery(ctx context.Context, event db.NotificationEvent, update repositories.NotificationDeliveryUpdate) error {
+	if event.LockedUntil != nil {
+		update.LockedUntil = *event.LockedUntil
+	}
+	err := s.repo.UpdateEventDelivery(ctx, event.ID, update)
+	if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.NotificationNotFound {
+		if s.logger != nil {
+			s.logger.Warn("notification lease lost", zap.String("request_id", "job"), zap.String("notification_event_id", event.ID.String()))
 		}
 		return nil
-	})
+	}
+	return err
+}
+
+// retryDelay doubles the base delay for every attempt already made, capped at
+// RetryMaxDelay.
+func (s *notificationService) retryDelay(attempts int) time.Duration {
+	delay := s.cfg.RetryBaseDelay
+	if delay <= 0 {
+		delay = time.Minute
+	}
+	maxDelay := s.cfg.RetryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = time.Hour
+	}
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// ListFailedEvents lists FAILED (awaiting retry) and DEAD events, newest first.
+func (s *notificationService) ListFailedEvents(ctx context.Context, page, pageSize int, status, userID, templateCode string) ([]dto.NotificationEventResponse, int64, error) {
+	filter := repositories.NotificationEventFilter{
+		Statuses:     []constants.NotificationStatus{constants.NotificationFailed, constants.NotificationDead},
+		TemplateCode: strings.TrimSpace(templateCode),
+	}
+	if status = strings.ToUpper(strings.TrimSpace(status)); status != "" {
+		if !isAllowed(status, failedNotificationStatuses) {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid status")
+		}
+		filter.Statuses = []constants.NotificationStatus{constants.NotificationStatus(status)}
+	}
+	if userID = strings.TrimSpace(userID); userID != "" {
+		uid, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid user_id")
+		}
+		filter.UserID = &uid
+	}
+
+	items, total, err := s.repo.ListEvents(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp := make([]dto.NotificationEventResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, toNotificationEventResponse(item))
+	}
+	return resp, total, nil
+}
+
+func (s *notificationService) RequeueEvent(ctx context.Context, id string) (dto.NotificationEventResponse, error) {
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return dto.NotificationEventResponse{}, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+	event, err := s.repo.Requeue(ctx, eventID)
+	if err != nil {
+		return dto.NotificationEventResponse{}, err
+	}
+	return toNotificationEventResponse(*event), nil
+}
+
+func toNotificationEventResponse(event db.NotificationEvent) dto.NotificationEventResponse {
+	return dto.NotificationEventResponse{
+		ID:            event.ID.String(),
+		UserID:        event.UserID.String(),
+		TemplateCode:  event.TemplateCode,
+		ScheduledAt:   event.ScheduledAt,
+		Status:        string(event.Status),
+		Attempts:      event.Attempts,
+		NextAttemptAt: event.NextAttemptAt,
+		LastError:     event.LastError,
+		SentAt:        event.SentAt,
+		CreatedAt:     event.CreatedAt,
+	}
 }
 
 func (s *notificationService) CancelWeeklyReminders(ctx context.Context, userID uuid.UUID) error {
@@ -2,25 +2,39 @@ package services
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 
 	"github.com/ParkPawapon/mhp-be/internal/config"
 	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/repositories"
 )
 
 type fakeNotificationRepo struct {
-	created []db.NotificationEvent
-}
-
-func (f *fakeNotificationRepo) WithTx(tx *gorm.DB) repositories.NotificationRepository {
-	return f
+	mu              sync.Mutex
+	created         []db.NotificationEvent
+	replaced        []db.NotificationEvent
+	due             []db.NotificationEvent
+	claimLimit      int
+	template        *db.NotificationTemplate
+	templateLocales []string
+	templateErr     error
+	updateErr       error
+	updates         []repositories.NotificationDeliveryUpdate
+	filter          repositories.NotificationEventFilter
+	inbox           []db.NotificationEvent
+	inboxBefore     *repositories.NotificationInboxCursor
+	unread          int64
+	read            []uuid.UUID
+	archived        []uuid.UUID
 }
 
 func (f *fakeNotificationRepo) CreateEvents(ctx context.Context, events []db.NotificationEvent) error {
@@ -32,34 +46,121 @@ func (f *fakeNotificationRepo) ListUpcoming(ctx context.Context, userID uuid.UUI
 	return nil, nil
 }
 
-func (f *fakeNotificationRepo) ListDueForUpdate(ctx context.Context, now time.Time, limit int) ([]db.NotificationEvent, error) {
-	return nil, nil
+func (f *fakeNotificationRepo) ClaimDue(ctx context.Context, now, lockedUntil time.Time, limit int) ([]db.NotificationEvent, error) {
+	f.claimLimit = limit
+	claimed := make([]db.NotificationEvent, 0, len(f.due))
+	for _, event := range f.due {
+		event.Status = constants.NotificationProcessing
+		event.LockedUntil = &lockedUntil
+		event.Attempts++
+		claimed = append(claimed, event)
+	}
+	return claimed, nil
 }
 
-func (f *fakeNotificationRepo) UpdateEventStatus(ctx context.Context, id uuid.UUID, status constants.NotificationStatus, sentAt *time.Time) error {
-	return nil
+func (f *fakeNotificationRepo) UpdateEventDelivery(ctx context.Context, id uuid.UUID, update repositories.NotificationDeliveryUpdate) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates = append(f.updates, update)
+	return f.updateErr
+}
+
+func (f *fakeNotificationRepo) ListEvents(ctx context.Context, filter repositories.NotificationEventFilter, page, pageSize int) ([]db.NotificationEvent, int64, error) {
+	f.filter = filter
+	return []db.NotificationEvent{{ID: uuid.New(), Status: constants.NotificationDead, Attempts: 5}}, 1, nil
 }
 
-func (f *fakeNotificationRepo) FindTemplateByCode(ctx context.Context, code string) (*db.NotificationTemplate, error) {
+func (f *fakeNotificationRepo) Requeue(ctx context.Context, id uuid.UUID) (*db.NotificationEvent, error) {
+	return &db.NotificationEvent{ID: id, Status: constants.NotificationPending}, nil
+}
+
+func (f *fakeNotificationRepo) FindTemplate(ctx context.Context, code string, locales ...string) (*db.NotificationTemplate, error) {
+	if f.templateErr != nil {
+		return nil, f.templateErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.templateLocales = locales
+	if f.template != nil {
+		return f.template, nil
+	}
 	return &db.NotificationTemplate{}, nil
 }
 
 func (f *fakeNotificationRepo) CancelPendingBySchedule(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate string) error {
 	return nil
 }
+func (f *fakeNotificationRepo) RestoreCancelledBySchedule(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate string, after time.Time) error {
+	return nil
+}
+func (f *fakeNotificationRepo) DeletePendingBySchedules(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	return nil
+}
+func (f *fakeNotificationRepo) ReplaceScheduleEvents(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, events []db.NotificationEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replaced = events
+	return nil
+}
 
 func (f *fakeNotificationRepo) CancelPendingByAppointment(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
 	return nil
 }
+func (f *fakeNotificationRepo) ReplaceAppointmentEvents(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID, events []db.NotificationEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replaced = events
+	return nil
+}
 
 func (f *fakeNotificationRepo) CancelPendingByTemplate(ctx context.Context, userID uuid.UUID, templateCode string) error {
 	return nil
 }
+func (f *fakeNotificationRepo) ReplacePendingByTemplate(ctx context.Context, userID uuid.UUID, templateCode string, events []db.NotificationEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.replaced = events
+	return nil
+}
+
+// ListInbox pages through inbox, which is kept newest first.
+func (f *fakeNotificationRepo) ListInbox(ctx context.Context, userID uuid.UUID, before *repositories.NotificationInboxCursor, unreadOnly bool, limit int) ([]db.NotificationEvent, error) {
+	f.inboxBefore = before
+	items := f.inbox
+	if before != nil {
+		for i, item := range f.inbox {
+			if item.ID == before.ID {
+				items = f.inbox[i+1:]
+			}
+		}
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+func (f *fakeNotificationRepo) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return f.unread, nil
+}
+func (f *fakeNotificationRepo) MarkRead(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	f.read = append(f.read, id)
+	f.unread--
+	return nil
+}
+func (f *fakeNotificationRepo) MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error) {
+	marked := f.unread
+	f.unread = 0
+	return marked, nil
+}
+func (f *fakeNotificationRepo) Archive(ctx context.Context, userID, id uuid.UUID, at time.Time) error {
+	f.archived = append(f.archived, id)
+	return nil
+}
 
 func TestScheduleAppointmentRemindersCreatesTwoEvents(t *testing.T) {
 	repo := &fakeNotificationRepo{}
 	cfg := config.NotificationConfig{ScheduleDays: 1, Timezone: "UTC"}
-	svc := NewNotificationService(cfg, nil, repo, nil, nil, zap.NewNop())
+	svc := NewNotificationService(cfg, repo, nil, nil, nil, nil, zap.NewNop())
 
 	impl, ok := svc.(*notificationService)
 	if !ok {
@@ -97,7 +198,7 @@ func TestScheduleAppointmentRemindersCreatesTwoEvents(t *testing.T) {
 func TestScheduleMedicineRemindersBeforeMealCreatesTwoEvents(t *testing.T) {
 	repo := &fakeNotificationRepo{}
 	cfg := config.NotificationConfig{ScheduleDays: 1, Timezone: "UTC"}
-	svc := NewNotificationService(cfg, nil, repo, nil, nil, zap.NewNop())
+	svc := NewNotificationService(cfg, repo, nil, nil, nil, nil, zap.NewNop())
 
 	impl, ok := svc.(*notificationService)
 	if !ok {
@@ -110,7 +211,7 @@ func TestScheduleMedicineRemindersBeforeMealCreatesTwoEvents(t *testing.T) {
 	timeSlot := time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC)
 	mealTiming := constants.MealTimingBeforeMeal
 
-	if err := impl.ScheduleMedicineReminders(context.Background(), uuid.New(), uuid.New(), &mealTiming, timeSlot); err != nil {
+	if err := impl.ScheduleMedicineReminders(context.Background(), uuid.New(), db.MedicineSchedule{ID: uuid.New(), TimeSlot: timeSlot, MealTiming: &mealTiming}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -127,3 +228,478 @@ func TestScheduleMedicineRemindersBeforeMealCreatesTwoEvents(t *testing.T) {
 		t.Fatalf("expected medicine template codes")
 	}
 }
+
+func TestRescheduleMedicineRemindersReplacesEvents(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	cfg := config.NotificationConfig{ScheduleDays: 2, Timezone: "UTC"}
+	svc := NewNotificationService(cfg, repo, nil, nil, nil, nil, zap.NewNop())
+	impl := svc.(*notificationService)
+	impl.now = func() time.Time { return time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC) }
+
+	timeSlot := time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC)
+	if err := svc.RescheduleMedicineReminders(context.Background(), uuid.New(), db.MedicineSchedule{ID: uuid.New(), TimeSlot: timeSlot}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.created) != 0 {
+		t.Fatalf("expected events to go through the replace path")
+	}
+	if len(repo.replaced) != 2 || repo.replaced[0].TemplateCode != constants.TemplateMedAfterMealNow {
+		t.Fatalf("expected 2 replacement events, got %+v", repo.replaced)
+	}
+}
+
+func TestScheduleMedicineRemindersHonorsRecurrence(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	cfg := config.NotificationConfig{ScheduleDays: 7, Timezone: "UTC"}
+	svc := NewNotificationService(cfg, repo, nil, nil, nil, nil, zap.NewNop())
+	impl := svc.(*notificationService)
+	// Thursday 2026-01-01; the horizon runs to Wednesday the 7th.
+	impl.now = func() time.Time { return time.Date(2026, 1, 1, 6, 0, 0, 0, time.UTC) }
+
+	end := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	schedule := db.MedicineSchedule{
+		ID:           uuid.New(),
+		TimeSlot:     time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC),
+		StartDate:    time.Date(2025, 12, 30, 0, 0, 0, 0, time.UTC),
+		EndDate:      &end,
+		IntervalDays: 2,
+		WeekdayMask:  constants.WeekdayMaskAll &^ (1 << uint(time.Saturday)),
+	}
+	if err := svc.ScheduleMedicineReminders(context.Background(), uuid.New(), schedule); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every other day from Dec 30 gives Jan 1, 3 and 5; the 3rd is a Saturday.
+	var got []string
+	for _, event := range repo.created {
+		got = append(got, event.ScheduledAt.Format("2006-01-02"))
+	}
+	if len(got) != 2 || got[0] != "2026-01-01" || got[1] != "2026-01-05" {
+		t.Fatalf("unexpected reminder days: %v", got)
+	}
+}
+
+type failingSender struct {
+	err error
+}
+
+func (s failingSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
+	return s.err
+}
+
+func TestDeliverRetriesWithBackoffThenDeadLetters(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	cfg := config.NotificationConfig{Timezone: "UTC", MaxAttempts: 3, RetryBaseDelay: time.Minute, RetryMaxDelay: 90 * time.Second}
+	svc := NewNotificationService(cfg, repo, nil, nil, nil, failingSender{err: errors.New("fcm unavailable")}, zap.NewNop())
+	impl := svc.(*notificationService)
+	fixedNow := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	impl.now = func() time.Time { return fixedNow }
+
+	event := db.NotificationEvent{ID: uuid.New(), TemplateCode: constants.TemplateAppt1Day, Status: constants.NotificationPending}
+	for i := 1; i <= 3; i++ {
+		event.Attempts = i
+		if err := impl.deliver(context.Background(), event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	first, second, third := repo.updates[0], repo.updates[1], repo.updates[2]
+	if first.Status != constants.NotificationFailed || first.Attempts != 1 || !first.NextAttemptAt.Equal(fixedNow.Add(time.Minute)) {
+		t.Fatalf("unexpected first attempt: %+v", first)
+	}
+	if second.Status != constants.NotificationFailed || !second.NextAttemptAt.Equal(fixedNow.Add(90*time.Second)) {
+		t.Fatalf("expected backoff capped at max delay: %+v", second)
+	}
+	if third.Status != constants.NotificationDead || third.Attempts != 3 || third.NextAttemptAt != nil {
+		t.Fatalf("expected dead after max attempts: %+v", third)
+	}
+	if third.LastError == nil || *third.LastError != "fcm unavailable" {
+		t.Fatalf("expected last error recorded: %+v", third)
+	}
+}
+
+func TestDeliverDeadLettersMissingTemplateAndMarksSent(t *testing.T) {
+	repo := &fakeNotificationRepo{templateErr: domain.NewError(constants.NotificationNotFound, "notification template not found")}
+	svc := NewNotificationService(config.NotificationConfig{Timezone: "UTC"}, repo, nil, nil, nil, nil, zap.NewNop())
+	impl := svc.(*notificationService)
+
+	event := db.NotificationEvent{ID: uuid.New(), TemplateCode: "UNKNOWN"}
+	if err := impl.deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.updates[0].Status != constants.NotificationDead || repo.updates[0].LastError == nil {
+		t.Fatalf("expected missing template to dead-letter: %+v", repo.updates[0])
+	}
+
+	repo.templateErr = nil
+	event.Attempts = 3
+	if err := impl.deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent := repo.updates[1]; sent.Status != constants.NotificationSent || sent.Attempts != 3 || sent.SentAt == nil || sent.LastError != nil {
+		t.Fatalf("unexpected sent update: %+v", sent)
+	}
+}
+
+func TestDeliverDeadLettersEventClaimedPastMaxAttempts(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	sender := &recordingSender{}
+	svc := NewNotificationService(config.NotificationConfig{Timezone: "UTC", MaxAttempts: 3}, repo, nil, nil, nil, sender, zap.NewNop())
+	impl := svc.(*notificationService)
+
+	// Every earlier claim expired mid-send, so this fourth claim is over the limit.
+	event := db.NotificationEvent{ID: uuid.New(), TemplateCode: constants.TemplateAppt1Day, Attempts: 4}
+	if err := impl.deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dead := repo.updates[0]; dead.Status != constants.NotificationDead || dead.Attempts != 4 || dead.LastError == nil || len(sender.messages) != 0 {
+		t.Fatalf("expected the event dead-lettered without sending, got %+v", dead)
+	}
+}
+
+func TestListFailedEventsFilters(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	svc := NewNotificationService(config.NotificationConfig{Timezone: "UTC"}, repo, nil, nil, nil, nil, zap.NewNop())
+
+	if _, _, err := svc.ListFailedEvents(context.Background(), 1, 20, "", "", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.filter.Statuses) != 2 {
+		t.Fatalf("expected FAILED and DEAD by default, got %v", repo.filter.Statuses)
+	}
+
+	items, total, err := svc.ListFailedEvents(context.Background(), 1, 20, "dead", "", "APPT_1D")
+	if err != nil || total != 1 || len(items) != 1 {
+		t.Fatalf("unexpected result: %v %d %v", items, total, err)
+	}
+	if len(repo.filter.Statuses) != 1 || repo.filter.Statuses[0] != constants.NotificationDead || repo.filter.TemplateCode != "APPT_1D" {
+		t.Fatalf("unexpected filter: %+v", repo.filter)
+	}
+
+	if _, _, err := svc.ListFailedEvents(context.Background(), 1, 20, "SENT", "", ""); err == nil {
+		t.Fatalf("expected invalid status")
+	}
+	if _, err := svc.RequeueEvent(context.Background(), "bad"); err == nil {
+		t.Fatalf("expected invalid id")
+	}
+}
+
+type slowSender struct {
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (s *slowSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
+	current := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if current <= peak || s.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func TestProcessDueSendsClaimedEventsWithBoundedPool(t *testing.T) {
+	repo := &fakeNotificationRepo{}
+	for i := 0; i < 10; i++ {
+		repo.due = append(repo.due, db.NotificationEvent{ID: uuid.New(), TemplateCode: constants.TemplateAppt1Day})
+	}
+	sender := &slowSender{}
+	cfg := config.NotificationConfig{Timezone: "UTC", JobBatchSize: 50, LeaseDuration: time.Minute, WorkerConcurrency: 3}
+	svc := NewNotificationService(cfg, repo, nil, nil, nil, sender, zap.NewNop())
+	impl := svc.(*notificationService)
+	fixedNow := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	impl.now = func() time.Time { return fixedNow }
+
+	if err := svc.ProcessDue(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if repo.claimLimit != 50 {
+		t.Fatalf("expected batch size as claim limit, got %d", repo.claimLimit)
+	}
+	if peak := sender.peak.Load(); peak < 2 || peak > 3 {
+		t.Fatalf("expected concurrent sends bounded by 3, peak %d", peak)
+	}
+	if len(repo.updates) != 10 {
+		t.Fatalf("expected every claimed event recorded, got %d", len(repo.updates))
+	}
+	for _, update := range repo.updates {
+		if update.Status != constants.NotificationSent || update.Attempts != 1 || !update.LockedUntil.Equal(fixedNow.Add(time.Minute)) {
+			t.Fatalf("expected sent under the claimed lease: %+v", update)
+		}
+	}
+
+	repo.updates = nil
+	repo.updateErr = domain.NewError(constants.NotificationNotFound, "notification lease lost")
+	if err := svc.ProcessDue(context.Background()); err != nil {
+		t.Fatalf("lost leases must not fail the run: %v", err)
+	}
+}
+
+func TestRemindersFollowUserTimezone(t *testing.T) {
+	bangkokUser, newYorkUser := uuid.New(), uuid.New()
+	newYork := "America/New_York"
+	prefs := preferenceRepoStub{
+		find: func(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
+			if userID == newYorkUser {
+				return &db.UserPreference{UserID: userID, WeeklyReminderEnabled: true, Timezone: &newYork}, nil
+			}
+			return nil, domain.NewError(constants.UserNotFound, "preferences not found")
+		},
+		weekly: []repositories.WeeklyReminderUser{{UserID: bangkokUser}, {UserID: newYorkUser, Timezone: &newYork}},
+	}
+	repo := &fakeNotificationRepo{}
+	cfg := config.NotificationConfig{ScheduleDays: 1, Timezone: "Asia/Bangkok", WeeklyReminderHour: 9}
+	svc := NewNotificationService(cfg, repo, prefs, nil, nil, nil, zap.NewNop())
+	impl := svc.(*notificationService)
+	// Sunday 2026-01-04 12:00 UTC: Sunday 19:00 in Bangkok, Sunday 07:00 in New York.
+	impl.now = func() time.Time { return time.Date(2026, 1, 4, 12, 0, 0, 0, time.UTC) }
+
+	timeSlot := time.Date(0, 1, 1, 8, 0, 0, 0, time.UTC)
+	if err := svc.ScheduleMedicineReminders(context.Background(), newYorkUser, db.MedicineSchedule{ID: uuid.New(), TimeSlot: timeSlot, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 08:00 in New York on the local Sunday.
+	if len(repo.created) != 1 || !repo.created[0].ScheduledAt.Equal(time.Date(2026, 1, 4, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected a reminder at 08:00 New York time, got %+v", repo.created)
+	}
+
+	repo.created = nil
+	if err := svc.EnsureWeeklyReminders(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[uuid.UUID]time.Time{
+		bangkokUser: time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC),
+		newYorkUser: time.Date(2026, 1, 5, 14, 0, 0, 0, time.UTC),
+	}
+	if len(repo.created) != 2 {
+		t.Fatalf("expected 2 weekly reminders, got %d", len(repo.created))
+	}
+	for _, event := range repo.created {
+		if !event.ScheduledAt.Equal(want[event.UserID]) {
+			t.Fatalf("expected Monday 09:00 local for %s, got %s", event.UserID, event.ScheduledAt)
+		}
+	}
+
+	if err := svc.RescheduleWeeklyReminder(context.Background(), newYorkUser); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.replaced) != 1 || !repo.replaced[0].ScheduledAt.Equal(want[newYorkUser]) {
+		t.Fatalf("expected the weekly reminder moved to New York time, got %+v", repo.replaced)
+	}
+}
+
+func TestRemindersFollowUserLeadTimes(t *testing.T) {
+	userID := uuid.New()
+	lead, followUp := 15, 45
+	prefs := preferenceRepoStub{
+		find: func(ctx context.Context, id uuid.UUID) (*db.UserPreference, error) {
+			return &db.UserPreference{
+				UserID:                    id,
+				AppointmentReminderDays:   []byte(`[7,1]`),
+				BeforeMealLeadMinutes:     &lead,
+				BeforeMealFollowUpMinutes: &followUp,
+			}, nil
+		},
+	}
+	repo := &fakeNotificationRepo{}
+	svc := NewNotificationService(config.NotificationConfig{ScheduleDays: 1, Timezone: "UTC"}, repo, prefs, nil, nil, nil, zap.NewNop())
+	impl := svc.(*notificationService)
+	fixedNow := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	impl.now = func() time.Time { return fixedNow }
+
+	mealTiming := constants.MealTimingBeforeMeal
+	schedule := db.MedicineSchedule{ID: uuid.New(), TimeSlot: time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC), MealTiming: &mealTiming}
+	if err := svc.ScheduleMedicineReminders(context.Background(), userID, schedule); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.created) != 2 ||
+		!repo.created[0].ScheduledAt.Equal(time.Date(2026, 1, 1, 8, 45, 0, 0, time.UTC)) ||
+		!repo.created[1].ScheduledAt.Equal(time.Date(2026, 1, 1, 9, 45, 0, 0, time.UTC)) {
+		t.Fatalf("expected reminders 15 minutes before and 45 after, got %+v", repo.created)
+	}
+
+	appt := &db.Appointment{ID: uuid.New(), UserID: userID, ApptType: constants.ApptHospital, ApptDateTime: fixedNow.AddDate(0, 0, 10)}
+	if err := svc.RescheduleAppointmentReminders(context.Background(), appt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.replaced) != 2 ||
+		repo.replaced[0].TemplateCode != constants.TemplateApptReminder || !repo.replaced[0].ScheduledAt.Equal(fixedNow.AddDate(0, 0, 3)) ||
+		repo.replaced[1].TemplateCode != constants.TemplateAppt1Day || !repo.replaced[1].ScheduledAt.Equal(fixedNow.AddDate(0, 0, 9)) {
+		t.Fatalf("expected reminders 7 and 1 days ahead, got %+v", repo.replaced)
+	}
+}
+
+type recordingSender struct {
+	mu       sync.Mutex
+	sent     []string
+	messages []NotificationMessage
+}
+
+func (s *recordingSender) Send(ctx context.Context, event db.NotificationEvent, msg NotificationMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, event.TemplateCode)
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func TestDeliverHonorsQuietHoursAndChannels(t *testing.T) {
+	start := time.Date(0, 1, 1, 22, 0, 0, 0, time.UTC)
+	end := time.Date(0, 1, 1, 7, 0, 0, 0, time.UTC)
+	timezone := "Asia/Bangkok"
+	smsOnly := uuid.New()
+	prefs := preferenceRepoStub{
+		find: func(ctx context.Context, id uuid.UUID) (*db.UserPreference, error) {
+			channels := []byte(`["line"]`)
+			if id == smsOnly {
+				channels = []byte(`["sms"]`)
+			}
+			return &db.UserPreference{
+				UserID:               id,
+				Timezone:             &timezone,
+				NotificationChannels: channels,
+				QuietHoursStart:      &start,
+				QuietHoursEnd:        &end,
+			}, nil
+		},
+	}
+	push, line := &recordingSender{}, &recordingSender{}
+	sender := MultiNotificationSender{
+		{Channel: constants.NotificationChannelPush, Sender: push},
+		{Channel: constants.NotificationChannelLine, Sender: line},
+	}
+	repo := &fakeNotificationRepo{}
+	svc := NewNotificationService(config.NotificationConfig{Timezone: "UTC"}, repo, prefs, nil, nil, sender, zap.NewNop())
+	impl := svc.(*notificationService)
+	// 16:30 UTC is 23:30 in Bangkok, inside the 22:00-07:00 quiet hours.
+	impl.now = func() time.Time { return time.Date(2026, 1, 1, 16, 30, 0, 0, time.UTC) }
+
+	weekly := db.NotificationEvent{ID: uuid.New(), UserID: uuid.New(), TemplateCode: constants.TemplateWeeklyHealthLog, Attempts: 2}
+	if err := impl.deliver(context.Background(), weekly); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deferred := repo.updates[0]
+	if deferred.Status != constants.NotificationPending || deferred.Attempts != 1 || deferred.NextAttemptAt == nil ||
+		!deferred.NextAttemptAt.Equal(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the weekly reminder deferred to 07:00 Bangkok, got %+v", deferred)
+	}
+
+	dose := db.NotificationEvent{ID: uuid.New(), UserID: weekly.UserID, TemplateCode: constants.TemplateMedAfterMealNow, Attempts: 1}
+	if err := impl.deliver(context.Background(), dose); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.updates[1].Status != constants.NotificationSent {
+		t.Fatalf("expected the dose reminder sent during quiet hours, got %+v", repo.updates[1])
+	}
+	if len(push.sent) != 0 || len(line.sent) != 1 {
+		t.Fatalf("expected delivery through LINE only, got push=%v line=%v", push.sent, line.sent)
+	}
+
+	// SMS is not configured, so nothing can reach this user.
+	unreachable := db.NotificationEvent{ID: uuid.New(), UserID: smsOnly, TemplateCode: constants.TemplateMedAfterMealNow, Attempts: 1}
+	if err := impl.deliver(context.Background(), unreachable); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.updates[2].Status != constants.NotificationDead || len(push.sent) != 0 || len(line.sent) != 1 {
+		t.Fatalf("expected the event dead-lettered without sending, got %+v", repo.updates[2])
+	}
+}
+
+func TestDeliverRendersInUserLanguage(t *testing.T) {
+	language := constants.LocaleEnglish
+	prefs := preferenceRepoStub{
+		find: func(ctx context.Context, id uuid.UUID) (*db.UserPreference, error) {
+			return &db.UserPreference{UserID: id, Language: &language}, nil
+		},
+	}
+	sender := &recordingSender{}
+	repo := &fakeNotificationRepo{template: &db.NotificationTemplate{Locale: constants.LocaleEnglish, Title: "Low supply", Body: "Runs out on {{date .run_out_date}}"}}
+	svc := NewNotificationService(config.NotificationConfig{Timezone: "UTC", DefaultLocale: constants.LocaleThai}, repo, prefs, nil, nil, sender, zap.NewNop())
+	impl := svc.(*notificationService)
+
+	event := db.NotificationEvent{ID: uuid.New(), UserID: uuid.New(), TemplateCode: constants.TemplateMedLowSupply, Payload: []byte(`{"run_out_date":"2026-01-26"}`)}
+	if err := impl.deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.templateLocales) != 2 || repo.templateLocales[0] != constants.LocaleEnglish || repo.templateLocales[1] != constants.LocaleThai {
+		t.Fatalf("expected the user's language before the default, got %v", repo.templateLocales)
+	}
+	if len(sender.messages) != 1 || sender.messages[0].Body != "Runs out on 26 Jan 2026" || repo.updates[0].Status != constants.NotificationSent {
+		t.Fatalf("expected the rendered message sent, got %+v %+v", sender.messages, repo.updates)
+	}
+	if stored := repo.updates[0]; stored.Title == nil || *stored.Title != "Low supply" || stored.Body == nil || *stored.Body != "Runs out on 26 Jan 2026" {
+		t.Fatalf("expected the rendered message stored for the inbox, got %+v", stored)
+	}
+
+	// A template that cannot be rendered is dead-lettered on the first attempt.
+	repo.template = &db.NotificationTemplate{Locale: constants.LocaleEnglish, Title: "t", Body: "{{.unknown}}"}
+	if err := impl.deliver(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.updates[1].Status != constants.NotificationDead || len(sender.messages) != 1 {
+		t.Fatalf("expected the event dead-lettered without sending, got %+v", repo.updates[1])
+	}
+}
+
+func TestListInboxPagesByCursor(t *testing.T) {
+	userID := uuid.New()
+	sentAt := func(hour int) *time.Time {
+		at := time.Date(2026, 1, 20, hour, 0, 0, 0, time.UTC)
+		return &at
+	}
+	readAt := sentAt(12)
+	repo := &fakeNotificationRepo{
+		template: &db.NotificationTemplate{Locale: constants.LocaleThai, Title: "บันทึกสุขภาพ", Body: "กรุณาบันทึก"},
+		unread:   2,
+		inbox: []db.NotificationEvent{
+			{ID: uuid.New(), UserID: userID, TemplateCode: constants.TemplateMedLowSupply, SentAt: sentAt(11), Title: strPtr("Low supply"), Body: strPtr("Metformin runs out soon"), Payload: []byte(`{"patient_medicine_id":"x"}`)},
+			{ID: uuid.New(), UserID: userID, TemplateCode: constants.TemplateAppt1Day, SentAt: sentAt(10), Title: strPtr("Appointment"), Body: strPtr("Tomorrow"), ReadAt: readAt},
+			// Sent before messages were stored: rendered with the current template.
+			{ID: uuid.New(), UserID: userID, TemplateCode: constants.TemplateWeeklyHealthLog, SentAt: sentAt(9)},
+		},
+	}
+	svc := NewNotificationService(config.NotificationConfig{Timezone: "UTC"}, repo, preferenceRepoStub{}, nil, nil, nil, zap.NewNop())
+	ctx := context.Background()
+
+	page, err := svc.ListInbox(ctx, userID, "", 2, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Items) != 2 || !page.HasMore || page.NextCursor == nil || page.Items[0].Title != "Low supply" || string(page.Items[0].Payload) != `{"patient_medicine_id":"x"}` || page.Items[1].ReadAt == nil {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+
+	page, err = svc.ListInbox(ctx, userID, *page.NextCursor, 2, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.inboxBefore == nil || repo.inboxBefore.ID != repo.inbox[1].ID || !repo.inboxBefore.SentAt.Equal(*sentAt(10)) {
+		t.Fatalf("expected the cursor to point below the last item, got %+v", repo.inboxBefore)
+	}
+	if len(page.Items) != 1 || page.HasMore || page.NextCursor != nil || page.Items[0].Title != "บันทึกสุขภาพ" || page.Items[0].Body != "กรุณาบันทึก" {
+		t.Fatalf("unexpected last page: %+v", page)
+	}
+
+	if _, err := svc.ListInbox(ctx, userID, "not-a-cursor", 0, false); err == nil {
+		t.Fatalf("expected an invalid cursor error")
+	}
+
+	count, err := svc.MarkRead(ctx, userID, repo.inbox[0].ID.String())
+	if err != nil || count.UnreadCount != 1 || len(repo.read) != 1 {
+		t.Fatalf("expected one item read, got %+v err=%v", count, err)
+	}
+	if count, err = svc.MarkAllRead(ctx, userID); err != nil || count.UnreadCount != 0 {
+		t.Fatalf("expected no unread items, got %+v err=%v", count, err)
+	}
+	if _, err := svc.ArchiveInboxItem(ctx, userID, repo.inbox[2].ID.String()); err != nil || len(repo.archived) != 1 {
+		t.Fatalf("expected the item archived, got %v", err)
+	}
+	if _, err := svc.MarkRead(ctx, userID, "bad"); err == nil {
+		t.Fatalf("expected an invalid id error")
+	}
+}
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type NotificationTemplateService interface {
+	ListTemplates(ctx context.Context, page, pageSize int, code, locale, active string) ([]dto.NotificationTemplateResponse, int64, error)
+	GetTemplate(ctx context.Context, id string) (dto.NotificationTemplateResponse, error)
+	CreateTemplate(ctx context.Context, req dto.CreateNotificationTemplateRequest) (dto.NotificationTemplateResponse, error)
+	UpdateTemplate(ctx context.Context, id string, req dto.UpdateNotificationTemplateRequest) (dto.NotificationTemplateResponse, error)
+	SetTemplateActive(ctx context.Context, id string, active bool) (dto.NotificationTemplateResponse, error)
+	DeleteTemplate(ctx context.Context, id string) error
+	PreviewTemplate(ctx context.Context, actorID uuid.UUID, id string, req dto.PreviewNotificationTemplateRequest) (dto.NotificationPreviewResponse, error)
+	TestSendTemplate(ctx context.Context, actorID uuid.UUID, id string, req dto.TestSendNotificationTemplateRequest) (dto.NotificationTestSendResponse, error)
+}
+
+var notificationLocales = []string{constants.LocaleThai, constants.LocaleEnglish}
+
+var (
+	medicineReminderVariables    = []string{"schedule_id", "target_date", "medicine_name", "dosage", "meal_timing", "dose_at"}
+	appointmentReminderVariables = []string{"appointment_id", "days_before", "appointment_title", "appointment_location", "appointment_at"}
+	escalationVariables          = []string{"type", "level", "patient_ids", "patient_count"}
+
+	// notificationTemplateVariables are the variables the events of each code
+	// carry: their payload and what notificationRenderer resolves it to.
+	// Templates may also use the keys of their own data.
+	notificationTemplateVariables = map[string][]string{
+		constants.TemplateMedBeforeMeal5Min:         medicineReminderVariables,
+		constants.TemplateMedBeforeMeal20Min:        medicineReminderVariables,
+		constants.TemplateMedAfterMealNow:           medicineReminderVariables,
+		constants.TemplateAppt5Days:                 appointmentReminderVariables,
+		constants.TemplateAppt1Day:                  appointmentReminderVariables,
+		constants.TemplateApptReminder:              appointmentReminderVariables,
+		constants.TemplateWeeklyHealthLog:           {"type"},
+		constants.TemplateMedLowSupply:              {"type", "patient_medicine_id", "medicine_name", "quantity_on_hand", "days_remaining", "run_out_date"},
+		constants.TemplateEscalationMissedCaregiver: escalationVariables,
+		constants.TemplateEscalationMissedNurse:     escalationVariables,
+	}
+)
+
+type notificationTemplateService struct {
+	repo      repositories.NotificationTemplateRepository
+	devices   repositories.DeviceTokenRepository
+	sender    NotificationSender
+	renderer  notificationRenderer
+	locations userLocations
+	now       func() time.Time
+}
+
+func NewNotificationTemplateService(repo repositories.NotificationTemplateRepository, prefs repositories.PreferenceRepository, medicines repositories.MedicineRepository, appointments repositories.AppointmentRepository, devices repositories.DeviceTokenRepository, sender NotificationSender, timezone string) NotificationTemplateService {
+	return &notificationTemplateService{
+		repo:      repo,
+		devices:   devices,
+		sender:    sender,
+		renderer:  newNotificationRenderer(medicines, appointments),
+		locations: newUserLocations(prefs, timezone),
+		now:       time.Now,
+	}
+}
+
+func (s *notificationTemplateService) ListTemplates(ctx context.Context, page, pageSize int, code, locale, active string) ([]dto.NotificationTemplateResponse, int64, error) {
+	filter := repositories.NotificationTemplateFilter{
+		Code:   strings.ToUpper(strings.TrimSpace(code)),
+		Locale: strings.ToLower(strings.TrimSpace(locale)),
+	}
+	if active = strings.TrimSpace(active); active != "" {
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid active")
+		}
+		filter.IsActive = &value
+	}
+
+	items, total, err := s.repo.List(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := make([]dto.NotificationTemplateResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, toNotificationTemplateResponse(item))
+	}
+	return resp, total, nil
+}
+
+func (s *notificationTemplateService) GetTemplate(ctx context.Context, id string) (dto.NotificationTemplateResponse, error) {
+	tpl, err := s.findTemplate(ctx, id)
+	if err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+	return toNotificationTemplateResponse(*tpl), nil
+}
+
+func (s *notificationTemplateService) CreateTemplate(ctx context.Context, req dto.CreateNotificationTemplateRequest) (dto.NotificationTemplateResponse, error) {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	locale := strings.ToLower(strings.TrimSpace(req.Locale))
+	if !isAllowed(locale, notificationLocales) {
+		return dto.NotificationTemplateResponse{}, domain.NewError(constants.ValidationFailed, "locale must be th or en")
+	}
+	title := strings.TrimSpace(req.Title)
+	if err := validateNotificationTemplate(code, title, req.Body, req.Data); err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+
+	tpl := &db.NotificationTemplate{
+		Code:     code,
+		Locale:   locale,
+		Title:    title,
+		Body:     req.Body,
+		Data:     templateDataJSON(req.Data),
+		IsActive: false,
+	}
+	if err := s.repo.Create(ctx, tpl); err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+	return toNotificationTemplateResponse(*tpl), nil
+}
+
+// UpdateTemplate edits a template in place; an active template is used by the
+// next delivery.
+func (s *notificationTemplateService) UpdateTemplate(ctx context.Context, id string, req dto.UpdateNotificationTemplateRequest) (dto.NotificationTemplateResponse, error) {
+	tpl, err := s.findTemplate(ctx, id)
+	if err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+
+	updates := map[string]any{}
+	title, body, data := tpl.Title, tpl.Body, templateDataMap(tpl.Data)
+	if req.Title != nil {
+		title = strings.TrimSpace(*req.Title)
+		updates["title"] = title
+	}
+	if req.Body != nil {
+		body = *req.Body
+		updates["body"] = body
+	}
+	if req.Data != nil {
+		data = req.Data
+		updates["data"] = templateDataJSON(req.Data)
+	}
+	if len(updates) == 0 {
+		return toNotificationTemplateResponse(*tpl), nil
+	}
+	if err := validateNotificationTemplate(tpl.Code, title, body, data); err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+
+	updated, err := s.repo.Update(ctx, tpl.ID, updates)
+	if err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+	return toNotificationTemplateResponse(*updated), nil
+}
+
+// SetTemplateActive activates or deactivates a template. The last active
+// template of a code stays active: without one its events are dead-lettered.
+func (s *notificationTemplateService) SetTemplateActive(ctx context.Context, id string, active bool) (dto.NotificationTemplateResponse, error) {
+	tpl, err := s.findTemplate(ctx, id)
+	if err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+	if tpl.IsActive == active {
+		return toNotificationTemplateResponse(*tpl), nil
+	}
+	if !active {
+		if err := s.ensureOtherActive(ctx, *tpl, "deactivated"); err != nil {
+			return dto.NotificationTemplateResponse{}, err
+		}
+	}
+
+	updated, err := s.repo.Update(ctx, tpl.ID, map[string]any{"is_active": active})
+	if err != nil {
+		return dto.NotificationTemplateResponse{}, err
+	}
+	return toNotificationTemplateResponse(*updated), nil
+}
+
+func (s *notificationTemplateService) DeleteTemplate(ctx context.Context, id string) error {
+	tpl, err := s.findTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+	if tpl.IsActive {
+		if err := s.ensureOtherActive(ctx, *tpl, "deleted"); err != nil {
+			return err
+		}
+	}
+	return s.repo.Delete(ctx, tpl.ID)
+}
+
+func (s *notificationTemplateService) ensureOtherActive(ctx context.Context, tpl db.NotificationTemplate, action string) error {
+	others, err := s.repo.CountActiveByCode(ctx, tpl.Code, tpl.ID)
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return domain.NewError(constants.NotificationInvalid, fmt.Sprintf("the last active %s template cannot be %s", tpl.Code, action))
+	}
+	return nil
+}
+
+// PreviewTemplate renders a template, active or not, for a sample user and
+// payload without sending it.
+func (s *notificationTemplateService) PreviewTemplate(ctx context.Context, actorID uuid.UUID, id string, req dto.PreviewNotificationTemplateRequest) (dto.NotificationPreviewResponse, error) {
+	tpl, err := s.findTemplate(ctx, id)
+	if err != nil {
+		return dto.NotificationPreviewResponse{}, err
+	}
+	userID := actorID
+	if req.UserID != nil && strings.TrimSpace(*req.UserID) != "" {
+		userID, err = uuid.Parse(strings.TrimSpace(*req.UserID))
+		if err != nil {
+			return dto.NotificationPreviewResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+		}
+	}
+
+	_, msg, err := s.render(ctx, *tpl, userID, req.Payload)
+	if err != nil {
+		return dto.NotificationPreviewResponse{}, err
+	}
+	return toNotificationPreviewResponse(*tpl, msg), nil
+}
+
+// TestSendTemplate renders a template for the caller and pushes it to the
+// caller's own active devices. Nothing is recorded as a notification event.
+func (s *notificationTemplateService) TestSendTemplate(ctx context.Context, actorID uuid.UUID, id string, req dto.TestSendNotificationTemplateRequest) (dto.NotificationTestSendResponse, error) {
+	if s.sender == nil {
+		return dto.NotificationTestSendResponse{}, domain.NewError(constants.InternalUnavailable, "notification delivery is disabled")
+	}
+	tpl, err := s.findTemplate(ctx, id)
+	if err != nil {
+		return dto.NotificationTestSendResponse{}, err
+	}
+	devices, err := s.devices.ListActiveByUser(ctx, actorID)
+	if err != nil {
+		return dto.NotificationTestSendResponse{}, err
+	}
+	if len(devices) == 0 {
+		return dto.NotificationTestSendResponse{}, domain.NewError(constants.NotificationInvalid, "no active devices registered for this account")
+	}
+
+	event, msg, err := s.render(ctx, *tpl, actorID, req.Payload)
+	if err != nil {
+		return dto.NotificationTestSendResponse{}, err
+	}
+	if via, ok := s.sender.(channelNotificationSender); ok {
+		err = via.SendVia(ctx, event, msg, []string{constants.NotificationChannelPush})
+	} else {
+		err = s.sender.Send(ctx, event, msg)
+	}
+	if err != nil {
+		return dto.NotificationTestSendResponse{}, domain.WrapError(constants.InternalUnavailable, "test send failed", err)
+	}
+	return dto.NotificationTestSendResponse{
+		NotificationPreviewResponse: toNotificationPreviewResponse(*tpl, msg),
+		Devices:                     len(devices),
+	}, nil
+}
+
+// render builds an unsaved event for userID and renders tpl for it in the
+// user's location. Render errors are the caller's to fix.
+func (s *notificationTemplateService) render(ctx context.Context, tpl db.NotificationTemplate, userID uuid.UUID, payload map[string]any) (db.NotificationEvent, NotificationMessage, error) {
+	location, err := s.locations.forUser(ctx, userID)
+	if err != nil {
+		return db.NotificationEvent{}, NotificationMessage{}, err
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	event := db.NotificationEvent{
+		ID:           uuid.New(),
+		UserID:       userID,
+		TemplateCode: tpl.Code,
+		ScheduledAt:  s.now().UTC(),
+		Status:       constants.NotificationPending,
+		Payload:      datatypes.JSON(payloadBytes),
+	}
+	msg, err := s.renderer.render(ctx, event, tpl, tpl.Locale, location)
+	if err != nil {
+		if errors.Is(err, errNotificationRender) {
+			return db.NotificationEvent{}, NotificationMessage{}, domain.NewError(constants.NotificationInvalid, strings.TrimPrefix(err.Error(), errNotificationRender.Error()+": "))
+		}
+		return db.NotificationEvent{}, NotificationMessage{}, err
+	}
+	return event, msg, nil
+}
+
+func (s *notificationTemplateService) findTemplate(ctx context.Context, id string) (*db.NotificationTemplate, error) {
+	templateID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+	return s.repo.FindByID(ctx, templateID)
+}
+
+// validateNotificationTemplate checks that title and body parse and read only
+// the variables events of code carry or keys of the template's data.
+func validateNotificationTemplate(code, title, body string, data map[string]any) error {
+	known, ok := notificationTemplateVariables[code]
+	if !ok {
+		return domain.NewError(constants.NotificationInvalid, "unknown template code")
+	}
+	if title == "" || strings.TrimSpace(body) == "" {
+		return domain.NewError(constants.NotificationInvalid, "title and body are required")
+	}
+	for _, field := range []struct{ name, text string }{{"title", title}, {"body", body}} {
+		names, err := templateVariables(field.name, field.text)
+		if err != nil {
+			return domain.NewError(constants.NotificationInvalid, fmt.Sprintf("invalid %s: %v", field.name, err))
+		}
+		for _, name := range names {
+			if _, ok := data[name]; ok || isAllowed(name, known) {
+				continue
+			}
+			return domain.NewError(constants.NotificationInvalid, fmt.Sprintf("unknown variable %q in %s", name, field.name))
+		}
+	}
+	return nil
+}
+
+// templateVariables lists the top-level variables a template reads: fields of
+// the root dot and of $. Inside with and range the dot is something else, so
+// fields there are not variables.
+func templateVariables(name, text string) ([]string, error) {
+	tpl, err := template.New(name).Funcs(notificationFuncs(constants.LocaleEnglish, time.UTC)).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if tpl.Tree == nil {
+		return nil, nil
+	}
+
+	var names []string
+	var nested bool
+	var walk func(node parse.Node, root bool)
+	walk = func(node parse.Node, root bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, root)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, root)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, root)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, root)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, root)
+		case *parse.FieldNode:
+			if root {
+				names = append(names, n.Ident[0])
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				names = append(names, n.Ident[1])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, root)
+			walk(n.List, root)
+			walk(n.ElseList, root)
+		case *parse.WithNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.RangeNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.TemplateNode:
+			nested = true
+		}
+	}
+	walk(tpl.Tree.Root, true)
+	if nested || len(tpl.Templates()) > 1 {
+		return nil, errors.New("nested templates are not supported")
+	}
+	return names, nil
+}
+
+func templateDataJSON(data map[string]any) datatypes.JSON {
+	if len(data) == 0 {
+		return nil
+	}
+	raw, _ := json.Marshal(data)
+	return datatypes.JSON(raw)
+}
+
+func templateDataMap(data datatypes.JSON) map[string]any {
+	values := map[string]any{}
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &values)
+	}
+	return values
+}
+
+func toNotificationTemplateResponse(tpl db.NotificationTemplate) dto.NotificationTemplateResponse {
+	return dto.NotificationTemplateResponse{
+		ID:        tpl.ID.String(),
+		Code:      tpl.Code,
+		Locale:    tpl.Locale,
+		Title:     tpl.Title,
+		Body:      tpl.Body,
+		Data:      templateDataMap(tpl.Data),
+		IsActive:  tpl.IsActive,
+		CreatedAt: tpl.CreatedAt,
+	}
+}
+
+func toNotificationPreviewResponse(tpl db.NotificationTemplate, msg NotificationMessage) dto.NotificationPreviewResponse {
+	return dto.NotificationPreviewResponse{
+		TemplateID: tpl.ID.String(),
+		Code:       tpl.Code,
+		Locale:     tpl.Locale,
+		Title:      msg.Title,
+		Body:       msg.Body,
+		Data:       msg.Data,
+	}
+}
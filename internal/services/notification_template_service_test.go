@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type fakeTemplateRepo struct {
+	templates map[uuid.UUID]*db.NotificationTemplate
+	created   []db.NotificationTemplate
+	deleted   []uuid.UUID
+}
+
+func (f *fakeTemplateRepo) List(ctx context.Context, filter repositories.NotificationTemplateFilter, page, pageSize int) ([]db.NotificationTemplate, int64, error) {
+	return nil, 0, nil
+}
+func (f *fakeTemplateRepo) FindByID(ctx context.Context, id uuid.UUID) (*db.NotificationTemplate, error) {
+	tpl, ok := f.templates[id]
+	if !ok {
+		return nil, domain.NewError(constants.NotificationNotFound, "notification template not found")
+	}
+	copied := *tpl
+	return &copied, nil
+}
+func (f *fakeTemplateRepo) Create(ctx context.Context, tpl *db.NotificationTemplate) error {
+	tpl.ID = uuid.New()
+	f.created = append(f.created, *tpl)
+	return nil
+}
+func (f *fakeTemplateRepo) Update(ctx context.Context, id uuid.UUID, updates map[string]any) (*db.NotificationTemplate, error) {
+	tpl := f.templates[id]
+	if v, ok := updates["title"].(string); ok {
+		tpl.Title = v
+	}
+	if v, ok := updates["body"].(string); ok {
+		tpl.Body = v
+	}
+	if v, ok := updates["is_active"].(bool); ok {
+		tpl.IsActive = v
+	}
+	copied := *tpl
+	return &copied, nil
+}
+func (f *fakeTemplateRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+func (f *fakeTemplateRepo) CountActiveByCode(ctx context.Context, code string, excludeID uuid.UUID) (int64, error) {
+	var count int64
+	for id, tpl := range f.templates {
+		if tpl.Code == code && tpl.IsActive && id != excludeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func assertAppErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+	appErr, ok := domain.AsAppError(err)
+	if !ok || appErr.Code != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
+
+func TestValidateNotificationTemplateVariables(t *testing.T) {
+	cases := []struct {
+		name  string
+		code  string
+		body  string
+		data  map[string]any
+		valid bool
+	}{
+		{"resolved variables", constants.TemplateMedAfterMealNow, "Take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} at {{time .dose_at}}", nil, true},
+		{"fields inside with are not variables", constants.TemplateAppt1Day, "{{with .appointment_location}}{{.name}}{{end}}", nil, true},
+		{"root variable inside with", constants.TemplateAppt1Day, "{{with .appointment_location}}{{$.appointment_title}}{{end}}", nil, true},
+		{"template data key", constants.TemplateWeeklyHealthLog, "Open {{.screen}}", map[string]any{"screen": "health"}, true},
+		{"variable of another code", constants.TemplateWeeklyHealthLog, "Take {{.medicine_name}}", nil, false},
+		{"unknown root variable inside with", constants.TemplateAppt1Day, "{{with .appointment_location}}{{$.room}}{{end}}", nil, false},
+		{"unknown function", constants.TemplateAppt1Day, "{{upper .appointment_title}}", nil, false},
+		{"syntax error", constants.TemplateAppt1Day, "{{.appointment_title", nil, false},
+		{"nested template", constants.TemplateAppt1Day, `{{define "x"}}hi{{end}}{{template "x"}}`, nil, false},
+		{"unknown code", "CUSTOM", "Hello", nil, false},
+	}
+	for _, tc := range cases {
+		err := validateNotificationTemplate(tc.code, "Title", tc.body, tc.data)
+		if tc.valid && err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if !tc.valid {
+			assertAppErrorCode(t, err, constants.NotificationInvalid)
+		}
+	}
+}
+
+func TestNotificationTemplateLifecycle(t *testing.T) {
+	thID, enID := uuid.New(), uuid.New()
+	repo := &fakeTemplateRepo{templates: map[uuid.UUID]*db.NotificationTemplate{
+		thID: {ID: thID, Code: constants.TemplateWeeklyHealthLog, Locale: constants.LocaleThai, Title: "บันทึกสุขภาพ", Body: "กรุณาบันทึก", IsActive: true},
+		enID: {ID: enID, Code: constants.TemplateWeeklyHealthLog, Locale: constants.LocaleEnglish, Title: "Weekly log", Body: "Please log", IsActive: false},
+	}}
+	svc := NewNotificationTemplateService(repo, preferenceRepoStub{}, nil, nil, nil, nil, "UTC")
+	ctx := context.Background()
+
+	created, err := svc.CreateTemplate(ctx, dto.CreateNotificationTemplateRequest{Code: " med_low_supply ", Locale: "EN", Title: "Low", Body: "{{.medicine_name}} runs out on {{date .run_out_date}}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Code != constants.TemplateMedLowSupply || created.Locale != constants.LocaleEnglish || created.IsActive {
+		t.Fatalf("expected an inactive normalized template, got %+v", created)
+	}
+	_, err = svc.CreateTemplate(ctx, dto.CreateNotificationTemplateRequest{Code: constants.TemplateMedLowSupply, Locale: "fr", Title: "t", Body: "b"})
+	assertAppErrorCode(t, err, constants.ValidationFailed)
+
+	_, err = svc.UpdateTemplate(ctx, thID.String(), dto.UpdateNotificationTemplateRequest{Body: strPtr("{{.medicine_name}}")})
+	assertAppErrorCode(t, err, constants.NotificationInvalid)
+	updated, err := svc.UpdateTemplate(ctx, thID.String(), dto.UpdateNotificationTemplateRequest{Body: strPtr("กรุณาบันทึกสุขภาพ")})
+	if err != nil || updated.Body != "กรุณาบันทึกสุขภาพ" {
+		t.Fatalf("expected the body updated, got %+v %v", updated, err)
+	}
+
+	// The only active template of a code cannot be switched off or deleted.
+	_, err = svc.SetTemplateActive(ctx, thID.String(), false)
+	assertAppErrorCode(t, err, constants.NotificationInvalid)
+	assertAppErrorCode(t, svc.DeleteTemplate(ctx, thID.String()), constants.NotificationInvalid)
+
+	if resp, err := svc.SetTemplateActive(ctx, enID.String(), true); err != nil || !resp.IsActive {
+		t.Fatalf("expected the English template activated, got %+v %v", resp, err)
+	}
+	if resp, err := svc.SetTemplateActive(ctx, thID.String(), false); err != nil || resp.IsActive {
+		t.Fatalf("expected the Thai template deactivated, got %+v %v", resp, err)
+	}
+	if err := svc.DeleteTemplate(ctx, thID.String()); err != nil || len(repo.deleted) != 1 {
+		t.Fatalf("expected the inactive template deleted, got %v", err)
+	}
+}
+
+func TestPreviewAndTestSendNotificationTemplate(t *testing.T) {
+	tplID := uuid.New()
+	repo := &fakeTemplateRepo{templates: map[uuid.UUID]*db.NotificationTemplate{
+		tplID: {ID: tplID, Code: constants.TemplateMedLowSupply, Locale: constants.LocaleThai, Title: "ยาใกล้หมด", Body: "ยา {{.medicine_name}} จะหมดวันที่ {{date .run_out_date}}"},
+	}}
+	push, line := &recordingSender{}, &recordingSender{}
+	sender := MultiNotificationSender{
+		{Channel: constants.NotificationChannelPush, Sender: push},
+		{Channel: constants.NotificationChannelLine, Sender: line},
+	}
+	tokens := &pushTokenRepoStub{}
+	svc := NewNotificationTemplateService(repo, preferenceRepoStub{}, nil, nil, tokens, sender, "Asia/Bangkok")
+	ctx := context.Background()
+	adminID := uuid.New()
+	payload := map[string]any{"medicine_name": "Metformin", "run_out_date": "2026-03-05"}
+
+	preview, err := svc.PreviewTemplate(ctx, adminID, tplID.String(), dto.PreviewNotificationTemplateRequest{Payload: payload})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if preview.Body != "ยา Metformin จะหมดวันที่ 5 มี.ค. 2569" || preview.Data["template_code"] != constants.TemplateMedLowSupply {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	_, err = svc.PreviewTemplate(ctx, adminID, tplID.String(), dto.PreviewNotificationTemplateRequest{Payload: map[string]any{"medicine_name": "Metformin"}})
+	assertAppErrorCode(t, err, constants.NotificationInvalid)
+
+	_, err = svc.TestSendTemplate(ctx, adminID, tplID.String(), dto.TestSendNotificationTemplateRequest{Payload: payload})
+	assertAppErrorCode(t, err, constants.NotificationInvalid)
+
+	tokens.tokens = []db.DeviceToken{{ID: uuid.New(), UserID: adminID, Platform: "android", Token: "admin-phone"}}
+	sent, err := svc.TestSendTemplate(ctx, adminID, tplID.String(), dto.TestSendNotificationTemplateRequest{Payload: payload})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent.Devices != 1 || len(push.messages) != 1 || push.messages[0].Body != preview.Body || len(line.sent) != 0 {
+		t.Fatalf("expected one push to the admin's device, got %+v push=%v line=%v", sent, push.messages, line.sent)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+)
+
+// recurrence decides which calendar days a medicine schedule is due. A day is
+// due when it lies within [start, end], is a whole number of intervalDays
+// after start, and its weekday is enabled in weekdayMask. Tapering is modelled
+// as consecutive schedules with adjoining date ranges.
+type recurrence struct {
+	start        time.Time
+	end          *time.Time
+	intervalDays int
+	weekdayMask  int
+}
+
+func scheduleRecurrence(schedule db.MedicineSchedule) recurrence {
+	return recurrence{
+		start:        schedule.StartDate,
+		end:          schedule.EndDate,
+		intervalDays: schedule.IntervalDays,
+		weekdayMask:  schedule.WeekdayMask,
+	}
+}
+
+// occursOn takes a calendar date; only its year, month and day are used.
+func (r recurrence) occursOn(day time.Time) bool {
+	date := calendarDate(day)
+	start := calendarDate(r.start)
+	if !r.start.IsZero() && date.Before(start) {
+		return false
+	}
+	if r.end != nil && date.After(calendarDate(*r.end)) {
+		return false
+	}
+	if r.intervalDays > 1 && !r.start.IsZero() {
+		days := int(date.Sub(start).Hours() / 24)
+		if days%r.intervalDays != 0 {
+			return false
+		}
+	}
+	mask := r.weekdayMask
+	if mask <= 0 {
+		mask = constants.WeekdayMaskAll
+	}
+	return mask&(1<<uint(date.Weekday())) != 0
+}
+
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func weekdayMask(days []string) (int, error) {
+	if len(days) == 0 {
+		return 0, domain.NewError(constants.ValidationFailed, "weekdays must not be empty")
+	}
+	mask := 0
+	for _, day := range days {
+		index := -1
+		for i, code := range constants.Weekdays {
+			if strings.EqualFold(strings.TrimSpace(day), code) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return 0, domain.NewError(constants.ValidationFailed, "invalid weekday")
+		}
+		mask |= 1 << uint(index)
+	}
+	return mask, nil
+}
+
+func weekdaysFromMask(mask int) []string {
+	if mask <= 0 {
+		mask = constants.WeekdayMaskAll
+	}
+	days := make([]string, 0, len(constants.Weekdays))
+	for i, code := range constants.Weekdays {
+		if mask&(1<<uint(i)) != 0 {
+			days = append(days, code)
+		}
+	}
+	return days
+}
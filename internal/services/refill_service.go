@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type RefillService interface {
+	ConsumeDose(ctx context.Context, userID, scheduleID uuid.UUID, doses float64) error
+	Refill(ctx context.Context, actorID uuid.UUID, role constants.Role, id string, req dto.RefillPatientMedicineRequest) (dto.PatientMedicineResponse, error)
+	ListRefillsNeeded(ctx context.Context, query dto.RefillListQuery, page, pageSize int) ([]dto.PatientRefillResponse, int64, error)
+}
+
+// supplyHorizonDays bounds how far ahead a run-out date is projected.
+const supplyHorizonDays = 366
+
+type refillService struct {
+	repo      repositories.RefillRepository
+	medicines repositories.MedicineRepository
+	cfg       config.RefillConfig
+	locations userLocations
+	now       func() time.Time
+}
+
+func NewRefillService(repo repositories.RefillRepository, medicines repositories.MedicineRepository, prefs repositories.PreferenceRepository, cfg config.RefillConfig, timezone string) RefillService {
+	return &refillService{
+		repo:      repo,
+		medicines: medicines,
+		cfg:       cfg,
+		locations: newUserLocations(prefs, timezone),
+		now:       time.Now,
+	}
+}
+
+// ConsumeDose takes doses doses of the schedule's medicine out of stock; a
+// negative count puts them back after a correction. Once the remaining stock
+// covers fewer than LowSupplyDays days, the patient gets one MED_LOW_SUPPLY
+// reminder per refill. Medicines of anyone but userID, the patient who took
+// the dose, medicines without a recorded stock, and ones whose dosage does not
+// start with a number are left alone.
+func (s *refillService) ConsumeDose(ctx context.Context, userID, scheduleID uuid.UUID, doses float64) error {
+	schedule, err := s.medicines.GetScheduleByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+	med, err := s.medicines.GetPatientMedicineByID(ctx, schedule.PatientMedicineID)
+	if err != nil {
+		return err
+	}
+	amount, ok := parseDosageAmount(med.DosageAmount)
+	if med.UserID != userID || med.QuantityOnHand == nil || !ok {
+		return nil
+	}
+
+	updated, err := s.repo.AdjustStock(ctx, med.ID, -amount*doses)
+	if err != nil || updated == nil || doses < 0 {
+		return err
+	}
+	if !updated.IsActive || updated.LowSupplyNotifiedAt != nil || updated.QuantityOnHand == nil {
+		return nil
+	}
+
+	schedules, err := s.medicines.ListSchedulesByMedicine(ctx, med.ID)
+	if err != nil {
+		return err
+	}
+	location, err := s.locations.forUser(ctx, med.UserID)
+	if err != nil {
+		return err
+	}
+	today := localDate(s.now(), location)
+	days, ok := supplyDays(*updated.QuantityOnHand, amount, schedules, today)
+	if !ok || days >= s.cfg.LowSupplyDays {
+		return nil
+	}
+
+	payload := map[string]any{
+		"type":                "low_supply",
+		"patient_medicine_id": med.ID.String(),
+		"medicine_name":       medicineDisplayName(ctx, s.medicines, *med),
+		"quantity_on_hand":    *updated.QuantityOnHand,
+		"days_remaining":      days,
+		"run_out_date":        today.AddDate(0, 0, days).Format("2006-01-02"),
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	_, err = s.repo.FlagLowSupply(ctx, med.ID, db.NotificationEvent{
+		UserID:       med.UserID,
+		TemplateCode: constants.TemplateMedLowSupply,
+		ScheduledAt:  s.now().UTC(),
+		Status:       constants.NotificationPending,
+		Payload:      datatypes.JSON(payloadBytes),
+	})
+	return err
+}
+
+// medicineDisplayName follows the same precedence as the medicine listings:
+// custom name, then master trade name, then category item name.
+func medicineDisplayName(ctx context.Context, medicines repositories.MedicineRepository, med db.PatientMedicine) string {
+	if med.CustomName != nil {
+		return *med.CustomName
+	}
+	if med.MedicineMasterID != nil {
+		if master, err := medicines.GetMasterByID(ctx, *med.MedicineMasterID); err == nil {
+			return master.TradeName
+		}
+	}
+	if med.CategoryItemID != nil {
+		if item, err := medicines.GetCategoryItemByID(ctx, *med.CategoryItemID); err == nil {
+			return item.DisplayName
+		}
+	}
+	return ""
+}
+
+// Refill adds a refill to the stock on hand: quantity units, or packs of the
+// medicine's pack size (one pack when neither is given). refill_date defaults
+// to the patient's today. Patients can only refill their own medicines.
+func (s *refillService) Refill(ctx context.Context, actorID uuid.UUID, role constants.Role, id string, req dto.RefillPatientMedicineRequest) (dto.PatientMedicineResponse, error) {
+	medID, err := uuid.Parse(id)
+	if err != nil {
+		return dto.PatientMedicineResponse{}, domain.NewError(constants.ValidationFailed, "invalid id")
+	}
+	if req.Packs != nil && req.Quantity != nil {
+		return dto.PatientMedicineResponse{}, domain.NewError(constants.ValidationFailed, "packs and quantity are mutually exclusive")
+	}
+
+	med, err := s.medicines.GetPatientMedicineByID(ctx, medID)
+	if err != nil {
+		return dto.PatientMedicineResponse{}, err
+	}
+	if role == constants.RolePatient && med.UserID != actorID {
+		return dto.PatientMedicineResponse{}, domain.NewError(constants.MedNotFound, "patient medicine not found")
+	}
+
+	var quantity float64
+	if req.Quantity != nil {
+		quantity = *req.Quantity
+	} else {
+		if med.PackSize == nil {
+			return dto.PatientMedicineResponse{}, domain.NewError(constants.ValidationFailed, "pack_size is not set; refill by quantity")
+		}
+		packs := 1.0
+		if req.Packs != nil {
+			packs = *req.Packs
+		}
+		quantity = packs * *med.PackSize
+	}
+
+	refillDate, err := parseRefillDate(req.RefillDate)
+	if err != nil {
+		return dto.PatientMedicineResponse{}, err
+	}
+	if refillDate == nil {
+		location, err := s.locations.forUser(ctx, med.UserID)
+		if err != nil {
+			return dto.PatientMedicineResponse{}, err
+		}
+		today := localDate(s.now(), location)
+		refillDate = &today
+	}
+
+	updated, err := s.repo.Restock(ctx, medID, quantity, *refillDate)
+	if err != nil {
+		return dto.PatientMedicineResponse{}, err
+	}
+	return toPatientMedicineResponse(*updated), nil
+}
+
+// ListRefillsNeeded lists, per patient, the tracked medicines that run out
+// within WithinDays days (LowSupplyDays by default) of the patient's today.
+// Patients are ordered by their soonest run-out.
+func (s *refillService) ListRefillsNeeded(ctx context.Context, query dto.RefillListQuery, page, pageSize int) ([]dto.PatientRefillResponse, int64, error) {
+	within := s.cfg.LowSupplyDays
+	if query.WithinDays != nil {
+		within = *query.WithinDays
+	}
+	var patientID *uuid.UUID
+	if value := strings.TrimSpace(query.PatientID); value != "" {
+		pid, err := uuid.Parse(value)
+		if err != nil {
+			return nil, 0, domain.NewError(constants.ValidationFailed, "invalid patient_id")
+		}
+		patientID = &pid
+	}
+
+	rows, err := s.repo.ListTrackedMedicines(ctx, patientID)
+	if err != nil {
+		return nil, 0, err
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.PatientMedicineID)
+	}
+	schedules, err := s.repo.ListSchedulesByMedicines(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	byMedicine := make(map[uuid.UUID][]db.MedicineSchedule, len(rows))
+	for _, schedule := range schedules {
+		byMedicine[schedule.PatientMedicineID] = append(byMedicine[schedule.PatientMedicineID], schedule)
+	}
+
+	now := s.now()
+	var patients []*dto.PatientRefillResponse
+	byPatient := make(map[uuid.UUID]*dto.PatientRefillResponse)
+	for _, row := range rows {
+		amount, ok := parseDosageAmount(row.DosageAmount)
+		if !ok {
+			continue
+		}
+		today := localDate(now, s.locations.resolve(row.Timezone))
+		days, ok := supplyDays(row.QuantityOnHand, amount, byMedicine[row.PatientMedicineID], today)
+		if !ok || days >= within {
+			continue
+		}
+
+		patient, ok := byPatient[row.UserID]
+		if !ok {
+			patient = &dto.PatientRefillResponse{
+				UserID:    row.UserID.String(),
+				Username:  row.Username,
+				FirstName: row.FirstName,
+				LastName:  row.LastName,
+				HN:        row.HN,
+			}
+			byPatient[row.UserID] = patient
+			patients = append(patients, patient)
+		}
+		var refillDate *string
+		if row.RefillDate != nil {
+			value := row.RefillDate.Format("2006-01-02")
+			refillDate = &value
+		}
+		patient.Medicines = append(patient.Medicines, dto.RefillMedicineResponse{
+			PatientMedicineID: row.PatientMedicineID.String(),
+			MedicineName:      row.MedicineName,
+			DosageAmount:      row.DosageAmount,
+			QuantityOnHand:    row.QuantityOnHand,
+			PackSize:          row.PackSize,
+			RefillDate:        refillDate,
+			DaysRemaining:     days,
+			RunOutDate:        today.AddDate(0, 0, days).Format("2006-01-02"),
+		})
+	}
+
+	for _, patient := range patients {
+		sort.SliceStable(patient.Medicines, func(i, j int) bool {
+			return patient.Medicines[i].DaysRemaining < patient.Medicines[j].DaysRemaining
+		})
+	}
+	sort.SliceStable(patients, func(i, j int) bool {
+		return patients[i].Medicines[0].DaysRemaining < patients[j].Medicines[0].DaysRemaining
+	})
+
+	total := int64(len(patients))
+	start := (page - 1) * pageSize
+	if start > len(patients) {
+		start = len(patients)
+	}
+	end := start + pageSize
+	if end > len(patients) {
+		end = len(patients)
+	}
+	resp := make([]dto.PatientRefillResponse, 0, end-start)
+	for _, patient := range patients[start:end] {
+		resp = append(resp, *patient)
+	}
+	return resp, total, nil
+}
+
+// supplyDays projects how many days, counting from today, quantity covers at
+// amount per dose on the given schedules. ok is false when no dose falls due
+// within the projection horizon.
+func supplyDays(quantity, amount float64, schedules []db.MedicineSchedule, today time.Time) (int, bool) {
+	rules := make([]recurrence, 0, len(schedules))
+	for _, schedule := range schedules {
+		rules = append(rules, scheduleRecurrence(schedule))
+	}
+
+	remaining := quantity
+	due := false
+	for day := 0; day < supplyHorizonDays; day++ {
+		date := today.AddDate(0, 0, day)
+		var need float64
+		for _, rule := range rules {
+			if rule.occursOn(date) {
+				need += amount
+			}
+		}
+		if need == 0 {
+			continue
+		}
+		due = true
+		if remaining < need {
+			return day, true
+		}
+		remaining -= need
+	}
+	return supplyHorizonDays, due
+}
+
+// parseDosageAmount reads the units per dose from the start of a dosage text
+// such as "1", "1/2", "1.5", "1 1/2" or "2 tablets". ok is false when the text
+// does not start with a positive number.
+func parseDosageAmount(value string) (float64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	amount, ok := parseQuantity(fields[0])
+	if !ok {
+		return 0, false
+	}
+	if len(fields) > 1 && strings.Contains(fields[1], "/") {
+		if fraction, ok := parseQuantity(fields[1]); ok && fraction < 1 {
+			amount += fraction
+		}
+	}
+	return amount, amount > 0
+}
+
+func parseQuantity(token string) (float64, bool) {
+	if numerator, denominator, found := strings.Cut(token, "/"); found {
+		n, err := strconv.ParseFloat(numerator, 64)
+		if err != nil {
+			return 0, false
+		}
+		d, err := strconv.ParseFloat(denominator, 64)
+		if err != nil || d == 0 {
+			return 0, false
+		}
+		return finite(n / d)
+	}
+	v, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return 0, false
+	}
+	return finite(v)
+}
+
+func finite(v float64) (float64, bool) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/config"
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type refillRepoStub struct {
+	medicine  *db.PatientMedicine
+	events    []db.NotificationEvent
+	restocked float64
+	tracked   []repositories.TrackedMedicineRow
+	schedules []db.MedicineSchedule
+}
+
+func (s *refillRepoStub) AdjustStock(ctx context.Context, id uuid.UUID, delta float64) (*db.PatientMedicine, error) {
+	if s.medicine == nil || s.medicine.QuantityOnHand == nil {
+		return nil, nil
+	}
+	quantity := math.Max(*s.medicine.QuantityOnHand+delta, 0)
+	s.medicine.QuantityOnHand = &quantity
+	updated := *s.medicine
+	return &updated, nil
+}
+func (s *refillRepoStub) Restock(ctx context.Context, id uuid.UUID, quantity float64, refillDate time.Time) (*db.PatientMedicine, error) {
+	s.restocked = quantity
+	updated := *s.medicine
+	total := quantity
+	if updated.QuantityOnHand != nil {
+		total += *updated.QuantityOnHand
+	}
+	updated.QuantityOnHand = &total
+	updated.RefillDate = &refillDate
+	updated.LowSupplyNotifiedAt = nil
+	return &updated, nil
+}
+func (s *refillRepoStub) FlagLowSupply(ctx context.Context, id uuid.UUID, event db.NotificationEvent) (bool, error) {
+	if s.medicine.LowSupplyNotifiedAt != nil {
+		return false, nil
+	}
+	s.medicine.LowSupplyNotifiedAt = &event.ScheduledAt
+	s.events = append(s.events, event)
+	return true, nil
+}
+func (s *refillRepoStub) ListTrackedMedicines(ctx context.Context, patientID *uuid.UUID) ([]repositories.TrackedMedicineRow, error) {
+	return s.tracked, nil
+}
+func (s *refillRepoStub) ListSchedulesByMedicines(ctx context.Context, medicineIDs []uuid.UUID) ([]db.MedicineSchedule, error) {
+	return s.schedules, nil
+}
+
+func TestParseDosageAmount(t *testing.T) {
+	cases := map[string]float64{"1": 1, "1/2": 0.5, "1/4": 0.25, "1.5 tablets": 1.5, "1 1/2": 1.5, "2 เม็ด": 2}
+	for input, want := range cases {
+		if got, ok := parseDosageAmount(input); !ok || got != want {
+			t.Fatalf("%q: expected %v, got %v ok=%v", input, want, got, ok)
+		}
+	}
+	for _, input := range []string{"", "half", "ครึ่งเม็ด", "0", "1/0", "NaN"} {
+		if _, ok := parseDosageAmount(input); ok {
+			t.Fatalf("%q: expected no amount", input)
+		}
+	}
+}
+
+func TestSupplyDays(t *testing.T) {
+	today := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+	twiceDaily := []db.MedicineSchedule{
+		{StartDate: today.AddDate(0, 0, -10), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll},
+		{StartDate: today.AddDate(0, 0, -10), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll},
+	}
+	if days, ok := supplyDays(9, 1, twiceDaily, today); !ok || days != 4 {
+		t.Fatalf("expected 4 days, got %d ok=%v", days, ok)
+	}
+	everyOtherDay := []db.MedicineSchedule{{StartDate: today, IntervalDays: 2, WeekdayMask: constants.WeekdayMaskAll}}
+	if days, ok := supplyDays(1.5, 0.5, everyOtherDay, today); !ok || days != 6 {
+		t.Fatalf("expected 6 days, got %d ok=%v", days, ok)
+	}
+	ended := today.AddDate(0, 0, -1)
+	if _, ok := supplyDays(10, 1, []db.MedicineSchedule{{StartDate: today.AddDate(0, 0, -10), EndDate: &ended, IntervalDays: 1}}, today); ok {
+		t.Fatalf("expected no projection for an ended schedule")
+	}
+}
+
+func TestConsumeDoseQueuesLowSupplyOnce(t *testing.T) {
+	now := time.Date(2026, 1, 20, 3, 0, 0, 0, time.UTC)
+	name := "Amlodipine"
+	quantity := 9.0
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: uuid.New(), CustomName: &name, DosageAmount: "1", QuantityOnHand: &quantity, IsActive: true}
+	schedule := db.MedicineSchedule{ID: uuid.New(), PatientMedicineID: med.ID, StartDate: now.AddDate(0, 0, -30), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	medicines := &medicineRepoStub{patientMedicine: med, schedule: &schedule, schedules: []db.MedicineSchedule{schedule}}
+	repo := &refillRepoStub{medicine: med}
+	svc := NewRefillService(repo, medicines, nil, config.RefillConfig{LowSupplyDays: 7}, "UTC").(*refillService)
+	svc.now = func() time.Time { return now }
+
+	// 8 left covers 8 days: no reminder yet.
+	if err := svc.ConsumeDose(context.Background(), med.UserID, schedule.ID, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *med.QuantityOnHand != 8 || len(repo.events) != 0 {
+		t.Fatalf("expected 8 left and no reminder, got %v %+v", *med.QuantityOnHand, repo.events)
+	}
+
+	// 6 left covers 6 days: reminded once.
+	for i := 0; i < 3; i++ {
+		if err := svc.ConsumeDose(context.Background(), med.UserID, schedule.ID, 1); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if *med.QuantityOnHand != 5 || len(repo.events) != 1 {
+		t.Fatalf("expected one reminder, got quantity=%v events=%d", *med.QuantityOnHand, len(repo.events))
+	}
+	event := repo.events[0]
+	var payload struct {
+		MedicineName  string `json:"medicine_name"`
+		DaysRemaining int    `json:"days_remaining"`
+		RunOutDate    string `json:"run_out_date"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if event.TemplateCode != constants.TemplateMedLowSupply || event.UserID != med.UserID || payload.MedicineName != name || payload.DaysRemaining != 6 || payload.RunOutDate != "2026-01-26" {
+		t.Fatalf("unexpected reminder: %+v %+v", event, payload)
+	}
+
+	// A dose recorded by another patient does not touch the stock.
+	if err := svc.ConsumeDose(context.Background(), uuid.New(), schedule.ID, 1); err != nil || *med.QuantityOnHand != 5 {
+		t.Fatalf("expected another patient's dose ignored, got %v err=%v", *med.QuantityOnHand, err)
+	}
+
+	// A reversed dose puts stock back without a reminder.
+	if err := svc.ConsumeDose(context.Background(), med.UserID, schedule.ID, -1); err != nil || *med.QuantityOnHand != 6 || len(repo.events) != 1 {
+		t.Fatalf("expected stock restored, got %v err=%v", *med.QuantityOnHand, err)
+	}
+
+	// Untracked stock is left alone.
+	med.QuantityOnHand = nil
+	if err := svc.ConsumeDose(context.Background(), med.UserID, schedule.ID, 1); err != nil || med.QuantityOnHand != nil {
+		t.Fatalf("expected untracked medicine untouched, err=%v", err)
+	}
+}
+
+func TestRefillPatientMedicine(t *testing.T) {
+	now := time.Date(2026, 1, 20, 20, 0, 0, 0, time.UTC)
+	quantity := 2.0
+	med := &db.PatientMedicine{ID: uuid.New(), UserID: uuid.New(), DosageAmount: "1", QuantityOnHand: &quantity, IsActive: true}
+	medicines := &medicineRepoStub{patientMedicine: med}
+	repo := &refillRepoStub{medicine: med}
+	svc := NewRefillService(repo, medicines, nil, config.RefillConfig{LowSupplyDays: 7}, "Asia/Bangkok").(*refillService)
+	svc.now = func() time.Time { return now }
+
+	_, err := svc.Refill(context.Background(), med.UserID, constants.RolePatient, med.ID.String(), dto.RefillPatientMedicineRequest{})
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.ValidationFailed {
+		t.Fatalf("expected pack refill without pack_size to fail, got %v", err)
+	}
+
+	packSize := 30.0
+	med.PackSize = &packSize
+	resp, err := svc.Refill(context.Background(), med.UserID, constants.RolePatient, med.ID.String(), dto.RefillPatientMedicineRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.restocked != 30 || resp.QuantityOnHand == nil || *resp.QuantityOnHand != 32 || resp.RefillDate == nil || *resp.RefillDate != "2026-01-21" {
+		t.Fatalf("expected one pack added on the local date, got %+v", resp)
+	}
+
+	packs, units := 2.0, 14.0
+	if _, err := svc.Refill(context.Background(), med.UserID, constants.RolePatient, med.ID.String(), dto.RefillPatientMedicineRequest{Packs: &packs, Quantity: &units}); err == nil {
+		t.Fatalf("expected packs and quantity together to be rejected")
+	}
+	if _, err := svc.Refill(context.Background(), med.UserID, constants.RolePatient, med.ID.String(), dto.RefillPatientMedicineRequest{Quantity: &units}); err != nil || repo.restocked != 14 {
+		t.Fatalf("expected 14 units added, got %v err=%v", repo.restocked, err)
+	}
+
+	repo.restocked = 0
+	_, err = svc.Refill(context.Background(), uuid.New(), constants.RolePatient, med.ID.String(), dto.RefillPatientMedicineRequest{Quantity: &units})
+	if appErr, ok := domain.AsAppError(err); !ok || appErr.Code != constants.MedNotFound || repo.restocked != 0 {
+		t.Fatalf("expected another patient's medicine not found, got %v", err)
+	}
+	if _, err := svc.Refill(context.Background(), uuid.New(), constants.RoleNurse, med.ID.String(), dto.RefillPatientMedicineRequest{Quantity: &units}); err != nil || repo.restocked != 14 {
+		t.Fatalf("expected a nurse to refill, got %v err=%v", repo.restocked, err)
+	}
+}
+
+func TestListRefillsNeeded(t *testing.T) {
+	now := time.Date(2026, 1, 20, 3, 0, 0, 0, time.UTC)
+	patientA, patientB := uuid.New(), uuid.New()
+	medA1, medA2, medB, medUntracked := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	daily := func(medID uuid.UUID) db.MedicineSchedule {
+		return db.MedicineSchedule{PatientMedicineID: medID, StartDate: now.AddDate(0, 0, -30), IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll}
+	}
+	repo := &refillRepoStub{
+		tracked: []repositories.TrackedMedicineRow{
+			{PatientMedicineID: medA1, UserID: patientA, MedicineName: "A1", DosageAmount: "1", QuantityOnHand: 5},
+			{PatientMedicineID: medA2, UserID: patientA, MedicineName: "A2", DosageAmount: "1", QuantityOnHand: 60},
+			{PatientMedicineID: medB, UserID: patientB, MedicineName: "B", DosageAmount: "1/2", QuantityOnHand: 1},
+			{PatientMedicineID: medUntracked, UserID: patientB, MedicineName: "PRN", DosageAmount: "as needed", QuantityOnHand: 0},
+		},
+		schedules: []db.MedicineSchedule{daily(medA1), daily(medA2), daily(medB), daily(medUntracked)},
+	}
+	svc := NewRefillService(repo, nil, nil, config.RefillConfig{LowSupplyDays: 7}, "UTC").(*refillService)
+	svc.now = func() time.Time { return now }
+
+	items, total, err := svc.ListRefillsNeeded(context.Background(), dto.RefillListQuery{}, 1, 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if total != 2 || len(items) != 2 || items[0].UserID != patientB.String() || items[1].UserID != patientA.String() {
+		t.Fatalf("expected B (2 days) before A (5 days), got %+v", items)
+	}
+	if len(items[1].Medicines) != 1 || items[1].Medicines[0].MedicineName != "A1" || items[1].Medicines[0].DaysRemaining != 5 || items[1].Medicines[0].RunOutDate != "2026-01-25" {
+		t.Fatalf("expected only A1 for patient A, got %+v", items[1].Medicines)
+	}
+
+	within := 90
+	items, total, err = svc.ListRefillsNeeded(context.Background(), dto.RefillListQuery{WithinDays: &within}, 2, 1)
+	if err != nil || total != 2 || len(items) != 1 || len(items[0].Medicines) != 2 {
+		t.Fatalf("expected the second page to hold A with both medicines, got %+v total=%d err=%v", items, total, err)
+	}
+}
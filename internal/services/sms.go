@@ -10,6 +10,11 @@ type SmsSender interface {
 	SendOTP(ctx context.Context, phone, otpCode, refCode string) error
 }
 
+// SmsMessageSender sends a free-form text message, e.g. a notification.
+type SmsMessageSender interface {
+	SendMessage(ctx context.Context, phone, message string) error
+}
+
 type ConsoleSender struct {
 	Logger *zap.Logger
 }
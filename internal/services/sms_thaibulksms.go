@@ -58,12 +58,15 @@ func NewThaiBulkSMSSender(cfg config.ThaiBulkSMSConfig, logger *zap.Logger) (*Th
 }
 
 func (s *ThaiBulkSMSSender) SendOTP(ctx context.Context, phone, otpCode, refCode string) error {
+	return s.SendMessage(ctx, phone, s.renderTemplate(otpCode, refCode))
+}
+
+func (s *ThaiBulkSMSSender) SendMessage(ctx context.Context, phone, message string) error {
 	phone = strings.TrimSpace(phone)
 	if phone == "" {
 		return errors.New("phone required")
 	}
 
-	message := s.renderTemplate(otpCode, refCode)
 	form := url.Values{}
 	form.Set("msisdn", phone)
 	form.Set("message", message)
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+	"github.com/ParkPawapon/mhp-be/internal/utils"
+)
+
+type SyncService interface {
+	ApplyBatch(ctx context.Context, userID string, req dto.SyncBatchRequest) (dto.SyncBatchResponse, error)
+	ListChanges(ctx context.Context, userID string, since string, limit int) (dto.SyncChangesResponse, error)
+}
+
+type syncService struct {
+	repo   repositories.SyncRepository
+	intake IntakeService
+	health HealthService
+}
+
+func NewSyncService(repo repositories.SyncRepository, intake IntakeService, health HealthService) SyncService {
+	return &syncService{repo: repo, intake: intake, health: health}
+}
+
+// ApplyBatch applies offline mutations in order through the regular intake and
+// health services. Each outcome is stored under the client id, so a replayed
+// mutation, including one retried while the first attempt is still running,
+// returns its original result instead of being applied twice. A mutation's
+// writes commit in the same transaction as its outcome. Internal failures
+// leave neither behind and come back as FAILED for a retry.
+func (s *syncService) ApplyBatch(ctx context.Context, userID string, req dto.SyncBatchRequest) (dto.SyncBatchResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.SyncBatchResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+	if len(req.Items) == 0 || len(req.Items) > constants.MaxSyncBatchItems {
+		return dto.SyncBatchResponse{}, domain.NewError(constants.ValidationFailed, "items must contain 1 to 100 mutations")
+	}
+
+	results := make([]dto.SyncMutationResult, 0, len(req.Items))
+	for _, item := range req.Items {
+		clientID, err := uuid.Parse(item.ClientID)
+		if err != nil {
+			results = append(results, rejectedSyncResult(item.ClientID, domain.NewError(constants.ValidationFailed, "invalid client_id")))
+			continue
+		}
+
+		clientTimestamp, err := parseRFC3339(item.ClientTimestamp)
+		if err != nil {
+			results = append(results, rejectedSyncResult(item.ClientID, domain.NewError(constants.ValidationFailed, "invalid client_timestamp")))
+			continue
+		}
+
+		mutation, duplicate, err := s.repo.ApplyMutation(ctx, uid, clientID, func(ctx context.Context) (*db.SyncMutation, error) {
+			return s.applyItem(ctx, &db.SyncMutation{
+				UserID:          uid,
+				ClientID:        clientID,
+				MutationType:    item.Type,
+				ClientTimestamp: clientTimestamp.UTC(),
+				Status:          constants.SyncApplied,
+			}, item)
+		})
+		if err != nil {
+			results = append(results, dto.SyncMutationResult{
+				ClientID: item.ClientID,
+				Status:   constants.SyncFailed,
+				Error:    &dto.SyncItemError{Code: constants.InternalError, Message: "mutation could not be applied; retry later"},
+			})
+			continue
+		}
+		result := toSyncResult(*mutation)
+		result.Duplicate = duplicate
+		results = append(results, result)
+	}
+	return dto.SyncBatchResponse{Results: results}, nil
+}
+
+// applyItem applies one mutation and fills in its outcome to store: APPLIED
+// with the entity id, or REJECTED with the error. Internal failures are
+// returned as errors so nothing is stored.
+func (s *syncService) applyItem(ctx context.Context, mutation *db.SyncMutation, item dto.SyncMutationRequest) (*db.SyncMutation, error) {
+	entityID, err := s.apply(ctx, mutation.UserID, item)
+	if err == nil {
+		mutation.EntityID = &entityID
+		return mutation, nil
+	}
+	appErr, ok := domain.AsAppError(err)
+	if !ok || strings.HasPrefix(appErr.Code, "INTERNAL_") {
+		return nil, err
+	}
+	mutation.Status = constants.SyncRejected
+	mutation.ErrorCode = &appErr.Code
+	mutation.ErrorMessage = &appErr.Message
+	return mutation, nil
+}
+
+func (s *syncService) apply(ctx context.Context, userID uuid.UUID, item dto.SyncMutationRequest) (uuid.UUID, error) {
+	var id string
+	switch item.Type {
+	case constants.SyncEntityIntake:
+		var req dto.CreateIntakeRequest
+		if err := decodeSyncData(item.Data, &req); err != nil {
+			return uuid.Nil, err
+		}
+		// An offline TAKEN tap is timed by the device, not by when it syncs.
+		if req.TakenAt == nil && req.Status == constants.MedTaken {
+			req.TakenAt = &item.ClientTimestamp
+		}
+		resp, err := s.intake.CreateIntake(ctx, userID.String(), req)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		id = resp.ID
+	case constants.SyncEntityHealthRecord:
+		var req dto.CreateHealthRecordRequest
+		if err := decodeSyncData(item.Data, &req); err != nil {
+			return uuid.Nil, err
+		}
+		resp, err := s.health.CreateHealthRecord(ctx, userID.String(), req)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		id = resp.ID
+	case constants.SyncEntityAssessment:
+		var req dto.CreateDailyAssessmentRequest
+		if err := decodeSyncData(item.Data, &req); err != nil {
+			return uuid.Nil, err
+		}
+		resp, err := s.health.CreateDailyAssessment(ctx, userID.String(), req)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		id = resp.ID
+	default:
+		return uuid.Nil, domain.NewError(constants.ValidationFailed, "unsupported type")
+	}
+	return uuid.Parse(id)
+}
+
+// ListChanges pages through the user's change feed after the since cursor
+// (empty for the beginning). Pass next_cursor back as since to continue.
+func (s *syncService) ListChanges(ctx context.Context, userID string, since string, limit int) (dto.SyncChangesResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return dto.SyncChangesResponse{}, domain.NewError(constants.ValidationFailed, "invalid user_id")
+	}
+	var after repositories.SyncChangeCursor
+	if since != "" {
+		var ok bool
+		if after, ok = decodeSyncCursor(since); !ok {
+			return dto.SyncChangesResponse{}, domain.NewError(constants.ValidationFailed, "invalid since")
+		}
+	}
+	if limit <= 0 {
+		limit = constants.DefaultSyncChangeLimit
+	}
+	if limit > constants.MaxSyncChangeLimit {
+		limit = constants.MaxSyncChangeLimit
+	}
+
+	items, err := s.repo.ListChanges(ctx, uid, after, limit+1)
+	if err != nil {
+		return dto.SyncChangesResponse{}, err
+	}
+
+	resp := dto.SyncChangesResponse{Changes: []dto.SyncChangeItem{}, NextCursor: encodeSyncCursor(after)}
+	if len(items) > limit {
+		items = items[:limit]
+		resp.HasMore = true
+	}
+	for _, item := range items {
+		cursor := encodeSyncCursor(repositories.SyncChangeCursor{TxID: item.TxID, ID: item.ID})
+		resp.Changes = append(resp.Changes, dto.SyncChangeItem{
+			Cursor:     cursor,
+			EntityType: item.EntityType,
+			EntityID:   item.EntityID.String(),
+			Operation:  item.Operation,
+			Payload:    json.RawMessage(item.Payload),
+			ActorID:    stringPtr(item.ActorID),
+			CreatedAt:  item.CreatedAt,
+		})
+		resp.NextCursor = cursor
+	}
+	return resp, nil
+}
+
+// encodeSyncCursor formats a feed position as "<txid>-<id>".
+func encodeSyncCursor(cursor repositories.SyncChangeCursor) string {
+	return strconv.FormatUint(cursor.TxID, 10) + "-" + strconv.FormatInt(cursor.ID, 10)
+}
+
+// decodeSyncCursor parses a cursor from encodeSyncCursor. A bare id, the
+// format before cursors carried the transaction, points into the changes
+// recorded before migration 018.
+func decodeSyncCursor(cursor string) (repositories.SyncChangeCursor, bool) {
+	txid, id, found := strings.Cut(cursor, "-")
+	if !found {
+		txid, id = "0", cursor
+	}
+	parsedTxID, err := strconv.ParseUint(txid, 10, 64)
+	if err != nil {
+		return repositories.SyncChangeCursor{}, false
+	}
+	parsedID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || parsedID < 0 {
+		return repositories.SyncChangeCursor{}, false
+	}
+	return repositories.SyncChangeCursor{TxID: parsedTxID, ID: parsedID}, true
+}
+
+func decodeSyncData(data json.RawMessage, dst any) error {
+	if err := json.Unmarshal(data, dst); err != nil {
+		return domain.NewError(constants.ValidationFailed, "invalid data")
+	}
+	if err := utils.Validate.Struct(dst); err != nil {
+		return domain.WithDetails(domain.NewError(constants.ValidationFailed, "validation failed"), utils.ValidationErrors(err))
+	}
+	return nil
+}
+
+func rejectedSyncResult(clientID string, err *domain.AppError) dto.SyncMutationResult {
+	return dto.SyncMutationResult{
+		ClientID: clientID,
+		Status:   constants.SyncRejected,
+		Error:    &dto.SyncItemError{Code: err.Code, Message: err.Message},
+	}
+}
+
+func toSyncResult(mutation db.SyncMutation) dto.SyncMutationResult {
+	result := dto.SyncMutationResult{
+		ClientID: mutation.ClientID.String(),
+		Status:   mutation.Status,
+		EntityID: stringPtr(mutation.EntityID),
+	}
+	if mutation.ErrorCode != nil {
+		result.Error = &dto.SyncItemError{Code: *mutation.ErrorCode}
+		if mutation.ErrorMessage != nil {
+			result.Error.Message = *mutation.ErrorMessage
+		}
+	}
+	return result
+}
+
+// recordSyncChange publishes a server-side change to the patient's change
+// feed. Failures are ignored like reminder side effects: the change itself
+// has already been committed.
+func recordSyncChange(ctx context.Context, repo repositories.SyncRepository, userID uuid.UUID, entityType string, entityID uuid.UUID, operation string, payload any, actorID *uuid.UUID) {
+	if repo == nil {
+		return
+	}
+	change := &db.SyncChange{
+		UserID:     userID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Operation:  operation,
+		ActorID:    actorID,
+	}
+	if payload != nil {
+		if data, err := json.Marshal(payload); err == nil {
+			change.Payload = data
+		}
+	}
+	_ = repo.RecordChange(ctx, change)
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+type syncRepoStub struct {
+	mu        sync.Mutex
+	mutations map[uuid.UUID]db.SyncMutation
+	changes   []db.SyncChange
+	after     repositories.SyncChangeCursor
+	limit     int
+}
+
+// ApplyMutation serializes all mutations, standing in for the per-client-id
+// advisory lock.
+func (s *syncRepoStub) ApplyMutation(ctx context.Context, userID, clientID uuid.UUID, apply func(ctx context.Context) (*db.SyncMutation, error)) (*db.SyncMutation, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if mutation, ok := s.mutations[clientID]; ok && mutation.UserID == userID {
+		return &mutation, true, nil
+	}
+	mutation, err := apply(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if s.mutations == nil {
+		s.mutations = map[uuid.UUID]db.SyncMutation{}
+	}
+	s.mutations[clientID] = *mutation
+	return mutation, false, nil
+}
+func (s *syncRepoStub) RecordChange(ctx context.Context, change *db.SyncChange) error {
+	change.ID = int64(len(s.changes) + 1)
+	change.TxID = uint64(700 + change.ID)
+	s.changes = append(s.changes, *change)
+	return nil
+}
+func (s *syncRepoStub) ListChanges(ctx context.Context, userID uuid.UUID, after repositories.SyncChangeCursor, limit int) ([]db.SyncChange, error) {
+	s.after, s.limit = after, limit
+	var items []db.SyncChange
+	for _, change := range s.changes {
+		newer := change.TxID > after.TxID || (change.TxID == after.TxID && change.ID > after.ID)
+		if change.UserID == userID && newer && len(items) < limit {
+			items = append(items, change)
+		}
+	}
+	return items, nil
+}
+
+func syncItem(t *testing.T, clientID, itemType string, data any) dto.SyncMutationRequest {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return dto.SyncMutationRequest{ClientID: clientID, Type: itemType, ClientTimestamp: "2026-01-20T01:05:00+07:00", Data: raw}
+}
+
+func TestApplyBatchPerItemResultsAndReplay(t *testing.T) {
+	repo := &syncRepoStub{}
+	intake := &intakeServiceStub{}
+	health := &healthRepoStub{createErr: domain.WrapError(constants.InternalError, "create daily assessment failed", errors.New("conn reset"))}
+	svc := NewSyncService(repo, intake, NewHealthService(health))
+	userID := uuid.New().String()
+
+	intakeID := uuid.NewString()
+	recordID := uuid.NewString()
+	req := dto.SyncBatchRequest{Items: []dto.SyncMutationRequest{
+		syncItem(t, intakeID, constants.SyncEntityIntake, dto.CreateIntakeRequest{TargetDate: "2026-01-20", Status: constants.MedTaken}),
+		syncItem(t, recordID, constants.SyncEntityHealthRecord, dto.CreateHealthRecordRequest{RecordDate: "2026-01-20", TimePeriod: "MORNING", PulseRate: intPtr(72)}),
+		syncItem(t, uuid.NewString(), constants.SyncEntityHealthRecord, map[string]any{"time_period": "MORNING"}),
+		syncItem(t, uuid.NewString(), "APPOINTMENT", map[string]any{}),
+		syncItem(t, uuid.NewString(), constants.SyncEntityAssessment, dto.CreateDailyAssessmentRequest{LogDate: "2026-01-20"}),
+		syncItem(t, intakeID, constants.SyncEntityIntake, dto.CreateIntakeRequest{TargetDate: "2026-01-20", Status: constants.MedTaken}),
+	}}
+
+	resp, err := svc.ApplyBatch(context.Background(), userID, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		status    string
+		duplicate bool
+	}{
+		{constants.SyncApplied, false},
+		{constants.SyncApplied, false},
+		{constants.SyncRejected, false},
+		{constants.SyncRejected, false},
+		{constants.SyncFailed, false},
+		{constants.SyncApplied, true},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(resp.Results))
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.Status != w.status || got.Duplicate != w.duplicate {
+			t.Fatalf("item %d: expected %s duplicate=%v, got %+v", i, w.status, w.duplicate, got)
+		}
+	}
+	if resp.Results[0].EntityID == nil || resp.Results[5].EntityID == nil || *resp.Results[0].EntityID != *resp.Results[5].EntityID {
+		t.Fatalf("expected duplicate to return the original entity id")
+	}
+	if resp.Results[2].Error == nil || resp.Results[2].Error.Code != constants.ValidationFailed {
+		t.Fatalf("expected validation error, got %+v", resp.Results[2].Error)
+	}
+	if len(intake.created) != 1 || len(repo.mutations) != 4 {
+		t.Fatalf("expected one intake and four stored outcomes, got %d and %d", len(intake.created), len(repo.mutations))
+	}
+	if intake.created[0].TakenAt == nil || *intake.created[0].TakenAt != "2026-01-20T01:05:00+07:00" {
+		t.Fatalf("expected an offline TAKEN dose to default taken_at to the client timestamp, got %v", intake.created[0].TakenAt)
+	}
+
+	health.createErr = nil
+	resp, err = svc.ApplyBatch(context.Background(), userID, dto.SyncBatchRequest{Items: req.Items[:2]})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, result := range resp.Results {
+		if result.Status != constants.SyncApplied || !result.Duplicate {
+			t.Fatalf("expected replayed results, got %+v", result)
+		}
+	}
+	if len(intake.created) != 1 {
+		t.Fatalf("expected replay not to re-apply intake")
+	}
+}
+
+func TestApplyBatchConcurrentReplaysApplyOnce(t *testing.T) {
+	repo := &syncRepoStub{}
+	intake := &intakeServiceStub{}
+	svc := NewSyncService(repo, intake, nil)
+	userID := uuid.New().String()
+	req := dto.SyncBatchRequest{Items: []dto.SyncMutationRequest{
+		syncItem(t, uuid.NewString(), constants.SyncEntityIntake, dto.CreateIntakeRequest{TargetDate: "2026-01-20", Status: constants.MedTaken}),
+		syncItem(t, uuid.NewString(), constants.SyncEntityIntake, dto.CreateIntakeRequest{TargetDate: "2026-01-20", Status: constants.MedSkipped}),
+	}}
+
+	var wg sync.WaitGroup
+	responses := make([]dto.SyncBatchResponse, 2)
+	errs := make([]error, 2)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = svc.ApplyBatch(context.Background(), userID, req)
+		}(i)
+	}
+	wg.Wait()
+
+	if len(intake.created) != len(req.Items) {
+		t.Fatalf("expected each mutation applied once, got %d intakes", len(intake.created))
+	}
+	for i := range req.Items {
+		first, second := responses[0].Results[i], responses[1].Results[i]
+		if errs[0] != nil || errs[1] != nil || first.Status != constants.SyncApplied || second.Status != constants.SyncApplied {
+			t.Fatalf("item %d: expected both batches applied, got %+v %+v errs=%v", i, first, second, errs)
+		}
+		if first.Duplicate == second.Duplicate || *first.EntityID != *second.EntityID {
+			t.Fatalf("item %d: expected one original and one duplicate of the same entity, got %+v %+v", i, first, second)
+		}
+	}
+}
+
+func TestListSyncChangesPagesByCursor(t *testing.T) {
+	userID := uuid.New()
+	repo := &syncRepoStub{}
+	for i := 0; i < 3; i++ {
+		recordSyncChange(context.Background(), repo, userID, constants.SyncEntityAppointment, uuid.New(), constants.SyncOpUpsert, map[string]any{"status": "CONFIRMED"}, nil)
+	}
+	recordSyncChange(context.Background(), repo, uuid.New(), constants.SyncEntityAppointment, uuid.New(), constants.SyncOpDelete, nil, nil)
+	svc := NewSyncService(repo, nil, nil)
+
+	page, err := svc.ListChanges(context.Background(), userID.String(), "", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Changes) != 2 || !page.HasMore || page.NextCursor != "702-2" || page.Changes[0].Cursor != "701-1" || string(page.Changes[0].Payload) != `{"status":"CONFIRMED"}` {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+
+	page, err = svc.ListChanges(context.Background(), userID.String(), page.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Changes) != 1 || page.HasMore || page.NextCursor != "703-3" || repo.after != (repositories.SyncChangeCursor{TxID: 702, ID: 2}) {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+
+	page, err = svc.ListChanges(context.Background(), userID.String(), "703-3", 0)
+	if err != nil || len(page.Changes) != 0 || page.NextCursor != "703-3" || repo.limit != constants.DefaultSyncChangeLimit+1 {
+		t.Fatalf("expected empty page keeping the cursor, got %+v err=%v", page, err)
+	}
+
+	// A bare id from before cursors carried the transaction still resumes.
+	page, err = svc.ListChanges(context.Background(), userID.String(), "2", 0)
+	if err != nil || repo.after != (repositories.SyncChangeCursor{ID: 2}) || len(page.Changes) != 3 {
+		t.Fatalf("expected a legacy cursor to resume before the new changes, got %+v err=%v", page, err)
+	}
+
+	for _, since := range []string{"abc", "1-x", "-1"} {
+		if _, err := svc.ListChanges(context.Background(), userID.String(), since, 0); err == nil {
+			t.Fatalf("expected invalid cursor error for %q", since)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/db"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
+)
+
+// userLocations resolves the location a user's local dates and reminder times
+// are computed in: their preferred timezone, else NOTIFICATION_TIMEZONE.
+type userLocations struct {
+	prefs    repositories.PreferenceRepository
+	fallback *time.Location
+}
+
+func newUserLocations(prefs repositories.PreferenceRepository, timezone string) userLocations {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	return userLocations{prefs: prefs, fallback: location}
+}
+
+func (l userLocations) forUser(ctx context.Context, userID uuid.UUID) (*time.Location, error) {
+	pref, err := l.preference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if pref == nil {
+		return l.fallback, nil
+	}
+	return l.resolve(pref.Timezone), nil
+}
+
+// preference returns the user's saved preferences, or nil when there are none.
+func (l userLocations) preference(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
+	if l.prefs == nil {
+		return nil, nil
+	}
+	pref, err := l.prefs.FindByUserID(ctx, userID)
+	if err != nil {
+		if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.UserNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return pref, nil
+}
+
+// resolve loads a stored timezone; unset or unknown names fall back to the
+// default.
+func (l userLocations) resolve(timezone *string) *time.Location {
+	if timezone == nil || *timezone == "" {
+		return l.fallback
+	}
+	location, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return l.fallback
+	}
+	return location
+}
+
+// validTimezone accepts IANA zone names such as "Asia/Bangkok" or "UTC".
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
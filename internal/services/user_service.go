@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -18,19 +21,23 @@ type UserService interface {
 	GetMe(ctx context.Context, actorID uuid.UUID, role constants.Role) (dto.MeResponse, error)
 	UpdateProfile(ctx context.Context, actorID uuid.UUID, req dto.UpdateProfileRequest) error
 	SaveDeviceToken(ctx context.Context, actorID uuid.UUID, req dto.DeviceTokenRequest) error
+	GetPreferences(ctx context.Context, actorID uuid.UUID) (dto.PreferencesResponse, error)
 	UpdatePreferences(ctx context.Context, actorID uuid.UUID, req dto.UpdatePreferencesRequest) (dto.PreferencesResponse, error)
 }
 
 type userService struct {
-	userRepo    repositories.UserRepository
-	profileRepo repositories.ProfileRepository
-	deviceRepo  repositories.DeviceTokenRepository
-	prefRepo    repositories.PreferenceRepository
-	notify      NotificationService
+	userRepo     repositories.UserRepository
+	profileRepo  repositories.ProfileRepository
+	deviceRepo   repositories.DeviceTokenRepository
+	prefRepo     repositories.PreferenceRepository
+	notify       NotificationService
+	medicines    MedicineService
+	appointments AppointmentService
+	audit        AuditService
 }
 
-func NewUserService(userRepo repositories.UserRepository, profileRepo repositories.ProfileRepository, deviceRepo repositories.DeviceTokenRepository, prefRepo repositories.PreferenceRepository, notify NotificationService) UserService {
-	return &userService{userRepo: userRepo, profileRepo: profileRepo, deviceRepo: deviceRepo, prefRepo: prefRepo, notify: notify}
+func NewUserService(userRepo repositories.UserRepository, profileRepo repositories.ProfileRepository, deviceRepo repositories.DeviceTokenRepository, prefRepo repositories.PreferenceRepository, notify NotificationService, medicines MedicineService, appointments AppointmentService, audit AuditService) UserService {
+	return &userService{userRepo: userRepo, profileRepo: profileRepo, deviceRepo: deviceRepo, prefRepo: prefRepo, notify: notify, medicines: medicines, appointments: appointments, audit: audit}
 }
 
 func (s *userService) GetMe(ctx context.Context, actorID uuid.UUID, role constants.Role) (dto.MeResponse, error) {
@@ -49,41 +56,45 @@ func (s *userService) GetMe(ctx context.Context, actorID uuid.UUID, role constan
 	}
 
 	resp := dto.MeResponse{
-		ID:   user.ID.String(),
-		Role: user.Role,
-		Profile: dto.ProfileResponse{
-			FirstName: "",
-			LastName:  "",
-		},
+		ID:         user.ID.String(),
+		Role:       user.Role,
+		LineLinked: user.LineUserID != nil,
+		Profile:    toProfileResponse(profile, role),
 	}
 
+	return resp, nil
+}
+
+// toProfileResponse maps a profile (nil when not yet created) and applies the
+// citizen ID policy for the viewing role.
+func toProfileResponse(profile *db.UserProfile, viewerRole constants.Role) dto.ProfileResponse {
+	var resp dto.ProfileResponse
 	if profile != nil {
-		resp.Profile.FirstName = profile.FirstName
-		resp.Profile.LastName = profile.LastName
-		resp.Profile.HN = profile.HN
-		resp.Profile.CitizenID = profile.CitizenID
-		resp.Profile.DateOfBirth = profile.DateOfBirth.Format("2006-01-02")
-		resp.Profile.Gender = profile.Gender
-		resp.Profile.BloodType = profile.BloodType
-		resp.Profile.AddressText = profile.AddressText
-		resp.Profile.GPSLat = profile.GPSLat
-		resp.Profile.GPSLong = profile.GPSLong
-		resp.Profile.EmergencyContactName = profile.EmergencyContactName
-		resp.Profile.EmergencyContactPhone = profile.EmergencyContactPhone
-		resp.Profile.AvatarURL = profile.AvatarURL
-	}
-
-	if role != constants.RoleAdmin {
-		if resp.Profile.CitizenID != nil {
-			masked := utils.MaskCitizenID(*resp.Profile.CitizenID)
-			resp.Profile.CitizenID = &masked
+		resp.FirstName = profile.FirstName
+		resp.LastName = profile.LastName
+		resp.HN = profile.HN
+		resp.CitizenID = profile.CitizenID
+		resp.DateOfBirth = profile.DateOfBirth.Format("2006-01-02")
+		resp.Gender = profile.Gender
+		resp.BloodType = profile.BloodType
+		resp.AddressText = profile.AddressText
+		resp.GPSLat = profile.GPSLat
+		resp.GPSLong = profile.GPSLong
+		resp.EmergencyContactName = profile.EmergencyContactName
+		resp.EmergencyContactPhone = profile.EmergencyContactPhone
+		resp.AvatarURL = profile.AvatarURL
+	}
+
+	if viewerRole != constants.RoleAdmin {
+		if resp.CitizenID != nil {
+			masked := utils.MaskCitizenID(*resp.CitizenID)
+			resp.CitizenID = &masked
 		}
 	}
-	if role == constants.RoleCaregiver {
-		resp.Profile.CitizenID = nil
+	if viewerRole == constants.RoleCaregiver {
+		resp.CitizenID = nil
 	}
-
-	return resp, nil
+	return resp
 }
 
 func (s *userService) UpdateProfile(ctx context.Context, actorID uuid.UUID, req dto.UpdateProfileRequest) error {
@@ -144,7 +155,13 @@ func (s *userService) UpdateProfile(ctx context.Context, actorID uuid.UUID, req
 		}
 	}
 
-	return s.profileRepo.Upsert(ctx, profile)
+	if err := s.profileRepo.Upsert(ctx, profile); err != nil {
+		return err
+	}
+	if s.audit != nil {
+		s.audit.Record(ctx, domain.AuditEntry{ActorID: &actorID, TargetUserID: &actorID, Action: constants.AuditProfileUpdate})
+	}
+	return nil
 }
 
 func (s *userService) SaveDeviceToken(ctx context.Context, actorID uuid.UUID, req dto.DeviceTokenRequest) error {
@@ -169,22 +186,208 @@ func (s *userService) SaveDeviceToken(ctx context.Context, actorID uuid.UUID, re
 	return s.deviceRepo.Save(ctx, record)
 }
 
+func (s *userService) GetPreferences(ctx context.Context, actorID uuid.UUID) (dto.PreferencesResponse, error) {
+	if s.prefRepo == nil {
+		return dto.PreferencesResponse{}, domain.NewError(constants.InternalError, "preferences repository not configured")
+	}
+	pref, err := s.preferences(ctx, actorID)
+	if err != nil {
+		return dto.PreferencesResponse{}, err
+	}
+	return toPreferencesResponse(*pref), nil
+}
+
+// UpdatePreferences changes the preferences present in req and keeps the rest.
+// An empty timezone reverts to NOTIFICATION_TIMEZONE. Changing the timezone or
+// before-meal lead times replans the user's unsent medicine reminders, the
+// timezone also their weekly reminder, and the appointment lead times their
+// upcoming appointment reminders.
 func (s *userService) UpdatePreferences(ctx context.Context, actorID uuid.UUID, req dto.UpdatePreferencesRequest) (dto.PreferencesResponse, error) {
-	if req.WeeklyReminderEnabled == nil {
-		return dto.PreferencesResponse{}, domain.NewError(constants.ValidationFailed, "weekly_reminder_enabled required")
+	if req.WeeklyReminderEnabled == nil && req.Timezone == nil && req.Language == nil && req.Channels == nil && req.QuietHoursStart == nil && req.QuietHoursEnd == nil &&
+		req.AppointmentReminderDays == nil && req.BeforeMealLeadMinutes == nil && req.BeforeMealFollowUpMinutes == nil {
+		return dto.PreferencesResponse{}, domain.NewError(constants.ValidationFailed, "at least one preference required")
+	}
+	var timezone *string
+	if req.Timezone != nil {
+		if value := strings.TrimSpace(*req.Timezone); value != "" {
+			if !validTimezone(value) {
+				return dto.PreferencesResponse{}, domain.NewError(constants.ValidationFailed, "invalid timezone")
+			}
+			timezone = &value
+		}
+	}
+	var language *string
+	if req.Language != nil {
+		if value := strings.ToLower(strings.TrimSpace(*req.Language)); value != "" {
+			if value != constants.LocaleThai && value != constants.LocaleEnglish {
+				return dto.PreferencesResponse{}, domain.NewError(constants.ValidationFailed, "language must be th or en")
+			}
+			language = &value
+		}
 	}
 	if s.prefRepo == nil {
 		return dto.PreferencesResponse{}, domain.NewError(constants.InternalError, "preferences repository not configured")
 	}
 
-	pref := &db.UserPreference{UserID: actorID, WeeklyReminderEnabled: *req.WeeklyReminderEnabled}
+	pref, err := s.preferences(ctx, actorID)
+	if err != nil {
+		return dto.PreferencesResponse{}, err
+	}
+	previousTimezone := pref.Timezone
+	previous := notificationSettingsOf(pref)
+	if req.WeeklyReminderEnabled != nil {
+		pref.WeeklyReminderEnabled = *req.WeeklyReminderEnabled
+	}
+	if req.Timezone != nil {
+		pref.Timezone = timezone
+	}
+	if req.Language != nil {
+		pref.Language = language
+	}
+	available := notificationChannels
+	if s.notify != nil {
+		available = s.notify.Channels()
+	}
+	if err := applyNotificationPreferences(pref, req, available); err != nil {
+		return dto.PreferencesResponse{}, err
+	}
 	if err := s.prefRepo.Upsert(ctx, pref); err != nil {
 		return dto.PreferencesResponse{}, err
 	}
 
-	if s.notify != nil && !*req.WeeklyReminderEnabled {
+	current := notificationSettingsOf(pref)
+	timezoneChanged := !equalStringPtr(previousTimezone, pref.Timezone)
+	if s.notify != nil && req.WeeklyReminderEnabled != nil && !*req.WeeklyReminderEnabled {
 		_ = s.notify.CancelWeeklyReminders(ctx, actorID)
 	}
+	if s.medicines != nil && (timezoneChanged || previous.beforeMealLead != current.beforeMealLead || previous.beforeMealFollowUp != current.beforeMealFollowUp) {
+		_ = s.medicines.RescheduleReminders(ctx, actorID)
+	}
+	if s.notify != nil && timezoneChanged {
+		_ = s.notify.RescheduleWeeklyReminder(ctx, actorID)
+	}
+	if s.appointments != nil && !slices.Equal(previous.appointmentDays, current.appointmentDays) {
+		_ = s.appointments.RescheduleReminders(ctx, actorID)
+	}
 
-	return dto.PreferencesResponse{WeeklyReminderEnabled: pref.WeeklyReminderEnabled}, nil
+	return toPreferencesResponse(*pref), nil
+}
+
+// applyNotificationPreferences validates the channel, quiet hours and lead
+// time fields present in req and sets them on pref. Channels must be among
+// available, the channels this deployment can deliver through.
+func applyNotificationPreferences(pref *db.UserPreference, req dto.UpdatePreferencesRequest, available []string) error {
+	if req.Channels != nil {
+		channels := make([]string, 0, len(req.Channels))
+		for _, channel := range req.Channels {
+			channel = strings.ToLower(strings.TrimSpace(channel))
+			if !isAllowed(channel, notificationChannels) {
+				return domain.NewError(constants.ValidationFailed, "channels must be push, line or sms")
+			}
+			if !isAllowed(channel, available) {
+				return domain.NewError(constants.ValidationFailed, "channel "+channel+" is not available")
+			}
+			if !isAllowed(channel, channels) {
+				channels = append(channels, channel)
+			}
+		}
+		if len(channels) == 0 {
+			return domain.NewError(constants.ValidationFailed, "at least one channel required")
+		}
+		pref.NotificationChannels, _ = json.Marshal(channels)
+	}
+
+	if err := setQuietHour(&pref.QuietHoursStart, req.QuietHoursStart); err != nil {
+		return err
+	}
+	if err := setQuietHour(&pref.QuietHoursEnd, req.QuietHoursEnd); err != nil {
+		return err
+	}
+	if (pref.QuietHoursStart == nil) != (pref.QuietHoursEnd == nil) {
+		return domain.NewError(constants.ValidationFailed, "quiet_hours_start and quiet_hours_end must be set together")
+	}
+	if pref.QuietHoursStart != nil && minuteOfDay(*pref.QuietHoursStart) == minuteOfDay(*pref.QuietHoursEnd) {
+		return domain.NewError(constants.ValidationFailed, "quiet_hours_start and quiet_hours_end must differ")
+	}
+
+	if req.AppointmentReminderDays != nil {
+		if len(req.AppointmentReminderDays) > constants.MaxAppointmentReminders {
+			return domain.NewError(constants.ValidationFailed, "at most 5 appointment reminders")
+		}
+		days := make([]int, 0, len(req.AppointmentReminderDays))
+		for _, day := range req.AppointmentReminderDays {
+			if day < 1 || day > constants.MaxAppointmentReminderDays {
+				return domain.NewError(constants.ValidationFailed, "appointment_reminder_days must be between 1 and 30")
+			}
+			if !slices.Contains(days, day) {
+				days = append(days, day)
+			}
+		}
+		slices.SortFunc(days, func(a, b int) int { return b - a })
+		pref.AppointmentReminderDays, _ = json.Marshal(days)
+	}
+
+	if req.BeforeMealLeadMinutes != nil {
+		if *req.BeforeMealLeadMinutes < 0 || *req.BeforeMealLeadMinutes > constants.MaxBeforeMealLeadMinutes {
+			return domain.NewError(constants.ValidationFailed, "before_meal_lead_minutes must be between 0 and 60")
+		}
+		pref.BeforeMealLeadMinutes = req.BeforeMealLeadMinutes
+	}
+	if req.BeforeMealFollowUpMinutes != nil {
+		if *req.BeforeMealFollowUpMinutes < 1 || *req.BeforeMealFollowUpMinutes > constants.MaxBeforeMealFollowUpMinutes {
+			return domain.NewError(constants.ValidationFailed, "before_meal_follow_up_minutes must be between 1 and 120")
+		}
+		pref.BeforeMealFollowUpMinutes = req.BeforeMealFollowUpMinutes
+	}
+	return nil
+}
+
+// preferences returns the user's saved preferences, or the defaults when none
+// were saved yet.
+func (s *userService) preferences(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
+	pref, err := s.prefRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		if appErr, ok := domain.AsAppError(err); ok && appErr.Code == constants.UserNotFound {
+			return &db.UserPreference{UserID: userID, WeeklyReminderEnabled: true}, nil
+		}
+		return nil, err
+	}
+	return pref, nil
+}
+
+// setQuietHour parses an HH:MM value into target; empty clears it and nil
+// leaves it unchanged.
+func setQuietHour(target **time.Time, value *string) error {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		*target = nil
+		return nil
+	}
+	parsed, err := time.Parse(quietHoursLayout, trimmed)
+	if err != nil {
+		return domain.NewError(constants.ValidationFailed, "quiet hours must be HH:MM")
+	}
+	*target = &parsed
+	return nil
+}
+
+func toPreferencesResponse(pref db.UserPreference) dto.PreferencesResponse {
+	settings := notificationSettingsOf(&pref)
+	resp := dto.PreferencesResponse{
+		WeeklyReminderEnabled:     pref.WeeklyReminderEnabled,
+		Timezone:                  pref.Timezone,
+		Language:                  pref.Language,
+		Channels:                  settings.channels,
+		AppointmentReminderDays:   settings.appointmentDays,
+		BeforeMealLeadMinutes:     int(settings.beforeMealLead / time.Minute),
+		BeforeMealFollowUpMinutes: int(settings.beforeMealFollowUp / time.Minute),
+	}
+	if pref.QuietHoursStart != nil && pref.QuietHoursEnd != nil {
+		start, end := pref.QuietHoursStart.Format(quietHoursLayout), pref.QuietHoursEnd.Format(quietHoursLayout)
+		resp.QuietHoursStart, resp.QuietHoursEnd = &start, &end
+	}
+	return resp
 }
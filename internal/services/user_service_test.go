@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"slices"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/ParkPawapon/mhp-be/internal/domain"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/repositories"
 )
 
 type userRepoStub struct {
@@ -29,6 +31,12 @@ func (s userRepoStub) FindByID(ctx context.Context, id uuid.UUID) (*db.User, err
 func (s userRepoStub) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
 	panic("not used")
 }
+func (s userRepoStub) FindByLineUserID(ctx context.Context, lineUserID string) (*db.User, error) {
+	panic("not used")
+}
+func (s userRepoStub) UpdateLineUserID(ctx context.Context, id uuid.UUID, lineUserID *string) error {
+	panic("not used")
+}
 
 type profileRepoStub struct {
 	findByUserID func(ctx context.Context, userID uuid.UUID) (*db.UserProfile, error)
@@ -49,34 +57,63 @@ type deviceTokenRepoStub struct {
 func (s deviceTokenRepoStub) Save(ctx context.Context, token *db.DeviceToken) error {
 	return s.save(ctx, token)
 }
+func (s deviceTokenRepoStub) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]db.DeviceToken, error) {
+	return nil, nil
+}
+func (s deviceTokenRepoStub) Deactivate(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
 
 type preferenceRepoStub struct {
 	upsert func(ctx context.Context, pref *db.UserPreference) error
+	find   func(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error)
+	weekly []repositories.WeeklyReminderUser
 }
 
 func (s preferenceRepoStub) Upsert(ctx context.Context, pref *db.UserPreference) error {
 	return s.upsert(ctx, pref)
 }
 func (s preferenceRepoStub) FindByUserID(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
-	panic("not used")
+	if s.find == nil {
+		return nil, domain.NewError(constants.UserNotFound, "preferences not found")
+	}
+	return s.find(ctx, userID)
 }
-func (s preferenceRepoStub) ListWeeklyReminderUsers(ctx context.Context) ([]uuid.UUID, error) {
-	panic("not used")
+func (s preferenceRepoStub) ListWeeklyReminderUsers(ctx context.Context) ([]repositories.WeeklyReminderUser, error) {
+	return s.weekly, nil
 }
 
 type notificationStub struct {
-	cancelWeekly func(ctx context.Context, userID uuid.UUID) error
+	cancelWeekly       func(ctx context.Context, userID uuid.UUID) error
+	rescheduleMedicine func(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error
+	rescheduleWeekly   func(ctx context.Context, userID uuid.UUID) error
+	channels           []string
 }
 
-func (s notificationStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, mealTiming *string, timeSlot time.Time) error {
+func (s notificationStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
 	panic("not used")
 }
 func (s notificationStub) CancelMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
 	panic("not used")
 }
+func (s notificationStub) RestoreMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
+	return nil
+}
+func (s notificationStub) RescheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+	return s.rescheduleMedicine(ctx, userID, schedule)
+}
+func (s notificationStub) BuildMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) ([]db.NotificationEvent, error) {
+	panic("not used")
+}
+func (s notificationStub) CancelMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	panic("not used")
+}
 func (s notificationStub) ScheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
 	panic("not used")
 }
+func (s notificationStub) RescheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
+	panic("not used")
+}
 func (s notificationStub) CancelAppointmentReminders(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
 	panic("not used")
 }
@@ -95,6 +132,36 @@ func (s notificationStub) CancelWeeklyReminders(ctx context.Context, userID uuid
 	}
 	return nil
 }
+func (s notificationStub) RescheduleWeeklyReminder(ctx context.Context, userID uuid.UUID) error {
+	return s.rescheduleWeekly(ctx, userID)
+}
+func (s notificationStub) ListFailedEvents(ctx context.Context, page, pageSize int, status, userID, templateCode string) ([]dto.NotificationEventResponse, int64, error) {
+	return nil, 0, nil
+}
+func (s notificationStub) RequeueEvent(ctx context.Context, id string) (dto.NotificationEventResponse, error) {
+	return dto.NotificationEventResponse{}, nil
+}
+func (s notificationStub) ListInbox(ctx context.Context, userID uuid.UUID, cursor string, limit int, unreadOnly bool) (dto.NotificationInboxResponse, error) {
+	return dto.NotificationInboxResponse{}, nil
+}
+func (s notificationStub) UnreadCount(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (s notificationStub) MarkRead(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (s notificationStub) MarkAllRead(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (s notificationStub) ArchiveInboxItem(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (s notificationStub) Channels() []string {
+	if s.channels == nil {
+		return notificationChannels
+	}
+	return s.channels
+}
 
 func TestUserServiceGetMeMasking(t *testing.T) {
 	actorID := uuid.New()
@@ -113,7 +180,7 @@ func TestUserServiceGetMeMasking(t *testing.T) {
 		}, nil
 	}, upsert: func(ctx context.Context, profile *db.UserProfile) error { return nil }}
 
-	svc := NewUserService(userRepo, profileRepo, nil, nil, nil)
+	svc := NewUserService(userRepo, profileRepo, nil, nil, nil, nil, nil, nil)
 
 	resp, err := svc.GetMe(context.Background(), actorID, constants.RolePatient)
 	if err != nil {
@@ -151,11 +218,15 @@ func TestUserServiceUpdateProfileRequiresFieldsOnNew(t *testing.T) {
 		upsert: func(ctx context.Context, profile *db.UserProfile) error { return nil },
 	}
 
-	svc := NewUserService(userRepo, profileRepo, nil, nil, nil)
+	audit := &auditRecorderStub{}
+	svc := NewUserService(userRepo, profileRepo, nil, nil, nil, nil, nil, audit)
 
 	if err := svc.UpdateProfile(context.Background(), actorID, dto.UpdateProfileRequest{}); err == nil {
 		t.Fatalf("expected validation error for missing required fields")
 	}
+	if len(audit.entries) != 0 {
+		t.Fatalf("expected no audit entry for rejected update")
+	}
 
 	first := "Jane"
 	last := "Doe"
@@ -167,6 +238,9 @@ func TestUserServiceUpdateProfileRequiresFieldsOnNew(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != constants.AuditProfileUpdate || *audit.entries[0].TargetUserID != actorID {
+		t.Fatalf("expected profile update audit entry: %+v", audit.entries)
+	}
 }
 
 func TestUserServiceSaveDeviceToken(t *testing.T) {
@@ -180,7 +254,7 @@ func TestUserServiceSaveDeviceToken(t *testing.T) {
 		return nil
 	}}
 
-	svc := NewUserService(userRepoStub{findByID: func(ctx context.Context, id uuid.UUID) (*db.User, error) { return &db.User{}, nil }}, profileRepoStub{}, deviceRepo, preferenceRepoStub{}, nil)
+	svc := NewUserService(userRepoStub{findByID: func(ctx context.Context, id uuid.UUID) (*db.User, error) { return &db.User{}, nil }}, profileRepoStub{}, deviceRepo, preferenceRepoStub{}, nil, nil, nil, nil)
 
 	if err := svc.SaveDeviceToken(context.Background(), actorID, dto.DeviceTokenRequest{}); err == nil {
 		t.Fatalf("expected validation error")
@@ -204,7 +278,7 @@ func TestUserServiceUpdatePreferences(t *testing.T) {
 		return nil
 	}}
 
-	svc := NewUserService(userRepoStub{findByID: func(ctx context.Context, id uuid.UUID) (*db.User, error) { return &db.User{}, nil }}, profileRepoStub{}, deviceTokenRepoStub{}, prefRepo, notify)
+	svc := NewUserService(userRepoStub{findByID: func(ctx context.Context, id uuid.UUID) (*db.User, error) { return &db.User{}, nil }}, profileRepoStub{}, deviceTokenRepoStub{}, prefRepo, notify, nil, nil, nil)
 
 	if _, err := svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{}); err == nil {
 		t.Fatalf("expected validation error")
@@ -222,3 +296,163 @@ func TestUserServiceUpdatePreferences(t *testing.T) {
 		t.Fatalf("expected weekly reminders cancelled")
 	}
 }
+
+func TestUserServiceUpdateTimezone(t *testing.T) {
+	actorID := uuid.New()
+	saved := &db.UserPreference{UserID: actorID, WeeklyReminderEnabled: false}
+	prefRepo := preferenceRepoStub{
+		upsert: func(ctx context.Context, pref *db.UserPreference) error {
+			saved = pref
+			return nil
+		},
+		find: func(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
+			copied := *saved
+			return &copied, nil
+		},
+	}
+	var rescheduled []uuid.UUID
+	weekly := 0
+	notify := notificationStub{
+		rescheduleMedicine: func(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+			rescheduled = append(rescheduled, schedule.ID)
+			return nil
+		},
+		rescheduleWeekly: func(ctx context.Context, userID uuid.UUID) error {
+			weekly++
+			return nil
+		},
+	}
+	activeSchedule, stoppedSchedule := uuid.New(), uuid.New()
+	medicines := &medicineRepoStub{scheduleRows: []repositories.ScheduleWithMedicineRow{
+		{ScheduleID: activeSchedule, MedicineActive: true, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll},
+		{ScheduleID: stoppedSchedule, MedicineActive: false, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll},
+	}}
+	svc := NewUserService(userRepoStub{}, profileRepoStub{}, nil, prefRepo, notify, NewMedicineService(medicines, nil, notify, "UTC"), nil, nil)
+
+	invalid := "Mars/Olympus"
+	if _, err := svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{Timezone: &invalid}); err == nil {
+		t.Fatalf("expected unknown timezone to be rejected")
+	}
+
+	timezone := " America/New_York "
+	resp, err := svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{Timezone: &timezone})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Timezone == nil || *resp.Timezone != "America/New_York" || resp.WeeklyReminderEnabled {
+		t.Fatalf("expected timezone set and weekly reminders left off, got %+v", resp)
+	}
+	if len(rescheduled) != 1 || rescheduled[0] != activeSchedule || weekly != 1 {
+		t.Fatalf("expected active schedule and weekly reminder replanned, got %v weekly=%d", rescheduled, weekly)
+	}
+
+	// The same timezone again replans nothing.
+	if _, err := svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{Timezone: &timezone}); err != nil || len(rescheduled) != 1 || weekly != 1 {
+		t.Fatalf("expected no replanning, got %v weekly=%d err=%v", rescheduled, weekly, err)
+	}
+
+	empty := ""
+	resp, err = svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{Timezone: &empty})
+	if err != nil || resp.Timezone != nil || len(rescheduled) != 2 {
+		t.Fatalf("expected timezone cleared and reminders replanned, got %+v err=%v", resp, err)
+	}
+}
+
+func TestUserServiceUpdateNotificationPreferences(t *testing.T) {
+	actorID := uuid.New()
+	saved := &db.UserPreference{UserID: actorID, WeeklyReminderEnabled: true}
+	prefRepo := preferenceRepoStub{
+		upsert: func(ctx context.Context, pref *db.UserPreference) error {
+			saved = pref
+			return nil
+		},
+		find: func(ctx context.Context, userID uuid.UUID) (*db.UserPreference, error) {
+			copied := *saved
+			return &copied, nil
+		},
+	}
+	medicineReplans := 0
+	notify := notificationStub{rescheduleMedicine: func(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+		medicineReplans++
+		return nil
+	}}
+	medicines := &medicineRepoStub{scheduleRows: []repositories.ScheduleWithMedicineRow{
+		{ScheduleID: uuid.New(), MedicineActive: true, IntervalDays: 1, WeekdayMask: constants.WeekdayMaskAll},
+	}}
+	apptNotify := &notificationCancelStub{}
+	appointments := &appointmentRepoStub{appointments: []db.Appointment{
+		{ID: uuid.New(), ApptType: constants.ApptHospital, Status: constants.ApptPending, ApptDateTime: time.Now().AddDate(0, 0, 20)},
+	}}
+	svc := NewUserService(userRepoStub{}, profileRepoStub{}, nil, prefRepo, notify, NewMedicineService(medicines, nil, notify, "UTC"), NewAppointmentService(appointments, apptNotify, nil), nil)
+
+	resp, err := svc.GetPreferences(context.Background(), actorID)
+	if err != nil || len(resp.Channels) != 2 || !slices.Equal(resp.AppointmentReminderDays, []int{5, 1}) || resp.BeforeMealLeadMinutes != 5 || resp.BeforeMealFollowUpMinutes != 20 || resp.QuietHoursStart != nil {
+		t.Fatalf("expected defaults, got %+v err=%v", resp, err)
+	}
+
+	invalid := []dto.UpdatePreferencesRequest{
+		{Channels: []string{}},
+		{Channels: []string{"email"}},
+		{QuietHoursStart: strPtr("22:00")},
+		{QuietHoursStart: strPtr("22:00"), QuietHoursEnd: strPtr("22:00")},
+		{QuietHoursStart: strPtr("10pm"), QuietHoursEnd: strPtr("07:00")},
+		{AppointmentReminderDays: []int{0}},
+		{AppointmentReminderDays: []int{1, 2, 3, 4, 5, 6}},
+		{BeforeMealLeadMinutes: intPtr(61)},
+		{BeforeMealFollowUpMinutes: intPtr(0)},
+		{Language: strPtr("fr")},
+	}
+	for _, req := range invalid {
+		if _, err := svc.UpdatePreferences(context.Background(), actorID, req); err == nil {
+			t.Fatalf("expected %+v to be rejected", req)
+		}
+	}
+
+	resp, err = svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{
+		Channels:                []string{" LINE ", "sms", "line"},
+		QuietHoursStart:         strPtr("22:00"),
+		QuietHoursEnd:           strPtr("07:00"),
+		AppointmentReminderDays: []int{1, 7, 7},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(resp.Channels, []string{"line", "sms"}) || !slices.Equal(resp.AppointmentReminderDays, []int{7, 1}) ||
+		resp.QuietHoursStart == nil || *resp.QuietHoursStart != "22:00" || *resp.QuietHoursEnd != "07:00" {
+		t.Fatalf("unexpected preferences: %+v", resp)
+	}
+	if len(apptNotify.rescheduled) != 1 || medicineReplans != 0 {
+		t.Fatalf("expected only appointment reminders replanned, got appointments=%d medicines=%d", len(apptNotify.rescheduled), medicineReplans)
+	}
+
+	resp, err = svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{BeforeMealLeadMinutes: intPtr(10), QuietHoursStart: strPtr(""), QuietHoursEnd: strPtr("")})
+	if err != nil || resp.BeforeMealLeadMinutes != 10 || resp.QuietHoursStart != nil || !slices.Equal(resp.Channels, []string{"line", "sms"}) {
+		t.Fatalf("expected lead time set and quiet hours cleared, got %+v err=%v", resp, err)
+	}
+	if medicineReplans != 1 || len(apptNotify.rescheduled) != 1 {
+		t.Fatalf("expected only medicine reminders replanned, got appointments=%d medicines=%d", len(apptNotify.rescheduled), medicineReplans)
+	}
+
+	resp, err = svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{Language: strPtr(" EN ")})
+	if err != nil || resp.Language == nil || *resp.Language != constants.LocaleEnglish {
+		t.Fatalf("expected language en, got %+v err=%v", resp, err)
+	}
+	resp, err = svc.UpdatePreferences(context.Background(), actorID, dto.UpdatePreferencesRequest{Language: strPtr("")})
+	if err != nil || resp.Language != nil {
+		t.Fatalf("expected language cleared, got %+v err=%v", resp, err)
+	}
+}
+
+func TestUserServiceUpdatePreferencesRejectsUnavailableChannels(t *testing.T) {
+	prefRepo := preferenceRepoStub{upsert: func(ctx context.Context, pref *db.UserPreference) error { return nil }}
+	notify := notificationStub{channels: []string{constants.NotificationChannelPush, constants.NotificationChannelLine}}
+	svc := NewUserService(userRepoStub{}, profileRepoStub{}, nil, prefRepo, notify, nil, nil, nil)
+
+	if _, err := svc.UpdatePreferences(context.Background(), uuid.New(), dto.UpdatePreferencesRequest{Channels: []string{"sms"}}); err == nil {
+		t.Fatalf("expected sms to be rejected while it is not configured")
+	}
+	resp, err := svc.UpdatePreferences(context.Background(), uuid.New(), dto.UpdatePreferencesRequest{Channels: []string{"line"}})
+	if err != nil || !slices.Equal(resp.Channels, []string{"line"}) {
+		t.Fatalf("expected a configured channel to be accepted, got %+v err=%v", resp, err)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+	"github.com/ParkPawapon/mhp-be/internal/transport/httpx"
+)
+
+type AdherenceHandler struct {
+	service    services.AdherenceService
+	caregivers services.CaregiverService
+}
+
+func NewAdherenceHandler(service services.AdherenceService, caregivers services.CaregiverService) *AdherenceHandler {
+	return &AdherenceHandler{service: service, caregivers: caregivers}
+}
+
+func (h *AdherenceHandler) GetSummary(c *gin.Context) {
+	userID := c.Query("user_id")
+
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID, constants.AuditPatientRead)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.GetReport(c.Request.Context(), resolvedUserID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
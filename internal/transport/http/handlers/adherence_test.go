@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type adherenceServiceStub struct {
+	gotUserID string
+}
+
+func (s *adherenceServiceStub) GetReport(ctx context.Context, userID, from, to string) (dto.AdherenceReportResponse, error) {
+	s.gotUserID = userID
+	return dto.AdherenceReportResponse{UserID: userID, From: from, To: to}, nil
+}
+
+func TestAdherenceHandler(t *testing.T) {
+	actorID := uuid.New()
+	svc := &adherenceServiceStub{}
+	router := newTestRouter(withActor(constants.RolePatient, actorID))
+	handler := NewAdherenceHandler(svc, caregiverServiceStubSimple{})
+	router.GET("/intake/adherence", handler.GetSummary)
+
+	resp := performRequest(router, http.MethodGet, "/intake/adherence?from=2026-01-01&to=2026-01-31", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	if svc.gotUserID != actorID.String() {
+		t.Fatalf("expected patient to default to self")
+	}
+
+	resp = performRequest(router, http.MethodGet, "/intake/adherence?user_id="+uuid.New().String(), nil)
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for other patient, got %d", resp.Code)
+	}
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/domain"
@@ -26,7 +29,7 @@ func (h *AdminHandler) StaffLogin(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.StaffLogin(c.Request.Context(), req)
+	resp, err := h.service.StaffLogin(c.Request.Context(), req, c.ClientIP())
 	if err != nil {
 		httpx.Fail(c, err)
 		return
@@ -36,19 +39,34 @@ func (h *AdminHandler) StaffLogin(c *gin.Context) {
 
 func (h *AdminHandler) ListPatients(c *gin.Context) {
 	page, pageSize := parsePagination(c)
-	items, total, err := h.service.ListPatients(c.Request.Context(), page, pageSize)
+	query := dto.PatientListQuery{
+		Query:       c.Query("q"),
+		CaregiverID: c.Query("caregiver_id"),
+	}
+	if v := c.Query("is_active"); v != "" {
+		isActive, err := strconv.ParseBool(v)
+		if err != nil {
+			httpx.Fail(c, domain.NewError(constants.ValidationFailed, "invalid is_active"))
+			return
+		}
+		query.IsActive = &isActive
+	}
+
+	items, total, err := h.service.ListPatients(c.Request.Context(), query, page, pageSize)
 	if err != nil {
 		httpx.Fail(c, err)
 		return
 	}
+	middleware.MarkAuditAction(c, constants.AuditPatientList)
 
 	meta := httpx.PaginationMeta(middleware.GetRequestID(c), page, pageSize, total)
 	c.JSON(200, httpx.SuccessResponse{Data: items, Meta: meta})
 }
 
 func (h *AdminHandler) GetPatient(c *gin.Context) {
-	id := c.Param("id")
-	resp, err := h.service.GetPatient(c.Request.Context(), id)
+	actorID, _ := middleware.GetActorID(c)
+	role, _ := middleware.GetRole(c)
+	resp, err := h.service.GetPatient(c.Request.Context(), actorID, role, c.Param("id"))
 	if err != nil {
 		httpx.Fail(c, err)
 		return
@@ -69,5 +87,37 @@ func (h *AdminHandler) ListAdherence(c *gin.Context) {
 		httpx.Fail(c, err)
 		return
 	}
+	if pid, err := uuid.Parse(resp.UserID); err == nil {
+		middleware.MarkAudit(c, constants.AuditPatientRead, pid)
+	}
 	httpx.OK(c, resp)
 }
+
+func (h *AdminHandler) ListDoseTimings(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	query := dto.DoseTimingQuery{
+		PatientID: c.Query("patient_id"),
+		Timing:    c.Query("timing"),
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		SlotFrom:  c.Query("slot_from"),
+		SlotTo:    c.Query("slot_to"),
+	}
+	if v := c.Query("min_delay_minutes"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 0 {
+			httpx.Fail(c, domain.NewError(constants.ValidationFailed, "invalid min_delay_minutes"))
+			return
+		}
+		query.MinDelayMinutes = minutes
+	}
+
+	items, total, err := h.service.ListDoseTimings(c.Request.Context(), query, page, pageSize)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	meta := httpx.PaginationMeta(middleware.GetRequestID(c), page, pageSize, total)
+	c.JSON(200, httpx.SuccessResponse{Data: items, Meta: meta})
+}
@@ -7,22 +7,28 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/middleware"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
 )
 
 type adminServiceStub struct{}
 
-func (adminServiceStub) StaffLogin(ctx context.Context, req dto.StaffLoginRequest) (dto.TokenResponse, error) {
+func (adminServiceStub) StaffLogin(ctx context.Context, req dto.StaffLoginRequest, ip string) (dto.TokenResponse, error) {
 	return dto.TokenResponse{AccessToken: "a", RefreshToken: "r"}, nil
 }
-func (adminServiceStub) ListPatients(ctx context.Context, page, pageSize int) ([]dto.PatientSummaryResponse, int64, error) {
+func (adminServiceStub) ListPatients(ctx context.Context, query dto.PatientListQuery, page, pageSize int) ([]dto.PatientSummaryResponse, int64, error) {
 	return []dto.PatientSummaryResponse{{ID: uuid.New().String()}}, 1, nil
 }
-func (adminServiceStub) GetPatient(ctx context.Context, id string) (dto.PatientDetailResponse, error) {
+func (adminServiceStub) GetPatient(ctx context.Context, actorID uuid.UUID, viewerRole constants.Role, id string) (dto.PatientDetailResponse, error) {
 	return dto.PatientDetailResponse{ID: id}, nil
 }
-func (adminServiceStub) ListAdherence(ctx context.Context, patientID, from, to string) ([]dto.IntakeHistoryResponse, error) {
-	return []dto.IntakeHistoryResponse{{ID: uuid.New().String(), UserID: patientID}}, nil
+func (adminServiceStub) ListAdherence(ctx context.Context, patientID, from, to string) (dto.AdherenceReportResponse, error) {
+	return dto.AdherenceReportResponse{UserID: patientID}, nil
+}
+func (adminServiceStub) ListDoseTimings(ctx context.Context, query dto.DoseTimingQuery, page, pageSize int) ([]dto.DoseTimingResponse, int64, error) {
+	return []dto.DoseTimingResponse{{Timing: query.Timing}}, 1, nil
 }
 
 func TestAdminHandlers(t *testing.T) {
@@ -33,6 +39,7 @@ func TestAdminHandlers(t *testing.T) {
 	router.GET("/admin/patients", handler.ListPatients)
 	router.GET("/admin/patients/:id", handler.GetPatient)
 	router.GET("/admin/adherence", handler.ListAdherence)
+	router.GET("/admin/intake/timing", handler.ListDoseTimings)
 
 	resp := performRequest(router, http.MethodPost, "/staff/login", dto.StaffLoginRequest{Username: "admin", Password: "pass"})
 	if resp.Code != http.StatusOK {
@@ -44,6 +51,11 @@ func TestAdminHandlers(t *testing.T) {
 		t.Fatalf("list patients expected 200, got %d", resp.Code)
 	}
 
+	resp = performRequest(router, http.MethodGet, "/admin/patients?q=somchai&is_active=maybe", nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid is_active expected 400, got %d", resp.Code)
+	}
+
 	resp = performRequest(router, http.MethodGet, "/admin/patients/"+uuid.New().String(), nil)
 	if resp.Code != http.StatusOK {
 		t.Fatalf("get patient expected 200, got %d", resp.Code)
@@ -53,4 +65,53 @@ func TestAdminHandlers(t *testing.T) {
 	if resp.Code != http.StatusOK {
 		t.Fatalf("adherence expected 200, got %d", resp.Code)
 	}
+
+	resp = performRequest(router, http.MethodGet, "/admin/intake/timing?timing=LATE&slot_from=05:00&slot_to=11:59&min_delay_minutes=360", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("dose timings expected 200, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/admin/intake/timing?min_delay_minutes=-5", nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid min_delay_minutes expected 400, got %d", resp.Code)
+	}
+}
+
+type auditRecorderStub struct {
+	entries []domain.AuditEntry
+}
+
+func (r *auditRecorderStub) Record(ctx context.Context, entry domain.AuditEntry) {
+	r.entries = append(r.entries, entry)
+}
+
+func TestAdminListPatientsIsAudited(t *testing.T) {
+	actorID := uuid.New()
+	recorder := &auditRecorderStub{}
+	router := newTestRouter(withActor(constants.RoleNurse, actorID), middleware.Audit(recorder))
+	handler := NewAdminHandler(adminServiceStub{})
+	router.GET("/admin/patients", handler.ListPatients)
+
+	resp := performRequest(router, http.MethodGet, "/admin/patients?q=1103700000000", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("list patients expected 200, got %d", resp.Code)
+	}
+	if len(recorder.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(recorder.entries))
+	}
+	entry := recorder.entries[0]
+	if entry.Action != constants.AuditPatientList || entry.TargetUserID != nil {
+		t.Fatalf("unexpected audit entry: %+v", entry)
+	}
+	if entry.ActorID == nil || *entry.ActorID != actorID {
+		t.Fatalf("expected actor %s, got %+v", actorID, entry.ActorID)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/admin/patients?is_active=maybe", nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid is_active expected 400, got %d", resp.Code)
+	}
+	if len(recorder.entries) != 1 {
+		t.Fatalf("failed request must not be audited, got %d entries", len(recorder.entries))
+	}
 }
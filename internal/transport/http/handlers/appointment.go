@@ -3,6 +3,7 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/middleware"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
 	"github.com/ParkPawapon/mhp-be/internal/services"
@@ -20,7 +21,7 @@ func NewAppointmentHandler(service services.AppointmentService, caregivers servi
 
 func (h *AppointmentHandler) ListAppointments(c *gin.Context) {
 	userID := c.Query("user_id")
-	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID)
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID, constants.AuditPatientRead)
 	if err != nil {
 		httpx.Fail(c, err)
 		return
@@ -89,7 +90,7 @@ func (h *AppointmentHandler) CreateNurseVisitNote(c *gin.Context) {
 
 func (h *AppointmentHandler) ListVisitHistory(c *gin.Context) {
 	userID := c.Query("user_id")
-	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID)
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID, constants.AuditPatientRead)
 	if err != nil {
 		httpx.Fail(c, err)
 		return
@@ -32,6 +32,9 @@ func (appointmentServiceStub) CreateNurseVisitNote(ctx context.Context, appointm
 func (appointmentServiceStub) ListVisitHistory(ctx context.Context, userID string) ([]dto.VisitHistoryItem, error) {
 	return []dto.VisitHistoryItem{{AppointmentID: uuid.New().String(), VisitNoteID: uuid.New().String()}}, nil
 }
+func (appointmentServiceStub) RescheduleReminders(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
 
 func TestAppointmentHandlers(t *testing.T) {
 	actorID := uuid.New()
@@ -7,18 +7,27 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/middleware"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
 )
 
-type auditServiceStub struct{}
+type auditServiceStub struct {
+	entries []domain.AuditEntry
+}
+
+func (s *auditServiceStub) Record(ctx context.Context, entry domain.AuditEntry) {
+	s.entries = append(s.entries, entry)
+}
 
-func (auditServiceStub) ListAuditLogs(ctx context.Context, page, pageSize int, from, to, actorID, actionType string) ([]dto.AuditLogResponse, int64, error) {
+func (s *auditServiceStub) ListAuditLogs(ctx context.Context, page, pageSize int, from, to, actorID, actionType string) ([]dto.AuditLogResponse, int64, error) {
 	return []dto.AuditLogResponse{{ID: uuid.New().String()}}, 1, nil
 }
 
 func TestAuditHandlers(t *testing.T) {
 	router := newTestRouter()
-	handler := NewAuditHandler(auditServiceStub{})
+	handler := NewAuditHandler(&auditServiceStub{})
 
 	router.GET("/admin/audit-logs", handler.ListAuditLogs)
 
@@ -27,3 +36,32 @@ func TestAuditHandlers(t *testing.T) {
 		t.Fatalf("expected 200, got %d", resp.Code)
 	}
 }
+
+func TestAuditMiddlewareRecordsPatientReads(t *testing.T) {
+	caregiverID := uuid.New()
+	patientID := uuid.New()
+	audit := &auditServiceStub{}
+	router := newTestRouter(middleware.Audit(audit), withActor(constants.RoleCaregiver, caregiverID))
+	handler := NewAdherenceHandler(&adherenceServiceStub{}, caregiverServiceStubSimple{})
+	router.GET("/intake/adherence", handler.GetSummary)
+
+	resp := performRequest(router, http.MethodGet, "/intake/adherence?user_id="+patientID.String(), nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(audit.entries))
+	}
+	entry := audit.entries[0]
+	if entry.Action != constants.AuditPatientRead || *entry.ActorID != caregiverID || *entry.TargetUserID != patientID {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/intake/adherence", nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without user_id, got %d", resp.Code)
+	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected failed request not to be audited")
+	}
+}
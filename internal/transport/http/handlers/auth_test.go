@@ -24,6 +24,9 @@ func (authServiceStub) Register(ctx context.Context, req dto.RegisterRequest) (d
 func (authServiceStub) Login(ctx context.Context, req dto.LoginRequest) (dto.TokenResponse, error) {
 	return dto.TokenResponse{AccessToken: "a", RefreshToken: "r"}, nil
 }
+func (authServiceStub) StaffLogin(ctx context.Context, req dto.StaffLoginRequest, ip string) (dto.TokenResponse, error) {
+	return dto.TokenResponse{AccessToken: "a", RefreshToken: "r"}, nil
+}
 func (authServiceStub) ForgotPasswordRequestOTP(ctx context.Context, phone, ip string) (dto.RequestOTPResponse, error) {
 	return dto.RequestOTPResponse{RefCode: "ref", ExpiresAt: time.Now().UTC()}, nil
 }
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/ParkPawapon/mhp-be/internal/middleware"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+	"github.com/ParkPawapon/mhp-be/internal/transport/httpx"
+)
+
+type EscalationHandler struct {
+	service services.EscalationService
+}
+
+func NewEscalationHandler(service services.EscalationService) *EscalationHandler {
+	return &EscalationHandler{service: service}
+}
+
+func (h *EscalationHandler) GetPolicy(c *gin.Context) {
+	resp, err := h.service.GetPolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *EscalationHandler) UpdatePolicy(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	var req dto.UpdateEscalationPolicyRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.UpdatePolicy(c.Request.Context(), actorID, c.Param("id"), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
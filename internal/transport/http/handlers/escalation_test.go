@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type escalationServiceStub struct{}
+
+func (escalationServiceStub) EscalateMissedDoses(ctx context.Context) (int, error) {
+	return 0, nil
+}
+func (escalationServiceStub) GetPolicy(ctx context.Context, patientID string) (dto.EscalationPolicyResponse, error) {
+	return dto.EscalationPolicyResponse{UserID: patientID, Enabled: true, CaregiverThreshold: 1, NurseThreshold: 3, IsDefault: true}, nil
+}
+func (escalationServiceStub) UpdatePolicy(ctx context.Context, actorID uuid.UUID, patientID string, req dto.UpdateEscalationPolicyRequest) (dto.EscalationPolicyResponse, error) {
+	return dto.EscalationPolicyResponse{UserID: patientID}, nil
+}
+
+func TestEscalationHandlers(t *testing.T) {
+	router := newTestRouter(withActor(constants.RoleNurse, uuid.New()))
+	handler := NewEscalationHandler(escalationServiceStub{})
+
+	router.GET("/admin/patients/:id/escalation-policy", handler.GetPolicy)
+	router.PUT("/admin/patients/:id/escalation-policy", handler.UpdatePolicy)
+
+	path := "/admin/patients/" + uuid.New().String() + "/escalation-policy"
+	resp := performRequest(router, http.MethodGet, path, nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("get policy expected 200, got %d", resp.Code)
+	}
+
+	two := 2
+	resp = performRequest(router, http.MethodPut, path, dto.UpdateEscalationPolicyRequest{CaregiverThreshold: &two})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("update policy expected 200, got %d", resp.Code)
+	}
+
+	zero := 0
+	resp = performRequest(router, http.MethodPut, path, dto.UpdateEscalationPolicyRequest{NurseThreshold: &zero})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid threshold expected 400, got %d", resp.Code)
+	}
+}
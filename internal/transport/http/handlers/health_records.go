@@ -3,6 +3,7 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/middleware"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
 	"github.com/ParkPawapon/mhp-be/internal/services"
@@ -39,7 +40,7 @@ func (h *HealthRecordHandler) ListHealthRecords(c *gin.Context) {
 	to := c.Query("to")
 	userID := c.Query("user_id")
 
-	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID)
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID, constants.AuditPatientRead)
 	if err != nil {
 		httpx.Fail(c, err)
 		return
@@ -74,7 +75,7 @@ func (h *HealthRecordHandler) ListDailyAssessments(c *gin.Context) {
 	to := c.Query("to")
 	userID := c.Query("user_id")
 
-	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID)
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID, constants.AuditPatientRead)
 	if err != nil {
 		httpx.Fail(c, err)
 		return
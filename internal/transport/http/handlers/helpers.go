@@ -41,7 +41,10 @@ func parsePagination(c *gin.Context) (int, int) {
 	return page, pageSize
 }
 
-func authorizePatientAccess(c *gin.Context, caregiverSvc services.CaregiverService, targetUserID string) (string, error) {
+// authorizePatientAccess resolves whose records the request targets and checks
+// the actor may reach them. Access to another user's records is audited under
+// the caller's action once the request succeeds.
+func authorizePatientAccess(c *gin.Context, caregiverSvc services.CaregiverService, targetUserID string, action constants.AuditAction) (string, error) {
 	actorID, _ := middleware.GetActorID(c)
 	role, _ := middleware.GetRole(c)
 
@@ -74,5 +77,11 @@ func authorizePatientAccess(c *gin.Context, caregiverSvc services.CaregiverServi
 		}
 	}
 
+	if targetUserID != actorID.String() {
+		if pid, err := uuid.Parse(targetUserID); err == nil {
+			middleware.MarkAudit(c, action, pid)
+		}
+	}
+
 	return targetUserID, nil
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/ParkPawapon/mhp-be/internal/constants"
 	"github.com/ParkPawapon/mhp-be/internal/middleware"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
 	"github.com/ParkPawapon/mhp-be/internal/services"
@@ -34,12 +35,52 @@ func (h *IntakeHandler) CreateIntake(c *gin.Context) {
 	httpx.Created(c, resp)
 }
 
+func (h *IntakeHandler) UpdateIntake(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	var req dto.UpdateIntakeRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.UpdateIntake(c.Request.Context(), actorID.String(), c.Param("id"), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *IntakeHandler) DeleteIntake(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	if err := h.service.DeleteIntake(c.Request.Context(), actorID.String(), c.Param("id")); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, gin.H{"deleted": true})
+}
+
+func (h *IntakeHandler) ListChanges(c *gin.Context) {
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, c.Query("user_id"), constants.AuditPatientRead)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.ListChanges(c.Request.Context(), resolvedUserID, c.Param("id"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
 func (h *IntakeHandler) ListHistory(c *gin.Context) {
 	from := c.Query("from")
 	to := c.Query("to")
 	userID := c.Query("user_id")
 
-	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID)
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, userID, constants.AuditPatientRead)
 	if err != nil {
 		httpx.Fail(c, err)
 		return
@@ -52,3 +93,18 @@ func (h *IntakeHandler) ListHistory(c *gin.Context) {
 	}
 	httpx.OK(c, resp)
 }
+
+func (h *IntakeHandler) GetToday(c *gin.Context) {
+	resolvedUserID, err := authorizePatientAccess(c, h.caregivers, c.Query("user_id"), constants.AuditPatientRead)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.GetToday(c.Request.Context(), resolvedUserID, c.Query("date"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
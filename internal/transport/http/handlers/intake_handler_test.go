@@ -17,9 +17,24 @@ type intakeServiceStub struct{}
 func (intakeServiceStub) CreateIntake(ctx context.Context, userID string, req dto.CreateIntakeRequest) (dto.IntakeHistoryResponse, error) {
 	return dto.IntakeHistoryResponse{ID: uuid.New().String(), UserID: userID, Status: req.Status, TargetDate: time.Now().Format("2006-01-02")}, nil
 }
+func (intakeServiceStub) UpdateIntake(ctx context.Context, userID, id string, req dto.UpdateIntakeRequest) (dto.IntakeHistoryResponse, error) {
+	return dto.IntakeHistoryResponse{ID: id, UserID: userID, Status: req.Status}, nil
+}
+func (intakeServiceStub) DeleteIntake(ctx context.Context, userID, id string) error {
+	return nil
+}
+func (intakeServiceStub) ListChanges(ctx context.Context, userID, id string) ([]dto.IntakeChangeResponse, error) {
+	return []dto.IntakeChangeResponse{{ID: uuid.New().String(), IntakeID: id, Action: constants.IntakeChangeUpdate}}, nil
+}
 func (intakeServiceStub) ListHistory(ctx context.Context, userID, from, to string) ([]dto.IntakeHistoryResponse, error) {
 	return []dto.IntakeHistoryResponse{{ID: uuid.New().String(), UserID: userID, Status: constants.MedTaken}}, nil
 }
+func (intakeServiceStub) GetToday(ctx context.Context, userID, date string) (dto.TodayChecklistResponse, error) {
+	return dto.TodayChecklistResponse{UserID: userID, Date: date, Items: []dto.TodayDoseItem{}}, nil
+}
+func (intakeServiceStub) MarkMissedDoses(ctx context.Context) (int64, error) {
+	return 0, nil
+}
 
 type caregiverServiceStubSimple struct{}
 
@@ -40,6 +55,10 @@ func TestIntakeHandlers(t *testing.T) {
 
 	router.POST("/intake", handler.CreateIntake)
 	router.GET("/intake/history", handler.ListHistory)
+	router.GET("/intake/today", handler.GetToday)
+	router.PATCH("/intake/:id", handler.UpdateIntake)
+	router.DELETE("/intake/:id", handler.DeleteIntake)
+	router.GET("/intake/:id/changes", handler.ListChanges)
 
 	payload := dto.CreateIntakeRequest{Status: constants.MedTaken, TargetDate: time.Now().Format("2006-01-02")}
 	resp := performRequest(router, http.MethodPost, "/intake", payload)
@@ -51,4 +70,30 @@ func TestIntakeHandlers(t *testing.T) {
 	if resp.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", resp.Code)
 	}
+
+	resp = performRequest(router, http.MethodGet, "/intake/today?date=2025-01-15", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+
+	intakeID := uuid.New().String()
+	resp = performRequest(router, http.MethodPatch, "/intake/"+intakeID, dto.UpdateIntakeRequest{Status: constants.MedSkipped})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodPatch, "/intake/"+intakeID, map[string]any{})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without status, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/intake/"+intakeID+"/changes", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodDelete, "/intake/"+intakeID, nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
 }
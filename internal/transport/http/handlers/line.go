@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/middleware"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+	"github.com/ParkPawapon/mhp-be/internal/transport/httpx"
+)
+
+type LineHandler struct {
+	line services.LineService
+}
+
+func NewLineHandler(line services.LineService) *LineHandler {
+	return &LineHandler{line: line}
+}
+
+func (h *LineHandler) LinkURL(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+
+	resp, err := h.line.CreateLinkURL(c.Request.Context(), actorID)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *LineHandler) Link(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+
+	var req dto.LineLinkRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.line.Link(c.Request.Context(), actorID, req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *LineHandler) Unlink(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+
+	if err := h.line.Unlink(c.Request.Context(), actorID); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, dto.LineLinkResponse{Linked: false})
+}
+
+// Webhook needs the raw body because the LINE signature covers the exact bytes.
+func (h *LineHandler) Webhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		httpx.Fail(c, domain.NewError(constants.ValidationFailed, "invalid body"))
+		return
+	}
+
+	if err := h.line.HandleWebhook(c.Request.Context(), c.GetHeader("X-Line-Signature"), body); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, gin.H{"received": true})
+}
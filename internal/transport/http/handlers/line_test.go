@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type lineServiceStub struct {
+	signature string
+	body      string
+}
+
+func (s *lineServiceStub) CreateLinkURL(ctx context.Context, actorID uuid.UUID) (dto.LineLinkURLResponse, error) {
+	return dto.LineLinkURLResponse{AuthorizeURL: "https://access.line.me/oauth2/v2.1/authorize?state=abc", State: "abc"}, nil
+}
+func (s *lineServiceStub) Link(ctx context.Context, actorID uuid.UUID, req dto.LineLinkRequest) (dto.LineLinkResponse, error) {
+	return dto.LineLinkResponse{Linked: true}, nil
+}
+func (s *lineServiceStub) Unlink(ctx context.Context, actorID uuid.UUID) error { return nil }
+func (s *lineServiceStub) HandleWebhook(ctx context.Context, signature string, body []byte) error {
+	s.signature = signature
+	s.body = string(body)
+	if signature != "valid" {
+		return domain.NewError(constants.AuthUnauthorized, "invalid signature")
+	}
+	return nil
+}
+
+func TestLineHandlers(t *testing.T) {
+	stub := &lineServiceStub{}
+	handler := NewLineHandler(stub)
+	router := newTestRouter(withActor(constants.RolePatient, uuid.New()))
+	router.GET("/me/line/link-url", handler.LinkURL)
+	router.POST("/me/line/link", handler.Link)
+	router.DELETE("/me/line/link", handler.Unlink)
+
+	resp := performRequest(router, http.MethodGet, "/me/line/link-url", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	resp = performRequest(router, http.MethodPost, "/me/line/link", dto.LineLinkRequest{Code: "code"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without state, got %d", resp.Code)
+	}
+	resp = performRequest(router, http.MethodPost, "/me/line/link", dto.LineLinkRequest{Code: "code", State: "abc"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	resp = performRequest(router, http.MethodDelete, "/me/line/link", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+
+	public := newTestRouter()
+	public.POST("/line/webhook", handler.Webhook)
+
+	payload := map[string]any{"events": []any{}}
+	resp = performRequest(public, http.MethodPost, "/line/webhook", payload)
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without signature, got %d", resp.Code)
+	}
+	if stub.body != `{"events":[]}` {
+		t.Fatalf("expected raw body passed through, got %q", stub.body)
+	}
+}
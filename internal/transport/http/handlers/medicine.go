@@ -125,6 +125,25 @@ func (h *MedicineHandler) CreateSchedule(c *gin.Context) {
 	httpx.Created(c, resp)
 }
 
+func (h *MedicineHandler) UpdateSchedule(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	role, _ := middleware.GetRole(c)
+	id := c.Param("id")
+
+	var req dto.UpdateMedicineScheduleRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.UpdateSchedule(c.Request.Context(), actorID, role, id, req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
 func (h *MedicineHandler) DeleteSchedule(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.service.DeleteSchedule(c.Request.Context(), id); err != nil {
@@ -33,6 +33,9 @@ func (medicineServiceStub) DeletePatientMedicine(ctx context.Context, id string)
 func (medicineServiceStub) CreateSchedule(ctx context.Context, patientMedicineID string, req dto.CreateMedicineScheduleRequest) (dto.MedicineScheduleResponse, error) {
 	return dto.MedicineScheduleResponse{ID: uuid.New().String(), PatientMedicineID: patientMedicineID, TimeSlot: req.TimeSlot, CreatedAt: time.Now().UTC()}, nil
 }
+func (medicineServiceStub) UpdateSchedule(ctx context.Context, actorID uuid.UUID, role constants.Role, id string, req dto.UpdateMedicineScheduleRequest) (dto.MedicineScheduleResponse, error) {
+	return dto.MedicineScheduleResponse{ID: id, TimeSlot: *req.TimeSlot, CreatedAt: time.Now().UTC()}, nil
+}
 func (medicineServiceStub) DeleteSchedule(ctx context.Context, id string) error {
 	return nil
 }
@@ -48,6 +51,12 @@ func (medicineServiceStub) GetDosageOptions(ctx context.Context) []string {
 func (medicineServiceStub) GetMealTimingOptions(ctx context.Context) []string {
 	return []string{constants.MealTimingBeforeMeal}
 }
+func (medicineServiceStub) EnsureReminderHorizon(ctx context.Context) error {
+	return nil
+}
+func (medicineServiceStub) RescheduleReminders(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
 
 func TestMedicineHandlers(t *testing.T) {
 	actorID := uuid.New()
@@ -64,6 +73,7 @@ func TestMedicineHandlers(t *testing.T) {
 	router.PATCH("/medicines/patient/:id", handler.UpdatePatientMedicine)
 	router.DELETE("/medicines/patient/:id", handler.DeletePatientMedicine)
 	router.POST("/medicines/patient/:id/schedules", handler.CreateSchedule)
+	router.PATCH("/medicines/schedules/:id", handler.UpdateSchedule)
 	router.DELETE("/medicines/schedules/:id", handler.DeleteSchedule)
 
 	resp := performRequest(router, http.MethodGet, "/medicines/master?page=1&page_size=20", nil)
@@ -119,6 +129,11 @@ func TestMedicineHandlers(t *testing.T) {
 		t.Fatalf("create schedule: expected 201, got %d", resp.Code)
 	}
 
+	resp = performRequest(router, http.MethodPatch, "/medicines/schedules/"+uuid.New().String(), dto.UpdateMedicineScheduleRequest{TimeSlot: strPtr("09:30")})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("update schedule: expected 200, got %d", resp.Code)
+	}
+
 	resp = performRequest(router, http.MethodDelete, "/medicines/schedules/"+uuid.New().String(), nil)
 	if resp.Code != http.StatusOK {
 		t.Fatalf("delete schedule: expected 200, got %d", resp.Code)
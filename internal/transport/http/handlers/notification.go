@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/ParkPawapon/mhp-be/internal/middleware"
@@ -28,3 +30,86 @@ func (h *NotificationHandler) ListUpcoming(c *gin.Context) {
 	}
 	httpx.OK(c, resp)
 }
+
+func (h *NotificationHandler) ListInbox(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	unreadOnly := false
+	if v := c.Query("unread"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			unreadOnly = parsed
+		}
+	}
+
+	resp, err := h.service.ListInbox(c.Request.Context(), actorID, c.Query("cursor"), limit, unreadOnly)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	resp, err := h.service.UnreadCount(c.Request.Context(), actorID)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	resp, err := h.service.MarkRead(c.Request.Context(), actorID, c.Param("id"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	resp, err := h.service.MarkAllRead(c.Request.Context(), actorID)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationHandler) Archive(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	resp, err := h.service.ArchiveInboxItem(c.Request.Context(), actorID, c.Param("id"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationHandler) ListFailed(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	status := c.Query("status")
+	userID := c.Query("user_id")
+	templateCode := c.Query("template_code")
+
+	items, total, err := h.service.ListFailedEvents(c.Request.Context(), page, pageSize, status, userID, templateCode)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	meta := httpx.PaginationMeta(middleware.GetRequestID(c), page, pageSize, total)
+	c.JSON(200, httpx.SuccessResponse{Data: items, Meta: meta})
+}
+
+func (h *NotificationHandler) Requeue(c *gin.Context) {
+	resp, err := h.service.RequeueEvent(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/ParkPawapon/mhp-be/internal/middleware"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+	"github.com/ParkPawapon/mhp-be/internal/transport/httpx"
+)
+
+type NotificationTemplateHandler struct {
+	service services.NotificationTemplateService
+}
+
+func NewNotificationTemplateHandler(service services.NotificationTemplateService) *NotificationTemplateHandler {
+	return &NotificationTemplateHandler{service: service}
+}
+
+func (h *NotificationTemplateHandler) List(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+
+	items, total, err := h.service.ListTemplates(c.Request.Context(), page, pageSize, c.Query("code"), c.Query("locale"), c.Query("active"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	meta := httpx.PaginationMeta(middleware.GetRequestID(c), page, pageSize, total)
+	c.JSON(200, httpx.SuccessResponse{Data: items, Meta: meta})
+}
+
+func (h *NotificationTemplateHandler) Get(c *gin.Context) {
+	resp, err := h.service.GetTemplate(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationTemplateHandler) Create(c *gin.Context) {
+	var req dto.CreateNotificationTemplateRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	resp, err := h.service.CreateTemplate(c.Request.Context(), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.Created(c, resp)
+}
+
+func (h *NotificationTemplateHandler) Update(c *gin.Context) {
+	var req dto.UpdateNotificationTemplateRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	resp, err := h.service.UpdateTemplate(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationTemplateHandler) Activate(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *NotificationTemplateHandler) Deactivate(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *NotificationTemplateHandler) setActive(c *gin.Context, active bool) {
+	resp, err := h.service.SetTemplateActive(c.Request.Context(), c.Param("id"), active)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationTemplateHandler) Delete(c *gin.Context) {
+	if err := h.service.DeleteTemplate(c.Request.Context(), c.Param("id")); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, gin.H{"deleted": true})
+}
+
+func (h *NotificationTemplateHandler) Preview(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	var req dto.PreviewNotificationTemplateRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	resp, err := h.service.PreviewTemplate(c.Request.Context(), actorID, c.Param("id"), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *NotificationTemplateHandler) TestSend(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	var req dto.TestSendNotificationTemplateRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	resp, err := h.service.TestSendTemplate(c.Request.Context(), actorID, c.Param("id"), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
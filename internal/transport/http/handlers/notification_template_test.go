@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type notificationTemplateServiceStub struct {
+	actorID uuid.UUID
+}
+
+func (notificationTemplateServiceStub) ListTemplates(ctx context.Context, page, pageSize int, code, locale, active string) ([]dto.NotificationTemplateResponse, int64, error) {
+	return []dto.NotificationTemplateResponse{{ID: uuid.New().String(), Code: code, Locale: constants.LocaleThai, IsActive: true}}, 1, nil
+}
+func (notificationTemplateServiceStub) GetTemplate(ctx context.Context, id string) (dto.NotificationTemplateResponse, error) {
+	return dto.NotificationTemplateResponse{ID: id}, nil
+}
+func (notificationTemplateServiceStub) CreateTemplate(ctx context.Context, req dto.CreateNotificationTemplateRequest) (dto.NotificationTemplateResponse, error) {
+	if req.Body == "{{.unknown}}" {
+		return dto.NotificationTemplateResponse{}, domain.NewError(constants.NotificationInvalid, "unknown variable")
+	}
+	return dto.NotificationTemplateResponse{ID: uuid.New().String(), Code: req.Code, Locale: req.Locale}, nil
+}
+func (notificationTemplateServiceStub) UpdateTemplate(ctx context.Context, id string, req dto.UpdateNotificationTemplateRequest) (dto.NotificationTemplateResponse, error) {
+	return dto.NotificationTemplateResponse{ID: id}, nil
+}
+func (notificationTemplateServiceStub) SetTemplateActive(ctx context.Context, id string, active bool) (dto.NotificationTemplateResponse, error) {
+	return dto.NotificationTemplateResponse{ID: id, IsActive: active}, nil
+}
+func (notificationTemplateServiceStub) DeleteTemplate(ctx context.Context, id string) error {
+	return nil
+}
+func (notificationTemplateServiceStub) PreviewTemplate(ctx context.Context, actorID uuid.UUID, id string, req dto.PreviewNotificationTemplateRequest) (dto.NotificationPreviewResponse, error) {
+	return dto.NotificationPreviewResponse{TemplateID: id, Body: "preview"}, nil
+}
+func (s notificationTemplateServiceStub) TestSendTemplate(ctx context.Context, actorID uuid.UUID, id string, req dto.TestSendNotificationTemplateRequest) (dto.NotificationTestSendResponse, error) {
+	if actorID != s.actorID {
+		return dto.NotificationTestSendResponse{}, domain.NewError(constants.AuthForbidden, "forbidden")
+	}
+	return dto.NotificationTestSendResponse{Devices: 2}, nil
+}
+
+func TestNotificationTemplateHandlers(t *testing.T) {
+	actorID := uuid.New()
+	router := newTestRouter(withActor(constants.RoleAdmin, actorID))
+	handler := NewNotificationTemplateHandler(notificationTemplateServiceStub{actorID: actorID})
+
+	router.GET("/admin/notification-templates", handler.List)
+	router.POST("/admin/notification-templates", handler.Create)
+	router.PATCH("/admin/notification-templates/:id", handler.Update)
+	router.DELETE("/admin/notification-templates/:id", handler.Delete)
+	router.POST("/admin/notification-templates/:id/deactivate", handler.Deactivate)
+	router.POST("/admin/notification-templates/:id/preview", handler.Preview)
+	router.POST("/admin/notification-templates/:id/test-send", handler.TestSend)
+
+	resp := performRequest(router, http.MethodGet, "/admin/notification-templates?code=APPT_1D&active=true", nil)
+	var meta envelopeMeta
+	if resp.Code != http.StatusOK || json.Unmarshal(resp.Body.Bytes(), &meta) != nil || meta.Meta.Total != 1 {
+		t.Fatalf("expected a paginated list, got %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp = performRequest(router, http.MethodPost, "/admin/notification-templates", map[string]any{"code": "APPT_1D", "locale": "en", "title": "Appointment", "body": "Tomorrow"})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.Code)
+	}
+	resp = performRequest(router, http.MethodPost, "/admin/notification-templates", map[string]any{"code": "APPT_1D", "locale": "en", "title": "Appointment"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a body, got %d", resp.Code)
+	}
+	resp = performRequest(router, http.MethodPost, "/admin/notification-templates", map[string]any{"code": "APPT_1D", "locale": "en", "title": "Appointment", "body": "{{.unknown}}"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown variable, got %d", resp.Code)
+	}
+
+	id := uuid.New().String()
+	for _, tc := range []struct {
+		method, path string
+		payload      any
+	}{
+		{http.MethodPatch, "/admin/notification-templates/" + id, map[string]any{"title": "New title"}},
+		{http.MethodPost, "/admin/notification-templates/" + id + "/deactivate", nil},
+		{http.MethodPost, "/admin/notification-templates/" + id + "/preview", map[string]any{"payload": map[string]any{"appointment_id": uuid.New().String()}}},
+		{http.MethodPost, "/admin/notification-templates/" + id + "/test-send", map[string]any{"payload": map[string]any{}}},
+		{http.MethodDelete, "/admin/notification-templates/" + id, nil},
+	} {
+		if resp := performRequest(router, tc.method, tc.path, tc.payload); resp.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d %s", tc.method, tc.path, resp.Code, resp.Body.String())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
 	"github.com/ParkPawapon/mhp-be/internal/models/db"
 	"github.com/ParkPawapon/mhp-be/internal/models/dto"
 )
@@ -18,15 +20,30 @@ type notificationServiceStub struct{}
 func (notificationServiceStub) ListUpcoming(ctx context.Context, userID string, from, to string) ([]dto.NotificationUpcomingItem, error) {
 	return []dto.NotificationUpcomingItem{{ID: uuid.New().String(), TemplateCode: "T", ScheduledAt: time.Now().UTC(), Status: "PENDING"}}, nil
 }
-func (notificationServiceStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, mealTiming *string, timeSlot time.Time) error {
+func (notificationServiceStub) ScheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
 	panic("not used")
 }
 func (notificationServiceStub) CancelMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
 	panic("not used")
 }
+func (notificationServiceStub) RestoreMedicineAfterMealReminder(ctx context.Context, userID uuid.UUID, scheduleID uuid.UUID, targetDate time.Time) error {
+	return nil
+}
+func (notificationServiceStub) RescheduleMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) error {
+	panic("not used")
+}
+func (notificationServiceStub) BuildMedicineReminders(ctx context.Context, userID uuid.UUID, schedule db.MedicineSchedule) ([]db.NotificationEvent, error) {
+	panic("not used")
+}
+func (notificationServiceStub) CancelMedicineReminders(ctx context.Context, userID uuid.UUID, scheduleIDs []uuid.UUID) error {
+	panic("not used")
+}
 func (notificationServiceStub) ScheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
 	panic("not used")
 }
+func (notificationServiceStub) RescheduleAppointmentReminders(ctx context.Context, appt *db.Appointment) error {
+	panic("not used")
+}
 func (notificationServiceStub) CancelAppointmentReminders(ctx context.Context, userID uuid.UUID, appointmentID uuid.UUID) error {
 	panic("not used")
 }
@@ -39,6 +56,38 @@ func (notificationServiceStub) ProcessDue(ctx context.Context) error {
 func (notificationServiceStub) CancelWeeklyReminders(ctx context.Context, userID uuid.UUID) error {
 	panic("not used")
 }
+func (notificationServiceStub) RescheduleWeeklyReminder(ctx context.Context, userID uuid.UUID) error {
+	panic("not used")
+}
+func (notificationServiceStub) ListFailedEvents(ctx context.Context, page, pageSize int, status, userID, templateCode string) ([]dto.NotificationEventResponse, int64, error) {
+	return []dto.NotificationEventResponse{{ID: uuid.New().String(), Status: "DEAD", Attempts: 5}}, 1, nil
+}
+func (notificationServiceStub) RequeueEvent(ctx context.Context, id string) (dto.NotificationEventResponse, error) {
+	return dto.NotificationEventResponse{ID: id, Status: "PENDING"}, nil
+}
+
+func (notificationServiceStub) ListInbox(ctx context.Context, userID uuid.UUID, cursor string, limit int, unreadOnly bool) (dto.NotificationInboxResponse, error) {
+	if cursor == "bad" {
+		return dto.NotificationInboxResponse{}, domain.NewError(constants.ValidationFailed, "invalid cursor")
+	}
+	next := "next"
+	return dto.NotificationInboxResponse{Items: []dto.NotificationInboxItem{{ID: uuid.New().String(), Title: "t", Body: "b", SentAt: time.Now().UTC()}}, NextCursor: &next, HasMore: true}, nil
+}
+func (notificationServiceStub) UnreadCount(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{UnreadCount: 3}, nil
+}
+func (notificationServiceStub) MarkRead(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{UnreadCount: 2}, nil
+}
+func (notificationServiceStub) MarkAllRead(ctx context.Context, userID uuid.UUID) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{}, nil
+}
+func (notificationServiceStub) ArchiveInboxItem(ctx context.Context, userID uuid.UUID, id string) (dto.NotificationUnreadCountResponse, error) {
+	return dto.NotificationUnreadCountResponse{UnreadCount: 2}, nil
+}
+func (notificationServiceStub) Channels() []string {
+	panic("not used")
+}
 
 func TestNotificationHandlers(t *testing.T) {
 	actorID := uuid.New()
@@ -52,3 +101,66 @@ func TestNotificationHandlers(t *testing.T) {
 		t.Fatalf("expected 200, got %d", resp.Code)
 	}
 }
+
+func TestNotificationInboxHandlers(t *testing.T) {
+	router := newTestRouter(withActor(constants.RolePatient, uuid.New()))
+	handler := NewNotificationHandler(notificationServiceStub{})
+
+	router.GET("/notifications/inbox", handler.ListInbox)
+	router.GET("/notifications/unread-count", handler.UnreadCount)
+	router.POST("/notifications/read-all", handler.MarkAllRead)
+	router.POST("/notifications/:id/read", handler.MarkRead)
+	router.DELETE("/notifications/:id", handler.Archive)
+
+	resp := performRequest(router, http.MethodGet, "/notifications/inbox?limit=10&unread=true", nil)
+	var inbox struct {
+		Data dto.NotificationInboxResponse `json:"data"`
+	}
+	if resp.Code != http.StatusOK || json.Unmarshal(resp.Body.Bytes(), &inbox) != nil || len(inbox.Data.Items) != 1 || !inbox.Data.HasMore {
+		t.Fatalf("expected an inbox page, got %d %s", resp.Code, resp.Body.String())
+	}
+	if resp := performRequest(router, http.MethodGet, "/notifications/inbox?cursor=bad", nil); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad cursor, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/notifications/unread-count", nil)
+	var count struct {
+		Data dto.NotificationUnreadCountResponse `json:"data"`
+	}
+	if resp.Code != http.StatusOK || json.Unmarshal(resp.Body.Bytes(), &count) != nil || count.Data.UnreadCount != 3 {
+		t.Fatalf("expected the unread count, got %d %s", resp.Code, resp.Body.String())
+	}
+
+	id := uuid.New().String()
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/notifications/" + id + "/read"},
+		{http.MethodPost, "/notifications/read-all"},
+		{http.MethodDelete, "/notifications/" + id},
+	} {
+		if resp := performRequest(router, tc.method, tc.path, nil); resp.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d", tc.method, tc.path, resp.Code)
+		}
+	}
+}
+
+func TestNotificationAdminHandlers(t *testing.T) {
+	router := newTestRouter(withActor(constants.RoleAdmin, uuid.New()))
+	handler := NewNotificationHandler(notificationServiceStub{})
+
+	router.GET("/admin/notifications/failed", handler.ListFailed)
+	router.POST("/admin/notifications/:id/requeue", handler.Requeue)
+
+	resp := performRequest(router, http.MethodGet, "/admin/notifications/failed?status=DEAD&page=1&page_size=10", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	var meta envelopeMeta
+	if err := json.Unmarshal(resp.Body.Bytes(), &meta); err != nil || meta.Meta.Total != 1 {
+		t.Fatalf("expected pagination meta, got %s", resp.Body.String())
+	}
+
+	resp = performRequest(router, http.MethodPost, "/admin/notifications/"+uuid.New().String()+"/requeue", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/domain"
+	"github.com/ParkPawapon/mhp-be/internal/middleware"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+	"github.com/ParkPawapon/mhp-be/internal/transport/httpx"
+)
+
+type RefillHandler struct {
+	service services.RefillService
+}
+
+func NewRefillHandler(service services.RefillService) *RefillHandler {
+	return &RefillHandler{service: service}
+}
+
+func (h *RefillHandler) Refill(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	role, _ := middleware.GetRole(c)
+
+	var req dto.RefillPatientMedicineRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.Refill(c.Request.Context(), actorID, role, c.Param("id"), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *RefillHandler) ListRefillsNeeded(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	query := dto.RefillListQuery{PatientID: c.Query("patient_id")}
+	if v := c.Query("within_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 || days > 366 {
+			httpx.Fail(c, domain.NewError(constants.ValidationFailed, "invalid within_days"))
+			return
+		}
+		query.WithinDays = &days
+	}
+
+	items, total, err := h.service.ListRefillsNeeded(c.Request.Context(), query, page, pageSize)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	meta := httpx.PaginationMeta(middleware.GetRequestID(c), page, pageSize, total)
+	c.JSON(200, httpx.SuccessResponse{Data: items, Meta: meta})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type refillServiceStub struct{}
+
+func (refillServiceStub) ConsumeDose(ctx context.Context, userID, scheduleID uuid.UUID, doses float64) error {
+	return nil
+}
+func (refillServiceStub) Refill(ctx context.Context, actorID uuid.UUID, role constants.Role, id string, req dto.RefillPatientMedicineRequest) (dto.PatientMedicineResponse, error) {
+	return dto.PatientMedicineResponse{ID: id}, nil
+}
+func (refillServiceStub) ListRefillsNeeded(ctx context.Context, query dto.RefillListQuery, page, pageSize int) ([]dto.PatientRefillResponse, int64, error) {
+	return []dto.PatientRefillResponse{{UserID: uuid.New().String()}}, 1, nil
+}
+
+func TestRefillHandlers(t *testing.T) {
+	router := newTestRouter()
+	handler := NewRefillHandler(refillServiceStub{})
+
+	router.POST("/medicines/patient/:id/refill", handler.Refill)
+	router.GET("/admin/refills", handler.ListRefillsNeeded)
+
+	packs := 2.0
+	resp := performRequest(router, http.MethodPost, "/medicines/patient/"+uuid.New().String()+"/refill", dto.RefillPatientMedicineRequest{Packs: &packs})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("refill expected 200, got %d", resp.Code)
+	}
+
+	packs = 0
+	resp = performRequest(router, http.MethodPost, "/medicines/patient/"+uuid.New().String()+"/refill", dto.RefillPatientMedicineRequest{Packs: &packs})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("zero packs expected 400, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/admin/refills?within_days=14&page=1&page_size=20", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("list refills expected 200, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/admin/refills?within_days=0", nil)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("invalid within_days expected 400, got %d", resp.Code)
+	}
+}
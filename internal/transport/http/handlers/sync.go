@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ParkPawapon/mhp-be/internal/middleware"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+	"github.com/ParkPawapon/mhp-be/internal/services"
+	"github.com/ParkPawapon/mhp-be/internal/transport/httpx"
+)
+
+type SyncHandler struct {
+	service services.SyncService
+}
+
+func NewSyncHandler(service services.SyncService) *SyncHandler {
+	return &SyncHandler{service: service}
+}
+
+func (h *SyncHandler) ApplyBatch(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	var req dto.SyncBatchRequest
+	if err := bindAndValidateJSON(c, &req); err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+
+	resp, err := h.service.ApplyBatch(c.Request.Context(), actorID.String(), req)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
+func (h *SyncHandler) ListChanges(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	resp, err := h.service.ListChanges(c.Request.Context(), actorID.String(), c.Query("since"), limit)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ParkPawapon/mhp-be/internal/constants"
+	"github.com/ParkPawapon/mhp-be/internal/models/dto"
+)
+
+type syncServiceStub struct {
+	userID string
+	since  string
+	limit  int
+}
+
+func (s *syncServiceStub) ApplyBatch(ctx context.Context, userID string, req dto.SyncBatchRequest) (dto.SyncBatchResponse, error) {
+	s.userID = userID
+	results := make([]dto.SyncMutationResult, 0, len(req.Items))
+	for _, item := range req.Items {
+		results = append(results, dto.SyncMutationResult{ClientID: item.ClientID, Status: constants.SyncApplied})
+	}
+	return dto.SyncBatchResponse{Results: results}, nil
+}
+func (s *syncServiceStub) ListChanges(ctx context.Context, userID string, since string, limit int) (dto.SyncChangesResponse, error) {
+	s.userID, s.since, s.limit = userID, since, limit
+	return dto.SyncChangesResponse{Changes: []dto.SyncChangeItem{}, NextCursor: since}, nil
+}
+
+func TestSyncHandlers(t *testing.T) {
+	actorID := uuid.New()
+	router := newTestRouter(withActor(constants.RolePatient, actorID))
+	stub := &syncServiceStub{}
+	handler := NewSyncHandler(stub)
+
+	router.POST("/sync/batch", handler.ApplyBatch)
+	router.GET("/sync/changes", handler.ListChanges)
+
+	payload := dto.SyncBatchRequest{Items: []dto.SyncMutationRequest{{
+		ClientID:        uuid.NewString(),
+		Type:            constants.SyncEntityIntake,
+		ClientTimestamp: "2026-01-20T01:05:00Z",
+		Data:            json.RawMessage(`{"target_date":"2026-01-20","status":"TAKEN"}`),
+	}}}
+	resp := performRequest(router, http.MethodPost, "/sync/batch", payload)
+	if resp.Code != http.StatusOK || stub.userID != actorID.String() {
+		t.Fatalf("expected 200 for actor, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodPost, "/sync/batch", dto.SyncBatchRequest{})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty batch, got %d", resp.Code)
+	}
+
+	resp = performRequest(router, http.MethodGet, "/sync/changes?since=42&limit=10", nil)
+	if resp.Code != http.StatusOK || stub.since != "42" || stub.limit != 10 {
+		t.Fatalf("expected cursor passed through, got %d since=%s limit=%d", resp.Code, stub.since, stub.limit)
+	}
+}
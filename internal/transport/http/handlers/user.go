@@ -61,6 +61,17 @@ func (h *UserHandler) SaveDeviceToken(c *gin.Context) {
 	httpx.OK(c, gin.H{"saved": true})
 }
 
+func (h *UserHandler) GetPreferences(c *gin.Context) {
+	actorID, _ := middleware.GetActorID(c)
+
+	resp, err := h.users.GetPreferences(c.Request.Context(), actorID)
+	if err != nil {
+		httpx.Fail(c, err)
+		return
+	}
+	httpx.OK(c, resp)
+}
+
 func (h *UserHandler) UpdatePreferences(c *gin.Context) {
 	actorID, _ := middleware.GetActorID(c)
 
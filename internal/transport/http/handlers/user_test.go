@@ -24,6 +24,9 @@ func (userServiceStub) UpdateProfile(ctx context.Context, actorID uuid.UUID, req
 func (userServiceStub) SaveDeviceToken(ctx context.Context, actorID uuid.UUID, req dto.DeviceTokenRequest) error {
 	return nil
 }
+func (userServiceStub) GetPreferences(ctx context.Context, actorID uuid.UUID) (dto.PreferencesResponse, error) {
+	return dto.PreferencesResponse{WeeklyReminderEnabled: true}, nil
+}
 func (userServiceStub) UpdatePreferences(ctx context.Context, actorID uuid.UUID, req dto.UpdatePreferencesRequest) (dto.PreferencesResponse, error) {
 	return dto.PreferencesResponse{WeeklyReminderEnabled: true}, nil
 }
@@ -35,6 +38,7 @@ func TestUserHandlers(t *testing.T) {
 
 	router.GET("/me", handler.Me)
 	router.PATCH("/me/profile", handler.UpdateProfile)
+	router.GET("/me/preferences", handler.GetPreferences)
 	router.PATCH("/me/preferences", handler.UpdatePreferences)
 	router.POST("/me/device-tokens", handler.SaveDeviceToken)
 
@@ -63,6 +67,11 @@ func TestUserHandlers(t *testing.T) {
 		t.Fatalf("expected 200, got %d", resp.Code)
 	}
 
+	resp = performRequest(router, http.MethodGet, "/me/preferences", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+
 	resp = performRequest(router, http.MethodPost, "/me/device-tokens", dto.DeviceTokenRequest{Platform: "ios", DeviceToken: "token"})
 	if resp.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", resp.Code)
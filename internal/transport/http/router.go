@@ -21,22 +21,28 @@ import (
 )
 
 type Dependencies struct {
-	Config              config.Config
-	Logger              *zap.Logger
-	DB                  *gorm.DB
-	Redis               *redis.Client
-	AuthService         services.AuthService
-	UserService         services.UserService
-	CaregiverService    services.CaregiverService
-	MedicineService     services.MedicineService
-	IntakeService       services.IntakeService
-	HealthService       services.HealthService
-	AppointmentService  services.AppointmentService
-	ContentService      services.ContentService
-	NotificationService services.NotificationService
-	SupportService      services.SupportService
-	AdminService        services.AdminService
-	AuditService        services.AuditService
+	Config                      config.Config
+	Logger                      *zap.Logger
+	DB                          *gorm.DB
+	Redis                       *redis.Client
+	AuthService                 services.AuthService
+	UserService                 services.UserService
+	CaregiverService            services.CaregiverService
+	MedicineService             services.MedicineService
+	IntakeService               services.IntakeService
+	AdherenceService            services.AdherenceService
+	HealthService               services.HealthService
+	AppointmentService          services.AppointmentService
+	ContentService              services.ContentService
+	NotificationService         services.NotificationService
+	NotificationTemplateService services.NotificationTemplateService
+	SupportService              services.SupportService
+	AdminService                services.AdminService
+	AuditService                services.AuditService
+	LineService                 services.LineService
+	SyncService                 services.SyncService
+	EscalationService           services.EscalationService
+	RefillService               services.RefillService
 }
 
 func NewRouter(deps Dependencies) *gin.Engine {
@@ -46,6 +52,7 @@ func NewRouter(deps Dependencies) *gin.Engine {
 
 	r := gin.New()
 	r.Use(middleware.RequestID())
+	r.Use(middleware.Audit(deps.AuditService))
 	r.Use(middleware.Logging(deps.Logger))
 	r.Use(middleware.Recovery(deps.Logger))
 	r.Use(cors.New(cors.Config{
@@ -61,13 +68,19 @@ func NewRouter(deps Dependencies) *gin.Engine {
 	caregiverHandler := handlers.NewCaregiverHandler(deps.CaregiverService)
 	medicineHandler := handlers.NewMedicineHandler(deps.MedicineService)
 	intakeHandler := handlers.NewIntakeHandler(deps.IntakeService, deps.CaregiverService)
+	syncHandler := handlers.NewSyncHandler(deps.SyncService)
+	adherenceHandler := handlers.NewAdherenceHandler(deps.AdherenceService, deps.CaregiverService)
 	healthRecordHandler := handlers.NewHealthRecordsHandler(deps.HealthService, deps.CaregiverService)
 	appointmentHandler := handlers.NewAppointmentHandler(deps.AppointmentService, deps.CaregiverService)
 	contentHandler := handlers.NewContentHandler(deps.ContentService)
 	notificationHandler := handlers.NewNotificationHandler(deps.NotificationService)
+	templateHandler := handlers.NewNotificationTemplateHandler(deps.NotificationTemplateService)
 	supportHandler := handlers.NewSupportHandler(deps.SupportService)
 	adminHandler := handlers.NewAdminHandler(deps.AdminService)
+	escalationHandler := handlers.NewEscalationHandler(deps.EscalationService)
+	refillHandler := handlers.NewRefillHandler(deps.RefillService)
 	auditHandler := handlers.NewAuditHandler(deps.AuditService)
+	lineHandler := handlers.NewLineHandler(deps.LineService)
 
 	r.GET("/healthz", healthHandler.Healthz)
 	r.GET("/readyz", healthHandler.Readyz)
@@ -98,8 +111,17 @@ func NewRouter(deps Dependencies) *gin.Engine {
 		{
 			me.GET("", userHandler.Me)
 			me.PATCH("/profile", userHandler.UpdateProfile)
+			me.GET("/preferences", userHandler.GetPreferences)
 			me.PATCH("/preferences", userHandler.UpdatePreferences)
 			me.POST("/device-tokens", userHandler.SaveDeviceToken)
+			me.GET("/line/link-url", lineHandler.LinkURL)
+			me.POST("/line/link", lineHandler.Link)
+			me.DELETE("/line/link", lineHandler.Unlink)
+		}
+
+		line := api.Group("/line")
+		{
+			line.POST("/webhook", lineHandler.Webhook)
 		}
 
 		caregivers := api.Group("/caregivers")
@@ -123,7 +145,9 @@ func NewRouter(deps Dependencies) *gin.Engine {
 			medicines.GET("/patient", medicineHandler.ListPatientMedicines)
 			medicines.PATCH("/patient/:id", medicineHandler.UpdatePatientMedicine)
 			medicines.DELETE("/patient/:id", medicineHandler.DeletePatientMedicine)
+			medicines.POST("/patient/:id/refill", refillHandler.Refill)
 			medicines.POST("/patient/:id/schedules", medicineHandler.CreateSchedule)
+			medicines.PATCH("/schedules/:id", medicineHandler.UpdateSchedule)
 			medicines.DELETE("/schedules/:id", medicineHandler.DeleteSchedule)
 		}
 
@@ -132,6 +156,19 @@ func NewRouter(deps Dependencies) *gin.Engine {
 		{
 			intake.POST("", middleware.RequireRoles(constants.RolePatient, constants.RoleNurse, constants.RoleAdmin), intakeHandler.CreateIntake)
 			intake.GET("/history", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), intakeHandler.ListHistory)
+			intake.GET("/today", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), intakeHandler.GetToday)
+			intake.PATCH("/:id", middleware.RequireRoles(constants.RolePatient), intakeHandler.UpdateIntake)
+			intake.DELETE("/:id", middleware.RequireRoles(constants.RolePatient), intakeHandler.DeleteIntake)
+			intake.GET("/:id/changes", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), intakeHandler.ListChanges)
+			intake.GET("/adherence", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), adherenceHandler.GetSummary)
+		}
+
+		sync := api.Group("/sync")
+		sync.Use(middleware.RequireAuth(deps.Config.JWT))
+		sync.Use(middleware.RequireRoles(constants.RolePatient))
+		{
+			sync.POST("/batch", syncHandler.ApplyBatch)
+			sync.GET("/changes", syncHandler.ListChanges)
 		}
 
 		health := api.Group("/health")
@@ -178,6 +215,11 @@ func NewRouter(deps Dependencies) *gin.Engine {
 		notifications.Use(middleware.RequireAuth(deps.Config.JWT))
 		{
 			notifications.GET("/upcoming", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), notificationHandler.ListUpcoming)
+			notifications.GET("/inbox", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), notificationHandler.ListInbox)
+			notifications.GET("/unread-count", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), notificationHandler.UnreadCount)
+			notifications.POST("/read-all", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), notificationHandler.MarkAllRead)
+			notifications.POST("/:id/read", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), notificationHandler.MarkRead)
+			notifications.DELETE("/:id", middleware.RequireRoles(constants.RolePatient, constants.RoleCaregiver, constants.RoleNurse, constants.RoleAdmin), notificationHandler.Archive)
 		}
 
 		support := api.Group("/support")
@@ -196,12 +238,26 @@ func NewRouter(deps Dependencies) *gin.Engine {
 
 		admin := api.Group("/admin")
 		admin.Use(middleware.RequireAuth(deps.Config.JWT))
-		admin.Use(middleware.RequireRoles(constants.RoleAdmin))
 		{
-			admin.GET("/patients", adminHandler.ListPatients)
-			admin.GET("/patients/:id", adminHandler.GetPatient)
-			admin.GET("/adherence", adminHandler.ListAdherence)
-			admin.GET("/audit-logs", auditHandler.ListAuditLogs)
+			admin.GET("/patients", middleware.RequireRoles(constants.RoleNurse, constants.RoleAdmin), adminHandler.ListPatients)
+			admin.GET("/patients/:id", middleware.RequireRoles(constants.RoleNurse, constants.RoleAdmin), adminHandler.GetPatient)
+			admin.GET("/patients/:id/escalation-policy", middleware.RequireRoles(constants.RoleNurse, constants.RoleAdmin), escalationHandler.GetPolicy)
+			admin.PUT("/patients/:id/escalation-policy", middleware.RequireRoles(constants.RoleNurse, constants.RoleAdmin), escalationHandler.UpdatePolicy)
+			admin.GET("/adherence", middleware.RequireRoles(constants.RoleNurse, constants.RoleAdmin), adminHandler.ListAdherence)
+			admin.GET("/intake/timing", middleware.RequireRoles(constants.RoleNurse, constants.RoleAdmin), adminHandler.ListDoseTimings)
+			admin.GET("/refills", middleware.RequireRoles(constants.RoleNurse, constants.RoleAdmin), refillHandler.ListRefillsNeeded)
+			admin.GET("/audit-logs", middleware.RequireRoles(constants.RoleAdmin), auditHandler.ListAuditLogs)
+			admin.GET("/notifications/failed", middleware.RequireRoles(constants.RoleAdmin), notificationHandler.ListFailed)
+			admin.POST("/notifications/:id/requeue", middleware.RequireRoles(constants.RoleAdmin), notificationHandler.Requeue)
+			admin.GET("/notification-templates", middleware.RequireRoles(constants.RoleAdmin), templateHandler.List)
+			admin.POST("/notification-templates", middleware.RequireRoles(constants.RoleAdmin), templateHandler.Create)
+			admin.GET("/notification-templates/:id", middleware.RequireRoles(constants.RoleAdmin), templateHandler.Get)
+			admin.PATCH("/notification-templates/:id", middleware.RequireRoles(constants.RoleAdmin), templateHandler.Update)
+			admin.DELETE("/notification-templates/:id", middleware.RequireRoles(constants.RoleAdmin), templateHandler.Delete)
+			admin.POST("/notification-templates/:id/activate", middleware.RequireRoles(constants.RoleAdmin), templateHandler.Activate)
+			admin.POST("/notification-templates/:id/deactivate", middleware.RequireRoles(constants.RoleAdmin), templateHandler.Deactivate)
+			admin.POST("/notification-templates/:id/preview", middleware.RequireRoles(constants.RoleAdmin), templateHandler.Preview)
+			admin.POST("/notification-templates/:id/test-send", middleware.RequireRoles(constants.RoleAdmin), templateHandler.TestSend)
 		}
 	}
 
@@ -48,13 +48,15 @@ func statusFromCode(code string) int {
 		return http.StatusUnauthorized
 	case constants.AuthOTPExpired, constants.AuthOTPInvalid, constants.AuthOTPUsed:
 		return http.StatusBadRequest
-	case constants.UserConflict:
+	case constants.UserConflict, constants.HealthConflict, constants.IntakeConflict, constants.IntakeCorrectionClosed, constants.NotificationConflict:
 		return http.StatusConflict
+	case constants.AuthAccountLocked:
+		return http.StatusLocked
 	case constants.RateLimited:
 		return http.StatusTooManyRequests
-	case constants.ValidationFailed, constants.MedInvalid, constants.ApptInvalid, constants.HealthInvalid, constants.ContentInvalid:
+	case constants.ValidationFailed, constants.MedInvalid, constants.ApptInvalid, constants.HealthInvalid, constants.ContentInvalid, constants.NotificationInvalid:
 		return http.StatusBadRequest
-	case constants.UserNotFound, constants.MedNotFound, constants.ApptNotFound, constants.HealthNotFound, constants.ContentNotFound:
+	case constants.UserNotFound, constants.MedNotFound, constants.IntakeNotFound, constants.ApptNotFound, constants.HealthNotFound, constants.ContentNotFound, constants.NotificationNotFound:
 		return http.StatusNotFound
 	case constants.InternalNotImplemented:
 		return http.StatusNotImplemented
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_health_records_user_record_date;
+DROP INDEX IF EXISTS uq_daily_assessments_user_log_date;
@@ -0,0 +1,5 @@
+CREATE UNIQUE INDEX IF NOT EXISTS uq_daily_assessments_user_log_date
+    ON daily_assessments(user_id, log_date);
+
+CREATE INDEX IF NOT EXISTS idx_health_records_user_record_date
+    ON health_records(user_id, record_date);
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_notification_events_status_next_attempt_at;
+
+ALTER TABLE notification_events
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS next_attempt_at,
+    DROP COLUMN IF EXISTS attempts;
+
+UPDATE notification_events SET status = 'FAILED' WHERE status = 'DEAD';
+
+ALTER TYPE notification_status RENAME TO notification_status_old;
+CREATE TYPE notification_status AS ENUM ('PENDING', 'SENT', 'CANCELLED', 'FAILED');
+ALTER TABLE notification_events ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE notification_events
+    ALTER COLUMN status TYPE notification_status USING status::text::notification_status;
+ALTER TABLE notification_events ALTER COLUMN status SET DEFAULT 'PENDING';
+DROP TYPE notification_status_old;
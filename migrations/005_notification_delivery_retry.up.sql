@@ -0,0 +1,9 @@
+ALTER TYPE notification_status ADD VALUE IF NOT EXISTS 'DEAD';
+
+ALTER TABLE notification_events
+    ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_notification_events_status_next_attempt_at
+    ON notification_events(status, next_attempt_at);
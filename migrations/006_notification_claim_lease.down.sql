@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_notification_events_status_locked_until;
+
+ALTER TABLE notification_events
+    DROP COLUMN IF EXISTS locked_until;
+
+UPDATE notification_events SET status = 'PENDING' WHERE status = 'PROCESSING';
+
+ALTER TYPE notification_status RENAME TO notification_status_old;
+CREATE TYPE notification_status AS ENUM ('PENDING', 'SENT', 'CANCELLED', 'FAILED', 'DEAD');
+ALTER TABLE notification_events ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE notification_events
+    ALTER COLUMN status TYPE notification_status USING status::text::notification_status;
+ALTER TABLE notification_events ALTER COLUMN status SET DEFAULT 'PENDING';
+DROP TYPE notification_status_old;
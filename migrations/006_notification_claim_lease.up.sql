@@ -0,0 +1,7 @@
+ALTER TYPE notification_status ADD VALUE IF NOT EXISTS 'PROCESSING';
+
+ALTER TABLE notification_events
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_notification_events_status_locked_until
+    ON notification_events(status, locked_until);
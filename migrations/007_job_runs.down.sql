@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_job_runs_job_name_started_at;
+DROP TABLE IF EXISTS job_runs;
+DROP TYPE IF EXISTS job_run_status;
@@ -0,0 +1,10 @@
+ALTER TABLE medicine_schedules
+    DROP CONSTRAINT IF EXISTS chk_medicine_schedules_date_range,
+    DROP CONSTRAINT IF EXISTS chk_medicine_schedules_weekday_mask,
+    DROP CONSTRAINT IF EXISTS chk_medicine_schedules_interval_days;
+
+ALTER TABLE medicine_schedules
+    DROP COLUMN IF EXISTS weekday_mask,
+    DROP COLUMN IF EXISTS interval_days,
+    DROP COLUMN IF EXISTS end_date,
+    DROP COLUMN IF EXISTS start_date;
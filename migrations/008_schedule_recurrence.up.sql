@@ -0,0 +1,16 @@
+ALTER TABLE medicine_schedules
+    ADD COLUMN IF NOT EXISTS start_date DATE,
+    ADD COLUMN IF NOT EXISTS end_date DATE,
+    ADD COLUMN IF NOT EXISTS interval_days INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS weekday_mask SMALLINT NOT NULL DEFAULT 127;
+
+UPDATE medicine_schedules SET start_date = created_at::date WHERE start_date IS NULL;
+
+ALTER TABLE medicine_schedules
+    ALTER COLUMN start_date SET DEFAULT CURRENT_DATE,
+    ALTER COLUMN start_date SET NOT NULL;
+
+ALTER TABLE medicine_schedules
+    ADD CONSTRAINT chk_medicine_schedules_interval_days CHECK (interval_days BETWEEN 1 AND 365),
+    ADD CONSTRAINT chk_medicine_schedules_weekday_mask CHECK (weekday_mask BETWEEN 1 AND 127),
+    ADD CONSTRAINT chk_medicine_schedules_date_range CHECK (end_date IS NULL OR end_date >= start_date);
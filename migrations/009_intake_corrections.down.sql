@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS ux_intake_history_dose;
+
+DROP TABLE IF EXISTS intake_history_changes;
+
+ALTER TABLE intake_history
+    DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,44 @@
+ALTER TABLE intake_history
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+UPDATE intake_history SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE intake_history
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS intake_history_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    intake_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL,
+    previous_status med_intake_status,
+    new_status med_intake_status,
+    previous_taken_at TIMESTAMPTZ,
+    new_taken_at TIMESTAMPTZ,
+    previous_skip_reason TEXT,
+    new_skip_reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_intake_history_changes_intake_id ON intake_history_changes(intake_id, created_at);
+
+-- Collapse duplicate doses to their latest record; the dropped rows are kept in the change log.
+WITH ranked AS (
+    SELECT id,
+           ROW_NUMBER() OVER (PARTITION BY user_id, schedule_id, target_date ORDER BY created_at DESC, id DESC) AS rn
+    FROM intake_history
+    WHERE schedule_id IS NOT NULL
+), removed AS (
+    DELETE FROM intake_history AS ih
+    USING ranked
+    WHERE ih.id = ranked.id AND ranked.rn > 1
+    RETURNING ih.id, ih.user_id, ih.status, ih.taken_at, ih.skip_reason
+)
+INSERT INTO intake_history_changes (intake_id, user_id, action, previous_status, previous_taken_at, previous_skip_reason)
+SELECT id, user_id, 'DELETE', status, taken_at, skip_reason FROM removed;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_intake_history_dose
+    ON intake_history(user_id, schedule_id, target_date)
+    WHERE schedule_id IS NOT NULL;
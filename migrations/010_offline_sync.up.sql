@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS sync_mutations (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id UUID NOT NULL,
+    mutation_type VARCHAR(30) NOT NULL,
+    client_timestamp TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    entity_id UUID,
+    error_code VARCHAR(50),
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, client_id)
+);
+
+CREATE TABLE IF NOT EXISTS sync_changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id UUID NOT NULL,
+    operation VARCHAR(10) NOT NULL,
+    payload JSONB,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_user_id_id ON sync_changes(user_id, id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_intake_history_timing;
+
+ALTER TABLE intake_history
+    DROP CONSTRAINT IF EXISTS ck_intake_history_timing,
+    DROP COLUMN IF EXISTS timing,
+    DROP COLUMN IF EXISTS due_at;
@@ -0,0 +1,8 @@
+ALTER TABLE intake_history
+    ADD COLUMN IF NOT EXISTS due_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS timing VARCHAR(10);
+
+ALTER TABLE intake_history
+    ADD CONSTRAINT ck_intake_history_timing CHECK (timing IN ('ON_TIME', 'LATE', 'EARLY'));
+
+CREATE INDEX IF NOT EXISTS idx_intake_history_timing ON intake_history(timing, target_date) WHERE timing IS NOT NULL;
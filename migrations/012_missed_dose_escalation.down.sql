@@ -0,0 +1,5 @@
+DELETE FROM notification_templates WHERE code IN ('ESCALATION_MISSED_CAREGIVER', 'ESCALATION_MISSED_NURSE');
+
+DROP TABLE IF EXISTS missed_dose_escalations;
+
+DROP TABLE IF EXISTS escalation_policies;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS escalation_policies (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    caregiver_threshold INT CHECK (caregiver_threshold BETWEEN 1 AND 50),
+    nurse_threshold INT CHECK (nurse_threshold BETWEEN 1 AND 50),
+    nurse_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS missed_dose_escalations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    level VARCHAR(20) NOT NULL,
+    missed_count INT NOT NULL,
+    first_missed_at TIMESTAMPTZ NOT NULL,
+    last_missed_at TIMESTAMPTZ NOT NULL,
+    recipient_count INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_missed_dose_escalations_user_level
+    ON missed_dose_escalations(user_id, level, created_at DESC);
+
+INSERT INTO notification_templates (code, title, body)
+VALUES
+    ('ESCALATION_MISSED_CAREGIVER', 'Missed medicine doses', 'A patient you care for has missed medicine doses. Please check in with them.'),
+    ('ESCALATION_MISSED_NURSE', 'Patient missing consecutive doses', 'A patient has missed several consecutive medicine doses and may need follow-up.')
+ON CONFLICT (code) DO NOTHING;
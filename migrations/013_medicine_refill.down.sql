@@ -0,0 +1,11 @@
+DELETE FROM notification_templates WHERE code = 'MED_LOW_SUPPLY';
+
+DROP INDEX IF EXISTS idx_patient_medicines_stock;
+
+ALTER TABLE patient_medicines
+    DROP CONSTRAINT IF EXISTS ck_patient_medicines_pack_size,
+    DROP CONSTRAINT IF EXISTS ck_patient_medicines_quantity_on_hand,
+    DROP COLUMN IF EXISTS low_supply_notified_at,
+    DROP COLUMN IF EXISTS refill_date,
+    DROP COLUMN IF EXISTS pack_size,
+    DROP COLUMN IF EXISTS quantity_on_hand;
@@ -0,0 +1,16 @@
+ALTER TABLE patient_medicines
+    ADD COLUMN IF NOT EXISTS quantity_on_hand NUMERIC(10, 2),
+    ADD COLUMN IF NOT EXISTS pack_size NUMERIC(10, 2),
+    ADD COLUMN IF NOT EXISTS refill_date DATE,
+    ADD COLUMN IF NOT EXISTS low_supply_notified_at TIMESTAMPTZ;
+
+ALTER TABLE patient_medicines
+    ADD CONSTRAINT ck_patient_medicines_quantity_on_hand CHECK (quantity_on_hand >= 0),
+    ADD CONSTRAINT ck_patient_medicines_pack_size CHECK (pack_size > 0);
+
+CREATE INDEX IF NOT EXISTS idx_patient_medicines_stock ON patient_medicines(user_id)
+    WHERE quantity_on_hand IS NOT NULL AND deleted_at IS NULL;
+
+INSERT INTO notification_templates (code, title, body)
+VALUES ('MED_LOW_SUPPLY', 'Medicine running low', 'Your medicine is running low. Please arrange a refill before it runs out.')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,6 @@
+-- NULL keeps the user on NOTIFICATION_TIMEZONE, the zone every event queued
+-- before this migration was planned in, so pending events stay valid. Setting
+-- a timezone through PATCH /me/preferences re-plans that user's pending
+-- medicine and weekly reminders.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
@@ -0,0 +1,12 @@
+DELETE FROM notification_templates WHERE code = 'APPT_REMINDER';
+
+ALTER TABLE user_preferences
+    DROP CONSTRAINT IF EXISTS ck_user_preferences_before_meal_follow_up,
+    DROP CONSTRAINT IF EXISTS ck_user_preferences_before_meal_lead,
+    DROP CONSTRAINT IF EXISTS ck_user_preferences_quiet_hours,
+    DROP COLUMN IF EXISTS before_meal_follow_up_minutes,
+    DROP COLUMN IF EXISTS before_meal_lead_minutes,
+    DROP COLUMN IF EXISTS appointment_reminder_days,
+    DROP COLUMN IF EXISTS quiet_hours_end,
+    DROP COLUMN IF EXISTS quiet_hours_start,
+    DROP COLUMN IF EXISTS notification_channels;
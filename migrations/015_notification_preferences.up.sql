@@ -0,0 +1,20 @@
+-- NULL columns keep the defaults: push and LINE, no quiet hours, appointment
+-- reminders 5 and 1 days ahead, and before-meal reminders 5 minutes before and
+-- 20 minutes after the dose time.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS notification_channels JSONB,
+    ADD COLUMN IF NOT EXISTS quiet_hours_start TIME,
+    ADD COLUMN IF NOT EXISTS quiet_hours_end TIME,
+    ADD COLUMN IF NOT EXISTS appointment_reminder_days JSONB,
+    ADD COLUMN IF NOT EXISTS before_meal_lead_minutes INT,
+    ADD COLUMN IF NOT EXISTS before_meal_follow_up_minutes INT;
+
+ALTER TABLE user_preferences
+    ADD CONSTRAINT ck_user_preferences_quiet_hours CHECK ((quiet_hours_start IS NULL) = (quiet_hours_end IS NULL)),
+    ADD CONSTRAINT ck_user_preferences_before_meal_lead CHECK (before_meal_lead_minutes BETWEEN 0 AND 60),
+    ADD CONSTRAINT ck_user_preferences_before_meal_follow_up CHECK (before_meal_follow_up_minutes BETWEEN 1 AND 120);
+
+-- Appointment reminders at lead times other than 5 and 1 days.
+INSERT INTO notification_templates (code, title, body)
+VALUES ('APPT_REMINDER', 'Upcoming hospital appointment', 'You have a hospital appointment in {{days_before}} days.')
+ON CONFLICT (code) DO NOTHING;
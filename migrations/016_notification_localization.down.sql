@@ -0,0 +1,29 @@
+ALTER TABLE user_preferences
+    DROP CONSTRAINT IF EXISTS ck_user_preferences_language,
+    DROP COLUMN IF EXISTS language;
+
+DELETE FROM notification_templates WHERE locale <> 'en';
+
+UPDATE notification_templates AS t
+SET body = v.old_body
+FROM (VALUES
+    ('MED_BEFORE_MEAL_5MIN', 'Reminder: take your medicine before meal in 5 minutes.', 'Take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} at {{time .dose_at}}.'),
+    ('MED_BEFORE_MEAL_20MIN', 'Reminder: please take your medicine if you have not yet.', 'Have you taken {{.medicine_name}} {{.dosage}}? It was due at {{time .dose_at}}.'),
+    ('MED_AFTER_MEAL_NOW', 'หลังทานอาหารอย่าลืมทานยา', 'Time to take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}}.'),
+    ('APPT_5D', 'Upcoming appointment in 5 days.', '{{.appointment_title}} on {{date .appointment_at}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('APPT_1D', 'Upcoming appointment tomorrow.', 'Tomorrow: {{.appointment_title}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('APPT_REMINDER', 'You have a hospital appointment in {{.days_before}} days.', 'In {{.days_before}} days: {{.appointment_title}} on {{date .appointment_at}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('MED_LOW_SUPPLY', 'Your medicine is running low. Please arrange a refill before it runs out.', '{{.medicine_name}} runs out around {{date .run_out_date}} ({{.days_remaining}} days left). Please arrange a refill.')
+) AS v(code, old_body, new_body)
+WHERE t.code = v.code AND t.body = v.new_body;
+
+UPDATE notification_templates
+SET title = regexp_replace(title, '\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}', '{{\1}}', 'g'),
+    body = regexp_replace(body, '\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}', '{{\1}}', 'g');
+
+ALTER TABLE notification_templates
+    DROP CONSTRAINT IF EXISTS uq_notification_templates_code_locale,
+    ADD CONSTRAINT notification_templates_code_key UNIQUE (code);
+
+ALTER TABLE notification_templates
+    DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,54 @@
+-- Templates are kept per locale. Existing rows are English; every code gets a
+-- Thai row below and NOTIFICATION_DEFAULT_LOCALE picks the language of users
+-- who did not choose one.
+ALTER TABLE notification_templates
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'en';
+
+ALTER TABLE notification_templates
+    DROP CONSTRAINT IF EXISTS notification_templates_code_key,
+    ADD CONSTRAINT uq_notification_templates_code_locale UNIQUE (code, locale);
+
+-- Titles and bodies are now Go text/template: {{key}} becomes {{.key}}.
+UPDATE notification_templates
+SET title = regexp_replace(title, '\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}', '{{.\1}}', 'g'),
+    body = regexp_replace(body, '\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}', '{{.\1}}', 'g');
+
+-- Seeded wording that nobody edited moves to the variables the renderer
+-- resolves; edited templates are left alone.
+UPDATE notification_templates AS t
+SET body = v.new_body
+FROM (VALUES
+    ('MED_BEFORE_MEAL_5MIN', 'Reminder: take your medicine before meal in 5 minutes.', 'Take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} at {{time .dose_at}}.'),
+    ('MED_BEFORE_MEAL_20MIN', 'Reminder: please take your medicine if you have not yet.', 'Have you taken {{.medicine_name}} {{.dosage}}? It was due at {{time .dose_at}}.'),
+    ('MED_AFTER_MEAL_NOW', 'หลังทานอาหารอย่าลืมทานยา', 'Time to take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}}.'),
+    ('APPT_5D', 'Upcoming appointment in 5 days.', '{{.appointment_title}} on {{date .appointment_at}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('APPT_1D', 'Upcoming appointment tomorrow.', 'Tomorrow: {{.appointment_title}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('APPT_REMINDER', 'You have a hospital appointment in {{.days_before}} days.', 'In {{.days_before}} days: {{.appointment_title}} on {{date .appointment_at}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('MED_LOW_SUPPLY', 'Your medicine is running low. Please arrange a refill before it runs out.', '{{.medicine_name}} runs out around {{date .run_out_date}} ({{.days_remaining}} days left). Please arrange a refill.')
+) AS v(code, old_body, new_body)
+WHERE t.code = v.code AND t.locale = 'en' AND t.body = v.old_body;
+
+INSERT INTO notification_templates (code, locale, title, body)
+VALUES
+    ('MED_BEFORE_MEAL_5MIN', 'en', 'Medicine reminder', 'Take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} at {{time .dose_at}}.'),
+    ('MED_BEFORE_MEAL_20MIN', 'en', 'Medicine reminder', 'Have you taken {{.medicine_name}} {{.dosage}}? It was due at {{time .dose_at}}.'),
+    ('MED_AFTER_MEAL_NOW', 'en', 'Medicine reminder', 'Time to take {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}}.'),
+    ('APPT_5D', 'en', 'Appointment reminder', '{{.appointment_title}} on {{date .appointment_at}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('APPT_1D', 'en', 'Appointment reminder', 'Tomorrow: {{.appointment_title}} at {{time .appointment_at}}{{with .appointment_location}}, {{.}}{{end}}.'),
+    ('WEEKLY_HEALTH_LOG', 'en', 'Weekly health log', 'Please complete your weekly health behavior log.'),
+    ('MED_BEFORE_MEAL_5MIN', 'th', 'เตือนทานยา', 'เตรียมทานยา {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}} เวลา {{time .dose_at}}'),
+    ('MED_BEFORE_MEAL_20MIN', 'th', 'เตือนทานยา', 'ทานยา {{.medicine_name}} {{.dosage}} ของเวลา {{time .dose_at}} แล้วหรือยัง'),
+    ('MED_AFTER_MEAL_NOW', 'th', 'เตือนทานยา', 'ถึงเวลาทานยา {{.medicine_name}} {{.dosage}}{{with .meal_timing}} {{.}}{{end}}'),
+    ('APPT_5D', 'th', 'เตือนนัดหมาย', 'อีก 5 วันมีนัด {{.appointment_title}} วันที่ {{date .appointment_at}} เวลา {{time .appointment_at}}{{with .appointment_location}} ที่ {{.}}{{end}}'),
+    ('APPT_1D', 'th', 'เตือนนัดหมาย', 'พรุ่งนี้มีนัด {{.appointment_title}} เวลา {{time .appointment_at}}{{with .appointment_location}} ที่ {{.}}{{end}}'),
+    ('APPT_REMINDER', 'th', 'เตือนนัดหมาย', 'อีก {{.days_before}} วันมีนัด {{.appointment_title}} วันที่ {{date .appointment_at}} เวลา {{time .appointment_at}}{{with .appointment_location}} ที่ {{.}}{{end}}'),
+    ('WEEKLY_HEALTH_LOG', 'th', 'บันทึกสุขภาพประจำสัปดาห์', 'กรุณาบันทึกพฤติกรรมสุขภาพประจำสัปดาห์ของคุณ'),
+    ('MED_LOW_SUPPLY', 'th', 'ยาใกล้หมด', 'ยา {{.medicine_name}} จะหมดประมาณวันที่ {{date .run_out_date}} (เหลืออีก {{.days_remaining}} วัน) กรุณาติดต่อรับยาเพิ่ม'),
+    ('ESCALATION_MISSED_CAREGIVER', 'th', 'ผู้ป่วยขาดยา', 'ผู้ป่วยที่คุณดูแลขาดการทานยาหลายครั้ง กรุณาติดต่อสอบถาม'),
+    ('ESCALATION_MISSED_NURSE', 'th', 'ผู้ป่วยขาดยาต่อเนื่อง', 'ผู้ป่วย {{.patient_count}} รายขาดการทานยาต่อเนื่องและอาจต้องติดตามอาการ')
+ON CONFLICT (code, locale) DO NOTHING;
+
+-- NULL follows NOTIFICATION_DEFAULT_LOCALE.
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS language VARCHAR(10),
+    ADD CONSTRAINT ck_user_preferences_language CHECK (language IN ('th', 'en'));
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_notification_events_unread;
+DROP INDEX IF EXISTS idx_notification_events_inbox;
+
+ALTER TABLE notification_events
+    DROP COLUMN IF EXISTS archived_at,
+    DROP COLUMN IF EXISTS read_at,
+    DROP COLUMN IF EXISTS body,
+    DROP COLUMN IF EXISTS title;
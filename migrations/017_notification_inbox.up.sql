@@ -0,0 +1,21 @@
+-- Sent events form the user's inbox. The title and body are stored as
+-- rendered at send time; read_at and archived_at are set by the user.
+ALTER TABLE notification_events
+    ADD COLUMN IF NOT EXISTS title TEXT,
+    ADD COLUMN IF NOT EXISTS body TEXT,
+    ADD COLUMN IF NOT EXISTS read_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+-- Notifications sent before the inbox existed start out read, so the unread
+-- badge starts at zero.
+UPDATE notification_events
+SET read_at = COALESCE(sent_at, NOW())
+WHERE status = 'SENT' AND read_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_notification_events_inbox
+    ON notification_events(user_id, sent_at DESC, id DESC)
+    WHERE status = 'SENT' AND archived_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_notification_events_unread
+    ON notification_events(user_id)
+    WHERE status = 'SENT' AND archived_at IS NULL AND read_at IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sync_changes_user_txid_id;
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_user_id_id ON sync_changes(user_id, id);
+
+ALTER TABLE sync_changes DROP COLUMN IF EXISTS txid;
@@ -0,0 +1,13 @@
+-- The change feed is read in the order transactions were assigned ids and only
+-- up to the oldest transaction still running, so a change committed after a
+-- client read past its position is never skipped. Changes recorded before
+-- this migration keep txid 0 and their id order.
+ALTER TABLE sync_changes
+    ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT '0';
+
+ALTER TABLE sync_changes
+    ALTER COLUMN txid SET DEFAULT pg_current_xact_id();
+
+DROP INDEX IF EXISTS idx_sync_changes_user_id_id;
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_user_txid_id ON sync_changes(user_id, txid, id);
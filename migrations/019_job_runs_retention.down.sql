@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_job_runs_started_at;
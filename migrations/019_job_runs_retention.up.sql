@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_job_runs_started_at ON job_runs(started_at);
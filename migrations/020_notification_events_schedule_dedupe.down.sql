@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS uq_notification_events_dedupe;
+
+-- Keep one event per user, template and time so the old key can be restored.
+DELETE FROM notification_events a
+USING notification_events b
+WHERE a.user_id = b.user_id
+    AND a.template_code = b.template_code
+    AND a.scheduled_at = b.scheduled_at
+    AND a.id > b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_notification_events_user_template_scheduled ON notification_events(user_id, template_code, scheduled_at);
+
+ALTER TABLE notification_events DROP COLUMN IF EXISTS payload_schedule_id;
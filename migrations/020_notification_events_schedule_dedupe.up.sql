@@ -0,0 +1,10 @@
+-- Reminders for different medicine schedules due at the same time are separate
+-- events; the schedule is part of the dedupe key. Events without a schedule
+-- keep deduping on user, template and time (NULLS NOT DISTINCT).
+ALTER TABLE notification_events
+    ADD COLUMN IF NOT EXISTS payload_schedule_id TEXT GENERATED ALWAYS AS (payload->>'schedule_id') STORED;
+
+DROP INDEX IF EXISTS uq_notification_events_user_template_scheduled;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_notification_events_dedupe
+    ON notification_events(user_id, template_code, scheduled_at, payload_schedule_id) NULLS NOT DISTINCT;
@@ -0,0 +1,11 @@
+-- Deleted schedules with recorded intakes cannot be removed without their
+-- history and come back as live schedules.
+DELETE FROM medicine_schedules WHERE deleted_at IS NOT NULL
+    AND NOT EXISTS (SELECT 1 FROM intake_history AS ih WHERE ih.schedule_id = medicine_schedules.id);
+
+DROP INDEX IF EXISTS idx_medicine_schedules_deleted_at;
+
+ALTER TABLE medicine_schedules
+    DROP COLUMN IF EXISTS deleted_at;
+
+DROP TABLE IF EXISTS medicine_schedule_versions;
@@ -0,0 +1,40 @@
+-- Adherence and past checklists read each day against the schedule as it stood
+-- that day. A version keeps a schedule's earlier time, recurrence and medicine
+-- status for the days up to valid_until; the schedule row itself applies from
+-- the day after its latest version.
+CREATE TABLE IF NOT EXISTS medicine_schedule_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    schedule_id UUID NOT NULL REFERENCES medicine_schedules(id) ON DELETE CASCADE,
+    time_slot TIME NOT NULL,
+    meal_timing VARCHAR(50),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    interval_days INT NOT NULL,
+    weekday_mask SMALLINT NOT NULL,
+    medicine_active BOOLEAN NOT NULL,
+    valid_until DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_medicine_schedule_versions_schedule
+    ON medicine_schedule_versions(schedule_id, valid_until);
+
+-- Deleted schedules are kept so the doses they expected before deletion still
+-- count.
+ALTER TABLE medicine_schedules
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_medicine_schedules_deleted_at
+    ON medicine_schedules(deleted_at);
+
+-- Medicines stopped before versions existed were active until the day of
+-- their last update, on the patient's calendar (NOTIFICATION_TIMEZONE's
+-- default when unset).
+INSERT INTO medicine_schedule_versions
+    (schedule_id, time_slot, meal_timing, start_date, end_date, interval_days, weekday_mask, medicine_active, valid_until)
+SELECT ms.id, ms.time_slot, ms.meal_timing, ms.start_date, ms.end_date, ms.interval_days, ms.weekday_mask, TRUE,
+    (pm.updated_at AT TIME ZONE COALESCE(up.timezone, 'Asia/Bangkok'))::date - 1
+FROM medicine_schedules AS ms
+JOIN patient_medicines AS pm ON pm.id = ms.patient_medicine_id
+LEFT JOIN user_preferences AS up ON up.user_id = pm.user_id
+WHERE pm.is_active = FALSE;